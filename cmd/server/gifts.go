@@ -0,0 +1,268 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/h4ks-com/bean-bank/internal/config"
+	"github.com/h4ks-com/bean-bank/internal/database"
+	"github.com/h4ks-com/bean-bank/internal/models"
+	"github.com/h4ks-com/bean-bank/internal/repository"
+	"github.com/h4ks-com/bean-bank/internal/services"
+	"github.com/spf13/cobra"
+)
+
+var (
+	giftsFrom      string
+	giftsLabel     string
+	giftsCount     int
+	giftsAmount    int
+	giftsMessage   string
+	giftsExpiresIn string
+	giftsFormat    string
+	giftsOutput    string
+	giftsBaseURL   string
+)
+
+var giftsCmd = &cobra.Command{
+	Use:   "gifts",
+	Short: "Manage gift link campaigns",
+	Long: `Generate and manage gift campaigns: batches of identical single-use gift
+links for events, escrowed in one transaction.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+var giftsGenerateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: "Generate a batch of gift links",
+	Long: `Generate --count single-use gift links worth --amount beans each, paid
+from the --from wallet. The total is escrowed in one transaction, so either
+every link is created or none is.
+
+The codes and URLs are written as CSV (default) or JSON to --output, or to
+stdout. URLs use --base-url, falling back to PUBLIC_URL.`,
+	Example: `  beapin gifts generate --from alice --label "Meetup #12" --count 200 --amount 5 --expires-in 7d -o sheet.csv
+  beapin gifts generate --from alice --label raffle --count 10 --amount 50 --format json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runGiftsGenerate(); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+var giftsShowCmd = &cobra.Command{
+	Use:   "show <campaign-id>",
+	Short: "Show how many links of a campaign were redeemed",
+	Long: `Print the redemption counts of a campaign. With --output, also write the
+sheet of codes, URLs and statuses.`,
+	Example: `  beapin gifts show 3 --from alice
+  beapin gifts show 3 --from alice -o status.csv`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runGiftsShow(args[0]); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+var giftsRevokeCmd = &cobra.Command{
+	Use:   "revoke <campaign-id>",
+	Short: "Revoke the unclaimed links of a campaign and refund them",
+	Long: `Deactivate every link of the campaign that has not been claimed yet and
+refund their beans to the creator in one transaction.`,
+	Example: `  beapin gifts revoke 3 --from alice`,
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runGiftsRevoke(args[0]); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	giftsCmd.PersistentFlags().StringVar(&giftsFrom, "from", "", "Username that owns the campaign (required)")
+	giftsCmd.MarkPersistentFlagRequired("from")
+
+	giftsGenerateCmd.Flags().StringVar(&giftsLabel, "label", "", "Campaign label (required)")
+	giftsGenerateCmd.Flags().IntVar(&giftsCount, "count", 0, "Number of links to generate (required)")
+	giftsGenerateCmd.Flags().IntVar(&giftsAmount, "amount", 0, "Beans per link (required)")
+	giftsGenerateCmd.Flags().StringVar(&giftsMessage, "message", "", "Message shown on every link")
+	giftsGenerateCmd.Flags().StringVar(&giftsExpiresIn, "expires-in", "", "Expiry: a duration such as 36h, 7d or 2w, an RFC 3339 timestamp, or never")
+	giftsGenerateCmd.MarkFlagRequired("label")
+	giftsGenerateCmd.MarkFlagRequired("count")
+	giftsGenerateCmd.MarkFlagRequired("amount")
+
+	for _, cmd := range []*cobra.Command{giftsGenerateCmd, giftsShowCmd} {
+		cmd.Flags().StringVar(&giftsFormat, "format", "csv", "Sheet format: csv or json")
+		cmd.Flags().StringVarP(&giftsOutput, "output", "o", "", "File to write the sheet to (default stdout for generate)")
+		cmd.Flags().StringVar(&giftsBaseURL, "base-url", "", "Base URL for gift links (default PUBLIC_URL)")
+	}
+
+	giftsCmd.AddCommand(giftsGenerateCmd)
+	giftsCmd.AddCommand(giftsShowCmd)
+	giftsCmd.AddCommand(giftsRevokeCmd)
+}
+
+func setupGiftLinkService() (*config.Config, *services.GiftLinkService, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	db, err := database.Connect(cfg.Database.URL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	if err := database.Migrate(db); err != nil {
+		return nil, nil, fmt.Errorf("failed to migrate database: %w", err)
+	}
+
+	userRepo := repository.NewUserRepository(db)
+	transactionRepo := repository.NewTransactionRepository(db)
+	giftLinkRepo := repository.NewGiftLinkRepository(db)
+	transferService := services.NewTransferService(userRepo, transactionRepo, db)
+	giftLinkService := services.NewGiftLinkService(giftLinkRepo, userRepo, transferService, db, cfg.MaxExpiry)
+
+	return cfg, giftLinkService, nil
+}
+
+func runGiftsGenerate() error {
+	if giftsFormat != "csv" && giftsFormat != "json" {
+		return fmt.Errorf("format must be csv or json")
+	}
+
+	cfg, giftLinkService, err := setupGiftLinkService()
+	if err != nil {
+		return err
+	}
+
+	campaign, err := giftLinkService.CreateGiftCampaign(services.GiftCampaignParams{
+		From:      giftsFrom,
+		Label:     giftsLabel,
+		Count:     giftsCount,
+		Amount:    giftsAmount,
+		Message:   giftsMessage,
+		ExpiresIn: giftsExpiresIn,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to generate gift links: %w", err)
+	}
+
+	log.Printf("✅ Campaign %d %q: %d links of 🫘%d (🫘%d escrowed)",
+		campaign.ID, campaign.Label, campaign.LinkCount, campaign.Amount, campaign.Amount*campaign.LinkCount)
+
+	return writeGiftSheet(campaign, giftsBaseURLOrDefault(cfg))
+}
+
+func runGiftsShow(arg string) error {
+	id, err := parseCampaignID(arg)
+	if err != nil {
+		return err
+	}
+	if giftsFormat != "csv" && giftsFormat != "json" {
+		return fmt.Errorf("format must be csv or json")
+	}
+
+	cfg, giftLinkService, err := setupGiftLinkService()
+	if err != nil {
+		return err
+	}
+
+	campaign, err := giftLinkService.GetGiftCampaign(id, giftsFrom)
+	if err != nil {
+		return err
+	}
+
+	logGiftCampaign(campaign)
+
+	if giftsOutput == "" {
+		return nil
+	}
+	return writeGiftSheet(campaign, giftsBaseURLOrDefault(cfg))
+}
+
+func runGiftsRevoke(arg string) error {
+	id, err := parseCampaignID(arg)
+	if err != nil {
+		return err
+	}
+
+	_, giftLinkService, err := setupGiftLinkService()
+	if err != nil {
+		return err
+	}
+
+	campaign, refunded, err := giftLinkService.RevokeGiftCampaign(id, giftsFrom)
+	if err != nil {
+		return fmt.Errorf("failed to revoke campaign: %w", err)
+	}
+
+	logGiftCampaign(campaign)
+	log.Printf("✅ Revoked, refunded 🫘%d to %s", refunded, giftsFrom)
+	return nil
+}
+
+func parseCampaignID(arg string) (uint, error) {
+	id, err := strconv.ParseUint(arg, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid campaign id %q", arg)
+	}
+	return uint(id), nil
+}
+
+func giftsBaseURLOrDefault(cfg *config.Config) string {
+	if giftsBaseURL != "" {
+		return strings.TrimRight(giftsBaseURL, "/")
+	}
+	if cfg.PublicURL != "" {
+		return cfg.PublicURL
+	}
+	return "http://localhost:" + cfg.Port
+}
+
+func logGiftCampaign(campaign *models.GiftCampaign) {
+	stats := campaign.Stats()
+	log.Printf("Campaign %d %q: %d links of 🫘%d", campaign.ID, campaign.Label, campaign.LinkCount, campaign.Amount)
+	log.Printf("  Redeemed: %d", stats.Redeemed)
+	log.Printf("  Open: %d", stats.Open)
+	log.Printf("  Expired: %d", stats.Expired)
+	log.Printf("  Revoked: %d", stats.Revoked)
+}
+
+// writeGiftSheet writes the campaign sheet to --output, or to stdout when no
+// file was given.
+func writeGiftSheet(campaign *models.GiftCampaign, baseURL string) error {
+	var w io.Writer = os.Stdout
+	if giftsOutput != "" {
+		file, err := os.Create(giftsOutput)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer file.Close()
+		w = file
+	}
+
+	links := services.GiftCampaignLinks(campaign, baseURL)
+	if giftsFormat == "json" {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(links); err != nil {
+			return fmt.Errorf("failed to write sheet: %w", err)
+		}
+	} else if err := services.WriteGiftCampaignCSV(w, links); err != nil {
+		return fmt.Errorf("failed to write sheet: %w", err)
+	}
+
+	if giftsOutput != "" {
+		log.Printf("Wrote %d links to %s", len(links), giftsOutput)
+	}
+	return nil
+}
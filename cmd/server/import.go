@@ -5,10 +5,12 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 	"regexp"
 
 	"github.com/h4ks-com/bean-bank/internal/config"
 	"github.com/h4ks-com/bean-bank/internal/database"
+	"github.com/h4ks-com/bean-bank/internal/models"
 	"github.com/h4ks-com/bean-bank/internal/repository"
 	"github.com/h4ks-com/bean-bank/internal/services"
 	"github.com/spf13/cobra"
@@ -20,11 +22,11 @@ type WalletImport struct {
 }
 
 var (
-	importFile       string
-	skipZero         bool
-	skipInvalid      bool
-	strictMode       bool
-	usernameRegex    = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,50}$`)
+	importFile    string
+	skipZero      bool
+	skipInvalid   bool
+	strictMode    bool
+	usernameRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,50}$`)
 )
 
 var importCmd = &cobra.Command{
@@ -89,7 +91,7 @@ func runImport() error {
 
 	userRepo := repository.NewUserRepository(db)
 	transactionRepo := repository.NewTransactionRepository(db)
-	walletService := services.NewWalletService(userRepo, transactionRepo)
+	walletService := services.NewWalletService(userRepo, transactionRepo, db)
 
 	log.Printf("Starting import of %d wallets from %s", len(wallets), importFile)
 
@@ -145,7 +147,7 @@ func validateAndImportWallet(w WalletImport, walletService *services.WalletServi
 		log.Printf("User %s already exists with balance %d, updating to %d", w.Nick, user.BeanAmount, w.Beans)
 	}
 
-	if err := walletService.UpdateBalance(w.Nick, w.Beans); err != nil {
+	if err := walletService.SetBalance(w.Nick, w.Beans, models.TransactionKindImport, fmt.Sprintf("Imported from %s", filepath.Base(importFile))); err != nil {
 		return fmt.Errorf("failed to update balance: %w", err)
 	}
 
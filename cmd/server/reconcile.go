@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/h4ks-com/bean-bank/internal/config"
+	"github.com/h4ks-com/bean-bank/internal/database"
+	"github.com/h4ks-com/bean-bank/internal/repository"
+	"github.com/h4ks-com/bean-bank/internal/services"
+	"github.com/spf13/cobra"
+)
+
+var reconcileFix bool
+
+var reconcileCmd = &cobra.Command{
+	Use:   "reconcile",
+	Short: "Check wallet balances against the ledger",
+	Long: `Recompute every wallet balance from its ledger entries and report drift.
+
+Each transaction posts a debit and a credit, so the entries of a transaction
+must sum to zero and every wallet balance must equal the sum of its entries.
+New beans are issued from the mint account, whose negative balance is the
+total supply.
+
+With --fix, accounts that have drifted get a reconciliation transaction from
+the mint so the ledger matches the stored balance again. Unbalanced
+transactions are only reported and need manual review.
+
+Every wallet is locked while the check runs, so it is safe to run against a
+live server; transfers wait until it finishes.`,
+	Example: `  beapin reconcile
+  beapin reconcile --fix`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runReconcile(); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	reconcileCmd.Flags().BoolVar(&reconcileFix, "fix", false, "Post correcting entries for accounts that have drifted")
+}
+
+func runReconcile() error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	db, err := database.Connect(cfg.Database.URL)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	if err := database.Migrate(db); err != nil {
+		return fmt.Errorf("failed to migrate database: %w", err)
+	}
+
+	userRepo := repository.NewUserRepository(db)
+	transactionRepo := repository.NewTransactionRepository(db)
+	ledgerRepo := repository.NewLedgerRepository(db)
+	ledgerService := services.NewLedgerService(userRepo, transactionRepo, ledgerRepo, db)
+
+	report, err := ledgerService.Reconcile(reconcileFix)
+	if err != nil {
+		return fmt.Errorf("reconcile failed: %w", err)
+	}
+
+	log.Printf("Checked %d accounts", report.AccountsChecked)
+	log.Printf("  Minted: 🫘%d", report.TotalMinted)
+	log.Printf("  Circulating: 🫘%d", report.TotalCirculating)
+
+	if report.UnpostedTransactions > 0 {
+		log.Printf("  ⚠️  %d transactions predate the ledger and have no entries", report.UnpostedTransactions)
+	}
+
+	for _, drift := range report.Drifts {
+		log.Printf("  ❌ %s (id %d): stored 🫘%d, ledger 🫘%d, drift %+d",
+			drift.Username, drift.UserID, drift.StoredBalance, drift.LedgerBalance, drift.Drift())
+	}
+
+	for _, id := range report.UnbalancedTransactions {
+		log.Printf("  ❌ Transaction %d is unbalanced", id)
+	}
+
+	if report.CorrectionsPosted > 0 {
+		log.Printf("  🔧 Posted %d corrections", report.CorrectionsPosted)
+	}
+
+	if report.Clean() {
+		log.Printf("✅ Ledger is balanced")
+		return nil
+	}
+
+	if reconcileFix && len(report.UnbalancedTransactions) == 0 {
+		log.Printf("✅ Drift corrected")
+		return nil
+	}
+
+	return fmt.Errorf("ledger is out of balance")
+}
@@ -14,7 +14,9 @@ var rootCmd = &cobra.Command{
 
 It provides a REST API for managing bean transactions, wallets, and harvests.
 
-Run 'beapin serve' to start the server, or 'beapin import' to import wallets.`,
+Run 'beapin serve' to start the server, 'beapin import' to import wallets,
+'beapin reconcile' to check balances against the ledger, or 'beapin gifts'
+to generate gift links for events.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		cmd.Help()
 	},
@@ -30,4 +32,6 @@ func Execute() {
 func init() {
 	rootCmd.AddCommand(serveCmd)
 	rootCmd.AddCommand(importCmd)
+	rootCmd.AddCommand(reconcileCmd)
+	rootCmd.AddCommand(giftsCmd)
 }
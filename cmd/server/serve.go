@@ -1,10 +1,12 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-contrib/sessions/cookie"
@@ -15,6 +17,7 @@ import (
 	"github.com/h4ks-com/bean-bank/internal/handlers"
 	"github.com/h4ks-com/bean-bank/internal/middleware"
 	"github.com/h4ks-com/bean-bank/internal/repository"
+	"github.com/h4ks-com/bean-bank/internal/scheduler"
 	"github.com/h4ks-com/bean-bank/internal/services"
 	"github.com/spf13/cobra"
 
@@ -61,28 +64,83 @@ func runServer() {
 	transactionRepo := repository.NewTransactionRepository(db)
 	tokenRepo := repository.NewTokenRepository(db)
 	harvestRepo := repository.NewHarvestRepository(db)
+	harvestTemplateRepo := repository.NewHarvestTemplateRepository(db)
+	harvestCommentRepo := repository.NewHarvestCommentRepository(db)
 	giftLinkRepo := repository.NewGiftLinkRepository(db)
+	idempotencyRepo := repository.NewIdempotencyRepository(db)
+	adjustmentRepo := repository.NewAdjustmentRepository(db)
+	paymentRequestRepo := repository.NewPaymentRequestRepository(db)
+	scheduledTransferRepo := repository.NewScheduledTransferRepository(db)
 
-	walletService := services.NewWalletService(userRepo, transactionRepo)
+	jobScheduler := scheduler.New(scheduler.SystemClock())
+
+	walletService := services.NewWalletService(userRepo, transactionRepo, db)
 	transferService := services.NewTransferService(userRepo, transactionRepo, db)
-	tokenService := services.NewTokenService(tokenRepo, userRepo, cfg.JWT.Secret)
-	harvestService := services.NewHarvestService(harvestRepo, userRepo, transactionRepo, db)
+	tokenService := services.NewTokenService(tokenRepo, userRepo, cfg.JWT.Secret, cfg.MaxExpiry)
+	harvestService := services.NewHarvestService(harvestRepo, userRepo, transactionRepo, db, jobScheduler.Clock())
+	bountyService := services.NewBountyService(harvestService, harvestRepo, userRepo, transactionRepo, db)
+	harvestCommentService := services.NewHarvestCommentService(harvestService, harvestRepo, harvestCommentRepo, db)
+	harvestTemplateService := services.NewHarvestTemplateService(harvestTemplateRepo, harvestService, db, jobScheduler.Clock())
 	exportService := services.NewExportService(userRepo, transactionRepo, cfg.ExportSigningKey)
-	giftLinkService := services.NewGiftLinkService(giftLinkRepo, userRepo, transferService, db)
+	giftLinkService := services.NewGiftLinkService(giftLinkRepo, userRepo, transferService, db, cfg.MaxExpiry)
+	idempotencyService := services.NewIdempotencyService(idempotencyRepo, userRepo)
+	adjustmentService := services.NewAdjustmentService(userRepo, transactionRepo, adjustmentRepo, db)
+	paymentRequestService := services.NewPaymentRequestService(paymentRequestRepo, userRepo, transferService, db, cfg.MaxExpiry)
+	scheduledTransferService := services.NewScheduledTransferService(scheduledTransferRepo, userRepo, transferService, db, jobScheduler.Clock())
+
+	jobScheduler.Register("scheduled-transfers", cfg.Scheduler.Interval, func(now time.Time) error {
+		attempted, err := scheduledTransferService.RunDue(now)
+		if attempted > 0 {
+			log.Printf("[Scheduler] Ran %d scheduled transfer(s)", attempted)
+		}
+		return err
+	})
+	jobScheduler.Register("harvest-deadlines", cfg.Scheduler.Interval, func(now time.Time) error {
+		changed, err := harvestService.ExpireOverdue(now)
+		if changed > 0 {
+			log.Printf("[Scheduler] Expired or released %d overdue harvest(s)", changed)
+		}
+		return err
+	})
+	jobScheduler.Register("harvest-templates", cfg.Scheduler.Interval, func(now time.Time) error {
+		spawned, err := harvestTemplateService.RunDue(now)
+		if spawned > 0 {
+			log.Printf("[Scheduler] Spawned %d harvest(s) from templates", spawned)
+		}
+		return err
+	})
+	jobScheduler.Register("gift-link-refunds", cfg.Scheduler.Interval, func(now time.Time) error {
+		refunded, err := giftLinkService.SweepExpired(now)
+		if refunded > 0 {
+			log.Printf("[Scheduler] Refunded %d expired gift link(s)", refunded)
+		}
+		return err
+	})
+	if cfg.Scheduler.Enabled {
+		jobScheduler.Start(context.Background())
+	}
 
 	authMiddleware := middleware.NewAuthMiddleware(tokenService, cfg.TestMode)
 	adminMiddleware := middleware.NewAdminMiddleware(cfg.AdminUsers)
+	idempotencyMiddleware := middleware.NewIdempotencyMiddleware(idempotencyService)
 	logtoHandler := auth.NewLogtoHandler(&cfg.Logto)
 
 	walletHandler := handlers.NewWalletHandler(walletService)
 	transferHandler := handlers.NewTransferHandler(transferService)
 	tokenHandler := handlers.NewTokenHandler(tokenService)
-	adminHandler := handlers.NewAdminHandler(userRepo, transactionRepo, walletService)
+	adminHandler := handlers.NewAdminHandler(userRepo, walletService, adjustmentService)
 	publicHandler := handlers.NewPublicHandler(walletService, harvestService)
 	harvestHandler := handlers.NewHarvestHandler(harvestService)
+	harvestTemplateHandler := handlers.NewHarvestTemplateHandler(harvestTemplateService)
+	bountyHandler := handlers.NewBountyHandler(bountyService)
+	harvestCommentHandler := handlers.NewHarvestCommentHandler(harvestCommentService)
 	exportHandler := handlers.NewExportHandler(exportService)
 	giftLinkHandler := handlers.NewGiftLinkHandler(giftLinkService)
-	browserHandler := handlers.NewBrowserHandler(walletService, transferService, tokenService, giftLinkService, logtoHandler)
+	paymentRequestHandler := handlers.NewPaymentRequestHandler(paymentRequestService)
+	giftCampaignHandler := handlers.NewGiftCampaignHandler(giftLinkService, cfg.PublicURL)
+	qrCodeHandler := handlers.NewQRCodeHandler(giftLinkService, paymentRequestService, cfg.PublicURL)
+	scheduledTransferHandler := handlers.NewScheduledTransferHandler(scheduledTransferService)
+	browserHandler := handlers.NewBrowserHandler(walletService, transferService, tokenService, giftLinkService, paymentRequestService, scheduledTransferService, logtoHandler, cfg.PublicURL)
 
 	router := gin.Default()
 
@@ -137,7 +195,22 @@ func runServer() {
 	})
 
 	router.GET("/gift/:code", func(c *gin.Context) {
-		c.HTML(200, "gift.html", gin.H{
+		data := gin.H{
+			"TestMode": cfg.TestMode,
+		}
+
+		preview, err := giftLinkService.PreviewGiftLink(c.Param("code"), time.Now())
+		if err != nil {
+			log.Printf("[Gift] Failed to build link preview: %v", err)
+		} else {
+			data["Preview"] = handlers.NewGiftLinkPreview(preview, handlers.PublicBaseURL(c, cfg.PublicURL))
+		}
+
+		c.HTML(200, "gift.html", data)
+	})
+
+	router.GET("/pay/:code", func(c *gin.Context) {
+		c.HTML(200, "pay.html", gin.H{
 			"TestMode": cfg.TestMode,
 		})
 	})
@@ -183,6 +256,7 @@ func runServer() {
 		from := c.Param("from")
 		to := c.Param("to")
 		amount := c.Param("amount")
+		note, noteErr := services.SanitizeNote(c.Query("note"))
 
 		isAuthenticated := cfg.TestMode
 		var currentUser string
@@ -199,12 +273,19 @@ func runServer() {
 			"FromUser": from,
 			"ToUser":   to,
 			"Amount":   amount,
+			"Note":     note,
+		}
+
+		if parsedAmount, err := strconv.Atoi(amount); err == nil && parsedAmount > 0 && noteErr == nil {
+			data["Preview"] = handlers.NewTransferLinkPreview(from, to, parsedAmount, note, handlers.PublicBaseURL(c, cfg.PublicURL))
 		}
 
 		if !isAuthenticated {
 			data["NeedsAuth"] = true
 		} else if currentUser != from {
 			data["Error"] = "You can only send beans from your own account"
+		} else if noteErr != nil {
+			data["Error"] = "Note must be at most 200 characters"
 		}
 
 		c.HTML(200, "transfer.html", data)
@@ -214,6 +295,7 @@ func runServer() {
 		from := c.Param("from")
 		to := c.Param("to")
 		amountStr := c.Param("amount")
+		note := c.PostForm("note")
 
 		amount, err := strconv.Atoi(amountStr)
 		if err != nil || amount <= 0 {
@@ -221,6 +303,7 @@ func runServer() {
 				"FromUser": from,
 				"ToUser":   to,
 				"Amount":   amountStr,
+				"Note":     note,
 				"Error":    "Invalid amount",
 			})
 			return
@@ -235,6 +318,7 @@ func runServer() {
 					"FromUser":  from,
 					"ToUser":    to,
 					"Amount":    amountStr,
+					"Note":      note,
 					"NeedsAuth": true,
 				})
 				return
@@ -248,17 +332,19 @@ func runServer() {
 				"FromUser": from,
 				"ToUser":   to,
 				"Amount":   amountStr,
+				"Note":     note,
 				"Error":    "You can only send beans from your own account",
 			})
 			return
 		}
 
-		err = transferService.Transfer(from, to, amount, true)
+		err = transferService.Transfer(from, to, amount, true, note)
 		if err != nil {
 			c.HTML(400, "transfer.html", gin.H{
 				"FromUser": from,
 				"ToUser":   to,
 				"Amount":   amountStr,
+				"Note":     note,
 				"Error":    err.Error(),
 			})
 			return
@@ -268,6 +354,7 @@ func runServer() {
 			"FromUser": from,
 			"ToUser":   to,
 			"Amount":   amountStr,
+			"Note":     note,
 			"Success":  true,
 		})
 	})
@@ -289,16 +376,45 @@ func runServer() {
 		browser.GET("/wallet", browserHandler.GetWallet)
 		browser.GET("/transactions", browserHandler.GetTransactions)
 		browser.GET("/transactions/export", exportHandler.ExportTransactions)
-		browser.POST("/transfer", browserHandler.Transfer)
+		browser.POST("/transfer", idempotencyMiddleware.Handle(), browserHandler.Transfer)
 		browser.POST("/tokens", browserHandler.CreateToken)
 		browser.GET("/tokens", browserHandler.ListTokens)
 		browser.DELETE("/tokens/:id", browserHandler.DeleteToken)
 		browser.GET("/users/search", adminHandler.SearchUsers)
 
-		browser.POST("/giftlinks", browserHandler.CreateGiftLink)
+		browser.POST("/giftlinks", idempotencyMiddleware.Handle(), browserHandler.CreateGiftLink)
 		browser.GET("/giftlinks", browserHandler.ListGiftLinks)
 		browser.DELETE("/giftlinks/:id", browserHandler.DeleteGiftLink)
-		browser.POST("/gift/redeem", browserHandler.RedeemGiftLink)
+		browser.POST("/gift/redeem", idempotencyMiddleware.Handle(), browserHandler.RedeemGiftLink)
+
+		browser.POST("/giftcampaigns", idempotencyMiddleware.Handle(), browserHandler.CreateGiftCampaign)
+		browser.GET("/giftcampaigns", browserHandler.ListGiftCampaigns)
+		browser.GET("/giftcampaigns/:id/export", browserHandler.ExportGiftCampaign)
+		browser.POST("/giftcampaigns/:id/revoke", browserHandler.RevokeGiftCampaign)
+
+		browser.POST("/paymentrequests", browserHandler.CreatePaymentRequest)
+		browser.GET("/paymentrequests", browserHandler.ListPaymentRequests)
+		browser.POST("/paymentrequests/pay", idempotencyMiddleware.Handle(), browserHandler.PayPaymentRequest)
+		browser.POST("/paymentrequests/:id/decline", browserHandler.DeclinePaymentRequest)
+		browser.POST("/paymentrequests/:id/cancel", browserHandler.CancelPaymentRequest)
+
+		browser.POST("/scheduledtransfers", idempotencyMiddleware.Handle(), browserHandler.CreateScheduledTransfer)
+		browser.GET("/scheduledtransfers", browserHandler.ListScheduledTransfers)
+		browser.GET("/scheduledtransfers/:id/runs", browserHandler.ListScheduledTransferRuns)
+		browser.DELETE("/scheduledtransfers/:id", browserHandler.CancelScheduledTransfer)
+
+		browser.POST("/harvests/:id/claim", harvestHandler.ClaimHarvest)
+		browser.POST("/harvests/:id/join", harvestHandler.JoinHarvest)
+		browser.POST("/harvests/:id/submit", harvestHandler.SubmitHarvest)
+		browser.POST("/harvests/:id/withdraw", harvestHandler.WithdrawHarvest)
+		browser.POST("/harvests", bountyHandler.CreateBounty)
+		browser.POST("/harvests/:id/fund", bountyHandler.FundBounty)
+		browser.GET("/harvests/:id/submissions", bountyHandler.ListBountySubmissions)
+		browser.POST("/harvests/:id/review", bountyHandler.ReviewBounty)
+		browser.POST("/harvests/:id/cancel", bountyHandler.CancelBounty)
+		browser.POST("/harvests/:id/comments", harvestCommentHandler.AddComment)
+		browser.PUT("/harvests/:id/comments/:commentId", harvestCommentHandler.EditComment)
+		browser.DELETE("/harvests/:id/comments/:commentId", harvestCommentHandler.DeleteComment)
 
 		browserAdmin := browser.Group("/admin")
 		if !cfg.TestMode {
@@ -310,7 +426,19 @@ func runServer() {
 			browserAdmin.PUT("/harvests/:id", harvestHandler.UpdateHarvest)
 			browserAdmin.DELETE("/harvests/:id", harvestHandler.DeleteHarvest)
 			browserAdmin.POST("/harvests/:id/assign", harvestHandler.AssignUser)
+			browserAdmin.PUT("/harvests/:id/participants", harvestHandler.SetParticipants)
 			browserAdmin.POST("/harvests/:id/complete", harvestHandler.CompleteHarvest)
+			browserAdmin.POST("/harvests/:id/revert", harvestHandler.RevertHarvest)
+			browserAdmin.GET("/harvests/:id/submissions", harvestHandler.ListSubmissions)
+			browserAdmin.POST("/harvests/:id/review", harvestHandler.ReviewHarvest)
+			browserAdmin.POST("/harvests/:id/cancel", bountyHandler.AdminCancelBounty)
+			browserAdmin.DELETE("/harvests/:id/comments/:commentId", harvestCommentHandler.RemoveComment)
+			browserAdmin.GET("/harvest-templates", harvestTemplateHandler.ListTemplates)
+			browserAdmin.POST("/harvest-templates", harvestTemplateHandler.CreateTemplate)
+			browserAdmin.PUT("/harvest-templates/:id", harvestTemplateHandler.UpdateTemplate)
+			browserAdmin.DELETE("/harvest-templates/:id", harvestTemplateHandler.DeleteTemplate)
+			browserAdmin.POST("/harvest-templates/:id/pause", harvestTemplateHandler.PauseTemplate)
+			browserAdmin.POST("/harvest-templates/:id/resume", harvestTemplateHandler.ResumeTemplate)
 		}
 	}
 
@@ -319,15 +447,24 @@ func runServer() {
 		api.GET("/total", publicHandler.GetTotalBeans)
 		api.GET("/leaderboard", publicHandler.GetLeaderboard)
 		api.GET("/harvests", publicHandler.GetHarvests)
+		api.GET("/harvests/labels", publicHandler.GetHarvestLabels)
+		api.GET("/harvests/:id/events", harvestHandler.ListEvents)
+		api.GET("/harvests/:id/timeline", harvestHandler.ListTimeline)
+		api.GET("/harvests/:id/comments", harvestCommentHandler.ListComments)
 		api.POST("/transactions/verify", exportHandler.VerifyExport)
 		api.GET("/gift/:code", giftLinkHandler.GetGiftLinkInfo)
+		api.GET("/pay/:code", paymentRequestHandler.GetPaymentRequestInfo)
+		api.GET("/gift/:code/qr", qrCodeHandler.GiftLinkQRCode)
+		api.GET("/pay/:code/qr", qrCodeHandler.PaymentRequestQRCode)
+		api.GET("/transfer/:from/:to/:amount/qr", qrCodeHandler.TransferLinkQRCode)
 
 		authenticated := api.Group("")
 		authenticated.Use(authMiddleware.RequireAuth())
 		{
 			authenticated.GET("/wallet", walletHandler.GetWallet)
 			authenticated.GET("/transactions", walletHandler.GetTransactions)
-			authenticated.POST("/transfer", transferHandler.Transfer)
+			authenticated.POST("/transfer", idempotencyMiddleware.Handle(), transferHandler.Transfer)
+			authenticated.POST("/transfer/batch", idempotencyMiddleware.Handle(), transferHandler.BatchTransfer)
 			authenticated.GET("/transactions/export", exportHandler.ExportTransactions)
 
 			authenticated.POST("/tokens", tokenHandler.CreateToken)
@@ -335,10 +472,40 @@ func runServer() {
 			authenticated.DELETE("/tokens/:id", tokenHandler.DeleteToken)
 			authenticated.GET("/users/search", adminHandler.SearchUsers)
 
-			authenticated.POST("/giftlinks", giftLinkHandler.CreateGiftLink)
+			authenticated.POST("/giftlinks", idempotencyMiddleware.Handle(), giftLinkHandler.CreateGiftLink)
 			authenticated.GET("/giftlinks", giftLinkHandler.ListGiftLinks)
 			authenticated.DELETE("/giftlinks/:id", giftLinkHandler.DeleteGiftLink)
-			authenticated.POST("/gift/redeem", giftLinkHandler.RedeemGiftLink)
+			authenticated.POST("/gift/redeem", idempotencyMiddleware.Handle(), giftLinkHandler.RedeemGiftLink)
+
+			authenticated.POST("/giftcampaigns", idempotencyMiddleware.Handle(), giftCampaignHandler.CreateGiftCampaign)
+			authenticated.GET("/giftcampaigns", giftCampaignHandler.ListGiftCampaigns)
+			authenticated.GET("/giftcampaigns/:id", giftCampaignHandler.GetGiftCampaign)
+			authenticated.GET("/giftcampaigns/:id/export", giftCampaignHandler.ExportGiftCampaign)
+			authenticated.POST("/giftcampaigns/:id/revoke", giftCampaignHandler.RevokeGiftCampaign)
+
+			authenticated.POST("/paymentrequests", paymentRequestHandler.CreatePaymentRequest)
+			authenticated.GET("/paymentrequests", paymentRequestHandler.ListPaymentRequests)
+			authenticated.POST("/paymentrequests/pay", idempotencyMiddleware.Handle(), paymentRequestHandler.PayPaymentRequest)
+			authenticated.POST("/paymentrequests/:id/decline", paymentRequestHandler.DeclinePaymentRequest)
+			authenticated.POST("/paymentrequests/:id/cancel", paymentRequestHandler.CancelPaymentRequest)
+
+			authenticated.POST("/scheduledtransfers", idempotencyMiddleware.Handle(), scheduledTransferHandler.CreateScheduledTransfer)
+			authenticated.GET("/scheduledtransfers", scheduledTransferHandler.ListScheduledTransfers)
+			authenticated.GET("/scheduledtransfers/:id/runs", scheduledTransferHandler.ListScheduledTransferRuns)
+			authenticated.DELETE("/scheduledtransfers/:id", scheduledTransferHandler.CancelScheduledTransfer)
+
+			authenticated.POST("/harvests/:id/claim", harvestHandler.ClaimHarvest)
+			authenticated.POST("/harvests/:id/join", harvestHandler.JoinHarvest)
+			authenticated.POST("/harvests/:id/submit", harvestHandler.SubmitHarvest)
+			authenticated.POST("/harvests/:id/withdraw", harvestHandler.WithdrawHarvest)
+			authenticated.POST("/harvests", bountyHandler.CreateBounty)
+			authenticated.POST("/harvests/:id/fund", bountyHandler.FundBounty)
+			authenticated.GET("/harvests/:id/submissions", bountyHandler.ListBountySubmissions)
+			authenticated.POST("/harvests/:id/review", bountyHandler.ReviewBounty)
+			authenticated.POST("/harvests/:id/cancel", bountyHandler.CancelBounty)
+			authenticated.POST("/harvests/:id/comments", harvestCommentHandler.AddComment)
+			authenticated.PUT("/harvests/:id/comments/:commentId", harvestCommentHandler.EditComment)
+			authenticated.DELETE("/harvests/:id/comments/:commentId", harvestCommentHandler.DeleteComment)
 		}
 
 		admin := api.Group("/admin")
@@ -348,13 +515,26 @@ func runServer() {
 			admin.GET("/users", adminHandler.ListUsers)
 			admin.GET("/transactions", adminHandler.ListAllTransactions)
 			admin.PUT("/wallet/:username", adminHandler.UpdateWallet)
+			admin.GET("/adjustments", adminHandler.ListAdjustments)
 
 			admin.GET("/harvests", harvestHandler.GetAllHarvests)
 			admin.POST("/harvests", harvestHandler.CreateHarvest)
 			admin.PUT("/harvests/:id", harvestHandler.UpdateHarvest)
 			admin.DELETE("/harvests/:id", harvestHandler.DeleteHarvest)
 			admin.POST("/harvests/:id/assign", harvestHandler.AssignUser)
+			admin.PUT("/harvests/:id/participants", harvestHandler.SetParticipants)
 			admin.POST("/harvests/:id/complete", harvestHandler.CompleteHarvest)
+			admin.POST("/harvests/:id/revert", harvestHandler.RevertHarvest)
+			admin.GET("/harvests/:id/submissions", harvestHandler.ListSubmissions)
+			admin.POST("/harvests/:id/review", harvestHandler.ReviewHarvest)
+			admin.POST("/harvests/:id/cancel", bountyHandler.AdminCancelBounty)
+			admin.DELETE("/harvests/:id/comments/:commentId", harvestCommentHandler.RemoveComment)
+			admin.GET("/harvest-templates", harvestTemplateHandler.ListTemplates)
+			admin.POST("/harvest-templates", harvestTemplateHandler.CreateTemplate)
+			admin.PUT("/harvest-templates/:id", harvestTemplateHandler.UpdateTemplate)
+			admin.DELETE("/harvest-templates/:id", harvestTemplateHandler.DeleteTemplate)
+			admin.POST("/harvest-templates/:id/pause", harvestTemplateHandler.PauseTemplate)
+			admin.POST("/harvest-templates/:id/resume", harvestTemplateHandler.ResumeTemplate)
 		}
 	}
 
@@ -1,20 +1,26 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"strings"
+	"time"
 
+	"github.com/h4ks-com/bean-bank/internal/duration"
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
 	Port             string
 	GinMode          string
+	PublicURL        string
 	Database         DatabaseConfig
 	Logto            LogtoConfig
 	JWT              JWTConfig
 	Session          SessionConfig
+	Scheduler        SchedulerConfig
 	ExportSigningKey string
+	MaxExpiry        time.Duration
 	AdminUsers       []string
 	TestMode         bool
 }
@@ -24,11 +30,11 @@ type DatabaseConfig struct {
 }
 
 type LogtoConfig struct {
-	Endpoint         string
-	AppID            string
-	AppSecret        string
-	RedirectURI      string
-	PostLogoutURI    string
+	Endpoint      string
+	AppID         string
+	AppSecret     string
+	RedirectURI   string
+	PostLogoutURI string
 }
 
 type JWTConfig struct {
@@ -40,6 +46,11 @@ type SessionConfig struct {
 	Secure bool
 }
 
+type SchedulerConfig struct {
+	Enabled  bool
+	Interval time.Duration
+}
+
 func Load() (*Config, error) {
 	godotenv.Load()
 
@@ -52,9 +63,23 @@ func Load() (*Config, error) {
 		}
 	}
 
+	schedulerInterval, err := time.ParseDuration(getEnv("SCHEDULER_INTERVAL", "1m"))
+	if err != nil || schedulerInterval <= 0 {
+		return nil, fmt.Errorf("invalid SCHEDULER_INTERVAL: must be a positive duration such as 30s or 1m")
+	}
+
+	var maxExpiry time.Duration
+	if maxExpiryStr := getEnv("MAX_EXPIRY", ""); maxExpiryStr != "" {
+		maxExpiry, err = duration.Parse(maxExpiryStr)
+		if err != nil || maxExpiry <= 0 {
+			return nil, fmt.Errorf("invalid MAX_EXPIRY: must be a positive duration such as 720h, 90d or 52w")
+		}
+	}
+
 	return &Config{
-		Port:    getEnv("PORT", "8080"),
-		GinMode: getEnv("GIN_MODE", "debug"),
+		Port:      getEnv("PORT", "8080"),
+		GinMode:   getEnv("GIN_MODE", "debug"),
+		PublicURL: strings.TrimRight(getEnv("PUBLIC_URL", ""), "/"),
 		Database: DatabaseConfig{
 			URL: getEnv("DATABASE_URL", ""),
 		},
@@ -72,7 +97,12 @@ func Load() (*Config, error) {
 			Secret: getEnv("SESSION_SECRET", ""),
 			Secure: getEnv("SESSION_SECURE", "false") == "true",
 		},
+		Scheduler: SchedulerConfig{
+			Enabled:  getEnv("SCHEDULER_ENABLED", "true") == "true",
+			Interval: schedulerInterval,
+		},
 		ExportSigningKey: getEnv("EXPORT_SIGNING_KEY", ""),
+		MaxExpiry:        maxExpiry,
 		AdminUsers:       adminUsers,
 		TestMode:         getEnv("TEST_MODE", "false") == "true",
 	}, nil
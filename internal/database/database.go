@@ -24,8 +24,10 @@ func Connect(databaseURL string) (*gorm.DB, error) {
 	} else if len(databaseURL) > 10 && databaseURL[:6] == "sqlite" {
 		// Strip "sqlite:" prefix for SQLite driver
 		dbPath := databaseURL[7:]
-		// Add query parameters to ensure write access
-		dbPath = dbPath + "?_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)"
+		// Add query parameters to ensure write access. Writers take the lock
+		// when the transaction begins and wait for each other instead of
+		// failing with SQLITE_BUSY.
+		dbPath = dbPath + "?_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_busy_timeout=5000&_txlock=immediate"
 		db, err = gorm.Open(sqlite.Open(dbPath), config)
 	} else {
 		db, err = gorm.Open(postgres.Open(databaseURL), config)
@@ -46,13 +48,213 @@ func Migrate(db *gorm.DB) error {
 		&models.Transaction{},
 		&models.APIToken{},
 		&models.Harvest{},
+		&models.HarvestParticipant{},
+		&models.HarvestContribution{},
+		&models.HarvestTag{},
+		&models.HarvestSubmission{},
+		&models.HarvestEvent{},
+		&models.HarvestComment{},
+		&models.HarvestTemplate{},
+		&models.GiftCampaign{},
 		&models.GiftLink{},
+		&models.GiftLinkRedemption{},
+		&models.GiftLinkShare{},
+		&models.GiftLinkRecipient{},
+		&models.IdempotencyKey{},
+		&models.LedgerEntry{},
+		&models.BalanceAdjustment{},
+		&models.PaymentRequest{},
+		&models.ScheduledTransfer{},
+		&models.ScheduledTransferRun{},
+		&models.DataMigration{},
 	)
 
 	if err != nil {
 		return fmt.Errorf("migration failed: %w", err)
 	}
 
+	if err := claimReservedAccounts(db); err != nil {
+		return fmt.Errorf("migration failed: %w", err)
+	}
+
+	if err := backfillHarvestStatus(db); err != nil {
+		return fmt.Errorf("migration failed: %w", err)
+	}
+
+	if err := runOnce(db, "ledger_opening_balances", openLedger); err != nil {
+		return fmt.Errorf("migration failed: %w", err)
+	}
+
+	if err := setupHarvestSearch(db); err != nil {
+		return fmt.Errorf("migration failed: %w", err)
+	}
+
 	log.Println("Database migrations completed successfully")
 	return nil
 }
+
+// claimReservedAccounts flags the system and mint accounts created before
+// User.Reserved existed. The system name has always been reserved, but mint
+// was an ordinary username until the ledger introduced the mint account, so
+// a mint wallet that holds beans, has API tokens or has sent or received
+// transfers belongs to a person. Rather than take over their wallet, startup
+// fails until an admin renames it.
+func claimReservedAccounts(db *gorm.DB) error {
+	err := db.Model(&models.User{}).
+		Where("username = ? AND reserved = ?", models.SystemUsername, false).
+		Update("reserved", true).Error
+	if err != nil {
+		return err
+	}
+
+	var mint models.User
+	err = db.Where("username = ? AND reserved = ?", models.MintUsername, false).Limit(1).Find(&mint).Error
+	if err != nil || mint.ID == 0 {
+		return err
+	}
+
+	var tokens, transfers int64
+	if err := db.Model(&models.APIToken{}).Where("user_id = ?", mint.ID).Count(&tokens).Error; err != nil {
+		return err
+	}
+	err = db.Model(&models.Transaction{}).
+		Where("kind = ? AND (from_user_id = ? OR to_user_id = ?)", models.TransactionKindTransfer, mint.ID, mint.ID).
+		Count(&transfers).Error
+	if err != nil {
+		return err
+	}
+	if mint.BeanAmount > 0 || tokens > 0 || transfers > 0 {
+		return fmt.Errorf("user %d is a person named %q, which is now reserved for the mint account; rename them (UPDATE users SET username = 'new-name' WHERE id = %d) and restart",
+			mint.ID, models.MintUsername, mint.ID)
+	}
+
+	return db.Model(&mint).Update("reserved", true).Error
+}
+
+// runOnce applies a one-time data migration in a transaction and records it
+// in data_migrations, skipping it if it has already run.
+func runOnce(db *gorm.DB, name string, migrate func(tx *gorm.DB) error) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var done int64
+		if err := tx.Model(&models.DataMigration{}).Where("name = ?", name).Count(&done).Error; err != nil {
+			return err
+		}
+		if done > 0 {
+			return nil
+		}
+		if err := migrate(tx); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		return tx.Create(&models.DataMigration{Name: name}).Error
+	})
+}
+
+// openLedger gives every wallet whose balance predates the ledger an
+// opening_balance transaction from the mint for the part of its balance that
+// its ledger entries don't account for, and sets the mint balance from the
+// ledger. Afterwards the ledger matches every wallet, so the first reconcile
+// only reports drift that happens from then on.
+func openLedger(tx *gorm.DB) error {
+	var sums []struct {
+		UserID uint
+		Amount int
+	}
+	err := tx.Model(&models.LedgerEntry{}).
+		Select("user_id, SUM(amount) AS amount").
+		Group("user_id").
+		Scan(&sums).Error
+	if err != nil {
+		return err
+	}
+	ledger := make(map[uint]int, len(sums))
+	for _, sum := range sums {
+		ledger[sum.UserID] = sum.Amount
+	}
+
+	var users []models.User
+	if err := tx.Order("id ASC").Find(&users).Error; err != nil {
+		return err
+	}
+
+	var mint *models.User
+	var opening []*models.User
+	for i := range users {
+		switch {
+		case users[i].Username == models.MintUsername:
+			mint = &users[i]
+		case users[i].BeanAmount != ledger[users[i].ID]:
+			opening = append(opening, &users[i])
+		}
+	}
+	if mint == nil {
+		if len(opening) == 0 {
+			return nil
+		}
+		mint = &models.User{Username: models.MintUsername, Reserved: true}
+		if err := tx.Create(mint).Error; err != nil {
+			return err
+		}
+	}
+
+	mintBalance := ledger[mint.ID]
+	for _, user := range opening {
+		delta := user.BeanAmount - ledger[user.ID]
+		mintBalance -= delta
+
+		transaction := &models.Transaction{
+			FromUserID: mint.ID,
+			ToUserID:   user.ID,
+			Amount:     delta,
+			Note:       "Opening balance",
+			Kind:       models.TransactionKindOpeningBalance,
+		}
+		// A wallet holding less than its ledger says pays the difference
+		// back to the mint.
+		if delta < 0 {
+			transaction.FromUserID, transaction.ToUserID, transaction.Amount = user.ID, mint.ID, -delta
+		}
+		if err := tx.Create(transaction).Error; err != nil {
+			return err
+		}
+
+		entries := []models.LedgerEntry{
+			{TransactionID: transaction.ID, UserID: mint.ID, Amount: -delta, BalanceAfter: mintBalance},
+			{TransactionID: transaction.ID, UserID: user.ID, Amount: delta, BalanceAfter: user.BeanAmount},
+		}
+		if err := tx.Create(&entries).Error; err != nil {
+			return err
+		}
+	}
+
+	return tx.Model(mint).Update("bean_amount", mintBalance).Error
+}
+
+// backfillHarvestStatus gives harvests created before the status column a
+// status matching their assignment and completion, and turns their assignee
+// into a participant. Harvests in the new workflow never sit open with an
+// assignee or a payout, or have an assignee without participants, so this is
+// a no-op once applied.
+func backfillHarvestStatus(db *gorm.DB) error {
+	err := db.Model(&models.Harvest{}).
+		Where("status = ? AND completed = ?", models.HarvestOpen, true).
+		Update("status", models.HarvestPaid).Error
+	if err != nil {
+		return err
+	}
+
+	err = db.Model(&models.Harvest{}).
+		Where("status = ? AND assigned_user_id IS NOT NULL", models.HarvestOpen).
+		Update("status", models.HarvestClaimed).Error
+	if err != nil {
+		return err
+	}
+
+	// Harvests assigned before participants existed get their assignee as
+	// the only participant.
+	return db.Exec(`INSERT INTO harvest_participants (created_at, updated_at, harvest_id, user_id, amount)
+		SELECT CURRENT_TIMESTAMP, CURRENT_TIMESTAMP, h.id, h.assigned_user_id, 0
+		FROM harvests h
+		WHERE h.assigned_user_id IS NOT NULL
+		AND h.deleted_at IS NULL
+		AND NOT EXISTS (SELECT 1 FROM harvest_participants p WHERE p.harvest_id = h.id)`).Error
+}
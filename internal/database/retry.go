@@ -0,0 +1,57 @@
+package database
+
+import (
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/mattn/go-sqlite3"
+	"gorm.io/gorm"
+)
+
+const (
+	maxTransactionAttempts = 5
+	baseRetryDelay         = 10 * time.Millisecond
+	maxRetryDelay          = 500 * time.Millisecond
+)
+
+// Transaction runs fn in a database transaction and retries it with bounded,
+// jittered backoff when the database aborts it because of a deadlock, a
+// serialization failure or a busy SQLite file. fn may run more than once, so
+// it must not have side effects outside the transaction.
+func Transaction(db *gorm.DB, fn func(tx *gorm.DB) error) error {
+	for attempt := 1; ; attempt++ {
+		err := db.Transaction(fn)
+		if err == nil || !IsRetryable(err) || attempt >= maxTransactionAttempts {
+			return err
+		}
+		time.Sleep(retryDelay(attempt))
+	}
+}
+
+// IsRetryable reports whether err aborted a transaction that can safely be
+// run again.
+func IsRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		// serialization_failure, deadlock_detected
+		return pgErr.Code == "40001" || pgErr.Code == "40P01"
+	}
+
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked
+	}
+
+	return false
+}
+
+func retryDelay(attempt int) time.Duration {
+	delay := baseRetryDelay << (attempt - 1)
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	half := delay / 2
+	return half + rand.N(half+1)
+}
@@ -0,0 +1,70 @@
+package database
+
+import (
+	"log"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// HarvestSearchTable is the SQLite FTS5 table indexing harvest titles and
+// descriptions. Its rowid is the harvest ID.
+const HarvestSearchTable = "harvests_fts"
+
+// setupHarvestSearch indexes harvests for ranked full-text search. On
+// Postgres that is a generated tsvector column with a GIN index; on SQLite it
+// is an external-content FTS5 table kept in sync by triggers. SQLite builds
+// without FTS5 (see the sqlite_fts5 build tag) keep searching with LIKE.
+func setupHarvestSearch(db *gorm.DB) error {
+	switch db.Dialector.Name() {
+	case "postgres":
+		return db.Transaction(func(tx *gorm.DB) error {
+			err := tx.Exec(`ALTER TABLE harvests ADD COLUMN IF NOT EXISTS search_vector tsvector
+				GENERATED ALWAYS AS (
+					setweight(to_tsvector('simple', coalesce(title, '')), 'A') ||
+					setweight(to_tsvector('simple', coalesce(description, '')), 'B')
+				) STORED`).Error
+			if err != nil {
+				return err
+			}
+			return tx.Exec("CREATE INDEX IF NOT EXISTS idx_harvests_search ON harvests USING GIN (search_vector)").Error
+		})
+	case "sqlite":
+		if db.Migrator().HasTable(HarvestSearchTable) {
+			return nil
+		}
+		return db.Transaction(func(tx *gorm.DB) error {
+			err := tx.Exec(`CREATE VIRTUAL TABLE ` + HarvestSearchTable + ` USING fts5(
+				title, description, content='harvests', content_rowid='id'
+			)`).Error
+			if err != nil {
+				if strings.Contains(err.Error(), "no such module") {
+					log.Println("SQLite was built without FTS5, harvest search falls back to LIKE")
+					return nil
+				}
+				return err
+			}
+
+			for _, stmt := range []string{
+				`CREATE TRIGGER harvests_fts_insert AFTER INSERT ON harvests BEGIN
+					INSERT INTO harvests_fts (rowid, title, description) VALUES (new.id, new.title, new.description);
+				END`,
+				`CREATE TRIGGER harvests_fts_delete AFTER DELETE ON harvests BEGIN
+					INSERT INTO harvests_fts (harvests_fts, rowid, title, description) VALUES ('delete', old.id, old.title, old.description);
+				END`,
+				`CREATE TRIGGER harvests_fts_update AFTER UPDATE OF title, description ON harvests BEGIN
+					INSERT INTO harvests_fts (harvests_fts, rowid, title, description) VALUES ('delete', old.id, old.title, old.description);
+					INSERT INTO harvests_fts (rowid, title, description) VALUES (new.id, new.title, new.description);
+				END`,
+				// Index the harvests created before the table existed.
+				`INSERT INTO harvests_fts (harvests_fts) VALUES ('rebuild')`,
+			} {
+				if err := tx.Exec(stmt).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+// Package duration parses the human-friendly durations accepted by expires_in
+// values and settings such as MAX_EXPIRY.
+package duration
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Parse extends time.ParseDuration with days (d) and weeks (w), which may be
+// mixed with the standard units as in "1w2d" or "1d12h".
+func Parse(value string) (time.Duration, error) {
+	var b strings.Builder
+	rest := value
+	if rest != "" && (rest[0] == '-' || rest[0] == '+') {
+		b.WriteByte(rest[0])
+		rest = rest[1:]
+	}
+
+	// Rewrite every d and w component in hours and leave the rest for
+	// time.ParseDuration to validate.
+	for rest != "" {
+		n := strings.IndexFunc(rest, func(r rune) bool { return r != '.' && (r < '0' || r > '9') })
+		if n < 0 {
+			b.WriteString(rest)
+			break
+		}
+		number := rest[:n]
+		rest = rest[n:]
+
+		u := strings.IndexFunc(rest, func(r rune) bool { return r == '.' || (r >= '0' && r <= '9') })
+		if u < 0 {
+			u = len(rest)
+		}
+		unit := rest[:u]
+		rest = rest[u:]
+
+		hoursPer := 0.0
+		switch unit {
+		case "d":
+			hoursPer = 24
+		case "w":
+			hoursPer = 7 * 24
+		}
+		if hoursPer == 0 || number == "" {
+			b.WriteString(number + unit)
+			continue
+		}
+
+		f, err := strconv.ParseFloat(number, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		b.WriteString(strconv.FormatFloat(f*hoursPer, 'f', -1, 64) + "h")
+	}
+
+	d, err := time.ParseDuration(b.String())
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	return d, nil
+}
@@ -0,0 +1,38 @@
+package duration
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"90m", 90 * time.Minute},
+		{"36h", 36 * time.Hour},
+		{"7d", 7 * 24 * time.Hour},
+		{"2w", 14 * 24 * time.Hour},
+		{"1w2d", 9 * 24 * time.Hour},
+		{"1.5d", 36 * time.Hour},
+		{"1d12h30m", 36*time.Hour + 30*time.Minute},
+		{"-1d", -24 * time.Hour},
+	}
+
+	for _, tc := range cases {
+		got, err := Parse(tc.value)
+		require.NoError(t, err, tc.value)
+		assert.Equal(t, tc.want, got, tc.value)
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, value := range []string{"", "never", "7", "d", "1y", "1.5.5d", "99999999999w"} {
+		_, err := Parse(value)
+		assert.Error(t, err, value)
+	}
+}
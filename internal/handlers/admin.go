@@ -2,23 +2,27 @@ package handlers
 
 import (
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/h4ks-com/bean-bank/internal/middleware"
+	"github.com/h4ks-com/bean-bank/internal/models"
 	"github.com/h4ks-com/bean-bank/internal/repository"
 	"github.com/h4ks-com/bean-bank/internal/services"
 )
 
 type AdminHandler struct {
-	userRepo        *repository.UserRepository
-	transactionRepo *repository.TransactionRepository
-	walletService   *services.WalletService
+	userRepo          *repository.UserRepository
+	walletService     *services.WalletService
+	adjustmentService *services.AdjustmentService
 }
 
-func NewAdminHandler(userRepo *repository.UserRepository, transactionRepo *repository.TransactionRepository, walletService *services.WalletService) *AdminHandler {
+func NewAdminHandler(userRepo *repository.UserRepository, walletService *services.WalletService, adjustmentService *services.AdjustmentService) *AdminHandler {
 	return &AdminHandler{
-		userRepo:        userRepo,
-		transactionRepo: transactionRepo,
-		walletService:   walletService,
+		userRepo:          userRepo,
+		walletService:     walletService,
+		adjustmentService: adjustmentService,
 	}
 }
 
@@ -29,7 +33,35 @@ type UserListResponse struct {
 }
 
 type UpdateWalletRequest struct {
-	BeanAmount int `json:"bean_amount" binding:"required,gte=0"`
+	Delta      *int   `json:"delta"`
+	BeanAmount *int   `json:"bean_amount"`
+	Reason     string `json:"reason" binding:"required"`
+}
+
+type AdjustmentResponse struct {
+	ID            uint   `json:"id"`
+	Username      string `json:"username"`
+	Actor         string `json:"actor"`
+	Delta         int    `json:"delta"`
+	BalanceBefore int    `json:"balance_before"`
+	BalanceAfter  int    `json:"balance_after"`
+	Reason        string `json:"reason"`
+	TransactionID uint   `json:"transaction_id"`
+	Timestamp     string `json:"timestamp"`
+}
+
+func toAdjustmentResponse(adjustment models.BalanceAdjustment) AdjustmentResponse {
+	return AdjustmentResponse{
+		ID:            adjustment.ID,
+		Username:      adjustment.User.Username,
+		Actor:         adjustment.ActorUsername,
+		Delta:         adjustment.Delta,
+		BalanceBefore: adjustment.BalanceBefore,
+		BalanceAfter:  adjustment.BalanceAfter,
+		Reason:        adjustment.Reason,
+		TransactionID: adjustment.TransactionID,
+		Timestamp:     adjustment.CreatedAt.Format("2006-01-02T15:04:05Z"),
+	}
 }
 
 // ListUsers godoc
@@ -65,47 +97,54 @@ func (h *AdminHandler) ListUsers(c *gin.Context) {
 
 // ListAllTransactions godoc
 // @Summary List all transactions (Admin)
-// @Description Get a list of all transactions in the system
+// @Description Get a page of all transactions in the system, newest first. Accepts the same filters as /transactions, plus username to restrict to one wallet.
 // @Tags admin
 // @Accept json
 // @Produce json
 // @Security BearerAuth
-// @Success 200 {array} TransactionHistoryResponse
+// @Param username query string false "Only transactions involving this user"
+// @Param cursor query string false "Cursor from the previous page"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param from query string false "Only transactions at or after this date (YYYY-MM-DD or RFC 3339)"
+// @Param to query string false "Only transactions up to this date, inclusive (YYYY-MM-DD or RFC 3339)"
+// @Param counterparty query string false "Only transactions with this user"
+// @Param direction query string false "in or out, relative to username"
+// @Param min_amount query int false "Minimum amount"
+// @Param max_amount query int false "Maximum amount"
+// @Param q query string false "Text to search for in notes"
+// @Param kind query string false "Comma-separated transaction kinds"
+// @Success 200 {object} TransactionPageResponse
+// @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
 // @Failure 403 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /admin/transactions [get]
 func (h *AdminHandler) ListAllTransactions(c *gin.Context) {
-	transactions, err := h.transactionRepo.FindAll()
+	filter, err := parseTransactionFilter(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
 		return
 	}
+	filter.Username = strings.TrimSpace(c.Query("username"))
 
-	response := make([]TransactionHistoryResponse, len(transactions))
-	for i, tx := range transactions {
-		response[i] = TransactionHistoryResponse{
-			ID:        tx.ID,
-			FromUser:  tx.FromUser.Username,
-			ToUser:    tx.ToUser.Username,
-			Amount:    tx.Amount,
-			Timestamp: tx.Timestamp.Format("2006-01-02T15:04:05Z"),
-		}
+	if filter.Direction != "" && filter.Username == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "direction requires username"})
+		return
 	}
 
-	c.JSON(http.StatusOK, response)
+	respondTransactionPage(c, h.walletService, filter)
 }
 
 // UpdateWallet godoc
-// @Summary Update wallet balance (Admin)
-// @Description Update a user's wallet balance directly
+// @Summary Adjust wallet balance (Admin)
+// @Description Mint or burn beans for a user. Send either a signed delta or a target bean_amount, plus a reason. The change is posted against the mint account and recorded with the acting admin.
 // @Tags admin
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param username path string true "Username"
-// @Param request body UpdateWalletRequest true "New balance"
-// @Success 200 {object} WalletResponse
+// @Param request body UpdateWalletRequest true "Adjustment"
+// @Success 200 {object} AdjustmentResponse
 // @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
 // @Failure 403 {object} ErrorResponse
@@ -121,20 +160,72 @@ func (h *AdminHandler) UpdateWallet(c *gin.Context) {
 		return
 	}
 
-	err := h.walletService.UpdateBalance(username, req.BeanAmount)
+	adjustment, err := h.adjustmentService.Adjust(services.AdjustmentParams{
+		Actor:    middleware.GetUsername(c),
+		Username: username,
+		Delta:    req.Delta,
+		Target:   req.BeanAmount,
+		Reason:   req.Reason,
+	})
 	if err != nil {
-		if err == services.ErrUserNotFound {
+		switch err {
+		case services.ErrUserNotFound:
 			c.JSON(http.StatusNotFound, ErrorResponse{Error: "user not found"})
+		case services.ErrAdjustmentAmount, services.ErrReasonRequired, services.ErrNoBalanceChange,
+			services.ErrReservedAccount, services.ErrNoteTooLong:
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		case services.ErrInvalidAmount:
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "bean_amount must not be negative"})
+		case services.ErrInsufficientBalance:
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "cannot burn more beans than the wallet holds"})
+		default:
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, toAdjustmentResponse(*adjustment))
+}
+
+// ListAdjustments godoc
+// @Summary List balance adjustments (Admin)
+// @Description Get the audit history of admin mints and burns, newest first
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param username query string false "Only adjustments to this user"
+// @Param actor query string false "Only adjustments made by this admin"
+// @Param limit query int false "Maximum number of results (default 100)"
+// @Success 200 {array} AdjustmentResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/adjustments [get]
+func (h *AdminHandler) ListAdjustments(c *gin.Context) {
+	limit := 100
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "limit must be a positive integer"})
 			return
 		}
+		limit = parsed
+	}
+
+	adjustments, err := h.adjustmentService.ListAdjustments(c.Query("username"), c.Query("actor"), limit)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, WalletResponse{
-		Username:   username,
-		BeanAmount: req.BeanAmount,
-	})
+	response := make([]AdjustmentResponse, len(adjustments))
+	for i, adjustment := range adjustments {
+		response[i] = toAdjustmentResponse(adjustment)
+	}
+
+	c.JSON(http.StatusOK, response)
 }
 
 type UserSearchResponse struct {
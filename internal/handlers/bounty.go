@@ -0,0 +1,262 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/h4ks-com/bean-bank/internal/middleware"
+	"github.com/h4ks-com/bean-bank/internal/services"
+)
+
+type BountyHandler struct {
+	bountyService *services.BountyService
+}
+
+func NewBountyHandler(bountyService *services.BountyService) *BountyHandler {
+	return &BountyHandler{bountyService: bountyService}
+}
+
+type FundBountyRequest struct {
+	Amount int `json:"amount" binding:"required,min=1"`
+}
+
+// CreateBounty godoc
+// @Summary Post a bounty
+// @Description Post a harvest funded from your own wallet. The reward is escrowed straight away and paid out when you approve the work
+// @Tags bounties
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body CreateHarvestRequest true "Bounty details"
+// @Success 201 {object} HarvestResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /harvests [post]
+func (h *BountyHandler) CreateBounty(c *gin.Context) {
+	username := middleware.GetUsername(c)
+	if username == "" {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	var req CreateHarvestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	harvest, err := h.bountyService.CreateBounty(username, services.HarvestParams{
+		Title:        req.Title,
+		Description:  req.Description,
+		BeanAmount:   req.BeanAmount,
+		Deadline:     req.Deadline,
+		ClaimTimeout: req.ClaimTimeout,
+		Category:     req.Category,
+		Tags:         req.Tags,
+	})
+	if err != nil {
+		respondBountyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, toHarvestResponse(harvest))
+}
+
+// FundBounty godoc
+// @Summary Chip in to a bounty
+// @Description Add beans from your wallet to a bounty's escrow, raising its reward
+// @Tags bounties
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Harvest ID"
+// @Param request body FundBountyRequest true "Amount to add"
+// @Success 200 {object} HarvestResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /harvests/{id}/fund [post]
+func (h *BountyHandler) FundBounty(c *gin.Context) {
+	username := middleware.GetUsername(c)
+	if username == "" {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid harvest ID"})
+		return
+	}
+
+	var req FundBountyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	harvest, err := h.bountyService.FundBounty(uint(id), username, req.Amount)
+	if err != nil {
+		respondBountyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toHarvestResponse(harvest))
+}
+
+// ListBountySubmissions godoc
+// @Summary List submissions to your bounty
+// @Description List every submission handed in for a bounty you posted, newest first
+// @Tags bounties
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Harvest ID"
+// @Success 200 {array} HarvestSubmissionResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /harvests/{id}/submissions [get]
+func (h *BountyHandler) ListBountySubmissions(c *gin.Context) {
+	username := middleware.GetUsername(c)
+	if username == "" {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid harvest ID"})
+		return
+	}
+
+	submissions, err := h.bountyService.ListBountySubmissions(uint(id), username)
+	if err != nil {
+		respondBountyError(c, err)
+		return
+	}
+
+	responses := make([]HarvestSubmissionResponse, len(submissions))
+	for i := range submissions {
+		responses[i] = toHarvestSubmissionResponse(&submissions[i], submissions[i].User.Username)
+	}
+
+	c.JSON(http.StatusOK, responses)
+}
+
+// ReviewBounty godoc
+// @Summary Review a submission to your bounty
+// @Description Approve the submission awaiting review, which pays the participants out of escrow, or reject it with a comment
+// @Tags bounties
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Harvest ID"
+// @Param request body ReviewHarvestRequest true "Review decision"
+// @Success 200 {object} HarvestResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /harvests/{id}/review [post]
+func (h *BountyHandler) ReviewBounty(c *gin.Context) {
+	username := middleware.GetUsername(c)
+	if username == "" {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid harvest ID"})
+		return
+	}
+
+	var req ReviewHarvestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid request: " + err.Error()})
+		return
+	}
+
+	harvest, err := h.bountyService.ReviewBounty(uint(id), username, req.Decision == "approve", req.Comment)
+	if err != nil {
+		respondBountyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toHarvestResponse(harvest))
+}
+
+// CancelBounty godoc
+// @Summary Cancel your bounty
+// @Description Cancel a bounty you posted before work is submitted. The escrow is refunded to every contributor in proportion to what they put in
+// @Tags bounties
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Harvest ID"
+// @Success 200 {object} HarvestResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /harvests/{id}/cancel [post]
+func (h *BountyHandler) CancelBounty(c *gin.Context) {
+	h.cancel(c, false)
+}
+
+// AdminCancelBounty godoc
+// @Summary Cancel any bounty
+// @Description Cancel a bounty before work is submitted and refund its contributors pro rata (admin only)
+// @Tags bounties
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Harvest ID"
+// @Success 200 {object} HarvestResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /admin/harvests/{id}/cancel [post]
+func (h *BountyHandler) AdminCancelBounty(c *gin.Context) {
+	h.cancel(c, true)
+}
+
+func (h *BountyHandler) cancel(c *gin.Context, asAdmin bool) {
+	username := middleware.GetUsername(c)
+	if username == "" {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid harvest ID"})
+		return
+	}
+
+	harvest, err := h.bountyService.CancelBounty(uint(id), username, asAdmin)
+	if err != nil {
+		respondBountyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toHarvestResponse(harvest))
+}
+
+func respondBountyError(c *gin.Context, err error) {
+	switch err {
+	case services.ErrInvalidAmount, services.ErrInsufficientForBounty:
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	case services.ErrNotBountyPoster, services.ErrSelfApproval:
+		c.JSON(http.StatusForbidden, ErrorResponse{Error: err.Error()})
+	case services.ErrNotBounty, services.ErrBountyNotFundable, services.ErrBountyNotCancellable, services.ErrExplicitSplitFund:
+		c.JSON(http.StatusConflict, ErrorResponse{Error: err.Error()})
+	default:
+		respondHarvestError(c, err)
+	}
+}
@@ -6,15 +6,19 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/h4ks-com/bean-bank/internal/auth"
+	"github.com/h4ks-com/bean-bank/internal/models"
 	"github.com/h4ks-com/bean-bank/internal/services"
 )
 
 type BrowserHandler struct {
-	walletService   *services.WalletService
-	transferService *services.TransferService
-	tokenService    *services.TokenService
-	giftLinkService *services.GiftLinkService
-	logtoHandler    *auth.LogtoHandler
+	walletService            *services.WalletService
+	transferService          *services.TransferService
+	tokenService             *services.TokenService
+	giftLinkService          *services.GiftLinkService
+	paymentRequestService    *services.PaymentRequestService
+	scheduledTransferService *services.ScheduledTransferService
+	logtoHandler             *auth.LogtoHandler
+	publicURL                string
 }
 
 func NewBrowserHandler(
@@ -22,14 +26,20 @@ func NewBrowserHandler(
 	transferService *services.TransferService,
 	tokenService *services.TokenService,
 	giftLinkService *services.GiftLinkService,
+	paymentRequestService *services.PaymentRequestService,
+	scheduledTransferService *services.ScheduledTransferService,
 	logtoHandler *auth.LogtoHandler,
+	publicURL string,
 ) *BrowserHandler {
 	return &BrowserHandler{
-		walletService:   walletService,
-		transferService: transferService,
-		tokenService:    tokenService,
-		giftLinkService: giftLinkService,
-		logtoHandler:    logtoHandler,
+		walletService:            walletService,
+		transferService:          transferService,
+		tokenService:             tokenService,
+		giftLinkService:          giftLinkService,
+		paymentRequestService:    paymentRequestService,
+		scheduledTransferService: scheduledTransferService,
+		logtoHandler:             logtoHandler,
+		publicURL:                publicURL,
 	}
 }
 
@@ -59,24 +69,14 @@ func (h *BrowserHandler) GetTransactions(c *gin.Context) {
 		return
 	}
 
-	transactions, err := h.walletService.GetTransactionHistory(username)
+	filter, err := parseTransactionFilter(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
 		return
 	}
+	filter.Username = username
 
-	response := make([]TransactionHistoryResponse, len(transactions))
-	for i, tx := range transactions {
-		response[i] = TransactionHistoryResponse{
-			ID:        tx.ID,
-			FromUser:  tx.FromUser.Username,
-			ToUser:    tx.ToUser.Username,
-			Amount:    tx.Amount,
-			Timestamp: tx.Timestamp.Format("2006-01-02T15:04:05Z"),
-		}
-	}
-
-	c.JSON(http.StatusOK, response)
+	respondTransactionPage(c, h.walletService, filter)
 }
 
 func (h *BrowserHandler) Transfer(c *gin.Context) {
@@ -90,6 +90,7 @@ func (h *BrowserHandler) Transfer(c *gin.Context) {
 		ToUser string `json:"to_user" binding:"required"`
 		Amount int    `json:"amount" binding:"required"`
 		Force  bool   `json:"force"`
+		Note   string `json:"note"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -102,7 +103,13 @@ func (h *BrowserHandler) Transfer(c *gin.Context) {
 		return
 	}
 
-	if err := h.transferService.Transfer(username, req.ToUser, req.Amount, req.Force); err != nil {
+	note, err := services.SanitizeNote(req.Note)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "note must be at most 200 characters"})
+		return
+	}
+
+	if err := h.transferService.Transfer(username, req.ToUser, req.Amount, req.Force, note); err != nil {
 		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
 		return
 	}
@@ -111,6 +118,7 @@ func (h *BrowserHandler) Transfer(c *gin.Context) {
 		"message": "transfer successful",
 		"to_user": req.ToUser,
 		"amount":  req.Amount,
+		"note":    note,
 	})
 }
 
@@ -127,33 +135,15 @@ func (h *BrowserHandler) CreateToken(c *gin.Context) {
 		return
 	}
 
-	var duration time.Duration
-	var err error
-
-	if req.ExpiresIn == "never" {
-		duration = 87600 * time.Hour
-	} else {
-		duration, err = time.ParseDuration(req.ExpiresIn)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid expires_in format"})
-			return
-		}
-	}
-
-	if duration <= 0 {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "expires_in must be positive"})
-		return
-	}
-
-	token, err := h.tokenService.GenerateToken(username, duration)
+	token, expiresAt, err := h.tokenService.GenerateToken(username, req.ExpiresIn)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		respondTokenError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusCreated, CreateTokenResponse{
 		Token:     token,
-		ExpiresAt: time.Now().Add(duration).Format(time.RFC3339),
+		ExpiresAt: expiresAt.Format(time.RFC3339),
 	})
 }
 
@@ -220,9 +210,13 @@ func (h *BrowserHandler) CreateGiftLink(c *gin.Context) {
 	}
 
 	var req struct {
-		Amount    int    `json:"amount" binding:"required"`
-		Message   string `json:"message"`
-		ExpiresIn string `json:"expires_in"`
+		Amount            int      `json:"amount" binding:"required"`
+		Message           string   `json:"message"`
+		ExpiresIn         string   `json:"expires_in"`
+		MaxRedemptions    int      `json:"max_redemptions"`
+		Mode              string   `json:"mode"`
+		AllowedRecipients []string `json:"allowed_recipients"`
+		Pin               string   `json:"pin"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -235,7 +229,16 @@ func (h *BrowserHandler) CreateGiftLink(c *gin.Context) {
 		return
 	}
 
-	giftLink, err := h.giftLinkService.CreateGiftLink(username, req.Amount, req.Message, req.ExpiresIn)
+	giftLink, err := h.giftLinkService.CreateGiftLink(services.GiftLinkParams{
+		From:              username,
+		Amount:            req.Amount,
+		Message:           req.Message,
+		ExpiresIn:         req.ExpiresIn,
+		MaxRedemptions:    req.MaxRedemptions,
+		Mode:              models.GiftLinkMode(req.Mode),
+		AllowedRecipients: req.AllowedRecipients,
+		Pin:               req.Pin,
+	})
 	if err != nil {
 		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
 		return
@@ -294,6 +297,7 @@ func (h *BrowserHandler) RedeemGiftLink(c *gin.Context) {
 
 	var req struct {
 		Code string `json:"code" binding:"required"`
+		Pin  string `json:"pin"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -301,7 +305,7 @@ func (h *BrowserHandler) RedeemGiftLink(c *gin.Context) {
 		return
 	}
 
-	err := h.giftLinkService.RedeemGiftLink(req.Code, username)
+	err := h.giftLinkService.RedeemGiftLink(req.Code, username, req.Pin)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
 		return
@@ -309,3 +313,230 @@ func (h *BrowserHandler) RedeemGiftLink(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "gift link redeemed successfully"})
 }
+
+func (h *BrowserHandler) CreatePaymentRequest(c *gin.Context) {
+	username, ok := h.logtoHandler.GetCurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "not authenticated"})
+		return
+	}
+
+	var req CreatePaymentRequestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid request: " + err.Error()})
+		return
+	}
+
+	request, err := h.paymentRequestService.CreatePaymentRequest(services.PaymentRequestParams{
+		Requester: username,
+		Payer:     req.Payer,
+		Amount:    req.Amount,
+		Memo:      req.Memo,
+		ExpiresIn: req.ExpiresIn,
+	})
+	if err != nil {
+		respondPaymentRequestError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapPaymentRequestToResponse(request))
+}
+
+func (h *BrowserHandler) ListPaymentRequests(c *gin.Context) {
+	username, ok := h.logtoHandler.GetCurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "not authenticated"})
+		return
+	}
+
+	respondPaymentRequestList(c, h.paymentRequestService, username)
+}
+
+func (h *BrowserHandler) PayPaymentRequest(c *gin.Context) {
+	username, ok := h.logtoHandler.GetCurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "not authenticated"})
+		return
+	}
+
+	var req PayPaymentRequestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid request: " + err.Error()})
+		return
+	}
+
+	request, err := h.paymentRequestService.PayPaymentRequest(req.Code, username)
+	if err != nil {
+		respondPaymentRequestError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapPaymentRequestToResponse(request))
+}
+
+func (h *BrowserHandler) DeclinePaymentRequest(c *gin.Context) {
+	h.resolvePaymentRequest(c, h.paymentRequestService.DeclinePaymentRequest)
+}
+
+func (h *BrowserHandler) CancelPaymentRequest(c *gin.Context) {
+	h.resolvePaymentRequest(c, h.paymentRequestService.CancelPaymentRequest)
+}
+
+func (h *BrowserHandler) resolvePaymentRequest(c *gin.Context, resolve func(uint, string) (*models.PaymentRequest, error)) {
+	username, ok := h.logtoHandler.GetCurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "not authenticated"})
+		return
+	}
+
+	var idParam struct {
+		ID uint `uri:"id" binding:"required"`
+	}
+
+	if err := c.ShouldBindUri(&idParam); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid payment request ID"})
+		return
+	}
+
+	request, err := resolve(idParam.ID, username)
+	if err != nil {
+		respondPaymentRequestError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapPaymentRequestToResponse(request))
+}
+
+func (h *BrowserHandler) CreateScheduledTransfer(c *gin.Context) {
+	username, ok := h.logtoHandler.GetCurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "not authenticated"})
+		return
+	}
+
+	var req CreateScheduledTransferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid request: " + err.Error()})
+		return
+	}
+
+	respondCreateScheduledTransfer(c, h.scheduledTransferService, username, req)
+}
+
+func (h *BrowserHandler) ListScheduledTransfers(c *gin.Context) {
+	username, ok := h.logtoHandler.GetCurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "not authenticated"})
+		return
+	}
+
+	respondScheduledTransferList(c, h.scheduledTransferService, username)
+}
+
+func (h *BrowserHandler) ListScheduledTransferRuns(c *gin.Context) {
+	username, ok := h.logtoHandler.GetCurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "not authenticated"})
+		return
+	}
+
+	var idParam struct {
+		ID uint `uri:"id" binding:"required"`
+	}
+
+	if err := c.ShouldBindUri(&idParam); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid scheduled transfer ID"})
+		return
+	}
+
+	respondScheduledTransferRuns(c, h.scheduledTransferService, idParam.ID, username)
+}
+
+func (h *BrowserHandler) CancelScheduledTransfer(c *gin.Context) {
+	username, ok := h.logtoHandler.GetCurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "not authenticated"})
+		return
+	}
+
+	var idParam struct {
+		ID uint `uri:"id" binding:"required"`
+	}
+
+	if err := c.ShouldBindUri(&idParam); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid scheduled transfer ID"})
+		return
+	}
+
+	scheduled, err := h.scheduledTransferService.CancelScheduledTransfer(idParam.ID, username)
+	if err != nil {
+		respondScheduledTransferError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapScheduledTransferToResponse(scheduled))
+}
+
+func (h *BrowserHandler) CreateGiftCampaign(c *gin.Context) {
+	username, ok := h.logtoHandler.GetCurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "not authenticated"})
+		return
+	}
+
+	var req CreateGiftCampaignRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid request: " + err.Error()})
+		return
+	}
+
+	respondCreateGiftCampaign(c, h.giftLinkService, username, req, PublicBaseURL(c, h.publicURL))
+}
+
+func (h *BrowserHandler) ListGiftCampaigns(c *gin.Context) {
+	username, ok := h.logtoHandler.GetCurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "not authenticated"})
+		return
+	}
+
+	respondGiftCampaignList(c, h.giftLinkService, username)
+}
+
+func (h *BrowserHandler) ExportGiftCampaign(c *gin.Context) {
+	username, ok := h.logtoHandler.GetCurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "not authenticated"})
+		return
+	}
+
+	var idParam struct {
+		ID uint `uri:"id" binding:"required"`
+	}
+
+	if err := c.ShouldBindUri(&idParam); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid gift campaign ID"})
+		return
+	}
+
+	respondGiftCampaignExport(c, h.giftLinkService, idParam.ID, username, PublicBaseURL(c, h.publicURL))
+}
+
+func (h *BrowserHandler) RevokeGiftCampaign(c *gin.Context) {
+	username, ok := h.logtoHandler.GetCurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "not authenticated"})
+		return
+	}
+
+	var idParam struct {
+		ID uint `uri:"id" binding:"required"`
+	}
+
+	if err := c.ShouldBindUri(&idParam); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid gift campaign ID"})
+		return
+	}
+
+	respondRevokeGiftCampaign(c, h.giftLinkService, idParam.ID, username)
+}
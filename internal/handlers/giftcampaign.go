@@ -0,0 +1,323 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/h4ks-com/bean-bank/internal/middleware"
+	"github.com/h4ks-com/bean-bank/internal/models"
+	"github.com/h4ks-com/bean-bank/internal/services"
+)
+
+type GiftCampaignHandler struct {
+	giftLinkService *services.GiftLinkService
+	publicURL       string
+}
+
+func NewGiftCampaignHandler(giftLinkService *services.GiftLinkService, publicURL string) *GiftCampaignHandler {
+	return &GiftCampaignHandler{
+		giftLinkService: giftLinkService,
+		publicURL:       publicURL,
+	}
+}
+
+type CreateGiftCampaignRequest struct {
+	Label     string `json:"label" binding:"required"`
+	Count     int    `json:"count" binding:"required,gt=0"`
+	Amount    int    `json:"amount" binding:"required,gt=0"`
+	Message   string `json:"message"`
+	ExpiresIn string `json:"expires_in"`
+}
+
+type GiftCampaignResponse struct {
+	ID          uint                        `json:"id"`
+	Label       string                      `json:"label"`
+	FromUser    string                      `json:"from_user"`
+	Amount      int                         `json:"amount"`
+	LinkCount   int                         `json:"link_count"`
+	TotalAmount int                         `json:"total_amount"`
+	Message     string                      `json:"message,omitempty"`
+	ExpiresAt   *int64                      `json:"expires_at,omitempty"`
+	RevokedAt   *int64                      `json:"revoked_at,omitempty"`
+	CreatedAt   int64                       `json:"created_at"`
+	Stats       models.GiftCampaignStats    `json:"stats"`
+	Links       []services.GiftCampaignLink `json:"links,omitempty"`
+}
+
+type RevokeGiftCampaignResponse struct {
+	Campaign GiftCampaignResponse `json:"campaign"`
+	Refunded int                  `json:"refunded"`
+}
+
+// CreateGiftCampaign godoc
+// @Summary Generate a gift campaign
+// @Description Generate count single-use gift links worth amount each under a shared label and expiry. The total is escrowed in one transaction. Use the export endpoint for a printable CSV.
+// @Tags giftcampaigns
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body CreateGiftCampaignRequest true "Gift campaign"
+// @Param Idempotency-Key header string false "Unique key that makes retries of this request safe"
+// @Success 200 {object} GiftCampaignResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /giftcampaigns [post]
+func (h *GiftCampaignHandler) CreateGiftCampaign(c *gin.Context) {
+	username := middleware.GetUsername(c)
+	if username == "" {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	var req CreateGiftCampaignRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	respondCreateGiftCampaign(c, h.giftLinkService, username, req, PublicBaseURL(c, h.publicURL))
+}
+
+// ListGiftCampaigns godoc
+// @Summary List gift campaigns
+// @Description List the authenticated user's gift campaigns with redemption counts, newest first
+// @Tags giftcampaigns
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} GiftCampaignResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /giftcampaigns [get]
+func (h *GiftCampaignHandler) ListGiftCampaigns(c *gin.Context) {
+	username := middleware.GetUsername(c)
+	if username == "" {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	respondGiftCampaignList(c, h.giftLinkService, username)
+}
+
+// GetGiftCampaign godoc
+// @Summary Get a gift campaign
+// @Description Get a campaign with redemption counts and the status of every link
+// @Tags giftcampaigns
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Gift Campaign ID"
+// @Success 200 {object} GiftCampaignResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /giftcampaigns/{id} [get]
+func (h *GiftCampaignHandler) GetGiftCampaign(c *gin.Context) {
+	username := middleware.GetUsername(c)
+	if username == "" {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid gift campaign id"})
+		return
+	}
+
+	respondGiftCampaign(c, h.giftLinkService, uint(id), username, PublicBaseURL(c, h.publicURL))
+}
+
+// ExportGiftCampaign godoc
+// @Summary Export a gift campaign sheet
+// @Description Download the campaign's codes and URLs as CSV (default) or JSON, for printing
+// @Tags giftcampaigns
+// @Produce text/csv
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Gift Campaign ID"
+// @Param format query string false "csv (default) or json"
+// @Success 200 {array} services.GiftCampaignLink
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /giftcampaigns/{id}/export [get]
+func (h *GiftCampaignHandler) ExportGiftCampaign(c *gin.Context) {
+	username := middleware.GetUsername(c)
+	if username == "" {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid gift campaign id"})
+		return
+	}
+
+	respondGiftCampaignExport(c, h.giftLinkService, uint(id), username, PublicBaseURL(c, h.publicURL))
+}
+
+// RevokeGiftCampaign godoc
+// @Summary Revoke a gift campaign
+// @Description Deactivate every unclaimed link of the campaign and refund their beans in one transaction. Claimed links are not affected.
+// @Tags giftcampaigns
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Gift Campaign ID"
+// @Success 200 {object} RevokeGiftCampaignResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /giftcampaigns/{id}/revoke [post]
+func (h *GiftCampaignHandler) RevokeGiftCampaign(c *gin.Context) {
+	username := middleware.GetUsername(c)
+	if username == "" {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid gift campaign id"})
+		return
+	}
+
+	respondRevokeGiftCampaign(c, h.giftLinkService, uint(id), username)
+}
+
+func respondCreateGiftCampaign(c *gin.Context, giftLinkService *services.GiftLinkService, username string, req CreateGiftCampaignRequest, baseURL string) {
+	campaign, err := giftLinkService.CreateGiftCampaign(services.GiftCampaignParams{
+		From:      username,
+		Label:     req.Label,
+		Count:     req.Count,
+		Amount:    req.Amount,
+		Message:   req.Message,
+		ExpiresIn: req.ExpiresIn,
+	})
+	if err != nil {
+		respondGiftCampaignError(c, err)
+		return
+	}
+
+	response := mapGiftCampaignToResponse(campaign)
+	response.Links = services.GiftCampaignLinks(campaign, baseURL)
+	c.JSON(http.StatusOK, response)
+}
+
+func respondGiftCampaignList(c *gin.Context, giftLinkService *services.GiftLinkService, username string) {
+	campaigns, err := giftLinkService.ListGiftCampaigns(username)
+	if err != nil {
+		respondGiftCampaignError(c, err)
+		return
+	}
+
+	response := make([]GiftCampaignResponse, len(campaigns))
+	for i := range campaigns {
+		response[i] = mapGiftCampaignToResponse(&campaigns[i])
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func respondGiftCampaign(c *gin.Context, giftLinkService *services.GiftLinkService, id uint, username, baseURL string) {
+	campaign, err := giftLinkService.GetGiftCampaign(id, username)
+	if err != nil {
+		respondGiftCampaignError(c, err)
+		return
+	}
+
+	response := mapGiftCampaignToResponse(campaign)
+	response.Links = services.GiftCampaignLinks(campaign, baseURL)
+	c.JSON(http.StatusOK, response)
+}
+
+func respondGiftCampaignExport(c *gin.Context, giftLinkService *services.GiftLinkService, id uint, username, baseURL string) {
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "json" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "format must be csv or json"})
+		return
+	}
+
+	campaign, err := giftLinkService.GetGiftCampaign(id, username)
+	if err != nil {
+		respondGiftCampaignError(c, err)
+		return
+	}
+
+	links := services.GiftCampaignLinks(campaign, baseURL)
+	filename := fmt.Sprintf("gift-campaign-%d.%s", campaign.ID, format)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	if format == "json" {
+		c.JSON(http.StatusOK, links)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := services.WriteGiftCampaignCSV(&buf, links); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+}
+
+func respondRevokeGiftCampaign(c *gin.Context, giftLinkService *services.GiftLinkService, id uint, username string) {
+	campaign, refunded, err := giftLinkService.RevokeGiftCampaign(id, username)
+	if err != nil {
+		respondGiftCampaignError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, RevokeGiftCampaignResponse{
+		Campaign: mapGiftCampaignToResponse(campaign),
+		Refunded: refunded,
+	})
+}
+
+func respondGiftCampaignError(c *gin.Context, err error) {
+	switch err {
+	case services.ErrInvalidAmount, services.ErrInvalidCampaignSize, services.ErrInvalidCampaignLabel,
+		services.ErrInvalidExpiry, services.ErrExpiryNotInFuture, services.ErrExpiryTooFar:
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	case services.ErrInsufficientBalanceForGift:
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "insufficient balance"})
+	case services.ErrGiftCampaignNotFound, services.ErrUserNotFound:
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+	case services.ErrGiftCampaignRevoked:
+		c.JSON(http.StatusConflict, ErrorResponse{Error: err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
+}
+
+func mapGiftCampaignToResponse(campaign *models.GiftCampaign) GiftCampaignResponse {
+	response := GiftCampaignResponse{
+		ID:          campaign.ID,
+		Label:       campaign.Label,
+		FromUser:    campaign.FromUser.Username,
+		Amount:      campaign.Amount,
+		LinkCount:   campaign.LinkCount,
+		TotalAmount: campaign.Amount * campaign.LinkCount,
+		Message:     campaign.Message,
+		CreatedAt:   campaign.CreatedAt.Unix(),
+		Stats:       campaign.Stats(),
+	}
+
+	if campaign.ExpiresAt != nil {
+		expiresAt := campaign.ExpiresAt.Unix()
+		response.ExpiresAt = &expiresAt
+	}
+
+	if campaign.RevokedAt != nil {
+		revokedAt := campaign.RevokedAt.Unix()
+		response.RevokedAt = &revokedAt
+	}
+
+	return response
+}
@@ -19,35 +19,60 @@ func NewGiftLinkHandler(giftLinkService *services.GiftLinkService) *GiftLinkHand
 }
 
 type CreateGiftLinkRequest struct {
-	Amount    int    `json:"amount" binding:"required,gt=0"`
-	Message   string `json:"message"`
-	ExpiresIn string `json:"expires_in"`
+	Amount            int      `json:"amount" binding:"required,gt=0"`
+	Message           string   `json:"message"`
+	ExpiresIn         string   `json:"expires_in"`
+	MaxRedemptions    int      `json:"max_redemptions"`
+	Mode              string   `json:"mode"`
+	AllowedRecipients []string `json:"allowed_recipients"`
+	Pin               string   `json:"pin"`
 }
 
 type GiftLinkResponse struct {
-	ID           uint   `json:"id"`
-	Code         string `json:"code"`
-	Amount       int    `json:"amount"`
-	Message      string `json:"message"`
-	ExpiresAt    *int64 `json:"expires_at,omitempty"`
-	RedeemedAt   *int64 `json:"redeemed_at,omitempty"`
-	RedeemedBy   string `json:"redeemed_by,omitempty"`
-	FromUsername string `json:"from_username"`
-	Active       bool   `json:"active"`
-	CreatedAt    int64  `json:"created_at"`
+	ID                uint                         `json:"id"`
+	Code              string                       `json:"code"`
+	Amount            int                          `json:"amount"`
+	Message           string                       `json:"message"`
+	Mode              string                       `json:"mode"`
+	ExpiresAt         *int64                       `json:"expires_at,omitempty"`
+	RedeemedAt        *int64                       `json:"redeemed_at,omitempty"`
+	RedeemedBy        string                       `json:"redeemed_by,omitempty"`
+	FromUsername      string                       `json:"from_username"`
+	CampaignID        *uint                        `json:"campaign_id,omitempty"`
+	Active            bool                         `json:"active"`
+	ExpiredAt         *int64                       `json:"expired_at,omitempty"`
+	LockedAt          *int64                       `json:"locked_at,omitempty"`
+	PinRequired       bool                         `json:"pin_required"`
+	Restricted        bool                         `json:"restricted"`
+	AllowedRecipients []string                     `json:"allowed_recipients,omitempty"`
+	CreatedAt         int64                        `json:"created_at"`
+	MaxRedemptions    int                          `json:"max_redemptions"`
+	RedemptionCount   int                          `json:"redemption_count"`
+	RemainingShares   int                          `json:"remaining_shares"`
+	Redemptions       []GiftLinkRedemptionResponse `json:"redemptions,omitempty"`
+	Shares            []int                        `json:"shares,omitempty"`
+}
+
+type GiftLinkRedemptionResponse struct {
+	Username      string `json:"username"`
+	Amount        int    `json:"amount"`
+	TransactionID uint   `json:"transaction_id"`
+	RedeemedAt    int64  `json:"redeemed_at"`
 }
 
 // CreateGiftLink godoc
 // @Summary Create a gift link
-// @Description Create a shareable gift link that escrows beans until redeemed
+// @Description Create a shareable gift link that escrows beans until redeemed. Set max_redemptions to let several users claim amount each, or mode "random" to split amount into max_redemptions random shares. allowed_recipients and pin protect the link from being claimed by others.
 // @Tags giftlinks
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param request body CreateGiftLinkRequest true "Gift link creation request"
+// @Param Idempotency-Key header string false "Unique key that makes retries of this request safe"
 // @Success 200 {object} GiftLinkResponse
 // @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /giftlinks [post]
 func (h *GiftLinkHandler) CreateGiftLink(c *gin.Context) {
@@ -63,11 +88,24 @@ func (h *GiftLinkHandler) CreateGiftLink(c *gin.Context) {
 		return
 	}
 
-	giftLink, err := h.giftLinkService.CreateGiftLink(username, req.Amount, req.Message, req.ExpiresIn)
+	giftLink, err := h.giftLinkService.CreateGiftLink(services.GiftLinkParams{
+		From:              username,
+		Amount:            req.Amount,
+		Message:           req.Message,
+		ExpiresIn:         req.ExpiresIn,
+		MaxRedemptions:    req.MaxRedemptions,
+		Mode:              models.GiftLinkMode(req.Mode),
+		AllowedRecipients: req.AllowedRecipients,
+		Pin:               req.Pin,
+	})
 	if err != nil {
 		switch err {
 		case services.ErrInvalidAmount:
 			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid amount"})
+		case services.ErrInvalidMaxRedemptions, services.ErrInvalidGiftLinkMode, services.ErrTooFewBeansForShares,
+			services.ErrTooManyGiftRecipients, services.ErrInvalidGiftPin, services.ErrReservedAccount,
+			services.ErrInvalidExpiry, services.ErrExpiryNotInFuture, services.ErrExpiryTooFar:
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
 		case services.ErrUserNotFound:
 			c.JSON(http.StatusNotFound, ErrorResponse{Error: "user not found"})
 		case services.ErrInsufficientBalanceForGift:
@@ -78,13 +116,13 @@ func (h *GiftLinkHandler) CreateGiftLink(c *gin.Context) {
 		return
 	}
 
-	response := mapGiftLinkToResponse(giftLink)
+	response := mapOwnGiftLinkToResponse(giftLink)
 	c.JSON(http.StatusOK, response)
 }
 
 // ListGiftLinks godoc
 // @Summary List user's gift links
-// @Description Get all active gift links created by the authenticated user
+// @Description Get the active and expired gift links created by the authenticated user
 // @Tags giftlinks
 // @Produce json
 // @Security BearerAuth
@@ -111,7 +149,7 @@ func (h *GiftLinkHandler) ListGiftLinks(c *gin.Context) {
 
 	response := make([]GiftLinkResponse, len(giftLinks))
 	for i, gl := range giftLinks {
-		response[i] = mapGiftLinkToResponse(&gl)
+		response[i] = mapOwnGiftLinkToResponse(&gl)
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -119,7 +157,7 @@ func (h *GiftLinkHandler) ListGiftLinks(c *gin.Context) {
 
 // DeleteGiftLink godoc
 // @Summary Delete a gift link
-// @Description Delete a gift link and refund beans if not redeemed
+// @Description Delete a gift link and refund the beans nobody has claimed yet
 // @Tags giftlinks
 // @Produce json
 // @Security BearerAuth
@@ -189,20 +227,23 @@ func (h *GiftLinkHandler) GetGiftLinkInfo(c *gin.Context) {
 
 type RedeemGiftLinkRequest struct {
 	Code string `json:"code" binding:"required"`
+	Pin  string `json:"pin"`
 }
 
 // RedeemGiftLink godoc
 // @Summary Redeem a gift link
-// @Description Redeem a gift link and transfer beans to authenticated user
+// @Description Redeem a gift link and transfer beans to authenticated user. Send pin when the link requires one.
 // @Tags giftlinks
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param request body RedeemGiftLinkRequest true "Redeem request"
+// @Param Idempotency-Key header string false "Unique key that makes retries of this request safe"
 // @Success 200 {object} map[string]string
 // @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /gift/redeem [post]
 func (h *GiftLinkHandler) RedeemGiftLink(c *gin.Context) {
@@ -218,7 +259,7 @@ func (h *GiftLinkHandler) RedeemGiftLink(c *gin.Context) {
 		return
 	}
 
-	err := h.giftLinkService.RedeemGiftLink(req.Code, username)
+	err := h.giftLinkService.RedeemGiftLink(req.Code, username, req.Pin)
 	if err != nil {
 		switch err {
 		case services.ErrGiftLinkNotFound:
@@ -227,6 +268,12 @@ func (h *GiftLinkHandler) RedeemGiftLink(c *gin.Context) {
 			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "gift link has expired"})
 		case services.ErrGiftLinkRedeemed:
 			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "gift link already redeemed"})
+		case services.ErrGiftLinkAlreadyClaimed:
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "you have already claimed this gift link"})
+		case services.ErrGiftPinRequired:
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		case services.ErrGiftLinkNotForYou, services.ErrIncorrectGiftPin, services.ErrGiftLinkLocked:
+			c.JSON(http.StatusForbidden, ErrorResponse{Error: err.Error()})
 		case services.ErrGiftLinkInactive:
 			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "gift link is inactive"})
 		case services.ErrCannotRedeemOwnLink:
@@ -240,15 +287,33 @@ func (h *GiftLinkHandler) RedeemGiftLink(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "gift link redeemed successfully"})
 }
 
+// mapOwnGiftLinkToResponse is mapGiftLinkToResponse for the link's creator,
+// who also gets to see who the link is reserved for.
+func mapOwnGiftLinkToResponse(gl *models.GiftLink) GiftLinkResponse {
+	response := mapGiftLinkToResponse(gl)
+	response.AllowedRecipients = gl.AllowedUsernames()
+	return response
+}
+
+// mapGiftLinkToResponse describes a link to anyone holding its code. Who it is
+// reserved for stays private; Restricted only says that it is.
 func mapGiftLinkToResponse(gl *models.GiftLink) GiftLinkResponse {
 	response := GiftLinkResponse{
-		ID:           gl.ID,
-		Code:         gl.Code,
-		Amount:       gl.Amount,
-		Message:      gl.Message,
-		FromUsername: gl.FromUser.Username,
-		Active:       gl.Active,
-		CreatedAt:    gl.CreatedAt.Unix(),
+		ID:              gl.ID,
+		Code:            gl.Code,
+		Amount:          gl.Amount,
+		Message:         gl.Message,
+		Mode:            string(gl.Mode),
+		FromUsername:    gl.FromUser.Username,
+		CampaignID:      gl.CampaignID,
+		Active:          gl.Active,
+		CreatedAt:       gl.CreatedAt.Unix(),
+		MaxRedemptions:  gl.MaxRedemptions,
+		RedemptionCount: gl.RedemptionCount,
+		RemainingShares: gl.RemainingRedemptions(),
+		Shares:          gl.ShareAmounts(),
+		PinRequired:     gl.HasPin(),
+		Restricted:      len(gl.AllowedRecipients) > 0,
 	}
 
 	if gl.ExpiresAt != nil {
@@ -261,9 +326,28 @@ func mapGiftLinkToResponse(gl *models.GiftLink) GiftLinkResponse {
 		response.RedeemedAt = &redeemedAt
 	}
 
+	if gl.ExpiredAt != nil {
+		expiredAt := gl.ExpiredAt.Unix()
+		response.ExpiredAt = &expiredAt
+	}
+
+	if gl.LockedAt != nil {
+		lockedAt := gl.LockedAt.Unix()
+		response.LockedAt = &lockedAt
+	}
+
 	if gl.RedeemedBy != nil {
 		response.RedeemedBy = gl.RedeemedBy.Username
 	}
 
+	for _, redemption := range gl.Redemptions {
+		response.Redemptions = append(response.Redemptions, GiftLinkRedemptionResponse{
+			Username:      redemption.User.Username,
+			Amount:        redemption.Amount,
+			TransactionID: redemption.TransactionID,
+			RedeemedAt:    redemption.CreatedAt.Unix(),
+		})
+	}
+
 	return response
 }
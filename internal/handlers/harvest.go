@@ -3,8 +3,10 @@ package handlers
 import (
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/h4ks-com/bean-bank/internal/middleware"
 	"github.com/h4ks-com/bean-bank/internal/models"
 	"github.com/h4ks-com/bean-bank/internal/services"
 )
@@ -18,15 +20,23 @@ func NewHarvestHandler(harvestService *services.HarvestService) *HarvestHandler
 }
 
 type CreateHarvestRequest struct {
-	Title       string `json:"title" binding:"required"`
-	Description string `json:"description"`
-	BeanAmount  int    `json:"bean_amount" binding:"required,min=1"`
+	Title        string   `json:"title" binding:"required"`
+	Description  string   `json:"description"`
+	BeanAmount   int      `json:"bean_amount" binding:"required,min=1"`
+	Deadline     string   `json:"deadline"`
+	ClaimTimeout string   `json:"claim_timeout"`
+	Category     string   `json:"category"`
+	Tags         []string `json:"tags"`
 }
 
 type UpdateHarvestRequest struct {
-	Title       string `json:"title" binding:"required"`
-	Description string `json:"description"`
-	BeanAmount  int    `json:"bean_amount" binding:"required,min=1"`
+	Title        string   `json:"title" binding:"required"`
+	Description  string   `json:"description"`
+	BeanAmount   int      `json:"bean_amount" binding:"required,min=1"`
+	Deadline     string   `json:"deadline"`
+	ClaimTimeout string   `json:"claim_timeout"`
+	Category     string   `json:"category"`
+	Tags         []string `json:"tags"`
 }
 
 type AssignUserRequest struct {
@@ -34,20 +44,133 @@ type AssignUserRequest struct {
 	Username string `json:"username"`
 }
 
+type HarvestParticipantRequest struct {
+	Username string `json:"username" binding:"required"`
+	Amount   int    `json:"amount"`
+}
+
+type SetHarvestParticipantsRequest struct {
+	Split        string                      `json:"split" binding:"omitempty,oneof=equal explicit"`
+	Participants []HarvestParticipantRequest `json:"participants" binding:"required,min=1,dive"`
+}
+
+type HarvestParticipantResponse struct {
+	Username string `json:"username"`
+	Share    int    `json:"share"`
+}
+
 type HarvestResponse struct {
-	ID             uint   `json:"id"`
-	Title          string `json:"title"`
-	Description    string `json:"description"`
-	BeanAmount     int    `json:"bean_amount"`
-	AssignedUserID *uint  `json:"assigned_user_id,omitempty"`
-	AssignedUser   string `json:"assigned_user,omitempty"`
-	Completed      bool   `json:"completed"`
-	CreatedAt      string `json:"created_at"`
-	UpdatedAt      string `json:"updated_at"`
+	ID             uint                         `json:"id"`
+	Title          string                       `json:"title"`
+	Description    string                       `json:"description"`
+	BeanAmount     int                          `json:"bean_amount"`
+	AssignedUserID *uint                        `json:"assigned_user_id,omitempty"`
+	AssignedUser   string                       `json:"assigned_user,omitempty"`
+	Participants   []HarvestParticipantResponse `json:"participants"`
+	SplitMode      string                       `json:"split_mode"`
+	Status         string                       `json:"status"`
+	Completed      bool                         `json:"completed"`
+	TemplateID     *uint                        `json:"template_id,omitempty"`
+	Category       string                       `json:"category,omitempty"`
+	Tags           []string                     `json:"tags"`
+	HarvestTimingResponse
+	HarvestBountyResponse
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+// HarvestBountyResponse names who posted a bounty and who funded it. Both are
+// empty for harvests created by an admin.
+type HarvestBountyResponse struct {
+	Poster        string                        `json:"poster,omitempty"`
+	Contributions []HarvestContributionResponse `json:"contributions,omitempty"`
+}
+
+type HarvestContributionResponse struct {
+	Username string `json:"username"`
+	Amount   int    `json:"amount"`
+	Refunded int    `json:"refunded,omitempty"`
+}
+
+// HarvestTimingResponse reports the deadline and claim timeout of a harvest.
+// SecondsRemaining counts down to the deadline and is zero once it has
+// passed.
+type HarvestTimingResponse struct {
+	DueAt               string `json:"due_at,omitempty"`
+	SecondsRemaining    *int64 `json:"seconds_remaining,omitempty"`
+	Overdue             bool   `json:"overdue"`
+	ClaimTimeoutSeconds int64  `json:"claim_timeout_seconds,omitempty"`
+	ClaimExpiresAt      string `json:"claim_expires_at,omitempty"`
+}
+
+type SubmitHarvestRequest struct {
+	Proof string   `json:"proof" binding:"required"`
+	Links []string `json:"links"`
+}
+
+type ReviewHarvestRequest struct {
+	Decision string `json:"decision" binding:"required,oneof=approve reject"`
+	Comment  string `json:"comment"`
+}
+
+// RevertHarvestRequest undoes a completion. Policy decides what happens when
+// a recipient has spent some of their reward and defaults to refuse.
+type RevertHarvestRequest struct {
+	Policy string `json:"policy" binding:"omitempty,oneof=refuse allow_negative partial"`
+	Reason string `json:"reason" binding:"required"`
+}
+
+type HarvestClawbackResponse struct {
+	Username  string `json:"username"`
+	Owed      int    `json:"owed"`
+	Recovered int    `json:"recovered"`
+}
+
+type RevertHarvestResponse struct {
+	Harvest   *HarvestResponse          `json:"harvest"`
+	Clawbacks []HarvestClawbackResponse `json:"clawbacks"`
+}
+
+type HarvestSubmissionResponse struct {
+	ID            uint     `json:"id"`
+	HarvestID     uint     `json:"harvest_id"`
+	Username      string   `json:"username"`
+	Proof         string   `json:"proof"`
+	Links         []string `json:"links"`
+	Status        string   `json:"status"`
+	Reviewer      string   `json:"reviewer,omitempty"`
+	ReviewComment string   `json:"review_comment,omitempty"`
+	ReviewedAt    string   `json:"reviewed_at,omitempty"`
+	CreatedAt     string   `json:"created_at"`
+}
+
+type HarvestEventResponse struct {
+	ID         uint   `json:"id"`
+	Type       string `json:"type"`
+	FromStatus string `json:"from_status,omitempty"`
+	ToStatus   string `json:"to_status,omitempty"`
+	Actor      string `json:"actor,omitempty"`
+	Note       string `json:"note,omitempty"`
+	CreatedAt  string `json:"created_at"`
+}
+
+// HarvestTimelineEntry is a status change or, when Comment is set, a comment
+// being posted or removed.
+type HarvestTimelineEntry struct {
+	HarvestEventResponse
+	Comment *HarvestCommentResponse `json:"comment,omitempty"`
+}
+
+type HarvestTimelineResponse struct {
+	Entries    []HarvestTimelineEntry `json:"entries"`
+	Total      int64                  `json:"total"`
+	Page       int                    `json:"page"`
+	Limit      int                    `json:"limit"`
+	TotalPages int                    `json:"total_pages"`
 }
 
 // @Summary Create a harvest task
-// @Description Create a new harvest task that can be assigned to users for bean rewards
+// @Description Create a new harvest task that can be assigned to users for bean rewards. The optional deadline is a duration such as 7d or an RFC 3339 timestamp; the optional claim_timeout, such as 48h, releases a claim that goes that long without a submission.
 // @Tags harvests
 // @Accept json
 // @Produce json
@@ -65,9 +188,17 @@ func (h *HarvestHandler) CreateHarvest(c *gin.Context) {
 		return
 	}
 
-	harvest, err := h.harvestService.CreateHarvest(req.Title, req.Description, req.BeanAmount)
+	harvest, err := h.harvestService.CreateHarvest(services.HarvestParams{
+		Title:        req.Title,
+		Description:  req.Description,
+		BeanAmount:   req.BeanAmount,
+		Deadline:     req.Deadline,
+		ClaimTimeout: req.ClaimTimeout,
+		Category:     req.Category,
+		Tags:         req.Tags,
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		respondHarvestError(c, err)
 		return
 	}
 
@@ -75,7 +206,7 @@ func (h *HarvestHandler) CreateHarvest(c *gin.Context) {
 }
 
 // @Summary Update a harvest task
-// @Description Update the details of an existing harvest task
+// @Description Update the details of an existing harvest task. Giving an expired harvest a new deadline, or none, reopens it.
 // @Tags harvests
 // @Accept json
 // @Produce json
@@ -101,9 +232,17 @@ func (h *HarvestHandler) UpdateHarvest(c *gin.Context) {
 		return
 	}
 
-	harvest, err := h.harvestService.UpdateHarvest(uint(id), req.Title, req.Description, req.BeanAmount)
+	harvest, err := h.harvestService.UpdateHarvest(uint(id), services.HarvestParams{
+		Title:        req.Title,
+		Description:  req.Description,
+		BeanAmount:   req.BeanAmount,
+		Deadline:     req.Deadline,
+		ClaimTimeout: req.ClaimTimeout,
+		Category:     req.Category,
+		Tags:         req.Tags,
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		respondHarvestError(c, err)
 		return
 	}
 
@@ -111,7 +250,7 @@ func (h *HarvestHandler) UpdateHarvest(c *gin.Context) {
 }
 
 // @Summary Assign user to harvest
-// @Description Assign a user to a harvest task by username or user ID
+// @Description Assign a user to a harvest task by username or user ID, replacing whoever claimed it. Harvests under review or already approved cannot be reassigned.
 // @Tags harvests
 // @Accept json
 // @Produce json
@@ -121,6 +260,8 @@ func (h *HarvestHandler) UpdateHarvest(c *gin.Context) {
 // @Success 200 {object} HarvestResponse
 // @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /admin/harvests/{id}/assign [post]
 func (h *HarvestHandler) AssignUser(c *gin.Context) {
@@ -148,7 +289,52 @@ func (h *HarvestHandler) AssignUser(c *gin.Context) {
 	}
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		respondHarvestError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toHarvestResponse(harvest))
+}
+
+// @Summary Set harvest participants
+// @Description Replace everyone working on a harvest. The first participant becomes the lead. With split "explicit" each participant's amount is their share and the amounts must add up to the reward; with "equal" (the default) the reward is divided evenly.
+// @Tags harvests
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Harvest ID"
+// @Param request body SetHarvestParticipantsRequest true "Participants and split"
+// @Success 200 {object} HarvestResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /admin/harvests/{id}/participants [put]
+func (h *HarvestHandler) SetParticipants(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid harvest ID"})
+		return
+	}
+
+	var req SetHarvestParticipantsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid request: " + err.Error()})
+		return
+	}
+
+	split := models.HarvestSplitEqual
+	if req.Split != "" {
+		split = models.HarvestSplitMode(req.Split)
+	}
+	shares := make([]services.HarvestShare, len(req.Participants))
+	for i, p := range req.Participants {
+		shares[i] = services.HarvestShare{Username: p.Username, Amount: p.Amount}
+	}
+
+	harvest, err := h.harvestService.SetParticipants(uint(id), split, shares, middleware.GetUsername(c))
+	if err != nil {
+		respondHarvestError(c, err)
 		return
 	}
 
@@ -156,7 +342,7 @@ func (h *HarvestHandler) AssignUser(c *gin.Context) {
 }
 
 // @Summary Complete a harvest task
-// @Description Mark a harvest as completed and transfer beans to the assigned user
+// @Description Mark a harvest as completed and pay every participant their share, skipping review
 // @Tags harvests
 // @Produce json
 // @Security BearerAuth
@@ -177,25 +363,64 @@ func (h *HarvestHandler) CompleteHarvest(c *gin.Context) {
 
 	harvest, err := h.harvestService.CompleteHarvest(uint(id))
 	if err != nil {
-		if err == services.ErrHarvestNotFound {
-			c.JSON(http.StatusNotFound, ErrorResponse{Error: "harvest not found"})
-			return
-		}
-		if err == services.ErrHarvestAlreadyCompleted {
-			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "harvest already completed"})
-			return
-		}
-		if err == services.ErrNoAssignedUser {
-			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "harvest has no assigned user"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		respondHarvestError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, toHarvestResponse(harvest))
 }
 
+// @Summary Revert a harvest completion
+// @Description Take a paid harvest's reward back from its recipients and reopen it with no participants. Rewards go back to the mint, or to the escrow for a bounty. The policy decides what happens when a recipient has spent part of their share: refuse (the default) fails the revert, allow_negative takes it all and leaves a negative balance, and partial takes what is left. Bounties don't allow partial.
+// @Tags harvests
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Harvest ID"
+// @Param request body RevertHarvestRequest true "Clawback policy and reason"
+// @Success 200 {object} RevertHarvestResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /admin/harvests/{id}/revert [post]
+func (h *HarvestHandler) RevertHarvest(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid harvest ID"})
+		return
+	}
+
+	var req RevertHarvestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid request: " + err.Error()})
+		return
+	}
+	policy := services.ClawbackRefuse
+	if req.Policy != "" {
+		policy = services.ClawbackPolicy(req.Policy)
+	}
+
+	harvest, clawbacks, err := h.harvestService.RevertCompletion(uint(id), middleware.GetUsername(c), policy, req.Reason)
+	if err != nil {
+		respondHarvestError(c, err)
+		return
+	}
+
+	resp := RevertHarvestResponse{
+		Harvest:   toHarvestResponse(harvest),
+		Clawbacks: make([]HarvestClawbackResponse, len(clawbacks)),
+	}
+	for i, clawback := range clawbacks {
+		resp.Clawbacks[i] = HarvestClawbackResponse{
+			Username:  clawback.Username,
+			Owed:      clawback.Owed,
+			Recovered: clawback.Recovered,
+		}
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
 // @Summary Delete a harvest task
 // @Description Delete a harvest task by ID
 // @Tags harvests
@@ -204,6 +429,8 @@ func (h *HarvestHandler) CompleteHarvest(c *gin.Context) {
 // @Success 204 "No Content"
 // @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /admin/harvests/{id} [delete]
 func (h *HarvestHandler) DeleteHarvest(c *gin.Context) {
@@ -216,7 +443,7 @@ func (h *HarvestHandler) DeleteHarvest(c *gin.Context) {
 
 	err = h.harvestService.DeleteHarvest(uint(id))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		respondHarvestError(c, err)
 		return
 	}
 
@@ -247,15 +474,366 @@ func (h *HarvestHandler) GetAllHarvests(c *gin.Context) {
 	c.JSON(http.StatusOK, responses)
 }
 
+// @Summary Claim a harvest task
+// @Description Take an open harvest task for yourself
+// @Tags harvests
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Harvest ID"
+// @Success 200 {object} HarvestResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /harvests/{id}/claim [post]
+func (h *HarvestHandler) ClaimHarvest(c *gin.Context) {
+	username := middleware.GetUsername(c)
+	if username == "" {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid harvest ID"})
+		return
+	}
+
+	harvest, err := h.harvestService.ClaimHarvest(uint(id), username)
+	if err != nil {
+		respondHarvestError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toHarvestResponse(harvest))
+}
+
+// @Summary Join a harvest task
+// @Description Work on a harvest someone else has claimed. The reward is split equally between everyone on it.
+// @Tags harvests
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Harvest ID"
+// @Success 200 {object} HarvestResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /harvests/{id}/join [post]
+func (h *HarvestHandler) JoinHarvest(c *gin.Context) {
+	username := middleware.GetUsername(c)
+	if username == "" {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid harvest ID"})
+		return
+	}
+
+	harvest, err := h.harvestService.JoinHarvest(uint(id), username)
+	if err != nil {
+		respondHarvestError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toHarvestResponse(harvest))
+}
+
+// @Summary Withdraw from a harvest task
+// @Description Stop working on a harvest. When the last participant leaves, the harvest reopens and a submission awaiting review is withdrawn. Otherwise the next participant becomes the lead and an explicit split becomes equal.
+// @Tags harvests
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Harvest ID"
+// @Success 200 {object} HarvestResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /harvests/{id}/withdraw [post]
+func (h *HarvestHandler) WithdrawHarvest(c *gin.Context) {
+	username := middleware.GetUsername(c)
+	if username == "" {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid harvest ID"})
+		return
+	}
+
+	harvest, err := h.harvestService.WithdrawHarvest(uint(id), username)
+	if err != nil {
+		respondHarvestError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toHarvestResponse(harvest))
+}
+
+// @Summary Submit a harvest task for review
+// @Description Hand in proof of the work on a harvest you claimed, optionally with up to 10 http or https links
+// @Tags harvests
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Harvest ID"
+// @Param request body SubmitHarvestRequest true "Submission"
+// @Success 201 {object} HarvestSubmissionResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /harvests/{id}/submit [post]
+func (h *HarvestHandler) SubmitHarvest(c *gin.Context) {
+	username := middleware.GetUsername(c)
+	if username == "" {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid harvest ID"})
+		return
+	}
+
+	var req SubmitHarvestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid request: " + err.Error()})
+		return
+	}
+
+	submission, err := h.harvestService.SubmitHarvest(uint(id), username, req.Proof, req.Links)
+	if err != nil {
+		respondHarvestError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, toHarvestSubmissionResponse(submission, username))
+}
+
+// @Summary Review a harvest submission
+// @Description Approve the submission awaiting review, which pays the reward, or reject it with a comment so the assignee can resubmit
+// @Tags harvests
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Harvest ID"
+// @Param request body ReviewHarvestRequest true "Review decision"
+// @Success 200 {object} HarvestResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /admin/harvests/{id}/review [post]
+func (h *HarvestHandler) ReviewHarvest(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid harvest ID"})
+		return
+	}
+
+	var req ReviewHarvestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid request: " + err.Error()})
+		return
+	}
+
+	harvest, err := h.harvestService.ReviewHarvest(uint(id), middleware.GetUsername(c), req.Decision == "approve", req.Comment)
+	if err != nil {
+		respondHarvestError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toHarvestResponse(harvest))
+}
+
+// @Summary List harvest submissions
+// @Description List every submission handed in for a harvest, newest first
+// @Tags harvests
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Harvest ID"
+// @Success 200 {array} HarvestSubmissionResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /admin/harvests/{id}/submissions [get]
+func (h *HarvestHandler) ListSubmissions(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid harvest ID"})
+		return
+	}
+
+	submissions, err := h.harvestService.ListSubmissions(uint(id))
+	if err != nil {
+		respondHarvestError(c, err)
+		return
+	}
+
+	responses := make([]HarvestSubmissionResponse, len(submissions))
+	for i := range submissions {
+		responses[i] = toHarvestSubmissionResponse(&submissions[i], submissions[i].User.Username)
+	}
+
+	c.JSON(http.StatusOK, responses)
+}
+
+// @Summary Get harvest history
+// @Description List every status change made to a harvest, oldest first. Comments are on the timeline.
+// @Tags public
+// @Produce json
+// @Param id path int true "Harvest ID"
+// @Success 200 {array} HarvestEventResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /harvests/{id}/events [get]
+func (h *HarvestHandler) ListEvents(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid harvest ID"})
+		return
+	}
+
+	events, err := h.harvestService.ListEvents(uint(id))
+	if err != nil {
+		respondHarvestError(c, err)
+		return
+	}
+
+	responses := make([]HarvestEventResponse, len(events))
+	for i := range events {
+		responses[i] = toHarvestEventResponse(&events[i])
+	}
+
+	c.JSON(http.StatusOK, responses)
+}
+
+// @Summary Get harvest timeline
+// @Description List a page of a harvest's status changes and comments, oldest first. Deleted comments stay on the timeline without their text.
+// @Tags public
+// @Produce json
+// @Param id path int true "Harvest ID"
+// @Param page query int false "Page number (default 1)"
+// @Param limit query int false "Items per page (default 20, max 100)"
+// @Success 200 {object} HarvestTimelineResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /harvests/{id}/timeline [get]
+func (h *HarvestHandler) ListTimeline(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid harvest ID"})
+		return
+	}
+
+	page, limit := parsePage(c)
+	events, total, err := h.harvestService.ListTimeline(uint(id), page, limit)
+	if err != nil {
+		respondHarvestError(c, err)
+		return
+	}
+
+	entries := make([]HarvestTimelineEntry, len(events))
+	for i := range events {
+		entries[i] = HarvestTimelineEntry{HarvestEventResponse: toHarvestEventResponse(&events[i])}
+		if events[i].Comment != nil {
+			comment := toHarvestCommentResponse(events[i].Comment)
+			entries[i].Comment = &comment
+		}
+	}
+
+	c.JSON(http.StatusOK, HarvestTimelineResponse{
+		Entries:    entries,
+		Total:      total,
+		Page:       page,
+		Limit:      limit,
+		TotalPages: pageCount(total, limit),
+	})
+}
+
+func toHarvestEventResponse(event *models.HarvestEvent) HarvestEventResponse {
+	return HarvestEventResponse{
+		ID:         event.ID,
+		Type:       string(event.Type),
+		FromStatus: string(event.FromStatus),
+		ToStatus:   string(event.ToStatus),
+		Actor:      event.Actor,
+		Note:       event.Note,
+		CreatedAt:  event.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
+}
+
+func respondHarvestError(c *gin.Context, err error) {
+	switch err {
+	case services.ErrInvalidHarvestProof, services.ErrInvalidHarvestLinks, services.ErrReviewCommentRequired,
+		services.ErrReservedAccount, services.ErrNoAssignedUser, services.ErrHarvestAlreadyCompleted,
+		services.ErrInvalidParticipants, services.ErrInvalidSplit, services.ErrSplitMismatch, services.ErrRewardTooSmall,
+		services.ErrInvalidDeadline, services.ErrInvalidClaimTimeout, services.ErrBountyRewardFixed,
+		services.ErrInvalidHarvestCategory, services.ErrInvalidHarvestTags, services.ErrInvalidClawbackPolicy,
+		services.ErrBountyPartialClawback, services.ErrReasonRequired, services.ErrNoteTooLong:
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	case services.ErrNotHarvestAssignee, services.ErrOwnBounty:
+		c.JSON(http.StatusForbidden, ErrorResponse{Error: err.Error()})
+	case services.ErrHarvestNotFound, services.ErrUserNotFound:
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+	case services.ErrHarvestNotOpen, services.ErrHarvestNotAssignable,
+		services.ErrHarvestNotSubmittable, services.ErrHarvestNotSubmitted, services.ErrHarvestNotWithdrawable,
+		services.ErrHarvestNotJoinable, services.ErrAlreadyParticipant, services.ErrExplicitSplitJoin,
+		services.ErrBountyEscrowHeld, services.ErrHarvestNotCompleted, services.ErrClawbackShortfall,
+		services.ErrNoPayoutsOnRecord, services.ErrPayoutRecipientGone:
+		c.JSON(http.StatusConflict, ErrorResponse{Error: err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
+}
+
+func toHarvestSubmissionResponse(submission *models.HarvestSubmission, username string) HarvestSubmissionResponse {
+	resp := HarvestSubmissionResponse{
+		ID:            submission.ID,
+		HarvestID:     submission.HarvestID,
+		Username:      username,
+		Proof:         submission.Proof,
+		Links:         submission.LinkList(),
+		Status:        string(submission.Status),
+		Reviewer:      submission.Reviewer,
+		ReviewComment: submission.ReviewComment,
+		CreatedAt:     submission.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
+	if submission.ReviewedAt != nil {
+		resp.ReviewedAt = submission.ReviewedAt.Format("2006-01-02 15:04:05")
+	}
+	return resp
+}
+
 func toHarvestResponse(harvest *models.Harvest) *HarvestResponse {
 	resp := &HarvestResponse{
-		ID:          harvest.ID,
-		Title:       harvest.Title,
-		Description: harvest.Description,
-		BeanAmount:  harvest.BeanAmount,
-		Completed:   harvest.Completed,
-		CreatedAt:   harvest.CreatedAt.Format("2006-01-02 15:04:05"),
-		UpdatedAt:   harvest.UpdatedAt.Format("2006-01-02 15:04:05"),
+		ID:                    harvest.ID,
+		Title:                 harvest.Title,
+		Description:           harvest.Description,
+		BeanAmount:            harvest.BeanAmount,
+		Participants:          toHarvestParticipants(harvest),
+		SplitMode:             string(harvest.SplitMode),
+		Status:                string(harvest.Status),
+		Completed:             harvest.Completed,
+		TemplateID:            harvest.TemplateID,
+		Category:              harvest.Category,
+		Tags:                  harvest.TagNames(),
+		HarvestTimingResponse: toHarvestTiming(harvest, time.Now()),
+		HarvestBountyResponse: toHarvestBounty(harvest),
+		CreatedAt:             harvest.CreatedAt.Format("2006-01-02 15:04:05"),
+		UpdatedAt:             harvest.UpdatedAt.Format("2006-01-02 15:04:05"),
 	}
 
 	if harvest.AssignedUserID != nil {
@@ -267,3 +845,48 @@ func toHarvestResponse(harvest *models.Harvest) *HarvestResponse {
 
 	return resp
 }
+
+func toHarvestParticipants(harvest *models.Harvest) []HarvestParticipantResponse {
+	shares := harvest.Shares()
+	participants := make([]HarvestParticipantResponse, len(harvest.Participants))
+	for i, p := range harvest.Participants {
+		participants[i] = HarvestParticipantResponse{Username: p.User.Username, Share: shares[i]}
+	}
+	return participants
+}
+
+func toHarvestTiming(harvest *models.Harvest, now time.Time) HarvestTimingResponse {
+	timing := HarvestTimingResponse{
+		Overdue:             harvest.Overdue(now),
+		ClaimTimeoutSeconds: harvest.ClaimTimeoutSeconds,
+	}
+	if harvest.DueAt != nil {
+		timing.DueAt = harvest.DueAt.Format(time.RFC3339)
+		if !harvest.Completed {
+			remaining := int64(harvest.DueAt.Sub(now) / time.Second)
+			if remaining < 0 {
+				remaining = 0
+			}
+			timing.SecondsRemaining = &remaining
+		}
+	}
+	if harvest.ClaimExpiresAt != nil {
+		timing.ClaimExpiresAt = harvest.ClaimExpiresAt.Format(time.RFC3339)
+	}
+	return timing
+}
+
+func toHarvestBounty(harvest *models.Harvest) HarvestBountyResponse {
+	var bounty HarvestBountyResponse
+	if harvest.Poster != nil {
+		bounty.Poster = harvest.Poster.Username
+	}
+	for _, c := range harvest.Contributions {
+		bounty.Contributions = append(bounty.Contributions, HarvestContributionResponse{
+			Username: c.User.Username,
+			Amount:   c.Amount,
+			Refunded: c.Refunded,
+		})
+	}
+	return bounty
+}
@@ -0,0 +1,287 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/h4ks-com/bean-bank/internal/middleware"
+	"github.com/h4ks-com/bean-bank/internal/models"
+	"github.com/h4ks-com/bean-bank/internal/services"
+)
+
+type HarvestCommentHandler struct {
+	commentService *services.HarvestCommentService
+}
+
+func NewHarvestCommentHandler(commentService *services.HarvestCommentService) *HarvestCommentHandler {
+	return &HarvestCommentHandler{commentService: commentService}
+}
+
+type HarvestCommentRequest struct {
+	Body string `json:"body" binding:"required"`
+}
+
+// HarvestCommentResponse is a comment on a harvest. A deleted comment keeps
+// its author but loses its body; RemovedByModerator and RemovalReason are set
+// when an admin took it down.
+type HarvestCommentResponse struct {
+	ID                 uint   `json:"id"`
+	HarvestID          uint   `json:"harvest_id"`
+	Username           string `json:"username"`
+	Body               string `json:"body,omitempty"`
+	Deleted            bool   `json:"deleted,omitempty"`
+	RemovedByModerator bool   `json:"removed_by_moderator,omitempty"`
+	RemovalReason      string `json:"removal_reason,omitempty"`
+	CreatedAt          string `json:"created_at"`
+	EditedAt           string `json:"edited_at,omitempty"`
+}
+
+type HarvestCommentListResponse struct {
+	Comments   []HarvestCommentResponse `json:"comments"`
+	Total      int64                    `json:"total"`
+	Page       int                      `json:"page"`
+	Limit      int                      `json:"limit"`
+	TotalPages int                      `json:"total_pages"`
+}
+
+// ListComments godoc
+// @Summary List harvest comments
+// @Description List a page of the comments on a harvest, oldest first
+// @Tags public
+// @Produce json
+// @Param id path int true "Harvest ID"
+// @Param page query int false "Page number (default 1)"
+// @Param limit query int false "Items per page (default 20, max 100)"
+// @Success 200 {object} HarvestCommentListResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /harvests/{id}/comments [get]
+func (h *HarvestCommentHandler) ListComments(c *gin.Context) {
+	harvestID, ok := parseHarvestID(c)
+	if !ok {
+		return
+	}
+
+	page, limit := parsePage(c)
+	comments, total, err := h.commentService.ListComments(harvestID, page, limit)
+	if err != nil {
+		respondHarvestCommentError(c, err)
+		return
+	}
+
+	responses := make([]HarvestCommentResponse, len(comments))
+	for i := range comments {
+		responses[i] = toHarvestCommentResponse(&comments[i])
+	}
+
+	c.JSON(http.StatusOK, HarvestCommentListResponse{
+		Comments:   responses,
+		Total:      total,
+		Page:       page,
+		Limit:      limit,
+		TotalPages: pageCount(total, limit),
+	})
+}
+
+// AddComment godoc
+// @Summary Comment on a harvest
+// @Description Post a comment on a harvest. It also appears on the harvest's timeline
+// @Tags harvests
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Harvest ID"
+// @Param request body HarvestCommentRequest true "Comment text, up to 2000 characters"
+// @Success 201 {object} HarvestCommentResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /harvests/{id}/comments [post]
+func (h *HarvestCommentHandler) AddComment(c *gin.Context) {
+	username := middleware.GetUsername(c)
+	if username == "" {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	harvestID, ok := parseHarvestID(c)
+	if !ok {
+		return
+	}
+
+	var req HarvestCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	comment, err := h.commentService.AddComment(harvestID, username, req.Body)
+	if err != nil {
+		respondHarvestCommentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, toHarvestCommentResponse(comment))
+}
+
+// EditComment godoc
+// @Summary Edit your comment
+// @Description Replace the text of one of your own comments on a harvest
+// @Tags harvests
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Harvest ID"
+// @Param commentId path int true "Comment ID"
+// @Param request body HarvestCommentRequest true "New comment text"
+// @Success 200 {object} HarvestCommentResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /harvests/{id}/comments/{commentId} [put]
+func (h *HarvestCommentHandler) EditComment(c *gin.Context) {
+	username := middleware.GetUsername(c)
+	if username == "" {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	harvestID, commentID, ok := parseHarvestCommentID(c)
+	if !ok {
+		return
+	}
+
+	var req HarvestCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	comment, err := h.commentService.EditComment(harvestID, commentID, username, req.Body)
+	if err != nil {
+		respondHarvestCommentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toHarvestCommentResponse(comment))
+}
+
+// DeleteComment godoc
+// @Summary Delete your comment
+// @Description Delete one of your own comments on a harvest. The timeline notes that it was deleted
+// @Tags harvests
+// @Security BearerAuth
+// @Param id path int true "Harvest ID"
+// @Param commentId path int true "Comment ID"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /harvests/{id}/comments/{commentId} [delete]
+func (h *HarvestCommentHandler) DeleteComment(c *gin.Context) {
+	username := middleware.GetUsername(c)
+	if username == "" {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	harvestID, commentID, ok := parseHarvestCommentID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.commentService.DeleteComment(harvestID, commentID, username); err != nil {
+		respondHarvestCommentError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// RemoveComment godoc
+// @Summary Remove a comment
+// @Description Take down any comment on a harvest (admin only). The removal and its reason appear on the timeline
+// @Tags harvests
+// @Security BearerAuth
+// @Param id path int true "Harvest ID"
+// @Param commentId path int true "Comment ID"
+// @Param reason query string false "Why the comment was removed"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /admin/harvests/{id}/comments/{commentId} [delete]
+func (h *HarvestCommentHandler) RemoveComment(c *gin.Context) {
+	harvestID, commentID, ok := parseHarvestCommentID(c)
+	if !ok {
+		return
+	}
+
+	err := h.commentService.RemoveComment(harvestID, commentID, middleware.GetUsername(c), c.Query("reason"))
+	if err != nil {
+		respondHarvestCommentError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func parseHarvestID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid harvest ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func parseHarvestCommentID(c *gin.Context) (uint, uint, bool) {
+	harvestID, ok := parseHarvestID(c)
+	if !ok {
+		return 0, 0, false
+	}
+	commentID, err := strconv.ParseUint(c.Param("commentId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid comment ID"})
+		return 0, 0, false
+	}
+	return harvestID, uint(commentID), true
+}
+
+func respondHarvestCommentError(c *gin.Context, err error) {
+	switch err {
+	case services.ErrInvalidHarvestComment:
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	case services.ErrNotCommentAuthor:
+		c.JSON(http.StatusForbidden, ErrorResponse{Error: err.Error()})
+	case services.ErrHarvestCommentNotFound:
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+	default:
+		respondHarvestError(c, err)
+	}
+}
+
+func toHarvestCommentResponse(comment *models.HarvestComment) HarvestCommentResponse {
+	resp := HarvestCommentResponse{
+		ID:        comment.ID,
+		HarvestID: comment.HarvestID,
+		Username:  comment.User.Username,
+		CreatedAt: comment.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
+	if comment.EditedAt != nil {
+		resp.EditedAt = comment.EditedAt.Format("2006-01-02 15:04:05")
+	}
+
+	if comment.DeletedAt.Valid {
+		resp.Deleted = true
+		resp.RemovedByModerator = comment.RemovedBy != ""
+		resp.RemovalReason = comment.RemovalReason
+		return resp
+	}
+	resp.Body = comment.Body
+	return resp
+}
@@ -0,0 +1,290 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/h4ks-com/bean-bank/internal/models"
+	"github.com/h4ks-com/bean-bank/internal/services"
+)
+
+type HarvestTemplateHandler struct {
+	templateService *services.HarvestTemplateService
+}
+
+func NewHarvestTemplateHandler(templateService *services.HarvestTemplateService) *HarvestTemplateHandler {
+	return &HarvestTemplateHandler{templateService: templateService}
+}
+
+type HarvestTemplateRequest struct {
+	Title        string     `json:"title" binding:"required"`
+	Description  string     `json:"description"`
+	BeanAmount   int        `json:"bean_amount" binding:"required,min=1"`
+	Deadline     string     `json:"deadline"`
+	ClaimTimeout string     `json:"claim_timeout"`
+	Category     string     `json:"category"`
+	Tags         []string   `json:"tags"`
+	Assignee     string     `json:"assignee"`
+	Frequency    string     `json:"frequency" binding:"required"`
+	Cron         string     `json:"cron"`
+	StartAt      *time.Time `json:"start_at"`
+	EndsAt       *time.Time `json:"ends_at"`
+}
+
+type HarvestTemplateResponse struct {
+	ID                  uint       `json:"id"`
+	Title               string     `json:"title"`
+	Description         string     `json:"description"`
+	BeanAmount          int        `json:"bean_amount"`
+	DeadlineSeconds     int64      `json:"deadline_seconds,omitempty"`
+	ClaimTimeoutSeconds int64      `json:"claim_timeout_seconds,omitempty"`
+	Category            string     `json:"category,omitempty"`
+	Tags                []string   `json:"tags"`
+	Assignee            string     `json:"assignee,omitempty"`
+	Frequency           string     `json:"frequency"`
+	Cron                string     `json:"cron,omitempty"`
+	StartAt             time.Time  `json:"start_at"`
+	EndsAt              *time.Time `json:"ends_at,omitempty"`
+	NextRunAt           *time.Time `json:"next_run_at,omitempty"`
+	LastRunAt           *time.Time `json:"last_run_at,omitempty"`
+	Status              string     `json:"status"`
+	SpawnCount          int        `json:"spawn_count"`
+	CreatedAt           time.Time  `json:"created_at"`
+}
+
+// ListTemplates godoc
+// @Summary List harvest templates
+// @Description List every recurring harvest template, newest first (admin only)
+// @Tags harvests
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} HarvestTemplateResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/harvest-templates [get]
+func (h *HarvestTemplateHandler) ListTemplates(c *gin.Context) {
+	templates, err := h.templateService.ListTemplates()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	response := make([]HarvestTemplateResponse, len(templates))
+	for i := range templates {
+		response[i] = mapHarvestTemplateToResponse(&templates[i])
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// CreateTemplate godoc
+// @Summary Create a harvest template
+// @Description Create a template that spawns a fresh harvest daily, weekly, monthly or on a cron schedule. Deadline and claim_timeout are durations counted from each spawn; assignee claims every spawned harvest (admin only)
+// @Tags harvests
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body HarvestTemplateRequest true "Harvest template"
+// @Success 200 {object} HarvestTemplateResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/harvest-templates [post]
+func (h *HarvestTemplateHandler) CreateTemplate(c *gin.Context) {
+	var req HarvestTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	template, err := h.templateService.CreateTemplate(toHarvestTemplateParams(req))
+	if err != nil {
+		respondHarvestTemplateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapHarvestTemplateToResponse(template))
+}
+
+// UpdateTemplate godoc
+// @Summary Edit a harvest template
+// @Description Replace the details and recurrence rule of a harvest template. Harvests it already spawned are not changed (admin only)
+// @Tags harvests
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Harvest Template ID"
+// @Param request body HarvestTemplateRequest true "Harvest template"
+// @Success 200 {object} HarvestTemplateResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/harvest-templates/{id} [put]
+func (h *HarvestTemplateHandler) UpdateTemplate(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid harvest template ID"})
+		return
+	}
+
+	var req HarvestTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	template, err := h.templateService.UpdateTemplate(uint(id), toHarvestTemplateParams(req))
+	if err != nil {
+		respondHarvestTemplateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapHarvestTemplateToResponse(template))
+}
+
+// PauseTemplate godoc
+// @Summary Pause a harvest template
+// @Description Stop a harvest template from spawning harvests until it is resumed (admin only)
+// @Tags harvests
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Harvest Template ID"
+// @Success 200 {object} HarvestTemplateResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /admin/harvest-templates/{id}/pause [post]
+func (h *HarvestTemplateHandler) PauseTemplate(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid harvest template ID"})
+		return
+	}
+
+	template, err := h.templateService.PauseTemplate(uint(id))
+	if err != nil {
+		respondHarvestTemplateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapHarvestTemplateToResponse(template))
+}
+
+// ResumeTemplate godoc
+// @Summary Resume a harvest template
+// @Description Resume a paused harvest template from its next occurrence. Occurrences missed while paused are skipped (admin only)
+// @Tags harvests
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Harvest Template ID"
+// @Success 200 {object} HarvestTemplateResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /admin/harvest-templates/{id}/resume [post]
+func (h *HarvestTemplateHandler) ResumeTemplate(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid harvest template ID"})
+		return
+	}
+
+	template, err := h.templateService.ResumeTemplate(uint(id))
+	if err != nil {
+		respondHarvestTemplateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapHarvestTemplateToResponse(template))
+}
+
+// DeleteTemplate godoc
+// @Summary Delete a harvest template
+// @Description Delete a harvest template. Harvests it already spawned are kept (admin only)
+// @Tags harvests
+// @Security BearerAuth
+// @Param id path int true "Harvest Template ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /admin/harvest-templates/{id} [delete]
+func (h *HarvestTemplateHandler) DeleteTemplate(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid harvest template ID"})
+		return
+	}
+
+	if err := h.templateService.DeleteTemplate(uint(id)); err != nil {
+		respondHarvestTemplateError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func toHarvestTemplateParams(req HarvestTemplateRequest) services.HarvestTemplateParams {
+	return services.HarvestTemplateParams{
+		Title:        req.Title,
+		Description:  req.Description,
+		BeanAmount:   req.BeanAmount,
+		Deadline:     req.Deadline,
+		ClaimTimeout: req.ClaimTimeout,
+		Category:     req.Category,
+		Tags:         req.Tags,
+		Assignee:     req.Assignee,
+		Frequency:    models.ScheduleFrequency(req.Frequency),
+		Cron:         req.Cron,
+		StartAt:      req.StartAt,
+		EndsAt:       req.EndsAt,
+	}
+}
+
+func respondHarvestTemplateError(c *gin.Context, err error) {
+	switch err {
+	case services.ErrInvalidAmount, services.ErrReservedAccount, services.ErrInvalidTemplateFrequency,
+		services.ErrInvalidCron, services.ErrScheduleEndsBeforeStart, services.ErrScheduleNeverRuns,
+		services.ErrInvalidTemplateDeadline, services.ErrInvalidClaimTimeout,
+		services.ErrInvalidHarvestCategory, services.ErrInvalidHarvestTags:
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	case services.ErrHarvestTemplateNotFound, services.ErrUserNotFound:
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+	case services.ErrHarvestTemplateNotActive, services.ErrHarvestTemplateNotPaused:
+		c.JSON(http.StatusConflict, ErrorResponse{Error: err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
+}
+
+func mapHarvestTemplateToResponse(template *models.HarvestTemplate) HarvestTemplateResponse {
+	resp := HarvestTemplateResponse{
+		ID:                  template.ID,
+		Title:               template.Title,
+		Description:         template.Description,
+		BeanAmount:          template.BeanAmount,
+		DeadlineSeconds:     template.DeadlineSeconds,
+		ClaimTimeoutSeconds: template.ClaimTimeoutSeconds,
+		Category:            template.Category,
+		Tags:                template.TagList(),
+		Frequency:           string(template.Frequency),
+		Cron:                template.CronExpr,
+		StartAt:             template.StartAt,
+		EndsAt:              template.EndsAt,
+		NextRunAt:           template.NextRunAt,
+		LastRunAt:           template.LastRunAt,
+		Status:              string(template.Status),
+		SpawnCount:          template.SpawnCount,
+		CreatedAt:           template.CreatedAt,
+	}
+	if template.Assignee != nil {
+		resp.Assignee = template.Assignee.Username
+	}
+	return resp
+}
@@ -0,0 +1,302 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/h4ks-com/bean-bank/internal/middleware"
+	"github.com/h4ks-com/bean-bank/internal/models"
+	"github.com/h4ks-com/bean-bank/internal/services"
+)
+
+type PaymentRequestHandler struct {
+	paymentRequestService *services.PaymentRequestService
+}
+
+func NewPaymentRequestHandler(paymentRequestService *services.PaymentRequestService) *PaymentRequestHandler {
+	return &PaymentRequestHandler{paymentRequestService: paymentRequestService}
+}
+
+type CreatePaymentRequestRequest struct {
+	Payer     string `json:"payer"`
+	Amount    int    `json:"amount" binding:"required,gt=0"`
+	Memo      string `json:"memo"`
+	ExpiresIn string `json:"expires_in"`
+}
+
+type PayPaymentRequestRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type PaymentRequestResponse struct {
+	ID            uint   `json:"id"`
+	Code          string `json:"code"`
+	Requester     string `json:"requester"`
+	Payer         string `json:"payer,omitempty"`
+	Amount        int    `json:"amount"`
+	Memo          string `json:"memo,omitempty"`
+	Status        string `json:"status"`
+	ExpiresAt     *int64 `json:"expires_at,omitempty"`
+	PaidBy        string `json:"paid_by,omitempty"`
+	PaidAt        *int64 `json:"paid_at,omitempty"`
+	TransactionID *uint  `json:"transaction_id,omitempty"`
+	CreatedAt     int64  `json:"created_at"`
+}
+
+// CreatePaymentRequest godoc
+// @Summary Create a payment request
+// @Description Ask a user for beans. Leave payer empty to let anyone with the link pay.
+// @Tags paymentrequests
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body CreatePaymentRequestRequest true "Payment request"
+// @Success 200 {object} PaymentRequestResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /paymentrequests [post]
+func (h *PaymentRequestHandler) CreatePaymentRequest(c *gin.Context) {
+	username := middleware.GetUsername(c)
+	if username == "" {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	var req CreatePaymentRequestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	request, err := h.paymentRequestService.CreatePaymentRequest(services.PaymentRequestParams{
+		Requester: username,
+		Payer:     req.Payer,
+		Amount:    req.Amount,
+		Memo:      req.Memo,
+		ExpiresIn: req.ExpiresIn,
+	})
+	if err != nil {
+		respondPaymentRequestError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapPaymentRequestToResponse(request))
+}
+
+// ListPaymentRequests godoc
+// @Summary List payment requests
+// @Description List payment requests the authenticated user sent or was asked to pay, newest first
+// @Tags paymentrequests
+// @Produce json
+// @Security BearerAuth
+// @Param direction query string false "incoming or outgoing"
+// @Param status query string false "pending, paid, declined or cancelled"
+// @Success 200 {array} PaymentRequestResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /paymentrequests [get]
+func (h *PaymentRequestHandler) ListPaymentRequests(c *gin.Context) {
+	username := middleware.GetUsername(c)
+	if username == "" {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	respondPaymentRequestList(c, h.paymentRequestService, username)
+}
+
+// GetPaymentRequestInfo godoc
+// @Summary Get payment request information
+// @Description Get details about a payment request by code (public endpoint)
+// @Tags paymentrequests
+// @Produce json
+// @Param code path string true "Payment Request Code"
+// @Success 200 {object} PaymentRequestResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /pay/{code} [get]
+func (h *PaymentRequestHandler) GetPaymentRequestInfo(c *gin.Context) {
+	request, err := h.paymentRequestService.GetPaymentRequestByCode(c.Param("code"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if request == nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "payment request not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, mapPaymentRequestToResponse(request))
+}
+
+// PayPaymentRequest godoc
+// @Summary Pay a payment request
+// @Description Send the requested beans to the requester. The transaction is linked to the request.
+// @Tags paymentrequests
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body PayPaymentRequestRequest true "Pay request"
+// @Param Idempotency-Key header string false "Unique key that makes retries of this request safe"
+// @Success 200 {object} PaymentRequestResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /paymentrequests/pay [post]
+func (h *PaymentRequestHandler) PayPaymentRequest(c *gin.Context) {
+	username := middleware.GetUsername(c)
+	if username == "" {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	var req PayPaymentRequestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	request, err := h.paymentRequestService.PayPaymentRequest(req.Code, username)
+	if err != nil {
+		respondPaymentRequestError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapPaymentRequestToResponse(request))
+}
+
+// DeclinePaymentRequest godoc
+// @Summary Decline a payment request
+// @Description Decline a pending payment request addressed to the authenticated user
+// @Tags paymentrequests
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Payment Request ID"
+// @Success 200 {object} PaymentRequestResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /paymentrequests/{id}/decline [post]
+func (h *PaymentRequestHandler) DeclinePaymentRequest(c *gin.Context) {
+	h.resolvePaymentRequest(c, h.paymentRequestService.DeclinePaymentRequest)
+}
+
+// CancelPaymentRequest godoc
+// @Summary Cancel a payment request
+// @Description Withdraw a pending payment request created by the authenticated user
+// @Tags paymentrequests
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Payment Request ID"
+// @Success 200 {object} PaymentRequestResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /paymentrequests/{id}/cancel [post]
+func (h *PaymentRequestHandler) CancelPaymentRequest(c *gin.Context) {
+	h.resolvePaymentRequest(c, h.paymentRequestService.CancelPaymentRequest)
+}
+
+func (h *PaymentRequestHandler) resolvePaymentRequest(c *gin.Context, resolve func(uint, string) (*models.PaymentRequest, error)) {
+	username := middleware.GetUsername(c)
+	if username == "" {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid payment request id"})
+		return
+	}
+
+	request, err := resolve(uint(id), username)
+	if err != nil {
+		respondPaymentRequestError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapPaymentRequestToResponse(request))
+}
+
+func respondPaymentRequestList(c *gin.Context, paymentRequestService *services.PaymentRequestService, username string) {
+	requests, err := paymentRequestService.ListPaymentRequests(username, c.Query("direction"), c.Query("status"))
+	if err != nil {
+		respondPaymentRequestError(c, err)
+		return
+	}
+
+	response := make([]PaymentRequestResponse, len(requests))
+	for i := range requests {
+		response[i] = mapPaymentRequestToResponse(&requests[i])
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func respondPaymentRequestError(c *gin.Context, err error) {
+	switch err {
+	case services.ErrInvalidAmount, services.ErrNoteTooLong, services.ErrCannotPayOwnRequest,
+		services.ErrReservedAccount, services.ErrInvalidRequestDirection, services.ErrInvalidRequestStatus,
+		services.ErrPaymentRequestExpired, services.ErrInsufficientBalance,
+		services.ErrInvalidExpiry, services.ErrExpiryNotInFuture, services.ErrExpiryTooFar:
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	case services.ErrNotRequestPayer, services.ErrNotRequestRequester:
+		c.JSON(http.StatusForbidden, ErrorResponse{Error: err.Error()})
+	case services.ErrPaymentRequestNotFound, services.ErrPayerNotFound, services.ErrUserNotFound:
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+	case services.ErrPaymentRequestNotPending:
+		c.JSON(http.StatusConflict, ErrorResponse{Error: err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
+}
+
+func mapPaymentRequestToResponse(pr *models.PaymentRequest) PaymentRequestResponse {
+	response := PaymentRequestResponse{
+		ID:            pr.ID,
+		Code:          pr.Code,
+		Requester:     pr.Requester.Username,
+		Amount:        pr.Amount,
+		Memo:          pr.Memo,
+		Status:        string(pr.Status),
+		TransactionID: pr.TransactionID,
+		CreatedAt:     pr.CreatedAt.Unix(),
+	}
+
+	if pr.IsExpired(time.Now()) {
+		response.Status = "expired"
+	}
+
+	if pr.Payer != nil {
+		response.Payer = pr.Payer.Username
+	}
+
+	if pr.ExpiresAt != nil {
+		expiresAt := pr.ExpiresAt.Unix()
+		response.ExpiresAt = &expiresAt
+	}
+
+	if pr.PaidBy != nil {
+		response.PaidBy = pr.PaidBy.Username
+	}
+
+	if pr.PaidAt != nil {
+		paidAt := pr.PaidAt.Unix()
+		response.PaidAt = &paidAt
+	}
+
+	return response
+}
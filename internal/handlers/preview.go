@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/h4ks-com/bean-bank/internal/models"
+	"github.com/h4ks-com/bean-bank/internal/services"
+)
+
+// LinkPreview holds the OpenGraph and Twitter card fields that gift.html and
+// transfer.html render so shared links unfurl in chat apps.
+type LinkPreview struct {
+	Title       string
+	Description string
+	URL         string
+	Image       string
+}
+
+// NewGiftLinkPreview describes a gift link page. Anything that is not
+// claimable by whoever opens the link gets a generic preview.
+func NewGiftLinkPreview(preview *services.GiftLinkPreview, baseURL string) LinkPreview {
+	path := "/gift/" + url.PathEscape(preview.Code)
+	linkPreview := LinkPreview{
+		Title:       "🎁 A bean gift for you",
+		Description: "Open the link to see this gift on Bean Bank.",
+		URL:         baseURL + path,
+		Image:       baseURL + "/api/v1" + path + "/qr",
+	}
+
+	if !preview.Claimable {
+		return linkPreview
+	}
+
+	switch {
+	case preview.Mode == models.GiftLinkModeRandom:
+		linkPreview.Title = fmt.Sprintf("🧧 %s is sharing %s between %d people", preview.From, beanCount(preview.Amount), preview.MaxRedemptions)
+	case preview.MaxRedemptions > 1:
+		linkPreview.Title = fmt.Sprintf("🎁 %s is giving %s each to %d people", preview.From, beanCount(preview.Amount), preview.MaxRedemptions)
+	default:
+		linkPreview.Title = fmt.Sprintf("🎁 %s sent you %s", preview.From, beanCount(preview.Amount))
+	}
+	if preview.Message != "" {
+		linkPreview.Description = fmt.Sprintf("“%s” Open the link to claim your beans on Bean Bank.", preview.Message)
+	} else {
+		linkPreview.Description = "Open the link to claim your beans on Bean Bank."
+	}
+	return linkPreview
+}
+
+// NewTransferLinkPreview describes a /transfer confirmation page. Everything
+// shown is already part of the link itself.
+func NewTransferLinkPreview(from, to string, amount int, note, baseURL string) LinkPreview {
+	path := TransferLinkPath(from, to, amount, note)
+	qrPath := fmt.Sprintf("/api/v1/transfer/%s/%s/%d/qr", url.PathEscape(from), url.PathEscape(to), amount)
+	if note != "" {
+		qrPath += "?note=" + url.QueryEscape(note)
+	}
+
+	linkPreview := LinkPreview{
+		Title:       fmt.Sprintf("🫘 %s → %s: %s", from, to, beanCount(amount)),
+		Description: fmt.Sprintf("Confirm sending %s from %s to %s on Bean Bank.", beanCount(amount), from, to),
+		URL:         baseURL + path,
+		Image:       baseURL + qrPath,
+	}
+	if note != "" {
+		linkPreview.Description = fmt.Sprintf("“%s” %s", note, linkPreview.Description)
+	}
+	return linkPreview
+}
+
+func beanCount(amount int) string {
+	if amount == 1 {
+		return "1 bean"
+	}
+	return fmt.Sprintf("%d beans", amount)
+}
@@ -3,8 +3,12 @@ package handlers
 import (
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/h4ks-com/bean-bank/internal/models"
+	"github.com/h4ks-com/bean-bank/internal/repository"
 	"github.com/h4ks-com/bean-bank/internal/services"
 )
 
@@ -88,15 +92,23 @@ func (h *PublicHandler) GetLeaderboard(c *gin.Context) {
 }
 
 type HarvestListItem struct {
-	ID             uint   `json:"id"`
-	Title          string `json:"title"`
-	Description    string `json:"description"`
-	BeanAmount     int    `json:"bean_amount"`
-	AssignedUserID *uint  `json:"assigned_user_id,omitempty"`
-	AssignedUser   string `json:"assigned_user,omitempty"`
-	Completed      bool   `json:"completed"`
-	CreatedAt      string `json:"created_at"`
-	UpdatedAt      string `json:"updated_at"`
+	ID             uint                         `json:"id"`
+	Title          string                       `json:"title"`
+	Description    string                       `json:"description"`
+	BeanAmount     int                          `json:"bean_amount"`
+	AssignedUserID *uint                        `json:"assigned_user_id,omitempty"`
+	AssignedUser   string                       `json:"assigned_user,omitempty"`
+	Participants   []HarvestParticipantResponse `json:"participants"`
+	SplitMode      string                       `json:"split_mode"`
+	Status         string                       `json:"status"`
+	Completed      bool                         `json:"completed"`
+	TemplateID     *uint                        `json:"template_id,omitempty"`
+	Category       string                       `json:"category,omitempty"`
+	Tags           []string                     `json:"tags"`
+	HarvestTimingResponse
+	HarvestBountyResponse
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
 }
 
 type HarvestListResponse struct {
@@ -107,17 +119,30 @@ type HarvestListResponse struct {
 	TotalPages int               `json:"total_pages"`
 }
 
+type HarvestLabelsResponse struct {
+	Tags       []repository.HarvestLabelCount `json:"tags"`
+	Categories []repository.HarvestLabelCount `json:"categories"`
+}
+
 // GetHarvests godoc
 // @Summary Get harvests
-// @Description Get list of harvests with optional search and pagination, or a specific harvest by ID
+// @Description Get list of harvests with optional search, filters and pagination, or a specific harvest by ID
 // @Tags public
 // @Accept json
 // @Produce json
 // @Param id query int false "Harvest ID to fetch a specific harvest"
-// @Param search query string false "Search query for title and description"
+// @Param search query string false "Full-text search over title and description, best matches first"
+// @Param status query string false "Only list open, assigned, overdue or completed harvests"
+// @Param category query string false "Only list harvests in this category"
+// @Param tag query []string false "Only list harvests with this tag; repeat to require several" collectionFormat(multi)
+// @Param assignee query string false "Only list harvests this user is working on"
+// @Param min_reward query int false "Smallest reward to list"
+// @Param max_reward query int false "Largest reward to list"
+// @Param sort query string false "relevance, newest, reward or deadline (default relevance when searching, otherwise recently updated)"
 // @Param page query int false "Page number (default 1)"
 // @Param limit query int false "Items per page (default 20)"
 // @Success 200 {object} HarvestListResponse
+// @Failure 400 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /harvests [get]
@@ -135,22 +160,7 @@ func (h *PublicHandler) GetHarvests(c *gin.Context) {
 			return
 		}
 
-		item := HarvestListItem{
-			ID:          harvest.ID,
-			Title:       harvest.Title,
-			Description: harvest.Description,
-			BeanAmount:  harvest.BeanAmount,
-			Completed:   harvest.Completed,
-			CreatedAt:   harvest.CreatedAt.Format("2006-01-02 15:04:05"),
-			UpdatedAt:   harvest.UpdatedAt.Format("2006-01-02 15:04:05"),
-		}
-
-		if harvest.AssignedUserID != nil {
-			item.AssignedUserID = harvest.AssignedUserID
-			if harvest.AssignedUser != nil {
-				item.AssignedUser = harvest.AssignedUser.Username
-			}
-		}
+		item := toHarvestListItem(harvest, time.Now())
 
 		c.JSON(http.StatusOK, HarvestListResponse{
 			Harvests:   []HarvestListItem{item},
@@ -163,6 +173,82 @@ func (h *PublicHandler) GetHarvests(c *gin.Context) {
 	}
 
 	search := c.DefaultQuery("search", "")
+	status := repository.HarvestListStatus(c.Query("status"))
+	switch status {
+	case "", repository.HarvestListOpen, repository.HarvestListAssigned,
+		repository.HarvestListOverdue, repository.HarvestListCompleted:
+	default:
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "status must be one of open, assigned, overdue or completed"})
+		return
+	}
+
+	sort := repository.HarvestSort(c.Query("sort"))
+	switch sort {
+	case "", repository.HarvestSortRelevance, repository.HarvestSortNewest,
+		repository.HarvestSortReward, repository.HarvestSortDeadline:
+	default:
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "sort must be one of relevance, newest, reward or deadline"})
+		return
+	}
+
+	var rewards [2]int
+	for i, param := range []string{"min_reward", "max_reward"} {
+		if value := c.Query(param); value != "" {
+			amount, err := strconv.Atoi(value)
+			if err != nil || amount < 1 {
+				c.JSON(http.StatusBadRequest, ErrorResponse{Error: param + " must be a positive whole number"})
+				return
+			}
+			rewards[i] = amount
+		}
+	}
+
+	var tags []string
+	for _, value := range c.QueryArray("tag") {
+		for _, tag := range strings.Split(value, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+	}
+
+	page, limit := parsePage(c)
+
+	harvests, total, err := h.harvestService.SearchHarvests(repository.HarvestFilter{
+		Query:     search,
+		Status:    status,
+		Category:  c.Query("category"),
+		Tags:      tags,
+		Assignee:  c.Query("assignee"),
+		MinReward: rewards[0],
+		MaxReward: rewards[1],
+		Sort:      sort,
+		Page:      page,
+		Limit:     limit,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	now := time.Now()
+	items := make([]HarvestListItem, len(harvests))
+	for i := range harvests {
+		items[i] = toHarvestListItem(&harvests[i], now)
+	}
+
+	c.JSON(http.StatusOK, HarvestListResponse{
+		Harvests:   items,
+		Total:      total,
+		Page:       page,
+		Limit:      limit,
+		TotalPages: pageCount(total, limit),
+	})
+}
+
+// parsePage reads the page and limit query parameters, defaulting to the
+// first page of 20 and capping limit at 100.
+func parsePage(c *gin.Context) (int, int) {
 	page := 1
 	limit := 20
 
@@ -178,44 +264,60 @@ func (h *PublicHandler) GetHarvests(c *gin.Context) {
 		}
 	}
 
-	harvests, total, err := h.harvestService.SearchHarvests(search, page, limit)
+	return page, limit
+}
+
+func pageCount(total int64, limit int) int {
+	totalPages := int(total) / limit
+	if int(total)%limit != 0 {
+		totalPages++
+	}
+	return totalPages
+}
+
+// GetHarvestLabels godoc
+// @Summary List harvest tags and categories
+// @Description List the tags and categories in use with how many harvests carry each, most used first
+// @Tags public
+// @Produce json
+// @Success 200 {object} HarvestLabelsResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /harvests/labels [get]
+func (h *PublicHandler) GetHarvestLabels(c *gin.Context) {
+	tags, categories, err := h.harvestService.ListLabels()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
 		return
 	}
 
-	items := make([]HarvestListItem, len(harvests))
-	for i, harvest := range harvests {
-		item := HarvestListItem{
-			ID:          harvest.ID,
-			Title:       harvest.Title,
-			Description: harvest.Description,
-			BeanAmount:  harvest.BeanAmount,
-			Completed:   harvest.Completed,
-			CreatedAt:   harvest.CreatedAt.Format("2006-01-02 15:04:05"),
-			UpdatedAt:   harvest.UpdatedAt.Format("2006-01-02 15:04:05"),
-		}
-
-		if harvest.AssignedUserID != nil {
-			item.AssignedUserID = harvest.AssignedUserID
-			if harvest.AssignedUser != nil {
-				item.AssignedUser = harvest.AssignedUser.Username
-			}
-		}
+	c.JSON(http.StatusOK, HarvestLabelsResponse{Tags: tags, Categories: categories})
+}
 
-		items[i] = item
+func toHarvestListItem(harvest *models.Harvest, now time.Time) HarvestListItem {
+	item := HarvestListItem{
+		ID:                    harvest.ID,
+		Title:                 harvest.Title,
+		Description:           harvest.Description,
+		BeanAmount:            harvest.BeanAmount,
+		Participants:          toHarvestParticipants(harvest),
+		SplitMode:             string(harvest.SplitMode),
+		Status:                string(harvest.Status),
+		Completed:             harvest.Completed,
+		TemplateID:            harvest.TemplateID,
+		Category:              harvest.Category,
+		Tags:                  harvest.TagNames(),
+		HarvestTimingResponse: toHarvestTiming(harvest, now),
+		HarvestBountyResponse: toHarvestBounty(harvest),
+		CreatedAt:             harvest.CreatedAt.Format("2006-01-02 15:04:05"),
+		UpdatedAt:             harvest.UpdatedAt.Format("2006-01-02 15:04:05"),
 	}
 
-	totalPages := int(total) / limit
-	if int(total)%limit != 0 {
-		totalPages++
+	if harvest.AssignedUserID != nil {
+		item.AssignedUserID = harvest.AssignedUserID
+		if harvest.AssignedUser != nil {
+			item.AssignedUser = harvest.AssignedUser.Username
+		}
 	}
 
-	c.JSON(http.StatusOK, HarvestListResponse{
-		Harvests:   items,
-		Total:      total,
-		Page:       page,
-		Limit:      limit,
-		TotalPages: totalPages,
-	})
+	return item
 }
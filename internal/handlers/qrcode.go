@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/h4ks-com/bean-bank/internal/services"
+	"github.com/skip2/go-qrcode"
+)
+
+const (
+	defaultQRCodeSize = 256
+	minQRCodeSize     = 64
+	maxQRCodeSize     = 1024
+)
+
+type QRCodeHandler struct {
+	giftLinkService       *services.GiftLinkService
+	paymentRequestService *services.PaymentRequestService
+	publicURL             string
+}
+
+func NewQRCodeHandler(giftLinkService *services.GiftLinkService, paymentRequestService *services.PaymentRequestService, publicURL string) *QRCodeHandler {
+	return &QRCodeHandler{
+		giftLinkService:       giftLinkService,
+		paymentRequestService: paymentRequestService,
+		publicURL:             publicURL,
+	}
+}
+
+// PublicBaseURL is the scheme and host that shared links should point at. The
+// configured PUBLIC_URL wins; otherwise it is derived from the request's Host
+// and X-Forwarded-Proto headers, which the client controls, so anything built
+// from it must not be cached for other clients.
+func PublicBaseURL(c *gin.Context, configured string) string {
+	if configured != "" {
+		return configured
+	}
+
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host
+}
+
+// GiftLinkQRCode godoc
+// @Summary Get a QR code for a gift link
+// @Description Render the gift link URL as a QR code (public endpoint)
+// @Tags qrcodes
+// @Produce png
+// @Produce image/svg+xml
+// @Param code path string true "Gift Link Code"
+// @Param format query string false "Image format: png (default) or svg"
+// @Param size query int false "Image size in pixels, 64 to 1024 (default 256)"
+// @Success 200 {file} binary
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /gift/{code}/qr [get]
+func (h *QRCodeHandler) GiftLinkQRCode(c *gin.Context) {
+	giftLink, err := h.giftLinkService.GetGiftLinkByCode(c.Param("code"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if giftLink == nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "gift link not found"})
+		return
+	}
+
+	h.render(c, "/gift/"+url.PathEscape(giftLink.Code))
+}
+
+// PaymentRequestQRCode godoc
+// @Summary Get a QR code for a payment request link
+// @Description Render the payment request URL as a QR code (public endpoint)
+// @Tags qrcodes
+// @Produce png
+// @Produce image/svg+xml
+// @Param code path string true "Payment Request Code"
+// @Param format query string false "Image format: png (default) or svg"
+// @Param size query int false "Image size in pixels, 64 to 1024 (default 256)"
+// @Success 200 {file} binary
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /pay/{code}/qr [get]
+func (h *QRCodeHandler) PaymentRequestQRCode(c *gin.Context) {
+	request, err := h.paymentRequestService.GetPaymentRequestByCode(c.Param("code"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if request == nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "payment request not found"})
+		return
+	}
+
+	h.render(c, "/pay/"+url.PathEscape(request.Code))
+}
+
+// TransferLinkQRCode godoc
+// @Summary Get a QR code for a transfer link
+// @Description Render a /transfer/{from}/{to}/{amount} confirmation URL as a QR code (public endpoint)
+// @Tags qrcodes
+// @Produce png
+// @Produce image/svg+xml
+// @Param from path string true "Sender username"
+// @Param to path string true "Recipient username"
+// @Param amount path int true "Amount of beans"
+// @Param note query string false "Optional note carried in the link"
+// @Param format query string false "Image format: png (default) or svg"
+// @Param size query int false "Image size in pixels, 64 to 1024 (default 256)"
+// @Success 200 {file} binary
+// @Failure 400 {object} ErrorResponse
+// @Router /transfer/{from}/{to}/{amount}/qr [get]
+func (h *QRCodeHandler) TransferLinkQRCode(c *gin.Context) {
+	amount, err := strconv.Atoi(c.Param("amount"))
+	if err != nil || amount <= 0 {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid amount"})
+		return
+	}
+
+	note, err := services.SanitizeNote(c.Query("note"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	h.render(c, TransferLinkPath(c.Param("from"), c.Param("to"), amount, note))
+}
+
+// TransferLinkPath builds the /transfer confirmation path for a transfer link.
+func TransferLinkPath(from, to string, amount int, note string) string {
+	path := fmt.Sprintf("/transfer/%s/%s/%d", url.PathEscape(from), url.PathEscape(to), amount)
+	if note != "" {
+		path += "?note=" + url.QueryEscape(note)
+	}
+	return path
+}
+
+func (h *QRCodeHandler) render(c *gin.Context, path string) {
+	size := defaultQRCodeSize
+	if sizeStr := c.Query("size"); sizeStr != "" {
+		parsed, err := strconv.Atoi(sizeStr)
+		if err != nil || parsed < minQRCodeSize || parsed > maxQRCodeSize {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("size must be between %d and %d", minQRCodeSize, maxQRCodeSize)})
+			return
+		}
+		size = parsed
+	}
+
+	content := PublicBaseURL(c, h.publicURL) + path
+
+	// Without PUBLIC_URL the image encodes whatever Host the request carried,
+	// and shared caches don't key on that, so only the client may keep it.
+	cacheControl := "public, max-age=3600"
+	if h.publicURL == "" {
+		cacheControl = "private, max-age=3600"
+	}
+
+	switch c.DefaultQuery("format", "png") {
+	case "png":
+		png, err := qrcode.Encode(content, qrcode.Medium, size)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+			return
+		}
+		c.Header("Cache-Control", cacheControl)
+		c.Data(http.StatusOK, "image/png", png)
+	case "svg":
+		code, err := qrcode.New(content, qrcode.Medium)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+			return
+		}
+		c.Header("Cache-Control", cacheControl)
+		c.Data(http.StatusOK, "image/svg+xml", qrCodeSVG(code.Bitmap(), size))
+	default:
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "format must be png or svg"})
+	}
+}
+
+// qrCodeSVG draws the module bitmap (quiet zone included) as one path, merging
+// each horizontal run of dark modules into a single rectangle.
+func qrCodeSVG(bitmap [][]bool, size int) []byte {
+	modules := len(bitmap)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, size, size, modules, modules)
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="#ffffff"/>`, modules, modules)
+	b.WriteString(`<path fill="#000000" d="`)
+	for y, row := range bitmap {
+		for x := 0; x < len(row); {
+			if !row[x] {
+				x++
+				continue
+			}
+			start := x
+			for x < len(row) && row[x] {
+				x++
+			}
+			fmt.Fprintf(&b, "M%d %dh%dv1h-%dz", start, y, x-start, x-start)
+		}
+	}
+	b.WriteString(`"/></svg>`)
+	return []byte(b.String())
+}
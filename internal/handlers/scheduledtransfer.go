@@ -0,0 +1,278 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/h4ks-com/bean-bank/internal/middleware"
+	"github.com/h4ks-com/bean-bank/internal/models"
+	"github.com/h4ks-com/bean-bank/internal/services"
+)
+
+type ScheduledTransferHandler struct {
+	scheduledTransferService *services.ScheduledTransferService
+}
+
+func NewScheduledTransferHandler(scheduledTransferService *services.ScheduledTransferService) *ScheduledTransferHandler {
+	return &ScheduledTransferHandler{scheduledTransferService: scheduledTransferService}
+}
+
+type CreateScheduledTransferRequest struct {
+	ToUser        string     `json:"to_user" binding:"required"`
+	Amount        int        `json:"amount" binding:"required,gt=0"`
+	Note          string     `json:"note"`
+	Force         bool       `json:"force"`
+	Frequency     string     `json:"frequency" binding:"required"`
+	Cron          string     `json:"cron"`
+	StartAt       *time.Time `json:"start_at"`
+	EndsAt        *time.Time `json:"ends_at"`
+	FailurePolicy string     `json:"failure_policy"`
+	MaxRetries    int        `json:"max_retries"`
+}
+
+type ScheduledTransferResponse struct {
+	ID            uint       `json:"id"`
+	FromUser      string     `json:"from_user"`
+	ToUser        string     `json:"to_user"`
+	Amount        int        `json:"amount"`
+	Note          string     `json:"note,omitempty"`
+	Force         bool       `json:"force"`
+	Frequency     string     `json:"frequency"`
+	Cron          string     `json:"cron,omitempty"`
+	StartAt       time.Time  `json:"start_at"`
+	EndsAt        *time.Time `json:"ends_at,omitempty"`
+	NextRunAt     *time.Time `json:"next_run_at,omitempty"`
+	LastRunAt     *time.Time `json:"last_run_at,omitempty"`
+	Status        string     `json:"status"`
+	FailurePolicy string     `json:"failure_policy"`
+	MaxRetries    int        `json:"max_retries"`
+	RunCount      int        `json:"run_count"`
+	FailureCount  int        `json:"failure_count"`
+	LastError     string     `json:"last_error,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+type ScheduledTransferRunResponse struct {
+	ID            uint      `json:"id"`
+	RunAt         time.Time `json:"run_at"`
+	Attempt       int       `json:"attempt"`
+	Status        string    `json:"status"`
+	TransactionID *uint     `json:"transaction_id,omitempty"`
+	Error         string    `json:"error,omitempty"`
+}
+
+// CreateScheduledTransfer godoc
+// @Summary Schedule a transfer
+// @Description Schedule a one-off future transfer or a recurring one (daily, weekly, monthly or cron)
+// @Tags scheduledtransfers
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body CreateScheduledTransferRequest true "Scheduled transfer"
+// @Param Idempotency-Key header string false "Unique key that makes retries of this request safe"
+// @Success 200 {object} ScheduledTransferResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /scheduledtransfers [post]
+func (h *ScheduledTransferHandler) CreateScheduledTransfer(c *gin.Context) {
+	username := middleware.GetUsername(c)
+	if username == "" {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	var req CreateScheduledTransferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	respondCreateScheduledTransfer(c, h.scheduledTransferService, username, req)
+}
+
+// ListScheduledTransfers godoc
+// @Summary List scheduled transfers
+// @Description List the authenticated user's scheduled transfers, newest first
+// @Tags scheduledtransfers
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} ScheduledTransferResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /scheduledtransfers [get]
+func (h *ScheduledTransferHandler) ListScheduledTransfers(c *gin.Context) {
+	username := middleware.GetUsername(c)
+	if username == "" {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	respondScheduledTransferList(c, h.scheduledTransferService, username)
+}
+
+// ListScheduledTransferRuns godoc
+// @Summary List runs of a scheduled transfer
+// @Description List the most recent attempts of a scheduled transfer, including failures
+// @Tags scheduledtransfers
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Scheduled Transfer ID"
+// @Success 200 {array} ScheduledTransferRunResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /scheduledtransfers/{id}/runs [get]
+func (h *ScheduledTransferHandler) ListScheduledTransferRuns(c *gin.Context) {
+	username := middleware.GetUsername(c)
+	if username == "" {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid scheduled transfer id"})
+		return
+	}
+
+	respondScheduledTransferRuns(c, h.scheduledTransferService, uint(id), username)
+}
+
+// CancelScheduledTransfer godoc
+// @Summary Cancel a scheduled transfer
+// @Description Stop a scheduled transfer. Runs already made are not undone.
+// @Tags scheduledtransfers
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Scheduled Transfer ID"
+// @Success 200 {object} ScheduledTransferResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /scheduledtransfers/{id} [delete]
+func (h *ScheduledTransferHandler) CancelScheduledTransfer(c *gin.Context) {
+	username := middleware.GetUsername(c)
+	if username == "" {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid scheduled transfer id"})
+		return
+	}
+
+	scheduled, err := h.scheduledTransferService.CancelScheduledTransfer(uint(id), username)
+	if err != nil {
+		respondScheduledTransferError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapScheduledTransferToResponse(scheduled))
+}
+
+func respondCreateScheduledTransfer(c *gin.Context, scheduledTransferService *services.ScheduledTransferService, username string, req CreateScheduledTransferRequest) {
+	scheduled, err := scheduledTransferService.CreateScheduledTransfer(services.ScheduledTransferParams{
+		From:          username,
+		To:            req.ToUser,
+		Amount:        req.Amount,
+		Note:          req.Note,
+		Force:         req.Force,
+		Frequency:     models.ScheduleFrequency(req.Frequency),
+		Cron:          req.Cron,
+		StartAt:       req.StartAt,
+		EndsAt:        req.EndsAt,
+		FailurePolicy: models.FailurePolicy(req.FailurePolicy),
+		MaxRetries:    req.MaxRetries,
+	})
+	if err != nil {
+		respondScheduledTransferError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapScheduledTransferToResponse(scheduled))
+}
+
+func respondScheduledTransferList(c *gin.Context, scheduledTransferService *services.ScheduledTransferService, username string) {
+	scheduled, err := scheduledTransferService.ListScheduledTransfers(username)
+	if err != nil {
+		respondScheduledTransferError(c, err)
+		return
+	}
+
+	response := make([]ScheduledTransferResponse, len(scheduled))
+	for i := range scheduled {
+		response[i] = mapScheduledTransferToResponse(&scheduled[i])
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func respondScheduledTransferRuns(c *gin.Context, scheduledTransferService *services.ScheduledTransferService, id uint, username string) {
+	runs, err := scheduledTransferService.ListRuns(id, username, 50)
+	if err != nil {
+		respondScheduledTransferError(c, err)
+		return
+	}
+
+	response := make([]ScheduledTransferRunResponse, len(runs))
+	for i, run := range runs {
+		response[i] = ScheduledTransferRunResponse{
+			ID:            run.ID,
+			RunAt:         run.RunAt,
+			Attempt:       run.Attempt,
+			Status:        string(run.Status),
+			TransactionID: run.TransactionID,
+			Error:         run.Error,
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func respondScheduledTransferError(c *gin.Context, err error) {
+	switch err {
+	case services.ErrInvalidAmount, services.ErrNoteTooLong, services.ErrSelfTransfer, services.ErrReservedAccount,
+		services.ErrInvalidFrequency, services.ErrInvalidCron, services.ErrScheduleInPast,
+		services.ErrScheduleEndsBeforeStart, services.ErrScheduleNeverRuns,
+		services.ErrInvalidFailurePolicy, services.ErrInvalidMaxRetries:
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	case services.ErrScheduledTransferNotFound, services.ErrUserNotFound, services.ErrRecipientNotFound:
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+	case services.ErrScheduledTransferInactive:
+		c.JSON(http.StatusConflict, ErrorResponse{Error: err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
+}
+
+func mapScheduledTransferToResponse(st *models.ScheduledTransfer) ScheduledTransferResponse {
+	return ScheduledTransferResponse{
+		ID:            st.ID,
+		FromUser:      st.FromUser.Username,
+		ToUser:        st.ToUsername,
+		Amount:        st.Amount,
+		Note:          st.Note,
+		Force:         st.Force,
+		Frequency:     string(st.Frequency),
+		Cron:          st.CronExpr,
+		StartAt:       st.StartAt,
+		EndsAt:        st.EndsAt,
+		NextRunAt:     st.NextRunAt,
+		LastRunAt:     st.LastRunAt,
+		Status:        string(st.Status),
+		FailurePolicy: string(st.FailurePolicy),
+		MaxRetries:    st.MaxRetries,
+		RunCount:      st.RunCount,
+		FailureCount:  st.FailureCount,
+		LastError:     st.LastError,
+		CreatedAt:     st.CreatedAt,
+	}
+}
@@ -39,7 +39,7 @@ type TokenListResponse struct {
 // @Accept json
 // @Produce json
 // @Security BearerAuth
-// @Param request body CreateTokenRequest true "Token expiration (e.g., 24h, 7d, 30d)"
+// @Param request body CreateTokenRequest true "Token expiration: a duration (e.g., 90m, 24h, 7d, 2w), an RFC 3339 timestamp, or never"
 // @Success 201 {object} CreateTokenResponse
 // @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
@@ -54,29 +54,27 @@ func (h *TokenHandler) CreateToken(c *gin.Context) {
 		return
 	}
 
-	duration, err := time.ParseDuration(req.ExpiresIn)
+	token, expiresAt, err := h.tokenService.GenerateToken(username, req.ExpiresIn)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid expires_in format, use duration like 24h, 7d, 30d"})
-		return
-	}
-
-	if duration <= 0 {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "expires_in must be positive"})
-		return
-	}
-
-	token, err := h.tokenService.GenerateToken(username, duration)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		respondTokenError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusCreated, CreateTokenResponse{
 		Token:     token,
-		ExpiresAt: time.Now().Add(duration).Format(time.RFC3339),
+		ExpiresAt: expiresAt.Format(time.RFC3339),
 	})
 }
 
+func respondTokenError(c *gin.Context, err error) {
+	switch err {
+	case services.ErrInvalidExpiry, services.ErrExpiryNotInFuture, services.ErrExpiryTooFar:
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
+}
+
 // ListTokens godoc
 // @Summary List API tokens
 // @Description List all API tokens for authenticated user
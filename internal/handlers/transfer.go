@@ -20,18 +20,50 @@ type TransferRequest struct {
 	ToUser string `json:"to_user" binding:"required"`
 	Amount int    `json:"amount" binding:"required,gt=0"`
 	Force  bool   `json:"force"`
+	Note   string `json:"note"`
 }
 
 type TransferResponse struct {
 	Message string `json:"message"`
 	Amount  int    `json:"amount"`
 	ToUser  string `json:"to_user"`
+	Note    string `json:"note,omitempty"`
 }
 
 type ErrorResponse struct {
 	Error string `json:"error"`
 }
 
+type BatchRecipientRequest struct {
+	ToUser string `json:"to_user" binding:"required"`
+	Amount int    `json:"amount"`
+}
+
+type BatchTransferRequest struct {
+	Recipients []BatchRecipientRequest `json:"recipients" binding:"required,dive"`
+	Total      int                     `json:"total"`
+	Mode       string                  `json:"mode" enums:"atomic,best_effort"`
+	Force      bool                    `json:"force"`
+	Note       string                  `json:"note"`
+}
+
+type BatchRecipientResult struct {
+	ToUser        string `json:"to_user"`
+	Amount        int    `json:"amount"`
+	Status        string `json:"status"`
+	TransactionID uint   `json:"transaction_id,omitempty"`
+	Error         string `json:"error,omitempty"`
+}
+
+type BatchTransferResponse struct {
+	Mode      string                 `json:"mode"`
+	TotalSent int                    `json:"total_sent"`
+	Succeeded int                    `json:"succeeded"`
+	Failed    int                    `json:"failed"`
+	Results   []BatchRecipientResult `json:"results"`
+	Error     string                 `json:"error,omitempty"`
+}
+
 // Transfer godoc
 // @Summary Transfer beans
 // @Description Transfer beans from authenticated user to recipient
@@ -40,10 +72,12 @@ type ErrorResponse struct {
 // @Produce json
 // @Security BearerAuth
 // @Param request body TransferRequest true "Transfer details"
+// @Param Idempotency-Key header string false "Unique key that makes retries of this request safe"
 // @Success 200 {object} TransferResponse
 // @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /transfer [post]
 func (h *TransferHandler) Transfer(c *gin.Context) {
@@ -55,7 +89,13 @@ func (h *TransferHandler) Transfer(c *gin.Context) {
 		return
 	}
 
-	err := h.transferService.Transfer(username, req.ToUser, req.Amount, req.Force)
+	note, err := services.SanitizeNote(req.Note)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "note must be at most 200 characters"})
+		return
+	}
+
+	err = h.transferService.Transfer(username, req.ToUser, req.Amount, req.Force, note)
 	if err != nil {
 		switch err {
 		case services.ErrInsufficientBalance:
@@ -78,5 +118,116 @@ func (h *TransferHandler) Transfer(c *gin.Context) {
 		Message: "transfer successful",
 		Amount:  req.Amount,
 		ToUser:  req.ToUser,
+		Note:    note,
+	})
+}
+
+// BatchTransfer godoc
+// @Summary Transfer beans to many recipients
+// @Description Send beans to up to 100 recipients in one database transaction. Give each recipient an amount, or set total to split it evenly: everyone gets total/n and the remainder goes one bean each to the first recipients in list order. In atomic mode (default) any failure cancels the whole batch; in best_effort mode failed recipients are skipped and reported.
+// @Tags transfer
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body BatchTransferRequest true "Batch transfer details"
+// @Param Idempotency-Key header string false "Unique key that makes retries of this request safe"
+// @Success 200 {object} BatchTransferResponse
+// @Failure 400 {object} BatchTransferResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /transfer/batch [post]
+func (h *TransferHandler) BatchTransfer(c *gin.Context) {
+	username := middleware.GetUsername(c)
+
+	var req BatchTransferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid request: " + err.Error()})
+		return
+	}
+
+	if req.Total < 0 {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "total must be positive"})
+		return
+	}
+
+	recipients := make([]services.BatchRecipient, len(req.Recipients))
+	for i, recipient := range req.Recipients {
+		recipients[i] = services.BatchRecipient{To: recipient.ToUser, Amount: recipient.Amount}
+	}
+
+	mode := services.BatchMode(req.Mode)
+	if mode == "" {
+		mode = services.BatchModeAtomic
+	}
+
+	results, err := h.transferService.BatchTransfer(services.BatchTransferParams{
+		From:       username,
+		Recipients: recipients,
+		Total:      req.Total,
+		Force:      req.Force,
+		Note:       req.Note,
+		Mode:       mode,
 	})
+	if err != nil && err != services.ErrBatchAborted {
+		switch err {
+		case services.ErrNoRecipients, services.ErrTooManyRecipients, services.ErrDuplicateRecipient,
+			services.ErrSplitTooSmall, services.ErrAmountAndTotal, services.ErrInvalidBatchMode:
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		case services.ErrNoteTooLong:
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "note must be at most 200 characters"})
+		default:
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		}
+		return
+	}
+
+	response := BatchTransferResponse{
+		Mode:    string(mode),
+		Results: make([]BatchRecipientResult, len(results)),
+	}
+	for i, result := range results {
+		response.Results[i] = BatchRecipientResult{
+			ToUser:        result.To,
+			Amount:        result.Amount,
+			Status:        string(result.Status),
+			TransactionID: result.TransactionID,
+		}
+		if result.Err != nil {
+			response.Results[i].Error = transferErrorMessage(result.Err)
+		}
+
+		switch result.Status {
+		case services.BatchResultSent:
+			response.Succeeded++
+			response.TotalSent += result.Amount
+		case services.BatchResultFailed:
+			response.Failed++
+		}
+	}
+
+	if err == services.ErrBatchAborted {
+		response.Error = err.Error()
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func transferErrorMessage(err error) string {
+	switch err {
+	case services.ErrInsufficientBalance:
+		return "insufficient balance"
+	case services.ErrRecipientNotFound:
+		return "recipient not found, use force=true to create wallet"
+	case services.ErrInvalidAmount:
+		return "amount must be positive"
+	case services.ErrSelfTransfer:
+		return "cannot transfer to yourself"
+	case services.ErrUserNotFound:
+		return "sender not found"
+	default:
+		return err.Error()
+	}
 }
@@ -1,10 +1,17 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/h4ks-com/bean-bank/internal/middleware"
+	"github.com/h4ks-com/bean-bank/internal/models"
+	"github.com/h4ks-com/bean-bank/internal/repository"
 	"github.com/h4ks-com/bean-bank/internal/services"
 )
 
@@ -22,11 +29,23 @@ type WalletResponse struct {
 }
 
 type TransactionHistoryResponse struct {
-	ID         uint   `json:"id"`
-	FromUser   string `json:"from_user"`
-	ToUser     string `json:"to_user"`
-	Amount     int    `json:"amount"`
-	Timestamp  string `json:"timestamp"`
+	ID                  uint   `json:"id"`
+	FromUser            string `json:"from_user"`
+	ToUser              string `json:"to_user"`
+	Amount              int    `json:"amount"`
+	Note                string `json:"note,omitempty"`
+	Kind                string `json:"kind"`
+	GiftLinkID          *uint  `json:"gift_link_id,omitempty"`
+	GiftCampaignID      *uint  `json:"gift_campaign_id,omitempty"`
+	HarvestID           *uint  `json:"harvest_id,omitempty"`
+	PaymentRequestID    *uint  `json:"payment_request_id,omitempty"`
+	ScheduledTransferID *uint  `json:"scheduled_transfer_id,omitempty"`
+	Timestamp           string `json:"timestamp"`
+}
+
+type TransactionPageResponse struct {
+	Transactions []TransactionHistoryResponse `json:"transactions"`
+	NextCursor   *string                      `json:"next_cursor"`
 }
 
 // GetWallet godoc
@@ -61,12 +80,23 @@ func (h *WalletHandler) GetWallet(c *gin.Context) {
 
 // GetTransactions godoc
 // @Summary Get transaction history
-// @Description Get the authenticated user's transaction history
+// @Description Get a page of the authenticated user's transaction history, newest first. Pass next_cursor from the previous page as cursor to continue.
 // @Tags wallet
 // @Accept json
 // @Produce json
 // @Security BearerAuth
-// @Success 200 {array} TransactionHistoryResponse
+// @Param cursor query string false "Cursor from the previous page"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param from query string false "Only transactions at or after this date (YYYY-MM-DD or RFC 3339)"
+// @Param to query string false "Only transactions up to this date, inclusive (YYYY-MM-DD or RFC 3339)"
+// @Param counterparty query string false "Only transactions with this user"
+// @Param direction query string false "in or out"
+// @Param min_amount query int false "Minimum amount"
+// @Param max_amount query int false "Maximum amount"
+// @Param q query string false "Text to search for in notes"
+// @Param kind query string false "Comma-separated transaction kinds"
+// @Success 200 {object} TransactionPageResponse
+// @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /transactions [get]
@@ -77,22 +107,142 @@ func (h *WalletHandler) GetTransactions(c *gin.Context) {
 		return
 	}
 
-	transactions, err := h.walletService.GetTransactionHistory(username)
+	filter, err := parseTransactionFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	filter.Username = username
+
+	respondTransactionPage(c, h.walletService, filter)
+}
+
+// parseTransactionFilter reads the query parameters shared by every
+// transaction history endpoint. The caller decides whose history is shown.
+func parseTransactionFilter(c *gin.Context) (repository.TransactionFilter, error) {
+	var filter repository.TransactionFilter
+
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 {
+			return filter, errors.New("limit must be a positive integer")
+		}
+		filter.Limit = limit
+	}
+
+	switch direction := repository.TransactionDirection(c.Query("direction")); direction {
+	case "", repository.TransactionDirectionIn, repository.TransactionDirectionOut:
+		filter.Direction = direction
+	default:
+		return filter, errors.New("direction must be 'in' or 'out'")
+	}
+
+	since, _, err := parseHistoryDate(c.Query("from"))
 	if err != nil {
+		return filter, fmt.Errorf("invalid from date: %w", err)
+	}
+	until, dateOnly, err := parseHistoryDate(c.Query("to"))
+	if err != nil {
+		return filter, fmt.Errorf("invalid to date: %w", err)
+	}
+	switch {
+	case until == nil:
+	case dateOnly:
+		// A plain date as the upper bound covers the whole day.
+		next := until.AddDate(0, 0, 1)
+		until = &next
+	case until.Nanosecond() == 0:
+		// Timestamps are listed to the second, so a whole second covers
+		// everything listed at that time.
+		next := until.Add(time.Second)
+		until = &next
+	default:
+		filter.UntilInclusive = true
+	}
+	filter.Since, filter.Until = since, until
+
+	for _, param := range []struct {
+		name string
+		dest **int
+	}{{"min_amount", &filter.MinAmount}, {"max_amount", &filter.MaxAmount}} {
+		raw := c.Query(param.name)
+		if raw == "" {
+			continue
+		}
+		amount, err := strconv.Atoi(raw)
+		if err != nil || amount < 0 {
+			return filter, fmt.Errorf("%s must be a non-negative integer", param.name)
+		}
+		*param.dest = &amount
+	}
+
+	if raw := c.Query("kind"); raw != "" {
+		for _, kind := range strings.Split(raw, ",") {
+			if kind = strings.TrimSpace(kind); kind != "" {
+				filter.Kinds = append(filter.Kinds, models.TransactionKind(kind))
+			}
+		}
+	}
+
+	filter.Counterparty = strings.TrimSpace(c.Query("counterparty"))
+	filter.NoteQuery = strings.TrimSpace(c.Query("q"))
+
+	return filter, nil
+}
+
+// parseHistoryDate accepts RFC 3339 timestamps or plain dates, and reports
+// which one it got.
+func parseHistoryDate(raw string) (*time.Time, bool, error) {
+	if raw == "" {
+		return nil, false, nil
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return &t, false, nil
+	}
+	t, err := time.Parse("2006-01-02", raw)
+	if err != nil {
+		return nil, false, errors.New("use YYYY-MM-DD or RFC 3339")
+	}
+	return &t, true, nil
+}
+
+func respondTransactionPage(c *gin.Context, walletService *services.WalletService, filter repository.TransactionFilter) {
+	transactions, nextCursor, err := walletService.GetTransactionPage(filter, c.Query("cursor"))
+	if err != nil {
+		if err == services.ErrInvalidCursor {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid cursor"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
 		return
 	}
 
-	response := make([]TransactionHistoryResponse, len(transactions))
+	response := TransactionPageResponse{
+		Transactions: make([]TransactionHistoryResponse, len(transactions)),
+	}
 	for i, tx := range transactions {
-		response[i] = TransactionHistoryResponse{
-			ID:        tx.ID,
-			FromUser:  tx.FromUser.Username,
-			ToUser:    tx.ToUser.Username,
-			Amount:    tx.Amount,
-			Timestamp: tx.Timestamp.Format("2006-01-02T15:04:05Z"),
-		}
+		response.Transactions[i] = toTransactionHistoryResponse(tx)
+	}
+	if nextCursor != "" {
+		response.NextCursor = &nextCursor
 	}
 
 	c.JSON(http.StatusOK, response)
 }
+
+func toTransactionHistoryResponse(tx models.Transaction) TransactionHistoryResponse {
+	return TransactionHistoryResponse{
+		ID:                  tx.ID,
+		FromUser:            tx.FromUser.Username,
+		ToUser:              tx.ToUser.Username,
+		Amount:              tx.Amount,
+		Note:                tx.Note,
+		Kind:                string(tx.Kind),
+		GiftLinkID:          tx.GiftLinkID,
+		GiftCampaignID:      tx.GiftCampaignID,
+		HarvestID:           tx.HarvestID,
+		PaymentRequestID:    tx.PaymentRequestID,
+		ScheduledTransferID: tx.ScheduledTransferID,
+		Timestamp:           tx.Timestamp.Format("2006-01-02T15:04:05Z"),
+	}
+}
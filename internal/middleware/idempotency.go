@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/h4ks-com/bean-bank/internal/services"
+)
+
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+type IdempotencyMiddleware struct {
+	idempotencyService *services.IdempotencyService
+}
+
+func NewIdempotencyMiddleware(idempotencyService *services.IdempotencyService) *IdempotencyMiddleware {
+	return &IdempotencyMiddleware{
+		idempotencyService: idempotencyService,
+	}
+}
+
+type responseRecorder struct {
+	gin.ResponseWriter
+	body *bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (m *IdempotencyMiddleware) Handle() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		username := GetUsername(c)
+		if key == "" || username == "" {
+			c.Next()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read request body"})
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		fingerprint := m.idempotencyService.Fingerprint(c.Request.Method, c.Request.URL.Path, body)
+		record, replay, err := m.idempotencyService.Begin(username, key, fingerprint)
+		if err != nil {
+			switch err {
+			case services.ErrIdempotencyKeyReused, services.ErrIdempotencyKeyInProgress:
+				c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			case services.ErrIdempotencyKeyInvalid:
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			}
+			c.Abort()
+			return
+		}
+
+		if record == nil {
+			c.Next()
+			return
+		}
+
+		if replay {
+			c.Header("Idempotent-Replayed", "true")
+			c.Data(record.StatusCode, "application/json; charset=utf-8", record.ResponseBody)
+			c.Abort()
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer, body: &bytes.Buffer{}}
+		c.Writer = recorder
+
+		c.Next()
+
+		status := recorder.Status()
+		if status >= http.StatusInternalServerError {
+			if err := m.idempotencyService.Release(record); err != nil {
+				log.Printf("[Idempotency] Failed to release key %q for %s: %v", key, username, err)
+			}
+			return
+		}
+
+		if err := m.idempotencyService.Complete(record, status, recorder.body.Bytes()); err != nil {
+			log.Printf("[Idempotency] Failed to store response for key %q for %s: %v", key, username, err)
+		}
+	}
+}
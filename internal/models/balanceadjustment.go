@@ -0,0 +1,20 @@
+package models
+
+import (
+	"gorm.io/gorm"
+)
+
+// BalanceAdjustment is the audit record of an admin minting beans into or
+// burning beans from a wallet.
+type BalanceAdjustment struct {
+	gorm.Model
+	UserID        uint        `gorm:"not null;index" json:"user_id"`
+	User          User        `gorm:"foreignKey:UserID" json:"-"`
+	TransactionID uint        `gorm:"not null;index" json:"transaction_id"`
+	Transaction   Transaction `gorm:"foreignKey:TransactionID" json:"-"`
+	ActorUsername string      `gorm:"not null;size:255;index" json:"actor"`
+	Delta         int         `gorm:"not null" json:"delta"`
+	BalanceBefore int         `gorm:"not null" json:"balance_before"`
+	BalanceAfter  int         `gorm:"not null" json:"balance_after"`
+	Reason        string      `gorm:"not null;size:200" json:"reason"`
+}
@@ -0,0 +1,12 @@
+package models
+
+import (
+	"gorm.io/gorm"
+)
+
+// DataMigration records a one-time data migration that has already run, so
+// that it is not applied again on the next startup.
+type DataMigration struct {
+	gorm.Model
+	Name string `gorm:"size:64;not null;uniqueIndex" json:"name"`
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// GiftCampaign is a batch of identical single-use gift links generated
+// together, e.g. to hand out at an event. The whole batch is escrowed with one
+// transaction and can be revoked at once.
+type GiftCampaign struct {
+	gorm.Model
+	Label      string     `gorm:"size:100;not null" json:"label"`
+	FromUserID uint       `gorm:"not null;index" json:"from_user_id"`
+	FromUser   User       `gorm:"foreignKey:FromUserID" json:"-"`
+	Amount     int        `gorm:"not null" json:"amount"`
+	LinkCount  int        `gorm:"not null" json:"link_count"`
+	Message    string     `gorm:"type:text" json:"message"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	Links      []GiftLink `gorm:"foreignKey:CampaignID" json:"-"`
+}
+
+// GiftCampaignStats counts the links of a campaign by Status.
+type GiftCampaignStats struct {
+	Open     int `json:"open"`
+	Redeemed int `json:"redeemed"`
+	Expired  int `json:"expired"`
+	Revoked  int `json:"revoked"`
+}
+
+// Stats tallies the campaign's links, which must be loaded.
+func (c GiftCampaign) Stats() GiftCampaignStats {
+	var stats GiftCampaignStats
+	for _, link := range c.Links {
+		switch link.Status() {
+		case "redeemed":
+			stats.Redeemed++
+		case "expired":
+			stats.Expired++
+		case "revoked":
+			stats.Revoked++
+		default:
+			stats.Open++
+		}
+	}
+	return stats
+}
@@ -7,18 +7,41 @@ import (
 	"gorm.io/gorm"
 )
 
+type GiftLinkMode string
+
+const (
+	// GiftLinkModeFixed pays Amount to each of MaxRedemptions redeemers.
+	GiftLinkModeFixed GiftLinkMode = "fixed"
+	// GiftLinkModeRandom splits Amount into MaxRedemptions random shares
+	// ("red envelope"). The shares are stored when the link is created.
+	GiftLinkModeRandom GiftLinkMode = "random"
+)
+
+// GiftLink escrows beans that up to MaxRedemptions different users can claim,
+// once each. The link is used up when RedemptionCount reaches MaxRedemptions.
 type GiftLink struct {
 	gorm.Model
-	Code         string     `gorm:"uniqueIndex;not null;size:64" json:"code"`
-	FromUserID   uint       `gorm:"not null;index" json:"from_user_id"`
-	FromUser     User       `gorm:"foreignKey:FromUserID" json:"-"`
-	Amount       int        `gorm:"not null" json:"amount"`
-	Message      string     `gorm:"type:text" json:"message"`
-	ExpiresAt    *time.Time `gorm:"index" json:"expires_at"`
-	RedeemedAt   *time.Time `json:"redeemed_at"`
-	RedeemedByID *uint      `gorm:"index" json:"redeemed_by_id"`
-	RedeemedBy   *User      `gorm:"foreignKey:RedeemedByID" json:"-"`
-	Active       bool       `gorm:"default:true;index" json:"active"`
+	Code              string               `gorm:"uniqueIndex;not null;size:64" json:"code"`
+	FromUserID        uint                 `gorm:"not null;index" json:"from_user_id"`
+	FromUser          User                 `gorm:"foreignKey:FromUserID" json:"-"`
+	CampaignID        *uint                `gorm:"index" json:"campaign_id,omitempty"`
+	Amount            int                  `gorm:"not null" json:"amount"`
+	Message           string               `gorm:"type:text" json:"message"`
+	Mode              GiftLinkMode         `gorm:"size:16;not null;default:'fixed'" json:"mode"`
+	MaxRedemptions    int                  `gorm:"not null;default:1" json:"max_redemptions"`
+	RedemptionCount   int                  `gorm:"not null;default:0" json:"redemption_count"`
+	Redemptions       []GiftLinkRedemption `gorm:"foreignKey:GiftLinkID" json:"-"`
+	Shares            []GiftLinkShare      `gorm:"foreignKey:GiftLinkID" json:"-"`
+	AllowedRecipients []GiftLinkRecipient  `gorm:"foreignKey:GiftLinkID" json:"-"`
+	PinHash           string               `gorm:"size:100" json:"-"`
+	FailedPinAttempts int                  `gorm:"not null;default:0" json:"-"`
+	LockedAt          *time.Time           `json:"locked_at"`
+	ExpiresAt         *time.Time           `gorm:"index" json:"expires_at"`
+	RedeemedAt        *time.Time           `json:"redeemed_at"`
+	RedeemedByID      *uint                `gorm:"index" json:"redeemed_by_id"`
+	RedeemedBy        *User                `gorm:"foreignKey:RedeemedByID" json:"-"`
+	Active            bool                 `gorm:"default:true;index" json:"active"`
+	ExpiredAt         *time.Time           `json:"expired_at"`
 }
 
 func (g GiftLink) MarshalJSON() ([]byte, error) {
@@ -29,20 +52,31 @@ func (g GiftLink) MarshalJSON() ([]byte, error) {
 	}
 
 	return json.Marshal(&struct {
-		ID                 uint       `json:"id"`
-		CreatedAt          time.Time  `json:"created_at"`
-		UpdatedAt          time.Time  `json:"updated_at"`
-		Code               string     `json:"code"`
-		FromUserID         uint       `json:"from_user_id"`
-		FromUsername       string     `json:"from_username"`
-		Amount             int        `json:"amount"`
-		Message            string     `json:"message"`
-		ExpiresAt          *time.Time `json:"expires_at"`
-		RedeemedAt         *time.Time `json:"redeemed_at"`
-		RedeemedByID       *uint      `json:"redeemed_by_id"`
-		RedeemedBy         *string    `json:"redeemed_by,omitempty"`
-		RedeemedByUsername *string    `json:"redeemed_by_username,omitempty"`
-		Active             bool       `json:"active"`
+		ID                 uint                 `json:"id"`
+		CreatedAt          time.Time            `json:"created_at"`
+		UpdatedAt          time.Time            `json:"updated_at"`
+		Code               string               `json:"code"`
+		FromUserID         uint                 `json:"from_user_id"`
+		FromUsername       string               `json:"from_username"`
+		CampaignID         *uint                `json:"campaign_id,omitempty"`
+		Amount             int                  `json:"amount"`
+		Message            string               `json:"message"`
+		Mode               GiftLinkMode         `json:"mode"`
+		ExpiresAt          *time.Time           `json:"expires_at"`
+		RedeemedAt         *time.Time           `json:"redeemed_at"`
+		RedeemedByID       *uint                `json:"redeemed_by_id"`
+		RedeemedBy         *string              `json:"redeemed_by,omitempty"`
+		RedeemedByUsername *string              `json:"redeemed_by_username,omitempty"`
+		Active             bool                 `json:"active"`
+		ExpiredAt          *time.Time           `json:"expired_at,omitempty"`
+		LockedAt           *time.Time           `json:"locked_at,omitempty"`
+		PinRequired        bool                 `json:"pin_required"`
+		AllowedRecipients  []string             `json:"allowed_recipients,omitempty"`
+		MaxRedemptions     int                  `json:"max_redemptions"`
+		RedemptionCount    int                  `json:"redemption_count"`
+		RemainingShares    int                  `json:"remaining_shares"`
+		Redemptions        []GiftLinkRedemption `json:"redemptions,omitempty"`
+		Shares             []int                `json:"shares,omitempty"`
 	}{
 		ID:                 g.ID,
 		CreatedAt:          g.CreatedAt,
@@ -50,13 +84,118 @@ func (g GiftLink) MarshalJSON() ([]byte, error) {
 		Code:               g.Code,
 		FromUserID:         g.FromUserID,
 		FromUsername:       g.FromUser.Username,
+		CampaignID:         g.CampaignID,
 		Amount:             g.Amount,
 		Message:            g.Message,
+		Mode:               g.Mode,
 		ExpiresAt:          g.ExpiresAt,
 		RedeemedAt:         g.RedeemedAt,
 		RedeemedByID:       g.RedeemedByID,
 		RedeemedBy:         redeemedByUsername,
 		RedeemedByUsername: redeemedByUsername,
 		Active:             g.Active,
+		ExpiredAt:          g.ExpiredAt,
+		LockedAt:           g.LockedAt,
+		PinRequired:        g.HasPin(),
+		AllowedRecipients:  g.AllowedUsernames(),
+		MaxRedemptions:     g.MaxRedemptions,
+		RedemptionCount:    g.RedemptionCount,
+		RemainingShares:    g.RemainingRedemptions(),
+		Redemptions:        g.Redemptions,
+		Shares:             g.ShareAmounts(),
+	})
+}
+
+// RemainingRedemptions is how many more users can still claim the link.
+func (g GiftLink) RemainingRedemptions() int {
+	if g.RedemptionCount >= g.MaxRedemptions {
+		return 0
+	}
+	return g.MaxRedemptions - g.RedemptionCount
+}
+
+// Status is a one-word summary of the link for listings and exports: open,
+// redeemed (fully claimed), expired (refunded by the sweep) or revoked
+// (deleted by the creator).
+func (g GiftLink) Status() string {
+	switch {
+	case g.RedeemedAt != nil:
+		return "redeemed"
+	case g.ExpiredAt != nil:
+		return "expired"
+	case !g.Active:
+		return "revoked"
+	default:
+		return "open"
+	}
+}
+
+// HasPin reports whether redeeming requires a PIN.
+func (g GiftLink) HasPin() bool {
+	return g.PinHash != ""
+}
+
+// AllowedUsernames lists who may redeem the link, or nil if anyone may.
+func (g GiftLink) AllowedUsernames() []string {
+	if len(g.AllowedRecipients) == 0 {
+		return nil
+	}
+	usernames := make([]string, len(g.AllowedRecipients))
+	for i, recipient := range g.AllowedRecipients {
+		usernames[i] = recipient.Username
+	}
+	return usernames
+}
+
+// ShareAmounts lists the stored random split in claim order, or nil when the
+// shares were not loaded.
+func (g GiftLink) ShareAmounts() []int {
+	if len(g.Shares) == 0 {
+		return nil
+	}
+	amounts := make([]int, len(g.Shares))
+	for i, share := range g.Shares {
+		amounts[i] = share.Amount
+	}
+	return amounts
+}
+
+// GiftLinkShare is one precomputed portion of a random-split gift link. The
+// Nth claim receives the share at Position N-1.
+type GiftLinkShare struct {
+	gorm.Model
+	GiftLinkID uint `gorm:"not null;uniqueIndex:idx_gift_link_share_position" json:"gift_link_id"`
+	Position   int  `gorm:"not null;uniqueIndex:idx_gift_link_share_position" json:"position"`
+	Amount     int  `gorm:"not null" json:"amount"`
+}
+
+// GiftLinkRecipient is one username allowed to redeem a restricted gift link.
+type GiftLinkRecipient struct {
+	gorm.Model
+	GiftLinkID uint   `gorm:"not null;uniqueIndex:idx_gift_link_recipient" json:"gift_link_id"`
+	Username   string `gorm:"not null;size:255;uniqueIndex:idx_gift_link_recipient" json:"username"`
+}
+
+// GiftLinkRedemption records one user's claim on a gift link.
+type GiftLinkRedemption struct {
+	gorm.Model
+	GiftLinkID    uint `gorm:"not null;uniqueIndex:idx_gift_link_redeemer" json:"gift_link_id"`
+	UserID        uint `gorm:"not null;uniqueIndex:idx_gift_link_redeemer;index" json:"user_id"`
+	User          User `gorm:"foreignKey:UserID" json:"-"`
+	Amount        int  `gorm:"not null" json:"amount"`
+	TransactionID uint `gorm:"not null" json:"transaction_id"`
+}
+
+func (r GiftLinkRedemption) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&struct {
+		Username      string    `json:"username"`
+		Amount        int       `json:"amount"`
+		TransactionID uint      `json:"transaction_id"`
+		RedeemedAt    time.Time `json:"redeemed_at"`
+	}{
+		Username:      r.User.Username,
+		Amount:        r.Amount,
+		TransactionID: r.TransactionID,
+		RedeemedAt:    r.CreatedAt,
 	})
 }
@@ -1,13 +1,242 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"strings"
+	"time"
 
+	"gorm.io/gorm"
+)
+
+// HarvestStatus is where a harvest is in its lifecycle:
+// open → claimed → submitted → approved → paid, with rejected sending a
+// submission back to the assignee to fix and resubmit. A harvest whose
+// deadline passes before its work is submitted becomes expired, and a bounty
+// withdrawn by its poster becomes cancelled. An admin can revert a paid
+// harvest, which takes the reward back and reopens it.
+type HarvestStatus string
+
+const (
+	HarvestOpen      HarvestStatus = "open"
+	HarvestClaimed   HarvestStatus = "claimed"
+	HarvestSubmitted HarvestStatus = "submitted"
+	HarvestApproved  HarvestStatus = "approved"
+	HarvestRejected  HarvestStatus = "rejected"
+	HarvestPaid      HarvestStatus = "paid"
+	HarvestExpired   HarvestStatus = "expired"
+	HarvestCancelled HarvestStatus = "cancelled"
+)
+
+// HarvestSplitMode decides how the reward is divided between participants.
+type HarvestSplitMode string
+
+const (
+	// HarvestSplitEqual divides the reward evenly. Beans that don't divide
+	// evenly go one each to the earliest participants.
+	HarvestSplitEqual HarvestSplitMode = "equal"
+	// HarvestSplitExplicit pays each participant their own Amount. The
+	// amounts add up to the harvest's BeanAmount.
+	HarvestSplitExplicit HarvestSplitMode = "explicit"
+)
+
+// Harvest is a task that pays a reward on completion. AssignedUserID is the
+// lead participant, the first one in Participants. DueAt is the optional
+// deadline for submitting the work. When ClaimTimeoutSeconds is set, a claim
+// that goes that long without a submission is released at ClaimExpiresAt.
+// TemplateID links a harvest spawned by a HarvestTemplate back to it.
+// Category and Tags are lower-case labels for browsing and filtering.
+//
+// A harvest with a PosterID is a bounty: a user posted it and its reward is
+// escrowed in the system account from Contributions rather than minted, so
+// BeanAmount is always the sum of the contributions.
+//
+// SettledThroughTransactionID is the last of the harvest's transactions that
+// a revert has dealt with. Payouts up to it were clawed back or written off,
+// so a later revert only looks at the ones after it.
 type Harvest struct {
 	gorm.Model
-	Title          string `gorm:"not null" json:"title"`
-	Description    string `gorm:"type:text" json:"description"`
-	BeanAmount     int    `gorm:"not null" json:"bean_amount"`
-	AssignedUserID *uint  `gorm:"index" json:"assigned_user_id,omitempty"`
-	AssignedUser   *User  `gorm:"foreignKey:AssignedUserID" json:"assigned_user,omitempty"`
-	Completed      bool   `gorm:"default:false;index" json:"completed"`
+	Title                       string                `gorm:"not null" json:"title"`
+	Description                 string                `gorm:"type:text" json:"description"`
+	BeanAmount                  int                   `gorm:"not null" json:"bean_amount"`
+	AssignedUserID              *uint                 `gorm:"index" json:"assigned_user_id,omitempty"`
+	AssignedUser                *User                 `gorm:"foreignKey:AssignedUserID" json:"assigned_user,omitempty"`
+	Participants                []HarvestParticipant  `gorm:"foreignKey:HarvestID" json:"participants,omitempty"`
+	SplitMode                   HarvestSplitMode      `gorm:"size:16;not null;default:equal" json:"split_mode"`
+	Status                      HarvestStatus         `gorm:"size:16;not null;default:open;index" json:"status"`
+	Completed                   bool                  `gorm:"default:false;index" json:"completed"`
+	DueAt                       *time.Time            `gorm:"index" json:"due_at,omitempty"`
+	ClaimTimeoutSeconds         int64                 `gorm:"not null;default:0" json:"claim_timeout_seconds"`
+	ClaimExpiresAt              *time.Time            `gorm:"index" json:"claim_expires_at,omitempty"`
+	TemplateID                  *uint                 `gorm:"index" json:"template_id,omitempty"`
+	PosterID                    *uint                 `gorm:"index" json:"poster_id,omitempty"`
+	Poster                      *User                 `gorm:"foreignKey:PosterID" json:"poster,omitempty"`
+	Contributions               []HarvestContribution `gorm:"foreignKey:HarvestID" json:"contributions,omitempty"`
+	Category                    string                `gorm:"size:32;index" json:"category,omitempty"`
+	Tags                        []HarvestTag          `gorm:"foreignKey:HarvestID" json:"tags,omitempty"`
+	SettledThroughTransactionID uint                  `gorm:"not null;default:0" json:"-"`
+}
+
+// TagNames returns the names of the harvest's tags.
+func (h *Harvest) TagNames() []string {
+	names := make([]string, len(h.Tags))
+	for i, tag := range h.Tags {
+		names[i] = tag.Name
+	}
+	return names
+}
+
+// IsBounty reports whether the harvest is paid from escrow rather than
+// minted.
+func (h *Harvest) IsBounty() bool {
+	return h.PosterID != nil
+}
+
+// Overdue reports whether the deadline has passed without the harvest being
+// paid or cancelled.
+func (h *Harvest) Overdue(now time.Time) bool {
+	if h.Completed || h.Status == HarvestCancelled {
+		return false
+	}
+	return h.Status == HarvestExpired || (h.DueAt != nil && !h.DueAt.After(now))
+}
+
+// Shares returns what each participant is paid, in the order of
+// Participants.
+func (h *Harvest) Shares() []int {
+	shares := make([]int, len(h.Participants))
+	if len(shares) == 0 {
+		return shares
+	}
+
+	if h.SplitMode == HarvestSplitExplicit {
+		for i, p := range h.Participants {
+			shares[i] = p.Amount
+		}
+		return shares
+	}
+
+	base, remainder := h.BeanAmount/len(shares), h.BeanAmount%len(shares)
+	for i := range shares {
+		shares[i] = base
+		if i < remainder {
+			shares[i]++
+		}
+	}
+	return shares
+}
+
+// HarvestParticipant is one of the users working on a harvest. Amount is only
+// set when the harvest uses an explicit split.
+type HarvestParticipant struct {
+	gorm.Model
+	HarvestID uint `gorm:"not null;uniqueIndex:idx_harvest_participant" json:"harvest_id"`
+	UserID    uint `gorm:"not null;uniqueIndex:idx_harvest_participant;index" json:"user_id"`
+	User      User `gorm:"foreignKey:UserID" json:"user"`
+	Amount    int  `gorm:"not null;default:0" json:"amount"`
+}
+
+// HarvestContribution is what one user has put into a bounty's escrow,
+// including the poster's initial funding. Refunded is set when the bounty is
+// cancelled.
+type HarvestContribution struct {
+	gorm.Model
+	HarvestID uint `gorm:"not null;uniqueIndex:idx_harvest_contribution" json:"harvest_id"`
+	UserID    uint `gorm:"not null;uniqueIndex:idx_harvest_contribution;index" json:"user_id"`
+	User      User `gorm:"foreignKey:UserID" json:"user"`
+	Amount    int  `gorm:"not null" json:"amount"`
+	Refunded  int  `gorm:"not null;default:0" json:"refunded"`
+}
+
+// HarvestTag is one label on a harvest.
+type HarvestTag struct {
+	gorm.Model
+	HarvestID uint   `gorm:"not null;uniqueIndex:idx_harvest_tag" json:"harvest_id"`
+	Name      string `gorm:"size:32;not null;uniqueIndex:idx_harvest_tag;index" json:"name"`
+}
+
+type HarvestSubmissionStatus string
+
+const (
+	HarvestSubmissionPending   HarvestSubmissionStatus = "pending"
+	HarvestSubmissionApproved  HarvestSubmissionStatus = "approved"
+	HarvestSubmissionRejected  HarvestSubmissionStatus = "rejected"
+	HarvestSubmissionWithdrawn HarvestSubmissionStatus = "withdrawn"
+)
+
+// HarvestSubmission is the proof an assignee hands in for review. Links are
+// stored one per line.
+type HarvestSubmission struct {
+	gorm.Model
+	HarvestID     uint                    `gorm:"not null;index" json:"harvest_id"`
+	UserID        uint                    `gorm:"not null;index" json:"user_id"`
+	User          User                    `gorm:"foreignKey:UserID" json:"-"`
+	Proof         string                  `gorm:"type:text" json:"proof"`
+	Links         string                  `gorm:"type:text" json:"-"`
+	Status        HarvestSubmissionStatus `gorm:"size:16;not null;default:pending" json:"status"`
+	Reviewer      string                  `gorm:"size:255" json:"reviewer,omitempty"`
+	ReviewComment string                  `gorm:"type:text" json:"review_comment,omitempty"`
+	ReviewedAt    *time.Time              `json:"reviewed_at,omitempty"`
+}
+
+func (s *HarvestSubmission) LinkList() []string {
+	if s.Links == "" {
+		return []string{}
+	}
+	return strings.Split(s.Links, "\n")
+}
+
+type HarvestEventType string
+
+const (
+	HarvestEventCreated   HarvestEventType = "created"
+	HarvestEventAssigned  HarvestEventType = "assigned"
+	HarvestEventClaimed   HarvestEventType = "claimed"
+	HarvestEventJoined    HarvestEventType = "joined"
+	HarvestEventWithdrawn HarvestEventType = "withdrawn"
+	HarvestEventSubmitted HarvestEventType = "submitted"
+	HarvestEventApproved  HarvestEventType = "approved"
+	HarvestEventRejected  HarvestEventType = "rejected"
+	HarvestEventPaid      HarvestEventType = "paid"
+	HarvestEventReleased  HarvestEventType = "released"
+	HarvestEventExpired   HarvestEventType = "expired"
+	HarvestEventReopened  HarvestEventType = "reopened"
+	HarvestEventFunded    HarvestEventType = "funded"
+	HarvestEventCancelled HarvestEventType = "cancelled"
+	HarvestEventReverted  HarvestEventType = "reverted"
+	// HarvestEventCommented and HarvestEventCommentRemoved put the
+	// discussion on the timeline. They don't change the status.
+	HarvestEventCommented      HarvestEventType = "commented"
+	HarvestEventCommentRemoved HarvestEventType = "comment_removed"
+)
+
+// HarvestCommentEventTypes are the events about comments rather than the
+// harvest's lifecycle.
+var HarvestCommentEventTypes = []HarvestEventType{HarvestEventCommented, HarvestEventCommentRemoved}
+
+// HarvestEvent records one change to a harvest. Actor is the username that
+// caused it, or empty for changes made by an admin tool or the scheduler.
+// Comment events link to their comment through CommentID.
+type HarvestEvent struct {
+	gorm.Model
+	HarvestID  uint             `gorm:"not null;index" json:"harvest_id"`
+	Type       HarvestEventType `gorm:"size:32;not null" json:"type"`
+	FromStatus HarvestStatus    `gorm:"size:16" json:"from_status,omitempty"`
+	ToStatus   HarvestStatus    `gorm:"size:16" json:"to_status,omitempty"`
+	Actor      string           `gorm:"size:255" json:"actor,omitempty"`
+	Note       string           `gorm:"type:text" json:"note,omitempty"`
+	CommentID  *uint            `gorm:"index" json:"comment_id,omitempty"`
+	Comment    *HarvestComment  `gorm:"foreignKey:CommentID" json:"comment,omitempty"`
+}
+
+// HarvestComment is a message in the discussion on a harvest. EditedAt is set
+// when its author changes it. Deleting a comment soft-deletes it; when an
+// admin removes it, RemovedBy and RemovalReason say who and why.
+type HarvestComment struct {
+	gorm.Model
+	HarvestID     uint       `gorm:"not null;index" json:"harvest_id"`
+	UserID        uint       `gorm:"not null;index" json:"user_id"`
+	User          User       `gorm:"foreignKey:UserID" json:"-"`
+	Body          string     `gorm:"type:text;not null" json:"body"`
+	EditedAt      *time.Time `json:"edited_at,omitempty"`
+	RemovedBy     string     `gorm:"size:255" json:"-"`
+	RemovalReason string     `gorm:"type:text" json:"-"`
 }
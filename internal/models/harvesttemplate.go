@@ -0,0 +1,49 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type HarvestTemplateStatus string
+
+const (
+	HarvestTemplateActive   HarvestTemplateStatus = "active"
+	HarvestTemplatePaused   HarvestTemplateStatus = "paused"
+	HarvestTemplateFinished HarvestTemplateStatus = "finished"
+)
+
+// HarvestTemplate spawns a fresh harvest on every occurrence of its
+// recurrence rule, which works like a scheduled transfer's. DeadlineSeconds
+// and ClaimTimeoutSeconds are counted from each spawn. When AssigneeID is
+// set, spawned harvests start out claimed by that user. Category and Tags are
+// copied to every spawn; Tags are stored one per line.
+type HarvestTemplate struct {
+	gorm.Model
+	Title               string                `gorm:"not null" json:"title"`
+	Description         string                `gorm:"type:text" json:"description"`
+	BeanAmount          int                   `gorm:"not null" json:"bean_amount"`
+	DeadlineSeconds     int64                 `gorm:"not null;default:0" json:"deadline_seconds"`
+	ClaimTimeoutSeconds int64                 `gorm:"not null;default:0" json:"claim_timeout_seconds"`
+	AssigneeID          *uint                 `gorm:"index" json:"assignee_id,omitempty"`
+	Assignee            *User                 `gorm:"foreignKey:AssigneeID" json:"assignee,omitempty"`
+	Frequency           ScheduleFrequency     `gorm:"size:16;not null" json:"frequency"`
+	CronExpr            string                `gorm:"size:100" json:"cron,omitempty"`
+	StartAt             time.Time             `gorm:"not null" json:"start_at"`
+	EndsAt              *time.Time            `json:"ends_at"`
+	NextRunAt           *time.Time            `gorm:"index" json:"next_run_at"`
+	LastRunAt           *time.Time            `json:"last_run_at"`
+	Status              HarvestTemplateStatus `gorm:"size:16;not null;default:active;index" json:"status"`
+	SpawnCount          int                   `gorm:"not null;default:0" json:"spawn_count"`
+	Category            string                `gorm:"size:32" json:"category,omitempty"`
+	Tags                string                `gorm:"type:text" json:"-"`
+}
+
+func (t *HarvestTemplate) TagList() []string {
+	if t.Tags == "" {
+		return []string{}
+	}
+	return strings.Split(t.Tags, "\n")
+}
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"gorm.io/gorm"
+)
+
+type IdempotencyKey struct {
+	gorm.Model
+	UserID       uint   `gorm:"not null;uniqueIndex:idx_idempotency_user_key" json:"user_id"`
+	User         User   `gorm:"foreignKey:UserID" json:"-"`
+	Key          string `gorm:"not null;size:255;uniqueIndex:idx_idempotency_user_key" json:"key"`
+	RequestHash  string `gorm:"not null;size:64" json:"-"`
+	StatusCode   int    `json:"status_code"`
+	ResponseBody []byte `json:"-"`
+	Completed    bool   `gorm:"default:false" json:"completed"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import (
+	"gorm.io/gorm"
+)
+
+// LedgerEntry is one side of a Transaction. Every transaction posts a debit
+// against the sender and a matching credit to the recipient, so the entries of
+// a transaction always sum to zero.
+type LedgerEntry struct {
+	gorm.Model
+	TransactionID uint        `gorm:"not null;index" json:"transaction_id"`
+	Transaction   Transaction `gorm:"foreignKey:TransactionID" json:"-"`
+	UserID        uint        `gorm:"not null;index" json:"user_id"`
+	User          User        `gorm:"foreignKey:UserID" json:"-"`
+	Amount        int         `gorm:"not null" json:"amount"`
+	BalanceAfter  int         `gorm:"not null" json:"balance_after"`
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type PaymentRequestStatus string
+
+const (
+	PaymentRequestPending   PaymentRequestStatus = "pending"
+	PaymentRequestPaid      PaymentRequestStatus = "paid"
+	PaymentRequestDeclined  PaymentRequestStatus = "declined"
+	PaymentRequestCancelled PaymentRequestStatus = "cancelled"
+)
+
+// PaymentRequest asks a user for beans. When PayerID is nil anyone holding the
+// code may pay it.
+type PaymentRequest struct {
+	gorm.Model
+	Code          string               `gorm:"uniqueIndex;not null;size:64" json:"code"`
+	RequesterID   uint                 `gorm:"not null;index" json:"requester_id"`
+	Requester     User                 `gorm:"foreignKey:RequesterID" json:"-"`
+	PayerID       *uint                `gorm:"index" json:"payer_id"`
+	Payer         *User                `gorm:"foreignKey:PayerID" json:"-"`
+	Amount        int                  `gorm:"not null" json:"amount"`
+	Memo          string               `gorm:"type:text" json:"memo"`
+	ExpiresAt     *time.Time           `gorm:"index" json:"expires_at"`
+	Status        PaymentRequestStatus `gorm:"size:16;not null;default:pending;index" json:"status"`
+	PaidByID      *uint                `gorm:"index" json:"paid_by_id"`
+	PaidBy        *User                `gorm:"foreignKey:PaidByID" json:"-"`
+	PaidAt        *time.Time           `json:"paid_at"`
+	TransactionID *uint                `gorm:"index" json:"transaction_id"`
+}
+
+// IsExpired reports whether a pending request is past its expiry.
+func (p *PaymentRequest) IsExpired(now time.Time) bool {
+	return p.Status == PaymentRequestPending && p.ExpiresAt != nil && now.After(*p.ExpiresAt)
+}
@@ -0,0 +1,84 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ScheduleFrequency string
+
+const (
+	ScheduleOnce    ScheduleFrequency = "once"
+	ScheduleDaily   ScheduleFrequency = "daily"
+	ScheduleWeekly  ScheduleFrequency = "weekly"
+	ScheduleMonthly ScheduleFrequency = "monthly"
+	ScheduleCron    ScheduleFrequency = "cron"
+)
+
+type ScheduledTransferStatus string
+
+const (
+	ScheduledTransferActive    ScheduledTransferStatus = "active"
+	ScheduledTransferCompleted ScheduledTransferStatus = "completed"
+	ScheduledTransferFailed    ScheduledTransferStatus = "failed"
+	ScheduledTransferCancelled ScheduledTransferStatus = "cancelled"
+)
+
+// FailurePolicy decides what happens when a scheduled run cannot be made,
+// for example because the sender is short on beans. "skip" moves on to the
+// next occurrence; "retry" tries again with backoff until MaxRetries is used
+// up or the next occurrence comes around.
+type FailurePolicy string
+
+const (
+	FailurePolicySkip  FailurePolicy = "skip"
+	FailurePolicyRetry FailurePolicy = "retry"
+)
+
+// ScheduledTransfer is a future or recurring transfer. StartAt anchors the
+// daily, weekly and monthly recurrences; NextRunAt is when the scheduler will
+// next attempt it.
+type ScheduledTransfer struct {
+	gorm.Model
+	FromUserID    uint                    `gorm:"not null;index" json:"from_user_id"`
+	FromUser      User                    `gorm:"foreignKey:FromUserID" json:"-"`
+	ToUsername    string                  `gorm:"not null;size:255" json:"to_username"`
+	Amount        int                     `gorm:"not null" json:"amount"`
+	Note          string                  `gorm:"type:text" json:"note"`
+	Force         bool                    `gorm:"default:false" json:"force"`
+	Frequency     ScheduleFrequency       `gorm:"size:16;not null" json:"frequency"`
+	CronExpr      string                  `gorm:"size:100" json:"cron,omitempty"`
+	StartAt       time.Time               `gorm:"not null" json:"start_at"`
+	EndsAt        *time.Time              `json:"ends_at"`
+	NextRunAt     *time.Time              `gorm:"index" json:"next_run_at"`
+	LastRunAt     *time.Time              `json:"last_run_at"`
+	Status        ScheduledTransferStatus `gorm:"size:16;not null;default:active;index" json:"status"`
+	FailurePolicy FailurePolicy           `gorm:"size:16;not null;default:skip" json:"failure_policy"`
+	MaxRetries    int                     `gorm:"not null;default:0" json:"max_retries"`
+	RetryCount    int                     `gorm:"not null;default:0" json:"retry_count"`
+	RunCount      int                     `gorm:"not null;default:0" json:"run_count"`
+	FailureCount  int                     `gorm:"not null;default:0" json:"failure_count"`
+	LastError     string                  `gorm:"type:text" json:"last_error,omitempty"`
+}
+
+type ScheduledRunStatus string
+
+const (
+	ScheduledRunSucceeded ScheduledRunStatus = "succeeded"
+	ScheduledRunFailed    ScheduledRunStatus = "failed"
+	ScheduledRunSkipped   ScheduledRunStatus = "skipped"
+)
+
+// ScheduledTransferRun records one attempt of a scheduled transfer. Failed
+// attempts that will be retried are "failed"; an occurrence given up on is
+// "skipped".
+type ScheduledTransferRun struct {
+	gorm.Model
+	ScheduledTransferID uint               `gorm:"not null;index" json:"scheduled_transfer_id"`
+	RunAt               time.Time          `gorm:"not null" json:"run_at"`
+	Attempt             int                `gorm:"not null" json:"attempt"`
+	Status              ScheduledRunStatus `gorm:"size:16;not null" json:"status"`
+	TransactionID       *uint              `json:"transaction_id,omitempty"`
+	Error               string             `gorm:"type:text" json:"error,omitempty"`
+}
@@ -6,13 +6,40 @@ import (
 	"gorm.io/gorm"
 )
 
+type TransactionKind string
+
+const (
+	TransactionKindTransfer        TransactionKind = "transfer"
+	TransactionKindGiftEscrow      TransactionKind = "gift_escrow"
+	TransactionKindGiftRedeem      TransactionKind = "gift_redeem"
+	TransactionKindGiftRefund      TransactionKind = "gift_refund"
+	TransactionKindHarvestReward   TransactionKind = "harvest_reward"
+	TransactionKindAdminAdjustment TransactionKind = "admin_adjustment"
+	TransactionKindImport          TransactionKind = "import"
+	TransactionKindSignupBonus     TransactionKind = "signup_bonus"
+	TransactionKindReconciliation  TransactionKind = "reconciliation"
+	TransactionKindPaymentRequest  TransactionKind = "payment_request"
+	TransactionKindScheduled       TransactionKind = "scheduled_transfer"
+	TransactionKindBountyEscrow    TransactionKind = "bounty_escrow"
+	TransactionKindBountyPayout    TransactionKind = "bounty_payout"
+	TransactionKindBountyRefund    TransactionKind = "bounty_refund"
+	TransactionKindHarvestClawback TransactionKind = "harvest_clawback"
+	TransactionKindOpeningBalance  TransactionKind = "opening_balance"
+)
+
 type Transaction struct {
 	gorm.Model
-	FromUserID uint      `gorm:"not null;index" json:"from_user_id"`
-	FromUser   User      `gorm:"foreignKey:FromUserID" json:"from_user,omitempty"`
-	ToUserID   uint      `gorm:"not null;index" json:"to_user_id"`
-	ToUser     User      `gorm:"foreignKey:ToUserID" json:"to_user,omitempty"`
-	Amount     int       `gorm:"not null" json:"amount"`
-	Note       string    `gorm:"type:text" json:"note,omitempty"`
-	Timestamp  time.Time `gorm:"autoCreateTime" json:"timestamp"`
+	FromUserID          uint            `gorm:"not null;index" json:"from_user_id"`
+	FromUser            User            `gorm:"foreignKey:FromUserID" json:"from_user,omitempty"`
+	ToUserID            uint            `gorm:"not null;index" json:"to_user_id"`
+	ToUser              User            `gorm:"foreignKey:ToUserID" json:"to_user,omitempty"`
+	Amount              int             `gorm:"not null" json:"amount"`
+	Note                string          `gorm:"type:text" json:"note,omitempty"`
+	Kind                TransactionKind `gorm:"size:32;not null;default:transfer;index" json:"kind"`
+	GiftLinkID          *uint           `gorm:"index" json:"gift_link_id,omitempty"`
+	GiftCampaignID      *uint           `gorm:"index" json:"gift_campaign_id,omitempty"`
+	HarvestID           *uint           `gorm:"index" json:"harvest_id,omitempty"`
+	PaymentRequestID    *uint           `gorm:"index" json:"payment_request_id,omitempty"`
+	ScheduledTransferID *uint           `gorm:"index" json:"scheduled_transfer_id,omitempty"`
+	Timestamp           time.Time       `gorm:"autoCreateTime" json:"timestamp"`
 }
@@ -4,11 +4,28 @@ import (
 	"gorm.io/gorm"
 )
 
+const (
+	// SystemUsername is the reserved account that holds escrowed beans.
+	SystemUsername = "system"
+	// MintUsername is the reserved account that issues new beans. Its balance
+	// is the negative of all beans ever minted, so it is the only wallet
+	// allowed to go below zero.
+	MintUsername = "mint"
+)
+
+func IsReservedUsername(username string) bool {
+	return username == SystemUsername || username == MintUsername
+}
+
+// User is a wallet. Reserved is set on the system and mint accounts, so that
+// a person who signed up under one of those names before it was reserved is
+// never mistaken for it.
 type User struct {
 	gorm.Model
 	Username     string        `gorm:"uniqueIndex;not null" json:"username"`
 	Email        string        `gorm:"" json:"email,omitempty"`
 	BeanAmount   int           `gorm:"not null" json:"bean_amount"`
+	Reserved     bool          `gorm:"not null;default:false" json:"-"`
 	Transactions []Transaction `gorm:"foreignKey:FromUserID" json:"-"`
 	APITokens    []APIToken    `gorm:"foreignKey:UserID" json:"-"`
 }
@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"github.com/h4ks-com/bean-bank/internal/models"
+	"gorm.io/gorm"
+)
+
+type AdjustmentRepository struct {
+	db *gorm.DB
+}
+
+func NewAdjustmentRepository(db *gorm.DB) *AdjustmentRepository {
+	return &AdjustmentRepository{db: db}
+}
+
+func (r *AdjustmentRepository) Create(tx *gorm.DB, adjustment *models.BalanceAdjustment) error {
+	return tx.Create(adjustment).Error
+}
+
+// Find returns adjustments newest first. Empty username or actor match all.
+func (r *AdjustmentRepository) Find(username, actor string, limit int) ([]models.BalanceAdjustment, error) {
+	var adjustments []models.BalanceAdjustment
+	query := r.db.Preload("User").Order("balance_adjustments.created_at DESC, balance_adjustments.id DESC")
+
+	if username != "" {
+		query = query.Joins("JOIN users ON users.id = balance_adjustments.user_id").
+			Where("users.username = ?", username)
+	}
+	if actor != "" {
+		query = query.Where("balance_adjustments.actor_username = ?", actor)
+	}
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	err := query.Find(&adjustments).Error
+	return adjustments, err
+}
@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"time"
+
 	"github.com/h4ks-com/bean-bank/internal/models"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
@@ -20,7 +22,7 @@ func (r *GiftLinkRepository) Create(giftLink *models.GiftLink) error {
 
 func (r *GiftLinkRepository) FindByCode(code string) (*models.GiftLink, error) {
 	var giftLink models.GiftLink
-	err := r.db.Preload("FromUser").Preload("RedeemedBy").
+	err := r.db.Preload("FromUser").Preload("RedeemedBy").Preload("AllowedRecipients").
 		Where("code = ?", code).
 		First(&giftLink).Error
 
@@ -36,7 +38,7 @@ func (r *GiftLinkRepository) FindByCode(code string) (*models.GiftLink, error) {
 func (r *GiftLinkRepository) FindByCodeForUpdate(tx *gorm.DB, code string) (*models.GiftLink, error) {
 	var giftLink models.GiftLink
 	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
-		Preload("FromUser").
+		Preload("FromUser").Preload("AllowedRecipients").
 		Where("code = ?", code).
 		First(&giftLink).Error
 
@@ -49,10 +51,43 @@ func (r *GiftLinkRepository) FindByCodeForUpdate(tx *gorm.DB, code string) (*mod
 	return &giftLink, nil
 }
 
+func (r *GiftLinkRepository) FindByIDForUpdate(tx *gorm.DB, id uint) (*models.GiftLink, error) {
+	var giftLink models.GiftLink
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Preload("FromUser").
+		Where("id = ?", id).
+		First(&giftLink).Error
+
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &giftLink, nil
+}
+
+// FindExpiredIDs returns active, unredeemed links whose expiry is at or before
+// now, oldest expiry first.
+func (r *GiftLinkRepository) FindExpiredIDs(now time.Time, limit int) ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&models.GiftLink{}).
+		Where("active = ? AND redeemed_at IS NULL AND expires_at IS NOT NULL AND expires_at <= ?", true, now).
+		Order("expires_at ASC").
+		Limit(limit).
+		Pluck("id", &ids).Error
+
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
 func (r *GiftLinkRepository) ListByFromUserID(userID uint) ([]models.GiftLink, error) {
 	var giftLinks []models.GiftLink
-	err := r.db.Preload("FromUser").Preload("RedeemedBy").
-		Where("from_user_id = ? AND active = ?", userID, true).
+	err := r.db.Preload("FromUser").Preload("RedeemedBy").Preload("Redemptions.User").Preload("AllowedRecipients").
+		Preload("Shares", func(db *gorm.DB) *gorm.DB { return db.Order("position ASC") }).
+		Where("from_user_id = ? AND campaign_id IS NULL AND (active = ? OR expired_at IS NOT NULL)", userID, true).
 		Order("created_at DESC").
 		Find(&giftLinks).Error
 
@@ -88,3 +123,135 @@ func (r *GiftLinkRepository) UpdateInTx(tx *gorm.DB, giftLink *models.GiftLink)
 func (r *GiftLinkRepository) Delete(id uint) error {
 	return r.db.Delete(&models.GiftLink{}, id).Error
 }
+
+// FindRedemptionInTx returns the user's claim on the link, or nil if they
+// have not claimed it.
+func (r *GiftLinkRepository) FindRedemptionInTx(tx *gorm.DB, giftLinkID, userID uint) (*models.GiftLinkRedemption, error) {
+	var redemption models.GiftLinkRedemption
+	err := tx.Where("gift_link_id = ? AND user_id = ?", giftLinkID, userID).
+		First(&redemption).Error
+
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &redemption, nil
+}
+
+func (r *GiftLinkRepository) CreateRedemptionInTx(tx *gorm.DB, redemption *models.GiftLinkRedemption) error {
+	return tx.Create(redemption).Error
+}
+
+func (r *GiftLinkRepository) CreateSharesInTx(tx *gorm.DB, shares []models.GiftLinkShare) error {
+	return tx.Create(&shares).Error
+}
+
+// FindShareInTx returns the random-split share at position, or nil if the
+// link has no share there.
+func (r *GiftLinkRepository) FindShareInTx(tx *gorm.DB, giftLinkID uint, position int) (*models.GiftLinkShare, error) {
+	var share models.GiftLinkShare
+	err := tx.Where("gift_link_id = ? AND position = ?", giftLinkID, position).
+		First(&share).Error
+
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &share, nil
+}
+
+// SumSharesFromInTx totals the shares at or after position, which is what is
+// still unclaimed on a random-split link.
+func (r *GiftLinkRepository) SumSharesFromInTx(tx *gorm.DB, giftLinkID uint, position int) (int, error) {
+	var total int64
+	err := tx.Model(&models.GiftLinkShare{}).
+		Where("gift_link_id = ? AND position >= ?", giftLinkID, position).
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&total).Error
+
+	if err != nil {
+		return 0, err
+	}
+	return int(total), nil
+}
+
+func (r *GiftLinkRepository) CreateRecipientsInTx(tx *gorm.DB, recipients []models.GiftLinkRecipient) error {
+	return tx.Create(&recipients).Error
+}
+
+func (r *GiftLinkRepository) CreateCampaignInTx(tx *gorm.DB, campaign *models.GiftCampaign) error {
+	return tx.Create(campaign).Error
+}
+
+func (r *GiftLinkRepository) UpdateCampaignInTx(tx *gorm.DB, campaign *models.GiftCampaign) error {
+	return tx.Save(campaign).Error
+}
+
+func (r *GiftLinkRepository) CreateLinksInTx(tx *gorm.DB, giftLinks []models.GiftLink) error {
+	return tx.CreateInBatches(&giftLinks, 100).Error
+}
+
+// FindCampaignByID returns the campaign with its links in creation order, or
+// nil if it does not exist.
+func (r *GiftLinkRepository) FindCampaignByID(id uint) (*models.GiftCampaign, error) {
+	var campaign models.GiftCampaign
+	err := r.db.Preload("FromUser").Preload("Links.RedeemedBy").
+		Preload("Links", func(db *gorm.DB) *gorm.DB { return db.Order("id ASC") }).
+		Where("id = ?", id).
+		First(&campaign).Error
+
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &campaign, nil
+}
+
+func (r *GiftLinkRepository) FindCampaignByIDForUpdate(tx *gorm.DB, id uint) (*models.GiftCampaign, error) {
+	var campaign models.GiftCampaign
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Preload("FromUser").
+		Where("id = ?", id).
+		First(&campaign).Error
+
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &campaign, nil
+}
+
+func (r *GiftLinkRepository) ListCampaignsByFromUserID(userID uint) ([]models.GiftCampaign, error) {
+	var campaigns []models.GiftCampaign
+	err := r.db.Preload("FromUser").Preload("Links").
+		Where("from_user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&campaigns).Error
+
+	if err != nil {
+		return nil, err
+	}
+	return campaigns, nil
+}
+
+// FindCampaignLinksForUpdate locks every link of the campaign.
+func (r *GiftLinkRepository) FindCampaignLinksForUpdate(tx *gorm.DB, campaignID uint) ([]models.GiftLink, error) {
+	var giftLinks []models.GiftLink
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("campaign_id = ?", campaignID).
+		Order("id ASC").
+		Find(&giftLinks).Error
+
+	if err != nil {
+		return nil, err
+	}
+	return giftLinks, nil
+}
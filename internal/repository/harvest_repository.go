@@ -1,6 +1,12 @@
 package repository
 
 import (
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/h4ks-com/bean-bank/internal/database"
 	"github.com/h4ks-com/bean-bank/internal/models"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
@@ -8,6 +14,9 @@ import (
 
 type HarvestRepository struct {
 	db *gorm.DB
+
+	searchOnce sync.Once
+	search     harvestTextSearch
 }
 
 func NewHarvestRepository(db *gorm.DB) *HarvestRepository {
@@ -18,9 +27,13 @@ func (r *HarvestRepository) Create(harvest *models.Harvest) error {
 	return r.db.Create(harvest).Error
 }
 
+func (r *HarvestRepository) CreateInTx(tx *gorm.DB, harvest *models.Harvest) error {
+	return tx.Create(harvest).Error
+}
+
 func (r *HarvestRepository) FindByID(id uint) (*models.Harvest, error) {
 	var harvest models.Harvest
-	err := r.db.Preload("AssignedUser").First(&harvest, id).Error
+	err := withParticipants(r.db.Preload("AssignedUser")).First(&harvest, id).Error
 	if err != nil {
 		return nil, err
 	}
@@ -37,51 +50,494 @@ func (r *HarvestRepository) FindByIDForUpdate(tx *gorm.DB, id uint) (*models.Har
 }
 
 func (r *HarvestRepository) Update(harvest *models.Harvest) error {
-	return r.db.Save(harvest).Error
+	return r.db.Omit(clause.Associations).Save(harvest).Error
 }
 
 func (r *HarvestRepository) UpdateInTx(tx *gorm.DB, harvest *models.Harvest) error {
-	return tx.Save(harvest).Error
+	return tx.Omit(clause.Associations).Save(harvest).Error
 }
 
 func (r *HarvestRepository) Delete(id uint) error {
 	return r.db.Delete(&models.Harvest{}, id).Error
 }
 
-func (r *HarvestRepository) Search(query string, page, limit int) ([]models.Harvest, error) {
-	var harvests []models.Harvest
-	offset := (page - 1) * limit
+// HarvestListStatus groups harvest statuses the way the public listing
+// filters them.
+type HarvestListStatus string
 
-	db := r.db.Preload("AssignedUser")
+const (
+	HarvestListOpen      HarvestListStatus = "open"
+	HarvestListAssigned  HarvestListStatus = "assigned"
+	HarvestListOverdue   HarvestListStatus = "overdue"
+	HarvestListCompleted HarvestListStatus = "completed"
+)
 
-	if query != "" {
-		searchPattern := "%" + query + "%"
-		db = db.Where("title LIKE ? OR description LIKE ?", searchPattern, searchPattern)
-	}
+// HarvestSort orders a page of harvests.
+type HarvestSort string
 
-	err := db.Order("updated_at DESC").
-		Offset(offset).
-		Limit(limit).
+const (
+	// HarvestSortRelevance ranks full-text matches best first. It is the
+	// default when there is a query.
+	HarvestSortRelevance HarvestSort = "relevance"
+	HarvestSortNewest    HarvestSort = "newest"
+	// HarvestSortReward puts the biggest rewards first.
+	HarvestSortReward HarvestSort = "reward"
+	// HarvestSortDeadline puts the nearest deadlines first and harvests
+	// without one last.
+	HarvestSortDeadline HarvestSort = "deadline"
+)
+
+// HarvestFilter narrows a page of harvests. Zero values match everything.
+// A harvest must carry every one of Tags, and Assignee must be one of its
+// participants. Now decides which harvests are overdue. Without a Sort,
+// harvests are ranked when there is a Query and otherwise listed by when they
+// last changed.
+type HarvestFilter struct {
+	Query     string
+	Status    HarvestListStatus
+	Category  string
+	Tags      []string
+	Assignee  string
+	MinReward int
+	MaxReward int
+	Sort      HarvestSort
+	Now       time.Time
+	Page      int
+	Limit     int
+}
+
+func (r *HarvestRepository) Search(filter HarvestFilter) ([]models.Harvest, error) {
+	var harvests []models.Harvest
+	offset := (filter.Page - 1) * filter.Limit
+
+	db := withParticipants(r.db.Preload("AssignedUser"))
+	db = r.applyHarvestFilter(db, filter)
+	db = r.orderHarvests(db, filter)
+
+	err := db.Offset(offset).
+		Limit(filter.Limit).
 		Find(&harvests).Error
 
 	return harvests, err
 }
 
-func (r *HarvestRepository) CountSearch(query string) (int64, error) {
+func (r *HarvestRepository) CountSearch(filter HarvestFilter) (int64, error) {
 	var count int64
-	db := r.db.Model(&models.Harvest{})
-
-	if query != "" {
-		searchPattern := "%" + query + "%"
-		db = db.Where("title LIKE ? OR description LIKE ?", searchPattern, searchPattern)
-	}
+	db := r.applyHarvestFilter(r.db.Model(&models.Harvest{}), filter)
 
 	err := db.Count(&count).Error
 	return count, err
 }
 
+// harvestTextSearch is how the database answers a search query.
+type harvestTextSearch int
+
+const (
+	textSearchLike harvestTextSearch = iota
+	textSearchPostgres
+	textSearchFTS5
+)
+
+// textSearch reports how this database can search harvests, which depends on
+// whether migrations could set up a full-text index.
+func (r *HarvestRepository) textSearch() harvestTextSearch {
+	r.searchOnce.Do(func() {
+		switch r.db.Dialector.Name() {
+		case "postgres":
+			r.search = textSearchPostgres
+		case "sqlite":
+			if r.db.Migrator().HasTable(database.HarvestSearchTable) {
+				r.search = textSearchFTS5
+			}
+		}
+	})
+	return r.search
+}
+
+// searchTerms splits a query into the words it searches for, dropping the
+// punctuation full-text query syntax would otherwise interpret.
+func searchTerms(query string) []string {
+	return strings.FieldsFunc(strings.ToLower(query), func(c rune) bool {
+		return !unicode.IsLetter(c) && !unicode.IsNumber(c)
+	})
+}
+
+// tsQuery matches every term as a prefix, so "back" finds "backups".
+func tsQuery(terms []string) string {
+	parts := make([]string, len(terms))
+	for i, term := range terms {
+		parts[i] = term + ":*"
+	}
+	return strings.Join(parts, " & ")
+}
+
+// fts5Query matches every term as a prefix, like tsQuery.
+func fts5Query(terms []string) string {
+	parts := make([]string, len(terms))
+	for i, term := range terms {
+		parts[i] = `"` + term + `"*`
+	}
+	return strings.Join(parts, " ")
+}
+
+func (r *HarvestRepository) applyHarvestFilter(db *gorm.DB, filter HarvestFilter) *gorm.DB {
+	if filter.Query != "" {
+		terms := searchTerms(filter.Query)
+		switch {
+		case len(terms) == 0:
+		case r.textSearch() == textSearchPostgres:
+			db = db.Where("harvests.search_vector @@ to_tsquery('simple', ?)", tsQuery(terms))
+		case r.textSearch() == textSearchFTS5:
+			db = db.Joins("JOIN "+database.HarvestSearchTable+" ON "+database.HarvestSearchTable+".rowid = harvests.id").
+				Where(database.HarvestSearchTable+" MATCH ?", fts5Query(terms))
+		default:
+			searchPattern := "%" + filter.Query + "%"
+			db = db.Where("harvests.title LIKE ? OR harvests.description LIKE ?", searchPattern, searchPattern)
+		}
+	}
+
+	switch filter.Status {
+	case HarvestListOpen:
+		db = db.Where("harvests.status = ? AND (harvests.due_at IS NULL OR harvests.due_at > ?)", models.HarvestOpen, filter.Now)
+	case HarvestListAssigned:
+		db = db.Where("harvests.status IN ?", []models.HarvestStatus{
+			models.HarvestClaimed, models.HarvestSubmitted, models.HarvestRejected, models.HarvestApproved,
+		})
+	case HarvestListOverdue:
+		db = db.Where("harvests.completed = ? AND harvests.status <> ? AND (harvests.status = ? OR harvests.due_at <= ?)",
+			false, models.HarvestCancelled, models.HarvestExpired, filter.Now)
+	case HarvestListCompleted:
+		db = db.Where("harvests.completed = ?", true)
+	}
+
+	if filter.Category != "" {
+		db = db.Where("harvests.category = ?", filter.Category)
+	}
+	if len(filter.Tags) > 0 {
+		db = db.Where(`harvests.id IN (SELECT harvest_id FROM harvest_tags
+			WHERE name IN ? AND deleted_at IS NULL
+			GROUP BY harvest_id HAVING COUNT(*) = ?)`, filter.Tags, len(filter.Tags))
+	}
+	if filter.Assignee != "" {
+		db = db.Where(`harvests.id IN (SELECT p.harvest_id FROM harvest_participants p
+			JOIN users u ON u.id = p.user_id
+			WHERE u.username = ? AND p.deleted_at IS NULL)`, filter.Assignee)
+	}
+	if filter.MinReward > 0 {
+		db = db.Where("harvests.bean_amount >= ?", filter.MinReward)
+	}
+	if filter.MaxReward > 0 {
+		db = db.Where("harvests.bean_amount <= ?", filter.MaxReward)
+	}
+
+	return db
+}
+
+func (r *HarvestRepository) orderHarvests(db *gorm.DB, filter HarvestFilter) *gorm.DB {
+	sort := filter.Sort
+	terms := searchTerms(filter.Query)
+	if sort == "" && len(terms) > 0 {
+		sort = HarvestSortRelevance
+	}
+
+	switch sort {
+	case HarvestSortNewest:
+		return db.Order("harvests.created_at DESC").Order("harvests.id DESC")
+	case HarvestSortReward:
+		return db.Order("harvests.bean_amount DESC").Order("harvests.id DESC")
+	case HarvestSortDeadline:
+		return db.Order("harvests.due_at IS NULL").Order("harvests.due_at ASC").Order("harvests.id DESC")
+	case HarvestSortRelevance:
+		if len(terms) == 0 {
+			break
+		}
+		switch r.textSearch() {
+		case textSearchPostgres:
+			db = db.Order(clause.OrderBy{Expression: clause.Expr{
+				SQL:  "ts_rank(harvests.search_vector, to_tsquery('simple', ?)) DESC",
+				Vars: []interface{}{tsQuery(terms)},
+			}})
+		case textSearchFTS5:
+			// bm25 scores better matches lower; title hits count ten times
+			// as much as description hits.
+			db = db.Order("bm25(" + database.HarvestSearchTable + ", 10.0, 1.0)")
+		}
+	}
+	return db.Order("harvests.updated_at DESC").Order("harvests.id DESC")
+}
+
+// HarvestLabelCount is how many harvests carry a tag or category.
+type HarvestLabelCount struct {
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
+// ListTags returns the tags in use, most used first.
+func (r *HarvestRepository) ListTags(limit int) ([]HarvestLabelCount, error) {
+	rows := []HarvestLabelCount{}
+	err := r.db.Model(&models.HarvestTag{}).
+		Select("harvest_tags.name AS name, COUNT(*) AS count").
+		Joins("JOIN harvests ON harvests.id = harvest_tags.harvest_id AND harvests.deleted_at IS NULL").
+		Group("harvest_tags.name").
+		Order("count DESC").
+		Order("name ASC").
+		Limit(limit).
+		Scan(&rows).Error
+	return rows, err
+}
+
+// ListCategories returns the categories in use, most used first.
+func (r *HarvestRepository) ListCategories(limit int) ([]HarvestLabelCount, error) {
+	rows := []HarvestLabelCount{}
+	err := r.db.Model(&models.Harvest{}).
+		Select("category AS name, COUNT(*) AS count").
+		Where("category <> ''").
+		Group("category").
+		Order("count DESC").
+		Order("name ASC").
+		Limit(limit).
+		Scan(&rows).Error
+	return rows, err
+}
+
+// SetTagsInTx replaces the tags of a harvest. Old tags are deleted outright
+// so they can be added back without tripping the unique index.
+func (r *HarvestRepository) SetTagsInTx(tx *gorm.DB, harvestID uint, names []string) ([]models.HarvestTag, error) {
+	if err := tx.Unscoped().Where("harvest_id = ?", harvestID).Delete(&models.HarvestTag{}).Error; err != nil {
+		return nil, err
+	}
+
+	tags := make([]models.HarvestTag, len(names))
+	for i, name := range names {
+		tags[i] = models.HarvestTag{HarvestID: harvestID, Name: name}
+	}
+	if len(tags) == 0 {
+		return tags, nil
+	}
+	return tags, tx.Create(&tags).Error
+}
+
+// FindOverdueIDs lists harvests whose deadline passed before their work was
+// submitted, or whose claim ran past the claim timeout.
+func (r *HarvestRepository) FindOverdueIDs(now time.Time, limit int) ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&models.Harvest{}).
+		Where("(due_at <= ? AND status IN ?) OR (claim_expires_at <= ? AND status IN ?)",
+			now, []models.HarvestStatus{models.HarvestOpen, models.HarvestClaimed, models.HarvestRejected},
+			now, []models.HarvestStatus{models.HarvestClaimed, models.HarvestRejected}).
+		Order("id ASC").
+		Limit(limit).
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
 func (r *HarvestRepository) FindAll() ([]models.Harvest, error) {
 	var harvests []models.Harvest
-	err := r.db.Preload("AssignedUser").Order("updated_at DESC").Find(&harvests).Error
+	err := withParticipants(r.db.Preload("AssignedUser")).Order("updated_at DESC").Find(&harvests).Error
 	return harvests, err
 }
+
+// withParticipants preloads participants in the order they joined, which is
+// the order Harvest.Shares pays them in.
+func withParticipants(db *gorm.DB) *gorm.DB {
+	return db.Preload("Participants", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id ASC")
+	}).Preload("Participants.User").
+		Preload("Poster").
+		Preload("Contributions", func(db *gorm.DB) *gorm.DB {
+			return db.Order("id ASC")
+		}).Preload("Contributions.User").
+		Preload("Tags", func(db *gorm.DB) *gorm.DB {
+			return db.Order("name ASC")
+		})
+}
+
+// ListContributionsInTx returns the contributions to a bounty, oldest first.
+func (r *HarvestRepository) ListContributionsInTx(tx *gorm.DB, harvestID uint) ([]models.HarvestContribution, error) {
+	var contributions []models.HarvestContribution
+	err := tx.Preload("User").
+		Where("harvest_id = ?", harvestID).
+		Order("id ASC").
+		Find(&contributions).Error
+	return contributions, err
+}
+
+// FindContributionInTx returns what userID has put into a bounty, or nil.
+func (r *HarvestRepository) FindContributionInTx(tx *gorm.DB, harvestID, userID uint) (*models.HarvestContribution, error) {
+	var contribution models.HarvestContribution
+	err := tx.Where("harvest_id = ? AND user_id = ?", harvestID, userID).First(&contribution).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &contribution, nil
+}
+
+func (r *HarvestRepository) SaveContributionInTx(tx *gorm.DB, contribution *models.HarvestContribution) error {
+	return tx.Omit(clause.Associations).Save(contribution).Error
+}
+
+// ListParticipantsInTx returns the participants of a harvest in the order
+// they joined.
+func (r *HarvestRepository) ListParticipantsInTx(tx *gorm.DB, harvestID uint) ([]models.HarvestParticipant, error) {
+	var participants []models.HarvestParticipant
+	err := tx.Preload("User").
+		Where("harvest_id = ?", harvestID).
+		Order("id ASC").
+		Find(&participants).Error
+	return participants, err
+}
+
+func (r *HarvestRepository) AddParticipantInTx(tx *gorm.DB, participant *models.HarvestParticipant) error {
+	return tx.Create(participant).Error
+}
+
+// RemoveParticipantInTx deletes the row outright so the user can join again
+// without tripping the unique index.
+func (r *HarvestRepository) RemoveParticipantInTx(tx *gorm.DB, harvestID, userID uint) error {
+	return tx.Unscoped().
+		Where("harvest_id = ? AND user_id = ?", harvestID, userID).
+		Delete(&models.HarvestParticipant{}).Error
+}
+
+func (r *HarvestRepository) ClearParticipantsInTx(tx *gorm.DB, harvestID uint) error {
+	return tx.Unscoped().
+		Where("harvest_id = ?", harvestID).
+		Delete(&models.HarvestParticipant{}).Error
+}
+
+// HarvestPayout is what one user still holds of a harvest's reward: what
+// they were paid less anything already clawed back.
+type HarvestPayout struct {
+	UserID   uint
+	Username string
+	Amount   int
+}
+
+// ListPayoutsInTx returns who holds beans paid out for a harvest after the
+// transaction with ID afterID, in the order they were first paid. Users who
+// have given it all back are left out.
+func (r *HarvestRepository) ListPayoutsInTx(tx *gorm.DB, harvestID, afterID uint) ([]HarvestPayout, error) {
+	var paid []HarvestPayout
+	err := tx.Table("transactions").
+		Select("transactions.to_user_id AS user_id, users.username, SUM(transactions.amount) AS amount").
+		Joins("JOIN users ON users.id = transactions.to_user_id").
+		Where("transactions.harvest_id = ? AND transactions.id > ? AND transactions.deleted_at IS NULL", harvestID, afterID).
+		Where("transactions.kind IN ?", []models.TransactionKind{models.TransactionKindHarvestReward, models.TransactionKindBountyPayout}).
+		Group("transactions.to_user_id, users.username").
+		Order("MIN(transactions.id) ASC").
+		Scan(&paid).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var clawedBack []HarvestPayout
+	err = tx.Table("transactions").
+		Select("from_user_id AS user_id, SUM(amount) AS amount").
+		Where("harvest_id = ? AND id > ? AND kind = ? AND deleted_at IS NULL", harvestID, afterID, models.TransactionKindHarvestClawback).
+		Group("from_user_id").
+		Scan(&clawedBack).Error
+	if err != nil {
+		return nil, err
+	}
+
+	returned := make(map[uint]int, len(clawedBack))
+	for _, c := range clawedBack {
+		returned[c.UserID] = c.Amount
+	}
+
+	held := make([]HarvestPayout, 0, len(paid))
+	for _, p := range paid {
+		p.Amount -= returned[p.UserID]
+		if p.Amount > 0 {
+			held = append(held, p)
+		}
+	}
+	return held, nil
+}
+
+// LastTransactionIDInTx returns the ID of the newest transaction tagged with
+// the harvest, or 0 if there is none.
+func (r *HarvestRepository) LastTransactionIDInTx(tx *gorm.DB, harvestID uint) (uint, error) {
+	var id uint
+	err := tx.Model(&models.Transaction{}).
+		Where("harvest_id = ?", harvestID).
+		Select("COALESCE(MAX(id), 0)").
+		Scan(&id).Error
+	return id, err
+}
+
+func (r *HarvestRepository) CreateEventInTx(tx *gorm.DB, event *models.HarvestEvent) error {
+	return tx.Create(event).Error
+}
+
+// ListEvents returns the status history of a harvest, oldest first, leaving
+// out comments.
+func (r *HarvestRepository) ListEvents(harvestID uint) ([]models.HarvestEvent, error) {
+	var events []models.HarvestEvent
+	err := r.db.Where("harvest_id = ? AND type NOT IN ?", harvestID, models.HarvestCommentEventTypes).
+		Order("id ASC").
+		Find(&events).Error
+	return events, err
+}
+
+// ListTimeline returns a page of everything that happened on a harvest,
+// comments included, oldest first. Deleted comments are loaded too so the
+// timeline can say they were removed.
+func (r *HarvestRepository) ListTimeline(harvestID uint, page, limit int) ([]models.HarvestEvent, error) {
+	var events []models.HarvestEvent
+	err := r.db.Preload("Comment", func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped()
+	}).Preload("Comment.User").
+		Where("harvest_id = ?", harvestID).
+		Order("id ASC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&events).Error
+	return events, err
+}
+
+func (r *HarvestRepository) CountTimeline(harvestID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.HarvestEvent{}).
+		Where("harvest_id = ?", harvestID).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *HarvestRepository) CreateSubmissionInTx(tx *gorm.DB, submission *models.HarvestSubmission) error {
+	return tx.Create(submission).Error
+}
+
+func (r *HarvestRepository) UpdateSubmissionInTx(tx *gorm.DB, submission *models.HarvestSubmission) error {
+	return tx.Save(submission).Error
+}
+
+// FindPendingSubmissionForUpdate locks the submission awaiting review, if any.
+func (r *HarvestRepository) FindPendingSubmissionForUpdate(tx *gorm.DB, harvestID uint) (*models.HarvestSubmission, error) {
+	var submission models.HarvestSubmission
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("harvest_id = ? AND status = ?", harvestID, models.HarvestSubmissionPending).
+		Order("id DESC").
+		First(&submission).Error
+
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &submission, nil
+}
+
+// ListSubmissions returns every submission of a harvest, newest first.
+func (r *HarvestRepository) ListSubmissions(harvestID uint) ([]models.HarvestSubmission, error) {
+	var submissions []models.HarvestSubmission
+	err := r.db.Preload("User").
+		Where("harvest_id = ?", harvestID).
+		Order("id DESC").
+		Find(&submissions).Error
+	return submissions, err
+}
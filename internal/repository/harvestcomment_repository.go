@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"github.com/h4ks-com/bean-bank/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type HarvestCommentRepository struct {
+	db *gorm.DB
+}
+
+func NewHarvestCommentRepository(db *gorm.DB) *HarvestCommentRepository {
+	return &HarvestCommentRepository{db: db}
+}
+
+func (r *HarvestCommentRepository) CreateInTx(tx *gorm.DB, comment *models.HarvestComment) error {
+	return tx.Create(comment).Error
+}
+
+// FindByIDForUpdate locks a comment that has not been deleted, or returns
+// nil.
+func (r *HarvestCommentRepository) FindByIDForUpdate(tx *gorm.DB, id uint) (*models.HarvestComment, error) {
+	var comment models.HarvestComment
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Preload("User").
+		Where("id = ?", id).
+		First(&comment).Error
+
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &comment, nil
+}
+
+func (r *HarvestCommentRepository) UpdateInTx(tx *gorm.DB, comment *models.HarvestComment) error {
+	return tx.Omit(clause.Associations).Save(comment).Error
+}
+
+// DeleteInTx soft-deletes a comment so the timeline can still show that it
+// existed.
+func (r *HarvestCommentRepository) DeleteInTx(tx *gorm.DB, comment *models.HarvestComment) error {
+	return tx.Delete(comment).Error
+}
+
+// ListByHarvest returns a page of a harvest's comments, oldest first.
+func (r *HarvestCommentRepository) ListByHarvest(harvestID uint, page, limit int) ([]models.HarvestComment, error) {
+	var comments []models.HarvestComment
+	err := r.db.Preload("User").
+		Where("harvest_id = ?", harvestID).
+		Order("id ASC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&comments).Error
+	return comments, err
+}
+
+func (r *HarvestCommentRepository) CountByHarvest(harvestID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.HarvestComment{}).
+		Where("harvest_id = ?", harvestID).
+		Count(&count).Error
+	return count, err
+}
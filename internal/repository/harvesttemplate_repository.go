@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/h4ks-com/bean-bank/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type HarvestTemplateRepository struct {
+	db *gorm.DB
+}
+
+func NewHarvestTemplateRepository(db *gorm.DB) *HarvestTemplateRepository {
+	return &HarvestTemplateRepository{db: db}
+}
+
+func (r *HarvestTemplateRepository) Create(template *models.HarvestTemplate) error {
+	return r.db.Create(template).Error
+}
+
+func (r *HarvestTemplateRepository) FindByID(id uint) (*models.HarvestTemplate, error) {
+	var template models.HarvestTemplate
+	err := r.db.Preload("Assignee").
+		Where("id = ?", id).
+		First(&template).Error
+
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+func (r *HarvestTemplateRepository) FindByIDForUpdate(tx *gorm.DB, id uint) (*models.HarvestTemplate, error) {
+	var template models.HarvestTemplate
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Preload("Assignee").
+		Where("id = ?", id).
+		First(&template).Error
+
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+func (r *HarvestTemplateRepository) FindAll() ([]models.HarvestTemplate, error) {
+	var templates []models.HarvestTemplate
+	err := r.db.Preload("Assignee").
+		Order("id DESC").
+		Find(&templates).Error
+
+	if err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+// FindDueIDs returns active templates whose next run is at or before now,
+// oldest first.
+func (r *HarvestTemplateRepository) FindDueIDs(now time.Time, limit int) ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&models.HarvestTemplate{}).
+		Where("status = ? AND next_run_at <= ?", models.HarvestTemplateActive, now).
+		Order("next_run_at ASC").
+		Limit(limit).
+		Pluck("id", &ids).Error
+
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+func (r *HarvestTemplateRepository) UpdateInTx(tx *gorm.DB, template *models.HarvestTemplate) error {
+	return tx.Omit(clause.Associations).Save(template).Error
+}
+
+func (r *HarvestTemplateRepository) Delete(id uint) error {
+	return r.db.Delete(&models.HarvestTemplate{}, id).Error
+}
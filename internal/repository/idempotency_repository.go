@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/h4ks-com/bean-bank/internal/models"
+	"gorm.io/gorm"
+)
+
+type IdempotencyRepository struct {
+	db *gorm.DB
+}
+
+func NewIdempotencyRepository(db *gorm.DB) *IdempotencyRepository {
+	return &IdempotencyRepository{db: db}
+}
+
+func (r *IdempotencyRepository) Create(key *models.IdempotencyKey) error {
+	return r.db.Create(key).Error
+}
+
+func (r *IdempotencyRepository) FindByUserAndKey(userID uint, key string) (*models.IdempotencyKey, error) {
+	var record models.IdempotencyKey
+	err := r.db.Where("user_id = ? AND key = ?", userID, key).First(&record).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &record, nil
+}
+
+func (r *IdempotencyRepository) Update(key *models.IdempotencyKey) error {
+	return r.db.Save(key).Error
+}
+
+func (r *IdempotencyRepository) Delete(id uint) error {
+	return r.db.Unscoped().Delete(&models.IdempotencyKey{}, id).Error
+}
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"github.com/h4ks-com/bean-bank/internal/models"
+	"gorm.io/gorm"
+)
+
+type LedgerRepository struct {
+	db *gorm.DB
+}
+
+func NewLedgerRepository(db *gorm.DB) *LedgerRepository {
+	return &LedgerRepository{db: db}
+}
+
+type AccountLedgerSum struct {
+	UserID uint
+	Total  int
+}
+
+func (r *LedgerRepository) CreateEntries(tx *gorm.DB, entries []models.LedgerEntry) error {
+	return tx.Create(&entries).Error
+}
+
+func (r *LedgerRepository) FindByTransactionID(transactionID uint) ([]models.LedgerEntry, error) {
+	var entries []models.LedgerEntry
+	err := r.db.Where("transaction_id = ?", transactionID).Order("id ASC").Find(&entries).Error
+	return entries, err
+}
+
+func (r *LedgerRepository) SumByUser(tx *gorm.DB) (map[uint]int, error) {
+	var rows []AccountLedgerSum
+	err := tx.Model(&models.LedgerEntry{}).
+		Select("user_id, COALESCE(SUM(amount), 0) AS total").
+		Group("user_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	sums := make(map[uint]int, len(rows))
+	for _, row := range rows {
+		sums[row.UserID] = row.Total
+	}
+	return sums, nil
+}
+
+func (r *LedgerRepository) SumForUser(tx *gorm.DB, userID uint) (int, error) {
+	var total int
+	err := tx.Model(&models.LedgerEntry{}).
+		Where("user_id = ?", userID).
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&total).Error
+	return total, err
+}
+
+// FindUnbalancedTransactionIDs returns transactions whose entries do not sum
+// to zero or that do not have exactly one debit and one credit.
+func (r *LedgerRepository) FindUnbalancedTransactionIDs(tx *gorm.DB) ([]uint, error) {
+	var ids []uint
+	err := tx.Model(&models.LedgerEntry{}).
+		Select("transaction_id").
+		Group("transaction_id").
+		Having("SUM(amount) <> 0 OR COUNT(*) <> 2").
+		Order("transaction_id ASC").
+		Pluck("transaction_id", &ids).Error
+	return ids, err
+}
+
+func (r *LedgerRepository) CountTransactionsWithoutEntries(tx *gorm.DB) (int64, error) {
+	var count int64
+	err := tx.Model(&models.Transaction{}).
+		Where("NOT EXISTS (SELECT 1 FROM ledger_entries WHERE ledger_entries.transaction_id = transactions.id AND ledger_entries.deleted_at IS NULL)").
+		Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"github.com/h4ks-com/bean-bank/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PaymentRequestDirection narrows a listing to requests the user sent
+// (outgoing) or requests addressed to them (incoming).
+type PaymentRequestDirection string
+
+const (
+	PaymentRequestIncoming PaymentRequestDirection = "incoming"
+	PaymentRequestOutgoing PaymentRequestDirection = "outgoing"
+)
+
+type PaymentRequestRepository struct {
+	db *gorm.DB
+}
+
+func NewPaymentRequestRepository(db *gorm.DB) *PaymentRequestRepository {
+	return &PaymentRequestRepository{db: db}
+}
+
+func (r *PaymentRequestRepository) CreateInTx(tx *gorm.DB, request *models.PaymentRequest) error {
+	return tx.Create(request).Error
+}
+
+func (r *PaymentRequestRepository) FindByCode(code string) (*models.PaymentRequest, error) {
+	var request models.PaymentRequest
+	err := r.db.Preload("Requester").Preload("Payer").Preload("PaidBy").
+		Where("code = ?", code).
+		First(&request).Error
+
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+func (r *PaymentRequestRepository) FindByID(id uint) (*models.PaymentRequest, error) {
+	var request models.PaymentRequest
+	err := r.db.Preload("Requester").Preload("Payer").Preload("PaidBy").
+		Where("id = ?", id).
+		First(&request).Error
+
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+func (r *PaymentRequestRepository) FindByCodeForUpdate(tx *gorm.DB, code string) (*models.PaymentRequest, error) {
+	var request models.PaymentRequest
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("code = ?", code).
+		First(&request).Error
+
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+func (r *PaymentRequestRepository) FindByIDForUpdate(tx *gorm.DB, id uint) (*models.PaymentRequest, error) {
+	var request models.PaymentRequest
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ?", id).
+		First(&request).Error
+
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+// ListForUser returns requests the user sent or was asked to pay, newest
+// first. An empty direction or status matches everything.
+func (r *PaymentRequestRepository) ListForUser(userID uint, direction PaymentRequestDirection, status models.PaymentRequestStatus) ([]models.PaymentRequest, error) {
+	query := r.db.Preload("Requester").Preload("Payer").Preload("PaidBy")
+
+	switch direction {
+	case PaymentRequestIncoming:
+		query = query.Where("payer_id = ?", userID)
+	case PaymentRequestOutgoing:
+		query = query.Where("requester_id = ?", userID)
+	default:
+		query = query.Where("requester_id = ? OR payer_id = ?", userID, userID)
+	}
+
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var requests []models.PaymentRequest
+	if err := query.Order("id DESC").Find(&requests).Error; err != nil {
+		return nil, err
+	}
+	return requests, nil
+}
+
+func (r *PaymentRequestRepository) UpdateInTx(tx *gorm.DB, request *models.PaymentRequest) error {
+	return tx.Save(request).Error
+}
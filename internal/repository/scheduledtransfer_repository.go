@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/h4ks-com/bean-bank/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ScheduledTransferRepository struct {
+	db *gorm.DB
+}
+
+func NewScheduledTransferRepository(db *gorm.DB) *ScheduledTransferRepository {
+	return &ScheduledTransferRepository{db: db}
+}
+
+func (r *ScheduledTransferRepository) Create(scheduled *models.ScheduledTransfer) error {
+	return r.db.Create(scheduled).Error
+}
+
+func (r *ScheduledTransferRepository) FindByID(id uint) (*models.ScheduledTransfer, error) {
+	var scheduled models.ScheduledTransfer
+	err := r.db.Preload("FromUser").
+		Where("id = ?", id).
+		First(&scheduled).Error
+
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &scheduled, nil
+}
+
+func (r *ScheduledTransferRepository) FindByIDForUpdate(tx *gorm.DB, id uint) (*models.ScheduledTransfer, error) {
+	var scheduled models.ScheduledTransfer
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Preload("FromUser").
+		Where("id = ?", id).
+		First(&scheduled).Error
+
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &scheduled, nil
+}
+
+func (r *ScheduledTransferRepository) ListByFromUserID(userID uint) ([]models.ScheduledTransfer, error) {
+	var scheduled []models.ScheduledTransfer
+	err := r.db.Preload("FromUser").
+		Where("from_user_id = ?", userID).
+		Order("id DESC").
+		Find(&scheduled).Error
+
+	if err != nil {
+		return nil, err
+	}
+	return scheduled, nil
+}
+
+// FindDueIDs returns active schedules whose next run is at or before now,
+// oldest first.
+func (r *ScheduledTransferRepository) FindDueIDs(now time.Time, limit int) ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&models.ScheduledTransfer{}).
+		Where("status = ? AND next_run_at <= ?", models.ScheduledTransferActive, now).
+		Order("next_run_at ASC").
+		Limit(limit).
+		Pluck("id", &ids).Error
+
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+func (r *ScheduledTransferRepository) UpdateInTx(tx *gorm.DB, scheduled *models.ScheduledTransfer) error {
+	return tx.Save(scheduled).Error
+}
+
+func (r *ScheduledTransferRepository) CreateRunInTx(tx *gorm.DB, run *models.ScheduledTransferRun) error {
+	return tx.Create(run).Error
+}
+
+func (r *ScheduledTransferRepository) ListRuns(scheduledTransferID uint, limit int) ([]models.ScheduledTransferRun, error) {
+	var runs []models.ScheduledTransferRun
+	err := r.db.Where("scheduled_transfer_id = ?", scheduledTransferID).
+		Order("id DESC").
+		Limit(limit).
+		Find(&runs).Error
+
+	if err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
@@ -1,6 +1,9 @@
 package repository
 
 import (
+	"strings"
+	"time"
+
 	"github.com/h4ks-com/bean-bank/internal/models"
 	"gorm.io/gorm"
 )
@@ -17,6 +20,33 @@ func (r *TransactionRepository) Create(tx *gorm.DB, transaction *models.Transact
 	return tx.Create(transaction).Error
 }
 
+// Post moves transaction.Amount from one wallet to the other and records the
+// transaction with a debit and a credit ledger entry. Both users must already
+// be locked by the caller; balance checks are the caller's responsibility.
+func (r *TransactionRepository) Post(tx *gorm.DB, from, to *models.User, transaction *models.Transaction) error {
+	from.BeanAmount -= transaction.Amount
+	to.BeanAmount += transaction.Amount
+
+	if err := tx.Save(from).Error; err != nil {
+		return err
+	}
+	if err := tx.Save(to).Error; err != nil {
+		return err
+	}
+
+	transaction.FromUserID = from.ID
+	transaction.ToUserID = to.ID
+	if err := tx.Create(transaction).Error; err != nil {
+		return err
+	}
+
+	entries := []models.LedgerEntry{
+		{TransactionID: transaction.ID, UserID: from.ID, Amount: -transaction.Amount, BalanceAfter: from.BeanAmount},
+		{TransactionID: transaction.ID, UserID: to.ID, Amount: transaction.Amount, BalanceAfter: to.BeanAmount},
+	}
+	return tx.Create(&entries).Error
+}
+
 func (r *TransactionRepository) FindByUsername(username string) ([]models.Transaction, error) {
 	var transactions []models.Transaction
 	err := r.db.
@@ -30,12 +60,98 @@ func (r *TransactionRepository) FindByUsername(username string) ([]models.Transa
 	return transactions, err
 }
 
-func (r *TransactionRepository) FindAll() ([]models.Transaction, error) {
+type TransactionDirection string
+
+const (
+	TransactionDirectionIn  TransactionDirection = "in"
+	TransactionDirectionOut TransactionDirection = "out"
+)
+
+// TransactionFilter narrows a page of transactions. Zero values match
+// everything. Direction is relative to Username and Counterparty is the other
+// side of the transaction when Username is set, or either side otherwise.
+// Until is exclusive unless UntilInclusive is set.
+type TransactionFilter struct {
+	Username       string
+	Counterparty   string
+	Direction      TransactionDirection
+	Since          *time.Time
+	Until          *time.Time
+	UntilInclusive bool
+	MinAmount      *int
+	MaxAmount      *int
+	NoteQuery      string
+	Kinds          []models.TransactionKind
+	BeforeID       uint
+	Limit          int
+}
+
+// FindPage returns transactions newest first, ordered by ID so that pages stay
+// stable while new transactions are written. BeforeID is the keyset cursor.
+func (r *TransactionRepository) FindPage(filter TransactionFilter) ([]models.Transaction, error) {
+	query := r.db.
+		Joins("JOIN users as from_user ON from_user.id = transactions.from_user_id").
+		Joins("JOIN users as to_user ON to_user.id = transactions.to_user_id")
+
+	if filter.Username != "" {
+		switch filter.Direction {
+		case TransactionDirectionIn:
+			query = query.Where("to_user.username = ?", filter.Username)
+		case TransactionDirectionOut:
+			query = query.Where("from_user.username = ?", filter.Username)
+		default:
+			query = query.Where("from_user.username = ? OR to_user.username = ?", filter.Username, filter.Username)
+		}
+
+		if filter.Counterparty != "" {
+			query = query.Where(
+				"(from_user.username = ? AND to_user.username = ?) OR (to_user.username = ? AND from_user.username = ?)",
+				filter.Username, filter.Counterparty, filter.Username, filter.Counterparty,
+			)
+		}
+	} else if filter.Counterparty != "" {
+		query = query.Where("from_user.username = ? OR to_user.username = ?", filter.Counterparty, filter.Counterparty)
+	}
+
+	if filter.Since != nil {
+		query = query.Where("transactions.timestamp >= ?", *filter.Since)
+	}
+	if filter.Until != nil {
+		if filter.UntilInclusive {
+			query = query.Where("transactions.timestamp <= ?", *filter.Until)
+		} else {
+			query = query.Where("transactions.timestamp < ?", *filter.Until)
+		}
+	}
+	if filter.MinAmount != nil {
+		query = query.Where("transactions.amount >= ?", *filter.MinAmount)
+	}
+	if filter.MaxAmount != nil {
+		query = query.Where("transactions.amount <= ?", *filter.MaxAmount)
+	}
+	if filter.NoteQuery != "" {
+		query = query.Where("LOWER(transactions.note) LIKE ? ESCAPE '\\'", "%"+escapeLike(strings.ToLower(filter.NoteQuery))+"%")
+	}
+	if len(filter.Kinds) > 0 {
+		query = query.Where("transactions.kind IN ?", filter.Kinds)
+	}
+	if filter.BeforeID > 0 {
+		query = query.Where("transactions.id < ?", filter.BeforeID)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
 	var transactions []models.Transaction
-	err := r.db.
+	err := query.
 		Preload("FromUser").
 		Preload("ToUser").
-		Order("created_at DESC").
+		Order("transactions.id DESC").
 		Find(&transactions).Error
 	return transactions, err
 }
+
+func escapeLike(s string) string {
+	replacer := strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_")
+	return replacer.Replace(s)
+}
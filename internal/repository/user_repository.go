@@ -2,6 +2,7 @@ package repository
 
 import (
 	"errors"
+	"fmt"
 
 	"github.com/h4ks-com/bean-bank/internal/models"
 	"gorm.io/gorm"
@@ -17,12 +18,19 @@ func NewUserRepository(db *gorm.DB) *UserRepository {
 }
 
 func (r *UserRepository) Create(user *models.User) error {
-	if user.Username == "system" {
-		return errors.New("username 'system' is reserved")
+	if models.IsReservedUsername(user.Username) {
+		return fmt.Errorf("username '%s' is reserved", user.Username)
 	}
 	return r.db.Create(user).Error
 }
 
+func (r *UserRepository) CreateInTx(tx *gorm.DB, user *models.User) error {
+	if models.IsReservedUsername(user.Username) {
+		return fmt.Errorf("username '%s' is reserved", user.Username)
+	}
+	return tx.Create(user).Error
+}
+
 func (r *UserRepository) FindByID(id uint) (*models.User, error) {
 	var user models.User
 	err := r.db.First(&user, id).Error
@@ -47,15 +55,85 @@ func (r *UserRepository) FindByUsername(username string) (*models.User, error) {
 	return &user, nil
 }
 
-func (r *UserRepository) FindByUsernameForUpdate(tx *gorm.DB, username string) (*models.User, error) {
-	var user models.User
+// GetSystemUserForUpdate locks the system account, creating it on first use.
+func (r *UserRepository) GetSystemUserForUpdate(tx *gorm.DB) (*models.User, error) {
+	users, err := r.LockUsers(tx, models.SystemUsername)
+	if err != nil {
+		return nil, err
+	}
+	return users[models.SystemUsername], nil
+}
+
+// GetMintUserForUpdate locks the mint account, creating it on first use.
+func (r *UserRepository) GetMintUserForUpdate(tx *gorm.DB) (*models.User, error) {
+	users, err := r.LockUsers(tx, models.MintUsername)
+	if err != nil {
+		return nil, err
+	}
+	return users[models.MintUsername], nil
+}
+
+// LockUsers locks the named wallets in ascending ID order. Every operation that
+// touches more than one wallet must lock them here, in a single call, so that
+// concurrent transactions always acquire row locks in the same order and cannot
+// deadlock. Reserved accounts are created on first use; other unknown
+// usernames are simply missing from the result.
+func (r *UserRepository) LockUsers(tx *gorm.DB, usernames ...string) (map[string]*models.User, error) {
+	if err := r.createReserved(tx, usernames...); err != nil {
+		return nil, err
+	}
+
+	var users []models.User
 	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
-		Where("username = ?", username).
-		First(&user).Error
+		Where("username IN ?", usernames).
+		Order("id ASC").
+		Find(&users).Error
 	if err != nil {
 		return nil, err
 	}
-	return &user, nil
+
+	locked := make(map[string]*models.User, len(users))
+	for i := range users {
+		locked[users[i].Username] = &users[i]
+	}
+	return locked, nil
+}
+
+// LockAll locks every wallet, in the same ascending ID order as LockUsers, so
+// that nothing can post to any of them until the transaction ends. The
+// reserved accounts are created first so that they are locked too.
+func (r *UserRepository) LockAll(tx *gorm.DB) ([]models.User, error) {
+	if err := r.createReserved(tx, models.SystemUsername, models.MintUsername); err != nil {
+		return nil, err
+	}
+
+	var users []models.User
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Order("id ASC").
+		Find(&users).Error
+	return users, err
+}
+
+// createReserved creates the named reserved accounts that don't exist yet.
+// It refuses to use a wallet that merely has a reserved name; see
+// database.Migrate, which flags the real ones.
+func (r *UserRepository) createReserved(tx *gorm.DB, usernames ...string) error {
+	for _, username := range usernames {
+		if !models.IsReservedUsername(username) {
+			continue
+		}
+		reserved := models.User{Username: username}
+		err := tx.Where("username = ?", username).
+			Attrs(models.User{Reserved: true}).
+			FirstOrCreate(&reserved).Error
+		if err != nil {
+			return err
+		}
+		if !reserved.Reserved {
+			return fmt.Errorf("wallet %q is not the reserved %s account", username, username)
+		}
+	}
+	return nil
 }
 
 func (r *UserRepository) Update(user *models.User) error {
@@ -74,13 +152,19 @@ func (r *UserRepository) FindAll() ([]models.User, error) {
 
 func (r *UserRepository) GetTotalBeans() (int64, error) {
 	var total int64
-	err := r.db.Model(&models.User{}).Select("COALESCE(SUM(bean_amount), 0)").Scan(&total).Error
+	err := r.db.Model(&models.User{}).
+		Where("username <> ?", models.MintUsername).
+		Select("COALESCE(SUM(bean_amount), 0)").
+		Scan(&total).Error
 	return total, err
 }
 
 func (r *UserRepository) GetTopWallets(limit int) ([]models.User, error) {
 	var users []models.User
-	err := r.db.Order("bean_amount DESC").Limit(limit).Find(&users).Error
+	err := r.db.Where("username <> ?", models.MintUsername).
+		Order("bean_amount DESC").
+		Limit(limit).
+		Find(&users).Error
 	return users, err
 }
 
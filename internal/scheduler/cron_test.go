@@ -0,0 +1,57 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCron_Invalid(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		_, err := ParseCron(expr)
+		assert.Error(t, err, expr)
+	}
+}
+
+func TestCron_Next(t *testing.T) {
+	base := time.Date(2026, time.January, 30, 10, 17, 42, 0, time.UTC) // a Friday
+
+	cases := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2026, time.January, 30, 10, 18, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2026, time.January, 30, 10, 30, 0, 0, time.UTC)},
+		{"0 9 * * 1", time.Date(2026, time.February, 2, 9, 0, 0, 0, time.UTC)},
+		{"0 0 31 2,3 *", time.Date(2026, time.March, 31, 0, 0, 0, 0, time.UTC)},
+		{"30 8 1,15 * *", time.Date(2026, time.February, 1, 8, 30, 0, 0, time.UTC)},
+		{"0 12 * * 7", time.Date(2026, time.February, 1, 12, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2026, time.January, 31, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2026, time.February, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.expr, func(t *testing.T) {
+			cron, err := ParseCron(tc.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, cron.Next(base))
+		})
+	}
+}
+
+func TestCron_DayFieldsAreOredWhenBothRestricted(t *testing.T) {
+	cron, err := ParseCron("0 0 13 * 5")
+	require.NoError(t, err)
+
+	// Thursday the 1st: the next Friday (2nd) comes before the 13th.
+	next := cron.Next(time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, time.Date(2026, time.January, 2, 0, 0, 0, 0, time.UTC), next)
+}
+
+func TestCron_NeverMatches(t *testing.T) {
+	cron, err := ParseCron("0 0 30 2 *")
+	require.NoError(t, err)
+	assert.True(t, cron.Next(time.Now()).IsZero())
+}
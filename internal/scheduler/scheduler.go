@@ -0,0 +1,92 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// JobFunc does one pass of a background job. It receives the scheduler's
+// current time so that jobs never read the wall clock directly.
+type JobFunc func(now time.Time) error
+
+type job struct {
+	name     string
+	interval time.Duration
+	run      JobFunc
+}
+
+// Scheduler runs registered jobs on fixed intervals inside the server
+// process. Each job runs in its own goroutine and never overlaps itself.
+type Scheduler struct {
+	clock Clock
+	jobs  []job
+	wg    sync.WaitGroup
+}
+
+func New(clock Clock) *Scheduler {
+	if clock == nil {
+		clock = SystemClock()
+	}
+	return &Scheduler{clock: clock}
+}
+
+func (s *Scheduler) Clock() Clock {
+	return s.clock
+}
+
+// Register adds a job. Jobs must be registered before Start.
+func (s *Scheduler) Register(name string, interval time.Duration, run JobFunc) {
+	s.jobs = append(s.jobs, job{name: name, interval: interval, run: run})
+}
+
+// RunOnce runs every registered job a single time, in registration order.
+// Errors are logged and do not stop the remaining jobs.
+func (s *Scheduler) RunOnce() {
+	for _, j := range s.jobs {
+		s.runJob(j)
+	}
+}
+
+// Start runs each job immediately and then on its interval until ctx is
+// cancelled. Call Wait to block until all jobs have stopped.
+func (s *Scheduler) Start(ctx context.Context) {
+	for _, j := range s.jobs {
+		s.wg.Add(1)
+		go func(j job) {
+			defer s.wg.Done()
+
+			ticker := time.NewTicker(j.interval)
+			defer ticker.Stop()
+
+			s.runJob(j)
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					s.runJob(j)
+				}
+			}
+		}(j)
+	}
+	log.Printf("[Scheduler] Started %d job(s)", len(s.jobs))
+}
+
+// Wait blocks until every job started by Start has returned.
+func (s *Scheduler) Wait() {
+	s.wg.Wait()
+}
+
+func (s *Scheduler) runJob(j job) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("[Scheduler] Job %s panicked: %v", j.name, r)
+		}
+	}()
+
+	if err := j.run(s.clock.Now()); err != nil {
+		log.Printf("[Scheduler] Job %s failed: %v", j.name, err)
+	}
+}
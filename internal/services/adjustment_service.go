@@ -0,0 +1,136 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/h4ks-com/bean-bank/internal/database"
+	"github.com/h4ks-com/bean-bank/internal/models"
+	"github.com/h4ks-com/bean-bank/internal/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrReasonRequired   = errors.New("reason is required")
+	ErrAdjustmentAmount = errors.New("exactly one of delta or bean_amount must be set")
+	ErrNoBalanceChange  = errors.New("adjustment does not change the balance")
+	ErrReservedAccount  = errors.New("reserved accounts cannot be adjusted")
+)
+
+type AdjustmentService struct {
+	userRepo        *repository.UserRepository
+	transactionRepo *repository.TransactionRepository
+	adjustmentRepo  *repository.AdjustmentRepository
+	db              *gorm.DB
+}
+
+func NewAdjustmentService(userRepo *repository.UserRepository, transactionRepo *repository.TransactionRepository, adjustmentRepo *repository.AdjustmentRepository, db *gorm.DB) *AdjustmentService {
+	return &AdjustmentService{
+		userRepo:        userRepo,
+		transactionRepo: transactionRepo,
+		adjustmentRepo:  adjustmentRepo,
+		db:              db,
+	}
+}
+
+// AdjustmentParams describes an admin balance change. Either Delta or Target
+// must be set: a positive delta mints beans, a negative one burns them, and a
+// target is turned into the delta needed to reach it.
+type AdjustmentParams struct {
+	Actor    string
+	Username string
+	Delta    *int
+	Target   *int
+	Reason   string
+}
+
+// Adjust mints or burns beans through the mint account and records who made
+// the change and why.
+func (s *AdjustmentService) Adjust(params AdjustmentParams) (*models.BalanceAdjustment, error) {
+	if (params.Delta == nil) == (params.Target == nil) {
+		return nil, ErrAdjustmentAmount
+	}
+	if params.Target != nil && *params.Target < 0 {
+		return nil, ErrInvalidAmount
+	}
+
+	reason, err := SanitizeNote(params.Reason)
+	if err != nil {
+		return nil, err
+	}
+	if reason == "" {
+		return nil, ErrReasonRequired
+	}
+
+	if models.IsReservedUsername(params.Username) {
+		return nil, ErrReservedAccount
+	}
+
+	var adjustment *models.BalanceAdjustment
+
+	err = database.Transaction(s.db, func(tx *gorm.DB) error {
+		users, err := s.userRepo.LockUsers(tx, params.Username, models.MintUsername)
+		if err != nil {
+			return err
+		}
+
+		user, mintUser := users[params.Username], users[models.MintUsername]
+		if user == nil {
+			return ErrUserNotFound
+		}
+
+		var delta int
+		if params.Delta != nil {
+			delta = *params.Delta
+		} else {
+			delta = *params.Target - user.BeanAmount
+		}
+
+		if delta == 0 {
+			return ErrNoBalanceChange
+		}
+		if user.BeanAmount+delta < 0 {
+			return ErrInsufficientBalance
+		}
+
+		balanceBefore := user.BeanAmount
+		transaction := &models.Transaction{
+			Amount: delta,
+			Note:   reason,
+			Kind:   models.TransactionKindAdminAdjustment,
+		}
+		if delta < 0 {
+			transaction.Amount = -delta
+			err = s.transactionRepo.Post(tx, user, mintUser, transaction)
+		} else {
+			err = s.transactionRepo.Post(tx, mintUser, user, transaction)
+		}
+		if err != nil {
+			return err
+		}
+
+		adjustment = &models.BalanceAdjustment{
+			UserID:        user.ID,
+			TransactionID: transaction.ID,
+			ActorUsername: params.Actor,
+			Delta:         delta,
+			BalanceBefore: balanceBefore,
+			BalanceAfter:  user.BeanAmount,
+			Reason:        reason,
+		}
+		if err := s.adjustmentRepo.Create(tx, adjustment); err != nil {
+			return err
+		}
+		adjustment.User = *user
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return adjustment, nil
+}
+
+func (s *AdjustmentService) ListAdjustments(username, actor string, limit int) ([]models.BalanceAdjustment, error) {
+	return s.adjustmentRepo.Find(username, actor, limit)
+}
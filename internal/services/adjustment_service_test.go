@@ -0,0 +1,126 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/h4ks-com/bean-bank/internal/database"
+	"github.com/h4ks-com/bean-bank/internal/models"
+	"github.com/h4ks-com/bean-bank/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupAdjustmentTestDB(t *testing.T) (*repository.UserRepository, *repository.TransactionRepository, *AdjustmentService) {
+	db, err := database.Connect(":memory:")
+	require.NoError(t, err)
+
+	err = database.Migrate(db)
+	require.NoError(t, err)
+
+	userRepo := repository.NewUserRepository(db)
+	transactionRepo := repository.NewTransactionRepository(db)
+	adjustmentRepo := repository.NewAdjustmentRepository(db)
+	adjustmentService := NewAdjustmentService(userRepo, transactionRepo, adjustmentRepo, db)
+
+	return userRepo, transactionRepo, adjustmentService
+}
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func TestAdjustmentService_MintAndBurn(t *testing.T) {
+	userRepo, transactionRepo, service := setupAdjustmentTestDB(t)
+	require.NoError(t, userRepo.Create(&models.User{Username: "alice", BeanAmount: 100}))
+
+	minted, err := service.Adjust(AdjustmentParams{Actor: "admin", Username: "alice", Delta: intPtr(30), Reason: "contest prize"})
+	require.NoError(t, err)
+	assert.Equal(t, 30, minted.Delta)
+	assert.Equal(t, 100, minted.BalanceBefore)
+	assert.Equal(t, 130, minted.BalanceAfter)
+	assert.Equal(t, "admin", minted.ActorUsername)
+
+	burned, err := service.Adjust(AdjustmentParams{Actor: "mod", Username: "alice", Target: intPtr(90), Reason: "duplicate payout"})
+	require.NoError(t, err)
+	assert.Equal(t, -40, burned.Delta)
+	assert.Equal(t, 90, burned.BalanceAfter)
+
+	user, err := userRepo.FindByUsername("alice")
+	require.NoError(t, err)
+	assert.Equal(t, 90, user.BeanAmount)
+
+	transactions, err := transactionRepo.FindByUsername("alice")
+	require.NoError(t, err)
+	require.Len(t, transactions, 2)
+	for _, tx := range transactions {
+		assert.Equal(t, models.TransactionKindAdminAdjustment, tx.Kind)
+	}
+
+	var burn, mint models.Transaction
+	if transactions[0].FromUser.Username == "alice" {
+		burn, mint = transactions[0], transactions[1]
+	} else {
+		burn, mint = transactions[1], transactions[0]
+	}
+	assert.Equal(t, models.MintUsername, mint.FromUser.Username)
+	assert.Equal(t, 30, mint.Amount)
+	assert.Equal(t, "contest prize", mint.Note)
+	assert.Equal(t, models.MintUsername, burn.ToUser.Username)
+	assert.Equal(t, 40, burn.Amount)
+	assert.Equal(t, burned.TransactionID, burn.ID)
+}
+
+func TestAdjustmentService_Validation(t *testing.T) {
+	userRepo, _, service := setupAdjustmentTestDB(t)
+	require.NoError(t, userRepo.Create(&models.User{Username: "alice", BeanAmount: 10}))
+
+	tests := []struct {
+		name   string
+		params AdjustmentParams
+		err    error
+	}{
+		{"missing reason", AdjustmentParams{Username: "alice", Delta: intPtr(5), Reason: "  "}, ErrReasonRequired},
+		{"no amount", AdjustmentParams{Username: "alice", Reason: "x"}, ErrAdjustmentAmount},
+		{"both amounts", AdjustmentParams{Username: "alice", Delta: intPtr(5), Target: intPtr(5), Reason: "x"}, ErrAdjustmentAmount},
+		{"negative target", AdjustmentParams{Username: "alice", Target: intPtr(-1), Reason: "x"}, ErrInvalidAmount},
+		{"zero delta", AdjustmentParams{Username: "alice", Delta: intPtr(0), Reason: "x"}, ErrNoBalanceChange},
+		{"unchanged target", AdjustmentParams{Username: "alice", Target: intPtr(10), Reason: "x"}, ErrNoBalanceChange},
+		{"burn too much", AdjustmentParams{Username: "alice", Delta: intPtr(-11), Reason: "x"}, ErrInsufficientBalance},
+		{"unknown user", AdjustmentParams{Username: "ghost", Delta: intPtr(1), Reason: "x"}, ErrUserNotFound},
+		{"mint account", AdjustmentParams{Username: models.MintUsername, Delta: intPtr(1), Reason: "x"}, ErrReservedAccount},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.Adjust(tt.params)
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+}
+
+func TestAdjustmentService_ListAdjustments(t *testing.T) {
+	userRepo, _, service := setupAdjustmentTestDB(t)
+	require.NoError(t, userRepo.Create(&models.User{Username: "alice", BeanAmount: 0}))
+	require.NoError(t, userRepo.Create(&models.User{Username: "bob", BeanAmount: 0}))
+
+	_, err := service.Adjust(AdjustmentParams{Actor: "admin", Username: "alice", Delta: intPtr(5000), Reason: "migration from old bank"})
+	require.NoError(t, err)
+	_, err = service.Adjust(AdjustmentParams{Actor: "mod", Username: "bob", Delta: intPtr(10), Reason: "bug bounty"})
+	require.NoError(t, err)
+
+	all, err := service.ListAdjustments("", "", 0)
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+
+	forAlice, err := service.ListAdjustments("alice", "", 0)
+	require.NoError(t, err)
+	require.Len(t, forAlice, 1)
+	assert.Equal(t, "alice", forAlice[0].User.Username)
+	assert.Equal(t, "migration from old bank", forAlice[0].Reason)
+	assert.Equal(t, "admin", forAlice[0].ActorUsername)
+
+	byMod, err := service.ListAdjustments("", "mod", 0)
+	require.NoError(t, err)
+	require.Len(t, byMod, 1)
+	assert.Equal(t, "bob", byMod[0].User.Username)
+}
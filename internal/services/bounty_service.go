@@ -0,0 +1,352 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/h4ks-com/bean-bank/internal/database"
+	"github.com/h4ks-com/bean-bank/internal/models"
+	"github.com/h4ks-com/bean-bank/internal/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrNotBounty             = errors.New("harvest is not a bounty")
+	ErrNotBountyPoster       = errors.New("only the poster of this bounty can do that")
+	ErrBountyNotFundable     = errors.New("bounty is no longer taking contributions")
+	ErrBountyNotCancellable  = errors.New("bounty can only be cancelled before work is submitted or paid")
+	ErrExplicitSplitFund     = errors.New("bounty has an explicit reward split, ask an admin to change it before adding to it")
+	ErrInsufficientForBounty = errors.New("insufficient balance to fund bounty")
+	ErrSelfApproval          = errors.New("you cannot approve a submission you are working on")
+)
+
+// BountyService lets users post harvests funded from their own wallets. The
+// reward is escrowed in the system account when the bounty is posted or
+// funded, paid out of escrow on approval, and refunded if it is cancelled.
+type BountyService struct {
+	harvestService  *HarvestService
+	harvestRepo     *repository.HarvestRepository
+	userRepo        *repository.UserRepository
+	transactionRepo *repository.TransactionRepository
+	db              *gorm.DB
+}
+
+func NewBountyService(
+	harvestService *HarvestService,
+	harvestRepo *repository.HarvestRepository,
+	userRepo *repository.UserRepository,
+	transactionRepo *repository.TransactionRepository,
+	db *gorm.DB,
+) *BountyService {
+	return &BountyService{
+		harvestService:  harvestService,
+		harvestRepo:     harvestRepo,
+		userRepo:        userRepo,
+		transactionRepo: transactionRepo,
+		db:              db,
+	}
+}
+
+// CreateBounty posts a bounty and escrows its reward from the poster's
+// wallet.
+func (s *BountyService) CreateBounty(poster string, params HarvestParams) (*models.Harvest, error) {
+	if params.BeanAmount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	if models.IsReservedUsername(poster) {
+		return nil, ErrReservedAccount
+	}
+
+	dueAt, claimTimeout, err := parseHarvestTiming(params, s.harvestService.clock.Now())
+	if err != nil {
+		return nil, err
+	}
+	category, tags, err := parseHarvestLabels(params.Category, params.Tags)
+	if err != nil {
+		return nil, err
+	}
+
+	var harvestID uint
+	err = database.Transaction(s.db, func(tx *gorm.DB) error {
+		users, err := s.userRepo.LockUsers(tx, poster, models.SystemUsername)
+		if err != nil {
+			return err
+		}
+		posterUser := users[poster]
+		if posterUser == nil {
+			return ErrUserNotFound
+		}
+		if posterUser.BeanAmount < params.BeanAmount {
+			return ErrInsufficientForBounty
+		}
+
+		harvest := &models.Harvest{
+			Title:               params.Title,
+			Description:         params.Description,
+			BeanAmount:          params.BeanAmount,
+			DueAt:               dueAt,
+			ClaimTimeoutSeconds: int64(claimTimeout / time.Second),
+			Status:              models.HarvestOpen,
+			PosterID:            &posterUser.ID,
+			Category:            category,
+		}
+		if err := s.harvestRepo.CreateInTx(tx, harvest); err != nil {
+			return err
+		}
+		if _, err := s.harvestRepo.SetTagsInTx(tx, harvest.ID, tags); err != nil {
+			return err
+		}
+		harvestID = harvest.ID
+
+		if err := s.escrowInTx(tx, harvest, posterUser, users[models.SystemUsername], params.BeanAmount); err != nil {
+			return err
+		}
+		return s.harvestService.recordEvent(tx, harvest, models.HarvestEventCreated, "", poster,
+			fmt.Sprintf("Posted with %d beans in escrow", params.BeanAmount))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.harvestRepo.FindByID(harvestID)
+}
+
+// FundBounty adds amount from the user's wallet to a bounty's escrow and its
+// reward.
+func (s *BountyService) FundBounty(harvestID uint, username string, amount int) (*models.Harvest, error) {
+	if amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	if models.IsReservedUsername(username) {
+		return nil, ErrReservedAccount
+	}
+
+	err := database.Transaction(s.db, func(tx *gorm.DB) error {
+		harvest, err := s.harvestService.lockHarvest(tx, harvestID)
+		if err != nil {
+			return err
+		}
+		if !harvest.IsBounty() {
+			return ErrNotBounty
+		}
+		switch harvest.Status {
+		case models.HarvestApproved, models.HarvestPaid, models.HarvestCancelled:
+			return ErrBountyNotFundable
+		}
+		if harvest.SplitMode == models.HarvestSplitExplicit {
+			return ErrExplicitSplitFund
+		}
+
+		users, err := s.userRepo.LockUsers(tx, username, models.SystemUsername)
+		if err != nil {
+			return err
+		}
+		funder := users[username]
+		if funder == nil {
+			return ErrUserNotFound
+		}
+		if funder.BeanAmount < amount {
+			return ErrInsufficientForBounty
+		}
+
+		if err := s.escrowInTx(tx, harvest, funder, users[models.SystemUsername], amount); err != nil {
+			return err
+		}
+		harvest.BeanAmount += amount
+		if err := s.harvestRepo.UpdateInTx(tx, harvest); err != nil {
+			return err
+		}
+		return s.harvestService.recordEvent(tx, harvest, models.HarvestEventFunded, harvest.Status, username,
+			fmt.Sprintf("Added %d beans, reward is now %d", amount, harvest.BeanAmount))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.harvestRepo.FindByID(harvestID)
+}
+
+// escrowInTx moves amount from the contributor to the system account and
+// adds it to their contribution.
+func (s *BountyService) escrowInTx(tx *gorm.DB, harvest *models.Harvest, contributor, system *models.User, amount int) error {
+	transaction := &models.Transaction{
+		Amount:    amount,
+		Note:      fmt.Sprintf("Bounty escrow: %s", harvest.Title),
+		Kind:      models.TransactionKindBountyEscrow,
+		HarvestID: &harvest.ID,
+	}
+	if err := s.transactionRepo.Post(tx, contributor, system, transaction); err != nil {
+		return fmt.Errorf("failed to escrow beans: %w", err)
+	}
+
+	contribution, err := s.harvestRepo.FindContributionInTx(tx, harvest.ID, contributor.ID)
+	if err != nil {
+		return err
+	}
+	if contribution == nil {
+		contribution = &models.HarvestContribution{HarvestID: harvest.ID, UserID: contributor.ID}
+	}
+	contribution.Amount += amount
+	return s.harvestRepo.SaveContributionInTx(tx, contribution)
+}
+
+// ReviewBounty lets the poster approve or reject the submission awaiting
+// review. Approval pays the participants out of escrow, so a poster who is
+// somehow among them (an admin can add anyone) cannot approve.
+func (s *BountyService) ReviewBounty(harvestID uint, username string, approve bool, comment string) (*models.Harvest, error) {
+	harvest, err := s.findPosted(harvestID, username)
+	if err != nil {
+		return nil, err
+	}
+	if approve {
+		for _, p := range harvest.Participants {
+			if p.UserID == *harvest.PosterID {
+				return nil, ErrSelfApproval
+			}
+		}
+	}
+	return s.harvestService.ReviewHarvest(harvestID, username, approve, comment)
+}
+
+// ListBountySubmissions returns the submissions of a bounty to its poster,
+// newest first.
+func (s *BountyService) ListBountySubmissions(harvestID uint, username string) ([]models.HarvestSubmission, error) {
+	if _, err := s.findPosted(harvestID, username); err != nil {
+		return nil, err
+	}
+	return s.harvestRepo.ListSubmissions(harvestID)
+}
+
+// CancelBounty withdraws a bounty and refunds its escrow to the contributors
+// in proportion to what each put in. Only the poster can cancel, unless
+// asAdmin is set.
+func (s *BountyService) CancelBounty(harvestID uint, username string, asAdmin bool) (*models.Harvest, error) {
+	if !asAdmin {
+		if _, err := s.findPosted(harvestID, username); err != nil {
+			return nil, err
+		}
+	}
+
+	err := database.Transaction(s.db, func(tx *gorm.DB) error {
+		harvest, err := s.harvestService.lockHarvest(tx, harvestID)
+		if err != nil {
+			return err
+		}
+		if !harvest.IsBounty() {
+			return ErrNotBounty
+		}
+		switch harvest.Status {
+		case models.HarvestOpen, models.HarvestClaimed, models.HarvestRejected, models.HarvestExpired:
+		default:
+			return ErrBountyNotCancellable
+		}
+
+		contributions, err := s.harvestRepo.ListContributionsInTx(tx, harvest.ID)
+		if err != nil {
+			return err
+		}
+
+		usernames := make([]string, 0, len(contributions)+1)
+		weights := make([]int, len(contributions))
+		for i, c := range contributions {
+			usernames = append(usernames, c.User.Username)
+			weights[i] = c.Amount
+		}
+		users, err := s.userRepo.LockUsers(tx, append(usernames, models.SystemUsername)...)
+		if err != nil {
+			return err
+		}
+		system := users[models.SystemUsername]
+
+		refunds := proRata(harvest.BeanAmount, weights)
+		refunded := make([]string, 0, len(contributions))
+		for i := range contributions {
+			if refunds[i] == 0 {
+				continue
+			}
+			transaction := &models.Transaction{
+				Amount:    refunds[i],
+				Note:      fmt.Sprintf("Bounty cancelled: %s", harvest.Title),
+				Kind:      models.TransactionKindBountyRefund,
+				HarvestID: &harvest.ID,
+			}
+			if err := s.transactionRepo.Post(tx, system, users[usernames[i]], transaction); err != nil {
+				return fmt.Errorf("failed to refund bounty: %w", err)
+			}
+			contributions[i].Refunded = refunds[i]
+			if err := s.harvestRepo.SaveContributionInTx(tx, &contributions[i]); err != nil {
+				return err
+			}
+			refunded = append(refunded, fmt.Sprintf("%d beans to %s", refunds[i], usernames[i]))
+		}
+
+		if err := s.harvestRepo.ClearParticipantsInTx(tx, harvest.ID); err != nil {
+			return err
+		}
+		harvest.AssignedUserID = nil
+		harvest.AssignedUser = nil
+		harvest.ClaimExpiresAt = nil
+		note := "Nothing to refund"
+		if len(refunded) > 0 {
+			note = "Refunded " + strings.Join(refunded, ", ")
+		}
+		return s.harvestService.transition(tx, harvest, models.HarvestCancelled, models.HarvestEventCancelled, username, note)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.harvestRepo.FindByID(harvestID)
+}
+
+// findPosted returns the bounty if username posted it.
+func (s *BountyService) findPosted(harvestID uint, username string) (*models.Harvest, error) {
+	harvest, err := s.harvestService.findHarvest(harvestID)
+	if err != nil {
+		return nil, err
+	}
+	if !harvest.IsBounty() {
+		return nil, ErrNotBounty
+	}
+	if harvest.Poster == nil || harvest.Poster.Username != username {
+		return nil, ErrNotBountyPoster
+	}
+	return harvest, nil
+}
+
+// proRata splits total in proportion to weights. Beans lost to rounding go
+// one each to the largest remainders, earliest first on ties, so the parts
+// always add up to total.
+func proRata(total int, weights []int) []int {
+	parts := make([]int, len(weights))
+	sum := 0
+	for _, w := range weights {
+		sum += w
+	}
+	if sum == 0 || total <= 0 {
+		return parts
+	}
+
+	remainders := make([]int, len(weights))
+	given := 0
+	for i, w := range weights {
+		product := int64(total) * int64(w)
+		parts[i] = int(product / int64(sum))
+		remainders[i] = int(product % int64(sum))
+		given += parts[i]
+	}
+
+	for ; given < total; given++ {
+		best := 0
+		for i := range remainders {
+			if remainders[i] > remainders[best] {
+				best = i
+			}
+		}
+		parts[best]++
+		remainders[best] = -1
+	}
+	return parts
+}
@@ -0,0 +1,252 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/h4ks-com/bean-bank/internal/database"
+	"github.com/h4ks-com/bean-bank/internal/models"
+	"github.com/h4ks-com/bean-bank/internal/repository"
+	"github.com/h4ks-com/bean-bank/internal/scheduler"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupBountyTestDB(t *testing.T) (*repository.UserRepository, *repository.TransactionRepository, *HarvestService, *BountyService) {
+	db, err := database.Connect(":memory:")
+	assert.NoError(t, err)
+	assert.NoError(t, database.Migrate(db))
+
+	harvestRepo := repository.NewHarvestRepository(db)
+	userRepo := repository.NewUserRepository(db)
+	transactionRepo := repository.NewTransactionRepository(db)
+	clock := scheduler.NewManualClock(time.Date(2026, time.March, 2, 9, 0, 0, 0, time.UTC))
+	harvestService := NewHarvestService(harvestRepo, userRepo, transactionRepo, db, clock)
+	bountyService := NewBountyService(harvestService, harvestRepo, userRepo, transactionRepo, db)
+
+	for _, user := range []models.User{
+		{Username: "poster", BeanAmount: 100},
+		{Username: "backer", BeanAmount: 50},
+		{Username: "worker", BeanAmount: 0},
+	} {
+		assert.NoError(t, userRepo.Create(&user))
+	}
+
+	return userRepo, transactionRepo, harvestService, bountyService
+}
+
+func assertBalance(t *testing.T, userRepo *repository.UserRepository, username string, want int) {
+	t.Helper()
+	user, err := userRepo.FindByUsername(username)
+	assert.NoError(t, err)
+	if assert.NotNil(t, user, username) {
+		assert.Equal(t, want, user.BeanAmount, username)
+	}
+}
+
+func TestBountyService_FundAndApprovePaysFromEscrow(t *testing.T) {
+	userRepo, transactionRepo, harvestService, bountyService := setupBountyTestDB(t)
+
+	bounty, err := bountyService.CreateBounty("poster", HarvestParams{Title: "Fix the printer", BeanAmount: 30})
+	assert.NoError(t, err)
+	assert.True(t, bounty.IsBounty())
+	assert.Equal(t, "poster", bounty.Poster.Username)
+	assertBalance(t, userRepo, "poster", 70)
+	assertBalance(t, userRepo, "system", 30)
+
+	funded, err := bountyService.FundBounty(bounty.ID, "backer", 20)
+	assert.NoError(t, err)
+	assert.Equal(t, 50, funded.BeanAmount)
+	assert.Len(t, funded.Contributions, 2)
+	assertBalance(t, userRepo, "backer", 30)
+	assertBalance(t, userRepo, "system", 50)
+
+	_, err = harvestService.ClaimHarvest(bounty.ID, "worker")
+	assert.NoError(t, err)
+	_, err = harvestService.SubmitHarvest(bounty.ID, "worker", "Printer prints", nil)
+	assert.NoError(t, err)
+
+	_, err = bountyService.ReviewBounty(bounty.ID, "backer", true, "")
+	assert.Equal(t, ErrNotBountyPoster, err)
+
+	submissions, err := bountyService.ListBountySubmissions(bounty.ID, "poster")
+	assert.NoError(t, err)
+	assert.Len(t, submissions, 1)
+
+	paid, err := bountyService.ReviewBounty(bounty.ID, "poster", true, "Thanks")
+	assert.NoError(t, err)
+	assert.Equal(t, models.HarvestPaid, paid.Status)
+	assertBalance(t, userRepo, "worker", 50)
+	assertBalance(t, userRepo, "system", 0)
+
+	mint, err := userRepo.FindByUsername("mint")
+	assert.NoError(t, err)
+	if mint != nil {
+		assert.Equal(t, 0, mint.BeanAmount)
+	}
+
+	transactions, err := transactionRepo.FindByUsername("worker")
+	assert.NoError(t, err)
+	assert.Len(t, transactions, 1)
+	assert.Equal(t, models.TransactionKindBountyPayout, transactions[0].Kind)
+	assert.Equal(t, "system", transactions[0].FromUser.Username)
+
+	_, err = bountyService.FundBounty(bounty.ID, "backer", 5)
+	assert.Equal(t, ErrBountyNotFundable, err)
+	_, err = bountyService.CancelBounty(bounty.ID, "poster", false)
+	assert.Equal(t, ErrBountyNotCancellable, err)
+}
+
+func TestBountyService_CancelRefundsContributors(t *testing.T) {
+	userRepo, _, harvestService, bountyService := setupBountyTestDB(t)
+
+	bounty, err := bountyService.CreateBounty("poster", HarvestParams{Title: "Cancel me", BeanAmount: 40})
+	assert.NoError(t, err)
+	_, err = bountyService.FundBounty(bounty.ID, "backer", 10)
+	assert.NoError(t, err)
+	_, err = bountyService.FundBounty(bounty.ID, "poster", 5)
+	assert.NoError(t, err)
+	_, err = harvestService.ClaimHarvest(bounty.ID, "worker")
+	assert.NoError(t, err)
+
+	_, err = bountyService.CancelBounty(bounty.ID, "backer", false)
+	assert.Equal(t, ErrNotBountyPoster, err)
+
+	cancelled, err := bountyService.CancelBounty(bounty.ID, "poster", false)
+	assert.NoError(t, err)
+	assert.Equal(t, models.HarvestCancelled, cancelled.Status)
+	assert.Empty(t, cancelled.Participants)
+	assert.Equal(t, 45, cancelled.Contributions[0].Refunded)
+	assert.Equal(t, 10, cancelled.Contributions[1].Refunded)
+
+	assertBalance(t, userRepo, "poster", 100)
+	assertBalance(t, userRepo, "backer", 50)
+	assertBalance(t, userRepo, "system", 0)
+
+	events, err := harvestService.ListEvents(bounty.ID)
+	assert.NoError(t, err)
+	last := events[len(events)-1]
+	assert.Equal(t, models.HarvestEventCancelled, last.Type)
+	assert.Equal(t, "Refunded 45 beans to poster, 10 beans to backer", last.Note)
+
+	assert.NoError(t, harvestService.DeleteHarvest(bounty.ID))
+}
+
+func TestBountyService_Guards(t *testing.T) {
+	userRepo, _, harvestService, bountyService := setupBountyTestDB(t)
+
+	_, err := bountyService.CreateBounty("backer", HarvestParams{Title: "Too rich", BeanAmount: 51})
+	assert.Equal(t, ErrInsufficientForBounty, err)
+	assertBalance(t, userRepo, "backer", 50)
+
+	_, err = bountyService.CreateBounty("system", HarvestParams{Title: "Reserved", BeanAmount: 1})
+	assert.Equal(t, ErrReservedAccount, err)
+
+	_, err = bountyService.CreateBounty("poster", HarvestParams{Title: "Bad deadline", BeanAmount: 1, Deadline: "later"})
+	assert.Equal(t, ErrInvalidDeadline, err)
+
+	bounty, err := bountyService.CreateBounty("poster", HarvestParams{Title: "Guarded", BeanAmount: 10})
+	assert.NoError(t, err)
+
+	_, err = bountyService.FundBounty(bounty.ID, "backer", 51)
+	assert.Equal(t, ErrInsufficientForBounty, err)
+
+	_, err = harvestService.UpdateHarvest(bounty.ID, HarvestParams{Title: "Guarded", BeanAmount: 500})
+	assert.Equal(t, ErrBountyRewardFixed, err)
+
+	err = harvestService.DeleteHarvest(bounty.ID)
+	assert.Equal(t, ErrBountyEscrowHeld, err)
+
+	_, err = harvestService.SetParticipants(bounty.ID, models.HarvestSplitExplicit, []HarvestShare{{Username: "worker", Amount: 10}}, "admin")
+	assert.NoError(t, err)
+	_, err = bountyService.FundBounty(bounty.ID, "backer", 5)
+	assert.Equal(t, ErrExplicitSplitFund, err)
+
+	regular, err := harvestService.CreateHarvest(HarvestParams{Title: "Minted", BeanAmount: 10})
+	assert.NoError(t, err)
+	_, err = bountyService.FundBounty(regular.ID, "backer", 5)
+	assert.Equal(t, ErrNotBounty, err)
+	_, err = bountyService.CancelBounty(regular.ID, "admin", true)
+	assert.Equal(t, ErrNotBounty, err)
+
+	cancelled, err := bountyService.CancelBounty(bounty.ID, "admin", true)
+	assert.NoError(t, err)
+	assert.Equal(t, models.HarvestCancelled, cancelled.Status)
+}
+
+func TestBountyService_PosterCannotWorkOnOwnBounty(t *testing.T) {
+	userRepo, _, harvestService, bountyService := setupBountyTestDB(t)
+
+	bounty, err := bountyService.CreateBounty("poster", HarvestParams{Title: "Mine all mine", BeanAmount: 1})
+	assert.NoError(t, err)
+	_, err = bountyService.FundBounty(bounty.ID, "backer", 40)
+	assert.NoError(t, err)
+
+	_, err = harvestService.ClaimHarvest(bounty.ID, "poster")
+	assert.Equal(t, ErrOwnBounty, err)
+
+	_, err = harvestService.ClaimHarvest(bounty.ID, "worker")
+	assert.NoError(t, err)
+	_, err = harvestService.JoinHarvest(bounty.ID, "poster")
+	assert.Equal(t, ErrOwnBounty, err)
+	assertBalance(t, userRepo, "system", 41)
+}
+
+func TestBountyService_ParticipantCannotApprove(t *testing.T) {
+	userRepo, _, harvestService, bountyService := setupBountyTestDB(t)
+
+	bounty, err := bountyService.CreateBounty("poster", HarvestParams{Title: "Shared job", BeanAmount: 10})
+	assert.NoError(t, err)
+	_, err = bountyService.FundBounty(bounty.ID, "backer", 30)
+	assert.NoError(t, err)
+
+	// Only an admin can put the poster on their own bounty.
+	_, err = harvestService.SetParticipants(bounty.ID, models.HarvestSplitEqual, []HarvestShare{{Username: "worker"}, {Username: "poster"}}, "admin")
+	assert.NoError(t, err)
+	_, err = harvestService.SubmitHarvest(bounty.ID, "worker", "Done together", nil)
+	assert.NoError(t, err)
+
+	_, err = bountyService.ReviewBounty(bounty.ID, "poster", true, "")
+	assert.Equal(t, ErrSelfApproval, err)
+	assertBalance(t, userRepo, "system", 40)
+
+	rejected, err := bountyService.ReviewBounty(bounty.ID, "poster", false, "Needs another pass")
+	assert.NoError(t, err)
+	assert.Equal(t, models.HarvestRejected, rejected.Status)
+}
+
+func TestProRata(t *testing.T) {
+	assert.Equal(t, []int{30, 20}, proRata(50, []int{30, 20}))
+	assert.Equal(t, []int{4, 3, 3}, proRata(10, []int{1, 1, 1}))
+	assert.Equal(t, []int{7, 2}, proRata(9, []int{3, 1}))
+	assert.Equal(t, []int{0, 0}, proRata(0, []int{3, 1}))
+	assert.Equal(t, []int{}, proRata(10, []int{}))
+}
+
+func TestBountyService_RevertRestoresEscrow(t *testing.T) {
+	userRepo, _, harvestService, bountyService := setupBountyTestDB(t)
+
+	bounty, err := bountyService.CreateBounty("poster", HarvestParams{Title: "Wrong fix", BeanAmount: 40})
+	assert.NoError(t, err)
+	_, err = harvestService.ClaimHarvest(bounty.ID, "worker")
+	assert.NoError(t, err)
+	_, err = harvestService.CompleteHarvest(bounty.ID)
+	assert.NoError(t, err)
+	assertBalance(t, userRepo, "worker", 40)
+	assertBalance(t, userRepo, "system", 0)
+
+	_, _, err = harvestService.RevertCompletion(bounty.ID, "admin", ClawbackPartial, "Not fixed")
+	assert.Equal(t, ErrBountyPartialClawback, err)
+
+	reverted, _, err := harvestService.RevertCompletion(bounty.ID, "admin", ClawbackRefuse, "Not fixed")
+	assert.NoError(t, err)
+	assert.Equal(t, models.HarvestOpen, reverted.Status)
+	assertBalance(t, userRepo, "worker", 0)
+	assertBalance(t, userRepo, "system", 40)
+	assertBalance(t, userRepo, "poster", 60)
+
+	cancelled, err := bountyService.CancelBounty(bounty.ID, "poster", false)
+	assert.NoError(t, err)
+	assert.Equal(t, models.HarvestCancelled, cancelled.Status)
+	assertBalance(t, userRepo, "poster", 100)
+	assertBalance(t, userRepo, "system", 0)
+}
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/h4ks-com/bean-bank/internal/duration"
+)
+
+var (
+	ErrInvalidExpiry     = errors.New("expires_in must be a duration such as 90m, 36h, 7d or 2w, an RFC 3339 timestamp, or never")
+	ErrExpiryNotInFuture = errors.New("expires_in must be in the future")
+	ErrExpiryTooFar      = errors.New("expires_in is later than the maximum expiry allowed on this instance")
+)
+
+// ParseExpiry turns an expires_in value shared by gift links, payment
+// requests and API tokens into an absolute time. It accepts "never" (or an
+// empty value) for no expiry, a duration from now as understood by
+// duration.Parse, or an RFC 3339 timestamp. A non-zero maxExpiry caps how far
+// past now the expiry may be: "never" is rejected, and an empty value expires
+// at the cap.
+func ParseExpiry(expiresIn string, now time.Time, maxExpiry time.Duration) (*time.Time, error) {
+	expiresIn = strings.TrimSpace(expiresIn)
+	switch {
+	case expiresIn == "" && maxExpiry > 0:
+		expiry := now.Add(maxExpiry)
+		return &expiry, nil
+	case expiresIn == "" || expiresIn == "never":
+		if maxExpiry > 0 {
+			return nil, ErrExpiryTooFar
+		}
+		return nil, nil
+	}
+
+	expiry, err := time.Parse(time.RFC3339, expiresIn)
+	if err != nil {
+		d, err := duration.Parse(expiresIn)
+		if err != nil {
+			return nil, ErrInvalidExpiry
+		}
+		expiry = now.Add(d)
+	}
+
+	if !expiry.After(now) {
+		return nil, ErrExpiryNotInFuture
+	}
+	if maxExpiry > 0 && expiry.Sub(now) > maxExpiry {
+		return nil, ErrExpiryTooFar
+	}
+	return &expiry, nil
+}
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseExpiry(t *testing.T) {
+	now := time.Date(2026, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("durations and timestamps", func(t *testing.T) {
+		cases := []struct {
+			value string
+			want  time.Time
+		}{
+			{"90m", now.Add(90 * time.Minute)},
+			{"1w2d", now.Add(9 * 24 * time.Hour)},
+			{"1.5d", now.Add(36 * time.Hour)},
+			{" 7d ", now.Add(7 * 24 * time.Hour)},
+			{"2026-03-04T09:30:00Z", time.Date(2026, time.March, 4, 9, 30, 0, 0, time.UTC)},
+			{"2026-03-04T09:30:00+02:00", time.Date(2026, time.March, 4, 7, 30, 0, 0, time.UTC)},
+		}
+
+		for _, tc := range cases {
+			expiry, err := ParseExpiry(tc.value, now, 0)
+			require.NoError(t, err, tc.value)
+			require.NotNil(t, expiry, tc.value)
+			assert.True(t, tc.want.Equal(*expiry), "%s: got %s", tc.value, expiry)
+		}
+	})
+
+	t.Run("never and empty mean no expiry", func(t *testing.T) {
+		for _, value := range []string{"", "never"} {
+			expiry, err := ParseExpiry(value, now, 0)
+			require.NoError(t, err)
+			assert.Nil(t, expiry)
+		}
+	})
+
+	t.Run("rejects unparseable values", func(t *testing.T) {
+		for _, value := range []string{"soon", "7", "1y", "2026-03-04", "tomorrow 9am"} {
+			_, err := ParseExpiry(value, now, 0)
+			assert.Equal(t, ErrInvalidExpiry, err, value)
+		}
+	})
+
+	t.Run("rejects the past", func(t *testing.T) {
+		for _, value := range []string{"-1h", "-2d", "0s", "2026-02-28T12:00:00Z", now.Format(time.RFC3339)} {
+			_, err := ParseExpiry(value, now, 0)
+			assert.Equal(t, ErrExpiryNotInFuture, err, value)
+		}
+	})
+
+	t.Run("maximum expiry", func(t *testing.T) {
+		maxExpiry := 30 * 24 * time.Hour
+
+		for _, value := range []string{"never", "31d", "5w", "2026-04-01T12:00:00Z"} {
+			_, err := ParseExpiry(value, now, maxExpiry)
+			assert.Equal(t, ErrExpiryTooFar, err, value)
+		}
+
+		for _, value := range []string{"30d", "4w", "2026-03-31T12:00:00Z"} {
+			expiry, err := ParseExpiry(value, now, maxExpiry)
+			require.NoError(t, err, value)
+			assert.NotNil(t, expiry, value)
+		}
+
+		for _, value := range []string{"", "  "} {
+			expiry, err := ParseExpiry(value, now, maxExpiry)
+			require.NoError(t, err)
+			require.NotNil(t, expiry)
+			assert.Equal(t, now.Add(maxExpiry), *expiry)
+		}
+	})
+}
@@ -17,22 +17,28 @@ var (
 )
 
 type TransactionExport struct {
-	UserID      uint                   `json:"user_id"`
-	Username    string                 `json:"username"`
-	Email       string                 `json:"email"`
-	TotalBeans  int                    `json:"total_beans"`
+	UserID       uint                    `json:"user_id"`
+	Username     string                  `json:"username"`
+	Email        string                  `json:"email"`
+	TotalBeans   int                     `json:"total_beans"`
 	Transactions []TransactionExportItem `json:"transactions"`
-	ExportedAt  time.Time              `json:"exported_at"`
-	Signature   string                 `json:"signature"`
+	ExportedAt   time.Time               `json:"exported_at"`
+	Signature    string                  `json:"signature"`
 }
 
 type TransactionExportItem struct {
-	ID          uint      `json:"id"`
-	FromUser    string    `json:"from_user"`
-	ToUser      string    `json:"to_user"`
-	Amount      int       `json:"amount"`
-	Note        string    `json:"note,omitempty"`
-	CreatedAt   time.Time `json:"created_at"`
+	ID                  uint      `json:"id"`
+	FromUser            string    `json:"from_user"`
+	ToUser              string    `json:"to_user"`
+	Amount              int       `json:"amount"`
+	Note                string    `json:"note,omitempty"`
+	Kind                string    `json:"kind,omitempty"`
+	GiftLinkID          *uint     `json:"gift_link_id,omitempty"`
+	GiftCampaignID      *uint     `json:"gift_campaign_id,omitempty"`
+	HarvestID           *uint     `json:"harvest_id,omitempty"`
+	PaymentRequestID    *uint     `json:"payment_request_id,omitempty"`
+	ScheduledTransferID *uint     `json:"scheduled_transfer_id,omitempty"`
+	CreatedAt           time.Time `json:"created_at"`
 }
 
 type ExportService struct {
@@ -66,12 +72,18 @@ func (s *ExportService) ExportTransactions(username string) (*TransactionExport,
 	exportItems := make([]TransactionExportItem, len(transactions))
 	for i, tx := range transactions {
 		exportItems[i] = TransactionExportItem{
-			ID:        tx.ID,
-			FromUser:  tx.FromUser.Username,
-			ToUser:    tx.ToUser.Username,
-			Amount:    tx.Amount,
-			Note:      tx.Note,
-			CreatedAt: tx.CreatedAt,
+			ID:                  tx.ID,
+			FromUser:            tx.FromUser.Username,
+			ToUser:              tx.ToUser.Username,
+			Amount:              tx.Amount,
+			Note:                tx.Note,
+			Kind:                string(tx.Kind),
+			GiftLinkID:          tx.GiftLinkID,
+			GiftCampaignID:      tx.GiftCampaignID,
+			HarvestID:           tx.HarvestID,
+			PaymentRequestID:    tx.PaymentRequestID,
+			ScheduledTransferID: tx.ScheduledTransferID,
+			CreatedAt:           tx.CreatedAt,
 		}
 	}
 
@@ -0,0 +1,283 @@
+package services
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/h4ks-com/bean-bank/internal/database"
+	"github.com/h4ks-com/bean-bank/internal/models"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidCampaignSize  = errors.New("a campaign must have between 1 and 1000 links")
+	ErrInvalidCampaignLabel = errors.New("campaign label must be between 1 and 100 characters")
+	ErrGiftCampaignNotFound = errors.New("gift campaign not found")
+	ErrGiftCampaignRevoked  = errors.New("gift campaign has already been revoked")
+)
+
+// MaxGiftCampaignLinks caps how many links one campaign can generate.
+const MaxGiftCampaignLinks = 1000
+
+// GiftCampaignParams describes a batch of Count single-use gift links worth
+// Amount each.
+type GiftCampaignParams struct {
+	From      string
+	Label     string
+	Count     int
+	Amount    int
+	Message   string
+	ExpiresIn string
+}
+
+// GiftCampaignLink is one row of a printable campaign sheet.
+type GiftCampaignLink struct {
+	Code       string `json:"code"`
+	URL        string `json:"url"`
+	Amount     int    `json:"amount"`
+	Status     string `json:"status"`
+	RedeemedBy string `json:"redeemed_by,omitempty"`
+}
+
+// CreateGiftCampaign generates the links of a campaign and escrows their total
+// with a single transaction, so either every link exists or none does.
+func (s *GiftLinkService) CreateGiftCampaign(params GiftCampaignParams) (*models.GiftCampaign, error) {
+	label := strings.TrimSpace(params.Label)
+	if label == "" || utf8.RuneCountInString(label) > 100 {
+		return nil, ErrInvalidCampaignLabel
+	}
+	if params.Count <= 0 || params.Count > MaxGiftCampaignLinks {
+		return nil, ErrInvalidCampaignSize
+	}
+	if params.Amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+
+	total := params.Amount * params.Count
+	if total/params.Count != params.Amount {
+		return nil, ErrInvalidAmount
+	}
+
+	expiry, err := ParseExpiry(params.ExpiresIn, time.Now(), s.maxExpiry)
+	if err != nil {
+		return nil, err
+	}
+
+	fromUser, err := s.userRepo.FindByUsername(params.From)
+	if err != nil {
+		return nil, err
+	}
+	if fromUser == nil {
+		return nil, ErrUserNotFound
+	}
+
+	if fromUser.BeanAmount < total {
+		return nil, ErrInsufficientBalanceForGift
+	}
+
+	codes := make([]string, 0, params.Count)
+	seen := make(map[string]bool, params.Count)
+	for len(codes) < params.Count {
+		code, err := s.generateUniqueCode()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate gift code: %w", err)
+		}
+		if !seen[code] {
+			seen[code] = true
+			codes = append(codes, code)
+		}
+	}
+
+	var campaignID uint
+	err = database.Transaction(s.db, func(tx *gorm.DB) error {
+		campaign := &models.GiftCampaign{
+			Label:      label,
+			FromUserID: fromUser.ID,
+			Amount:     params.Amount,
+			LinkCount:  params.Count,
+			Message:    params.Message,
+			ExpiresAt:  expiry,
+		}
+		if err := s.giftLinkRepo.CreateCampaignInTx(tx, campaign); err != nil {
+			return fmt.Errorf("failed to create gift campaign: %w", err)
+		}
+
+		giftLinks := make([]models.GiftLink, len(codes))
+		for i, code := range codes {
+			giftLinks[i] = models.GiftLink{
+				Code:           code,
+				FromUserID:     fromUser.ID,
+				CampaignID:     &campaign.ID,
+				Amount:         params.Amount,
+				Message:        params.Message,
+				Mode:           models.GiftLinkModeFixed,
+				MaxRedemptions: 1,
+				ExpiresAt:      expiry,
+				Active:         true,
+			}
+		}
+		if err := s.giftLinkRepo.CreateLinksInTx(tx, giftLinks); err != nil {
+			return fmt.Errorf("failed to create gift links: %w", err)
+		}
+
+		_, err := s.transferService.ExecuteInTx(tx, TransferParams{
+			From:           params.From,
+			To:             models.SystemUsername,
+			Amount:         total,
+			Force:          true,
+			Kind:           models.TransactionKindGiftEscrow,
+			GiftCampaignID: &campaign.ID,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to escrow beans: %w", err)
+		}
+
+		campaignID = campaign.ID
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.giftLinkRepo.FindCampaignByID(campaignID)
+}
+
+func (s *GiftLinkService) ListGiftCampaigns(username string) ([]models.GiftCampaign, error) {
+	user, err := s.userRepo.FindByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	return s.giftLinkRepo.ListCampaignsByFromUserID(user.ID)
+}
+
+// GetGiftCampaign returns one of the user's campaigns with its links.
+func (s *GiftLinkService) GetGiftCampaign(id uint, username string) (*models.GiftCampaign, error) {
+	campaign, err := s.giftLinkRepo.FindCampaignByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if campaign == nil || campaign.FromUser.Username != username {
+		return nil, ErrGiftCampaignNotFound
+	}
+	return campaign, nil
+}
+
+// RevokeGiftCampaign deactivates every link of the campaign that has not been
+// claimed and refunds their escrow to the creator in one transaction. It
+// returns the updated campaign and the number of beans refunded.
+func (s *GiftLinkService) RevokeGiftCampaign(id uint, username string) (*models.GiftCampaign, int, error) {
+	refunded := 0
+
+	err := database.Transaction(s.db, func(tx *gorm.DB) error {
+		refunded = 0
+
+		campaign, err := s.giftLinkRepo.FindCampaignByIDForUpdate(tx, id)
+		if err != nil {
+			return err
+		}
+		if campaign == nil || campaign.FromUser.Username != username {
+			return ErrGiftCampaignNotFound
+		}
+		if campaign.RevokedAt != nil {
+			return ErrGiftCampaignRevoked
+		}
+
+		giftLinks, err := s.giftLinkRepo.FindCampaignLinksForUpdate(tx, id)
+		if err != nil {
+			return err
+		}
+
+		for i := range giftLinks {
+			giftLink := &giftLinks[i]
+			if !giftLink.Active || giftLink.RedeemedAt != nil || giftLink.RemainingRedemptions() == 0 {
+				continue
+			}
+
+			unclaimed, err := s.unclaimedAmountInTx(tx, giftLink)
+			if err != nil {
+				return err
+			}
+			refunded += unclaimed
+
+			giftLink.Active = false
+			if err := s.giftLinkRepo.UpdateInTx(tx, giftLink); err != nil {
+				return fmt.Errorf("failed to deactivate gift link: %w", err)
+			}
+		}
+
+		if refunded > 0 {
+			_, err = s.transferService.ExecuteInTx(tx, TransferParams{
+				From:           models.SystemUsername,
+				To:             username,
+				Amount:         refunded,
+				Force:          true,
+				Kind:           models.TransactionKindGiftRefund,
+				GiftCampaignID: &campaign.ID,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to refund beans: %w", err)
+			}
+		}
+
+		now := time.Now()
+		campaign.RevokedAt = &now
+		if err := s.giftLinkRepo.UpdateCampaignInTx(tx, campaign); err != nil {
+			return fmt.Errorf("failed to revoke gift campaign: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	campaign, err := s.giftLinkRepo.FindCampaignByID(id)
+	if err != nil {
+		return nil, 0, err
+	}
+	return campaign, refunded, nil
+}
+
+// GiftCampaignLinks lists the campaign's links as sheet rows, with URLs built
+// from baseURL (scheme and host, no trailing slash).
+func GiftCampaignLinks(campaign *models.GiftCampaign, baseURL string) []GiftCampaignLink {
+	links := make([]GiftCampaignLink, len(campaign.Links))
+	for i, giftLink := range campaign.Links {
+		links[i] = GiftCampaignLink{
+			Code:   giftLink.Code,
+			URL:    baseURL + "/gift/" + url.PathEscape(giftLink.Code),
+			Amount: giftLink.Amount,
+			Status: giftLink.Status(),
+		}
+		if giftLink.RedeemedBy != nil {
+			links[i].RedeemedBy = giftLink.RedeemedBy.Username
+		}
+	}
+	return links
+}
+
+// WriteGiftCampaignCSV writes the sheet rows as CSV with a header line.
+func WriteGiftCampaignCSV(w io.Writer, links []GiftCampaignLink) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"code", "url", "amount", "status", "redeemed_by"}); err != nil {
+		return err
+	}
+	for _, link := range links {
+		if err := writer.Write([]string{link.Code, link.URL, strconv.Itoa(link.Amount), link.Status, link.RedeemedBy}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
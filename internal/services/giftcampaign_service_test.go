@@ -0,0 +1,142 @@
+package services
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/h4ks-com/bean-bank/internal/models"
+	"github.com/h4ks-com/bean-bank/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func setupGiftCampaignTest(t *testing.T) (*gorm.DB, *repository.UserRepository, *GiftLinkService) {
+	db := setupGiftLinkTestDB(t)
+	userRepo := repository.NewUserRepository(db)
+	transactionRepo := repository.NewTransactionRepository(db)
+	giftLinkRepo := repository.NewGiftLinkRepository(db)
+	transferService := NewTransferService(userRepo, transactionRepo, db)
+	service := NewGiftLinkService(giftLinkRepo, userRepo, transferService, db, 0)
+
+	require.NoError(t, db.Create(&models.User{Username: "alice", BeanAmount: 1000}).Error)
+	return db, userRepo, service
+}
+
+func TestGiftLinkService_CreateGiftCampaign(t *testing.T) {
+	t.Run("escrows the total in one transaction", func(t *testing.T) {
+		db, userRepo, service := setupGiftCampaignTest(t)
+
+		campaign, err := service.CreateGiftCampaign(GiftCampaignParams{
+			From: "alice", Label: "  Meetup #12 ", Count: 200, Amount: 5, Message: "Welcome!", ExpiresIn: "7d",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "Meetup #12", campaign.Label)
+		assert.Equal(t, 200, campaign.LinkCount)
+		require.Len(t, campaign.Links, 200)
+		require.NotNil(t, campaign.ExpiresAt)
+
+		codes := make(map[string]bool)
+		for _, giftLink := range campaign.Links {
+			codes[giftLink.Code] = true
+			assert.Equal(t, 5, giftLink.Amount)
+			assert.Equal(t, 1, giftLink.MaxRedemptions)
+			assert.Equal(t, "Welcome!", giftLink.Message)
+			assert.Equal(t, campaign.ExpiresAt.Unix(), giftLink.ExpiresAt.Unix())
+		}
+		assert.Len(t, codes, 200, "codes should be unique")
+
+		assert.Equal(t, 0, balanceOf(t, userRepo, "alice"))
+
+		var escrows []models.Transaction
+		require.NoError(t, db.Where("kind = ?", models.TransactionKindGiftEscrow).Find(&escrows).Error)
+		require.Len(t, escrows, 1)
+		assert.Equal(t, 1000, escrows[0].Amount)
+		assert.Equal(t, campaign.ID, *escrows[0].GiftCampaignID)
+
+		giftLinks, err := service.ListGiftLinks("alice")
+		require.NoError(t, err)
+		assert.Empty(t, giftLinks, "campaign links should not clutter the gift link list")
+	})
+
+	t.Run("validates the request", func(t *testing.T) {
+		_, _, service := setupGiftCampaignTest(t)
+
+		_, err := service.CreateGiftCampaign(GiftCampaignParams{From: "alice", Label: " ", Count: 10, Amount: 5})
+		assert.ErrorIs(t, err, ErrInvalidCampaignLabel)
+
+		_, err = service.CreateGiftCampaign(GiftCampaignParams{From: "alice", Label: "x", Count: MaxGiftCampaignLinks + 1, Amount: 1})
+		assert.ErrorIs(t, err, ErrInvalidCampaignSize)
+
+		_, err = service.CreateGiftCampaign(GiftCampaignParams{From: "alice", Label: "x", Count: 10, Amount: 0})
+		assert.ErrorIs(t, err, ErrInvalidAmount)
+
+		_, err = service.CreateGiftCampaign(GiftCampaignParams{From: "alice", Label: "x", Count: 201, Amount: 5})
+		assert.ErrorIs(t, err, ErrInsufficientBalanceForGift)
+	})
+}
+
+func TestGiftLinkService_RevokeGiftCampaign(t *testing.T) {
+	db, userRepo, service := setupGiftCampaignTest(t)
+
+	campaign, err := service.CreateGiftCampaign(GiftCampaignParams{From: "alice", Label: "Meetup", Count: 4, Amount: 10})
+	require.NoError(t, err)
+
+	require.NoError(t, service.RedeemGiftLink(campaign.Links[0].Code, "bob", ""))
+	require.NoError(t, service.RedeemGiftLink(campaign.Links[1].Code, "carol", ""))
+
+	campaign, err = service.GetGiftCampaign(campaign.ID, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, models.GiftCampaignStats{Open: 2, Redeemed: 2}, campaign.Stats())
+
+	t.Run("only the owner can see or revoke it", func(t *testing.T) {
+		_, err := service.GetGiftCampaign(campaign.ID, "bob")
+		assert.ErrorIs(t, err, ErrGiftCampaignNotFound)
+
+		_, _, err = service.RevokeGiftCampaign(campaign.ID, "bob")
+		assert.ErrorIs(t, err, ErrGiftCampaignNotFound)
+	})
+
+	revoked, refunded, err := service.RevokeGiftCampaign(campaign.ID, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, 20, refunded)
+	assert.NotNil(t, revoked.RevokedAt)
+	assert.Equal(t, models.GiftCampaignStats{Redeemed: 2, Revoked: 2}, revoked.Stats())
+	assert.Equal(t, 1000-40+20, balanceOf(t, userRepo, "alice"))
+
+	var refunds []models.Transaction
+	require.NoError(t, db.Where("kind = ?", models.TransactionKindGiftRefund).Find(&refunds).Error)
+	require.Len(t, refunds, 1, "the remainder should come back in a single refund")
+	assert.Equal(t, campaign.ID, *refunds[0].GiftCampaignID)
+
+	err = service.RedeemGiftLink(campaign.Links[2].Code, "dave", "")
+	assert.ErrorIs(t, err, ErrGiftLinkInactive)
+
+	_, _, err = service.RevokeGiftCampaign(campaign.ID, "alice")
+	assert.ErrorIs(t, err, ErrGiftCampaignRevoked)
+}
+
+func TestWriteGiftCampaignCSV(t *testing.T) {
+	_, _, service := setupGiftCampaignTest(t)
+
+	campaign, err := service.CreateGiftCampaign(GiftCampaignParams{From: "alice", Label: "Meetup", Count: 2, Amount: 5})
+	require.NoError(t, err)
+	require.NoError(t, service.RedeemGiftLink(campaign.Links[0].Code, "bob", ""))
+
+	campaign, err = service.GetGiftCampaign(campaign.ID, "alice")
+	require.NoError(t, err)
+
+	links := GiftCampaignLinks(campaign, "https://beans.example.com")
+	require.Len(t, links, 2)
+	assert.Equal(t, "https://beans.example.com/gift/"+campaign.Links[0].Code, links[0].URL)
+	assert.Equal(t, "redeemed", links[0].Status)
+	assert.Equal(t, "bob", links[0].RedeemedBy)
+	assert.Equal(t, "open", links[1].Status)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteGiftCampaignCSV(&buf, links))
+	expected := "code,url,amount,status,redeemed_by\n" +
+		links[0].Code + "," + links[0].URL + ",5,redeemed,bob\n" +
+		links[1].Code + "," + links[1].URL + ",5,open,\n"
+	assert.Equal(t, expected, buf.String())
+}
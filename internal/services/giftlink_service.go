@@ -5,40 +5,75 @@ import (
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"log"
+	"math/big"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/h4ks-com/bean-bank/internal/database"
 	"github.com/h4ks-com/bean-bank/internal/models"
 	"github.com/h4ks-com/bean-bank/internal/repository"
+	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
 var (
-	ErrGiftLinkNotFound          = errors.New("gift link not found")
-	ErrGiftLinkExpired           = errors.New("gift link has expired")
-	ErrGiftLinkRedeemed          = errors.New("gift link has already been redeemed")
-	ErrGiftLinkInactive          = errors.New("gift link is not active")
-	ErrCannotRedeemOwnLink       = errors.New("cannot redeem your own gift link")
+	ErrGiftLinkNotFound           = errors.New("gift link not found")
+	ErrGiftLinkExpired            = errors.New("gift link has expired")
+	ErrGiftLinkRedeemed           = errors.New("gift link has already been redeemed")
+	ErrGiftLinkInactive           = errors.New("gift link is not active")
+	ErrCannotRedeemOwnLink        = errors.New("cannot redeem your own gift link")
 	ErrInsufficientBalanceForGift = errors.New("insufficient balance to create gift link")
+	ErrGiftLinkAlreadyClaimed     = errors.New("you have already claimed this gift link")
+	ErrInvalidMaxRedemptions      = errors.New("max redemptions must be between 1 and 1000")
+	ErrInvalidGiftLinkMode        = errors.New("mode must be fixed or random")
+	ErrTooFewBeansForShares       = errors.New("a random split needs at least one bean per share")
+	ErrTooManyGiftRecipients      = errors.New("a gift link can be restricted to at most 100 recipients")
+	ErrInvalidGiftPin             = errors.New("pin must be between 4 and 64 characters")
+	ErrGiftLinkNotForYou          = errors.New("this gift link is reserved for someone else")
+	ErrGiftPinRequired            = errors.New("this gift link requires a pin")
+	ErrIncorrectGiftPin           = errors.New("incorrect pin")
+	ErrGiftLinkLocked             = errors.New("gift link is locked after too many incorrect pins")
 )
 
+const (
+	// MaxGiftRedemptions caps how many users can claim a single gift link.
+	MaxGiftRedemptions = 1000
+	// MaxGiftRecipients caps the allowed-recipient list of a gift link.
+	MaxGiftRecipients = 100
+	// MaxGiftPinAttempts is how many incorrect PINs lock a gift link for good.
+	// The creator can still delete it to get the beans back.
+	MaxGiftPinAttempts = 5
+)
+
+// giftSweepBatchSize caps how many expired links one sweep refunds so a large
+// backlog is worked through over several runs.
+const giftSweepBatchSize = 100
+
 type GiftLinkService struct {
 	giftLinkRepo    *repository.GiftLinkRepository
 	userRepo        *repository.UserRepository
 	transferService *TransferService
 	db              *gorm.DB
+	maxExpiry       time.Duration
 }
 
+// NewGiftLinkService creates the gift link service. A non-zero maxExpiry caps
+// how far ahead new links and campaigns may expire.
 func NewGiftLinkService(
 	giftLinkRepo *repository.GiftLinkRepository,
 	userRepo *repository.UserRepository,
 	transferService *TransferService,
 	db *gorm.DB,
+	maxExpiry time.Duration,
 ) *GiftLinkService {
 	return &GiftLinkService{
 		giftLinkRepo:    giftLinkRepo,
 		userRepo:        userRepo,
 		transferService: transferService,
 		db:              db,
+		maxExpiry:       maxExpiry,
 	}
 }
 
@@ -62,35 +97,79 @@ func (s *GiftLinkService) generateUniqueCode() (string, error) {
 	return "", errors.New("failed to generate unique code after 10 attempts")
 }
 
-func (s *GiftLinkService) parseExpiry(expiresIn string) *time.Time {
-	if expiresIn == "" || expiresIn == "never" {
-		return nil
+// GiftLinkParams describes a new gift link. In fixed mode Amount is what each
+// redeemer receives; in random mode it is the total split across
+// MaxRedemptions shares. MaxRedemptions defaults to 1 for a classic
+// single-use link. AllowedRecipients and Pin are optional protections
+// against the link being claimed by whoever sees it first.
+type GiftLinkParams struct {
+	From              string
+	Amount            int
+	Message           string
+	ExpiresIn         string
+	MaxRedemptions    int
+	Mode              models.GiftLinkMode
+	AllowedRecipients []string
+	Pin               string
+}
+
+func (s *GiftLinkService) CreateGiftLink(params GiftLinkParams) (*models.GiftLink, error) {
+	if params.Amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+
+	maxRedemptions := params.MaxRedemptions
+	if maxRedemptions == 0 {
+		maxRedemptions = 1
+	}
+	if maxRedemptions < 0 || maxRedemptions > MaxGiftRedemptions {
+		return nil, ErrInvalidMaxRedemptions
+	}
+
+	mode := params.Mode
+	if mode == "" {
+		mode = models.GiftLinkModeFixed
 	}
 
-	var duration time.Duration
-	switch expiresIn {
-	case "1h":
-		duration = 1 * time.Hour
-	case "24h":
-		duration = 24 * time.Hour
-	case "7d":
-		duration = 7 * 24 * time.Hour
-	case "30d":
-		duration = 30 * 24 * time.Hour
+	var escrow int
+	switch mode {
+	case models.GiftLinkModeFixed:
+		escrow = params.Amount * maxRedemptions
+		if escrow/maxRedemptions != params.Amount {
+			return nil, ErrInvalidAmount
+		}
+	case models.GiftLinkModeRandom:
+		if params.Amount < maxRedemptions {
+			return nil, ErrTooFewBeansForShares
+		}
+		escrow = params.Amount
 	default:
-		return nil
+		return nil, ErrInvalidGiftLinkMode
 	}
 
-	expiry := time.Now().Add(duration)
-	return &expiry
-}
+	recipients, err := normalizeRecipients(params.AllowedRecipients)
+	if err != nil {
+		return nil, err
+	}
 
-func (s *GiftLinkService) CreateGiftLink(fromUsername string, amount int, message string, expiresIn string) (*models.GiftLink, error) {
-	if amount <= 0 {
-		return nil, ErrInvalidAmount
+	expiry, err := ParseExpiry(params.ExpiresIn, time.Now(), s.maxExpiry)
+	if err != nil {
+		return nil, err
 	}
 
-	fromUser, err := s.userRepo.FindByUsername(fromUsername)
+	var pinHash string
+	if params.Pin != "" {
+		if len(params.Pin) < 4 || len(params.Pin) > 64 {
+			return nil, ErrInvalidGiftPin
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(params.Pin), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash pin: %w", err)
+		}
+		pinHash = string(hash)
+	}
+
+	fromUser, err := s.userRepo.FindByUsername(params.From)
 	if err != nil {
 		return nil, err
 	}
@@ -98,35 +177,74 @@ func (s *GiftLinkService) CreateGiftLink(fromUsername string, amount int, messag
 		return nil, ErrUserNotFound
 	}
 
-	if fromUser.BeanAmount < amount {
+	if fromUser.BeanAmount < escrow {
 		return nil, ErrInsufficientBalanceForGift
 	}
 
+	// Split only once the sender is known to hold the beans, so the size of
+	// the split is bounded by a real balance.
+	var shares []int
+	if mode == models.GiftLinkModeRandom {
+		shares, err = splitRandomly(params.Amount, maxRedemptions)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split gift: %w", err)
+		}
+	}
+
 	code, err := s.generateUniqueCode()
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate gift code: %w", err)
 	}
 
-	expiry := s.parseExpiry(expiresIn)
-
-	err = s.db.Transaction(func(tx *gorm.DB) error {
-		if err := s.transferService.TransferInTx(tx, fromUsername, "system", amount, true); err != nil {
-			return fmt.Errorf("failed to escrow beans: %w", err)
-		}
-
+	err = database.Transaction(s.db, func(tx *gorm.DB) error {
 		giftLink := &models.GiftLink{
-			Code:       code,
-			FromUserID: fromUser.ID,
-			Amount:     amount,
-			Message:    message,
-			ExpiresAt:  expiry,
-			Active:     true,
+			Code:           code,
+			FromUserID:     fromUser.ID,
+			Amount:         params.Amount,
+			Message:        params.Message,
+			Mode:           mode,
+			MaxRedemptions: maxRedemptions,
+			PinHash:        pinHash,
+			ExpiresAt:      expiry,
+			Active:         true,
 		}
 
 		if err := tx.Create(giftLink).Error; err != nil {
 			return fmt.Errorf("failed to create gift link: %w", err)
 		}
 
+		if len(recipients) > 0 {
+			rows := make([]models.GiftLinkRecipient, len(recipients))
+			for i, username := range recipients {
+				rows[i] = models.GiftLinkRecipient{GiftLinkID: giftLink.ID, Username: username}
+			}
+			if err := s.giftLinkRepo.CreateRecipientsInTx(tx, rows); err != nil {
+				return fmt.Errorf("failed to store gift recipients: %w", err)
+			}
+		}
+
+		if len(shares) > 0 {
+			rows := make([]models.GiftLinkShare, len(shares))
+			for i, amount := range shares {
+				rows[i] = models.GiftLinkShare{GiftLinkID: giftLink.ID, Position: i, Amount: amount}
+			}
+			if err := s.giftLinkRepo.CreateSharesInTx(tx, rows); err != nil {
+				return fmt.Errorf("failed to store gift shares: %w", err)
+			}
+		}
+
+		_, err := s.transferService.ExecuteInTx(tx, TransferParams{
+			From:       params.From,
+			To:         models.SystemUsername,
+			Amount:     escrow,
+			Force:      true,
+			Kind:       models.TransactionKindGiftEscrow,
+			GiftLinkID: &giftLink.ID,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to escrow beans: %w", err)
+		}
+
 		if err := tx.Preload("FromUser").First(giftLink, giftLink.ID).Error; err != nil {
 			return fmt.Errorf("failed to reload gift link: %w", err)
 		}
@@ -146,8 +264,16 @@ func (s *GiftLinkService) CreateGiftLink(fromUsername string, amount int, messag
 	return reloadedGift, nil
 }
 
-func (s *GiftLinkService) RedeemGiftLink(code string, redeemUsername string) error {
-	return s.db.Transaction(func(tx *gorm.DB) error {
+// RedeemGiftLink pays the next claim on the link to redeemUsername. pin is
+// ignored unless the link was created with one.
+func (s *GiftLinkService) RedeemGiftLink(code string, redeemUsername string, pin string) error {
+	// A wrong PIN must still commit the attempt counter, so it is reported
+	// after the transaction instead of rolling it back.
+	var pinErr error
+
+	err := database.Transaction(s.db, func(tx *gorm.DB) error {
+		pinErr = nil
+
 		giftLink, err := s.giftLinkRepo.FindByCodeForUpdate(tx, code)
 		if err != nil {
 			return err
@@ -160,6 +286,10 @@ func (s *GiftLinkService) RedeemGiftLink(code string, redeemUsername string) err
 			return ErrGiftLinkRedeemed
 		}
 
+		if giftLink.ExpiredAt != nil {
+			return ErrGiftLinkExpired
+		}
+
 		if !giftLink.Active {
 			return ErrGiftLinkInactive
 		}
@@ -168,26 +298,83 @@ func (s *GiftLinkService) RedeemGiftLink(code string, redeemUsername string) err
 			return ErrGiftLinkExpired
 		}
 
+		if giftLink.LockedAt != nil {
+			return ErrGiftLinkLocked
+		}
+
 		if giftLink.FromUser.Username == redeemUsername {
 			return ErrCannotRedeemOwnLink
 		}
 
-		if err := s.transferService.TransferInTx(tx, "system", redeemUsername, giftLink.Amount, true); err != nil {
+		if allowed := giftLink.AllowedUsernames(); allowed != nil && !slices.Contains(allowed, redeemUsername) {
+			return ErrGiftLinkNotForYou
+		}
+
+		// Checked after the recipient list so that only allowed users can
+		// use up the attempts.
+		if giftLink.HasPin() {
+			if pin == "" {
+				return ErrGiftPinRequired
+			}
+			if bcrypt.CompareHashAndPassword([]byte(giftLink.PinHash), []byte(pin)) != nil {
+				giftLink.FailedPinAttempts++
+				pinErr = ErrIncorrectGiftPin
+				if giftLink.FailedPinAttempts >= MaxGiftPinAttempts {
+					now := time.Now()
+					giftLink.LockedAt = &now
+					pinErr = ErrGiftLinkLocked
+				}
+				return s.giftLinkRepo.UpdateInTx(tx, giftLink)
+			}
+		}
+
+		if giftLink.RemainingRedemptions() == 0 {
+			return ErrGiftLinkRedeemed
+		}
+
+		amount, err := s.claimAmountInTx(tx, giftLink)
+		if err != nil {
+			return err
+		}
+
+		transaction, err := s.transferService.ExecuteInTx(tx, TransferParams{
+			From:       models.SystemUsername,
+			To:         redeemUsername,
+			Amount:     amount,
+			Force:      true,
+			Kind:       models.TransactionKindGiftRedeem,
+			GiftLinkID: &giftLink.ID,
+		})
+		if err != nil {
 			return fmt.Errorf("failed to transfer beans: %w", err)
 		}
 
-		redeemUser, err := s.userRepo.FindByUsernameForUpdate(tx, redeemUsername)
+		// The redeemer's ID is only known once the transfer has found or
+		// created their wallet; returning here rolls the transfer back.
+		claimed, err := s.giftLinkRepo.FindRedemptionInTx(tx, giftLink.ID, transaction.ToUserID)
 		if err != nil {
 			return err
 		}
-		if redeemUser == nil {
-			return ErrUserNotFound
+		if claimed != nil {
+			return ErrGiftLinkAlreadyClaimed
 		}
 
-		now := time.Now()
-		giftLink.RedeemedAt = &now
-		giftLink.RedeemedByID = &redeemUser.ID
-		giftLink.Active = false
+		if err := s.giftLinkRepo.CreateRedemptionInTx(tx, &models.GiftLinkRedemption{
+			GiftLinkID:    giftLink.ID,
+			UserID:        transaction.ToUserID,
+			Amount:        amount,
+			TransactionID: transaction.ID,
+		}); err != nil {
+			return fmt.Errorf("failed to record redemption: %w", err)
+		}
+
+		giftLink.RedemptionCount++
+		if giftLink.RemainingRedemptions() == 0 {
+			now := time.Now()
+			giftLink.RedeemedAt = &now
+			giftLink.RedeemedByID = &transaction.ToUserID
+			giftLink.Active = false
+		}
 
 		if err := s.giftLinkRepo.UpdateInTx(tx, giftLink); err != nil {
 			return fmt.Errorf("failed to update gift link: %w", err)
@@ -195,6 +382,10 @@ func (s *GiftLinkService) RedeemGiftLink(code string, redeemUsername string) err
 
 		return nil
 	})
+	if err != nil {
+		return err
+	}
+	return pinErr
 }
 
 func (s *GiftLinkService) ListGiftLinks(username string) ([]models.GiftLink, error) {
@@ -230,9 +421,32 @@ func (s *GiftLinkService) DeleteGiftLink(id uint, username string) error {
 		return errors.New("cannot delete gift link you don't own")
 	}
 
-	return s.db.Transaction(func(tx *gorm.DB) error {
-		if giftLink.RedeemedAt == nil && giftLink.Active {
-			if err := s.transferService.TransferInTx(tx, "system", username, giftLink.Amount, true); err != nil {
+	return database.Transaction(s.db, func(tx *gorm.DB) error {
+		// Re-read under lock so claims made since the check above are not
+		// refunded as well.
+		giftLink, err := s.giftLinkRepo.FindByIDForUpdate(tx, id)
+		if err != nil {
+			return err
+		}
+		if giftLink == nil {
+			return ErrGiftLinkNotFound
+		}
+
+		if giftLink.RedeemedAt == nil && giftLink.Active && giftLink.RemainingRedemptions() > 0 {
+			unclaimed, err := s.unclaimedAmountInTx(tx, giftLink)
+			if err != nil {
+				return err
+			}
+
+			_, err = s.transferService.ExecuteInTx(tx, TransferParams{
+				From:       models.SystemUsername,
+				To:         username,
+				Amount:     unclaimed,
+				Force:      true,
+				Kind:       models.TransactionKindGiftRefund,
+				GiftLinkID: &giftLink.ID,
+			})
+			if err != nil {
 				return fmt.Errorf("failed to refund beans: %w", err)
 			}
 		}
@@ -246,6 +460,227 @@ func (s *GiftLinkService) DeleteGiftLink(id uint, username string) error {
 	})
 }
 
+// SweepExpired refunds the unclaimed escrow of every active, unredeemed link
+// that expired at or before now and marks it expired. It returns how many links
+// were refunded; a link that fails is logged and left for the next sweep.
+func (s *GiftLinkService) SweepExpired(now time.Time) (int, error) {
+	ids, err := s.giftLinkRepo.FindExpiredIDs(now, giftSweepBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	refunded := 0
+	var firstErr error
+	for _, id := range ids {
+		ok, err := s.refundExpired(id, now)
+		if err != nil {
+			log.Printf("[GiftLinks] Failed to refund expired gift link %d: %v", id, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if ok {
+			refunded++
+		}
+	}
+
+	return refunded, firstErr
+}
+
+func (s *GiftLinkService) refundExpired(id uint, now time.Time) (bool, error) {
+	refunded := false
+
+	err := database.Transaction(s.db, func(tx *gorm.DB) error {
+		refunded = false
+
+		giftLink, err := s.giftLinkRepo.FindByIDForUpdate(tx, id)
+		if err != nil {
+			return err
+		}
+		// It may have been redeemed or deleted since it was listed.
+		if giftLink == nil || !giftLink.Active || giftLink.RedeemedAt != nil ||
+			giftLink.ExpiresAt == nil || giftLink.ExpiresAt.After(now) {
+			return nil
+		}
+
+		unclaimed, err := s.unclaimedAmountInTx(tx, giftLink)
+		if err != nil {
+			return err
+		}
+
+		_, err = s.transferService.ExecuteInTx(tx, TransferParams{
+			From:       models.SystemUsername,
+			To:         giftLink.FromUser.Username,
+			Amount:     unclaimed,
+			Force:      true,
+			Kind:       models.TransactionKindGiftRefund,
+			GiftLinkID: &giftLink.ID,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to refund beans: %w", err)
+		}
+
+		giftLink.Active = false
+		giftLink.ExpiredAt = &now
+		if err := s.giftLinkRepo.UpdateInTx(tx, giftLink); err != nil {
+			return fmt.Errorf("failed to expire gift link: %w", err)
+		}
+
+		refunded = true
+		return nil
+	})
+
+	return refunded, err
+}
+
+// claimAmountInTx is what the next redeemer of the link receives.
+func (s *GiftLinkService) claimAmountInTx(tx *gorm.DB, giftLink *models.GiftLink) (int, error) {
+	if giftLink.Mode != models.GiftLinkModeRandom {
+		return giftLink.Amount, nil
+	}
+
+	share, err := s.giftLinkRepo.FindShareInTx(tx, giftLink.ID, giftLink.RedemptionCount)
+	if err != nil {
+		return 0, err
+	}
+	if share == nil {
+		return 0, fmt.Errorf("gift link %d has no share at position %d", giftLink.ID, giftLink.RedemptionCount)
+	}
+	return share.Amount, nil
+}
+
+// unclaimedAmountInTx is the part of the escrow nobody has claimed yet.
+func (s *GiftLinkService) unclaimedAmountInTx(tx *gorm.DB, giftLink *models.GiftLink) (int, error) {
+	if giftLink.Mode != models.GiftLinkModeRandom {
+		return giftLink.Amount * giftLink.RemainingRedemptions(), nil
+	}
+	return s.giftLinkRepo.SumSharesFromInTx(tx, giftLink.ID, giftLink.RedemptionCount)
+}
+
+// normalizeRecipients trims, de-duplicates and validates an allowed-recipient
+// list.
+func normalizeRecipients(usernames []string) ([]string, error) {
+	seen := make(map[string]bool, len(usernames))
+	var recipients []string
+	for _, username := range usernames {
+		username = strings.TrimSpace(username)
+		if username == "" || seen[username] {
+			continue
+		}
+		if models.IsReservedUsername(username) {
+			return nil, ErrReservedAccount
+		}
+		seen[username] = true
+		recipients = append(recipients, username)
+	}
+
+	if len(recipients) > MaxGiftRecipients {
+		return nil, ErrTooManyGiftRecipients
+	}
+	return recipients, nil
+}
+
+// splitRandomly divides total into the given number of shares of at least one
+// bean each. It picks shares-1 distinct cut points in 1..total-1 with
+// crypto/rand, so every possible split is equally likely. The work depends on
+// the number of shares, not on total.
+func splitRandomly(total, shares int) ([]int, error) {
+	if shares <= 0 || total < shares {
+		return nil, ErrTooFewBeansForShares
+	}
+
+	gaps := total - 1
+	cutCount := shares - 1
+
+	// Picking the points to leave out is cheaper when most are cut.
+	complement := cutCount > gaps/2
+	pickCount := cutCount
+	if complement {
+		pickCount = gaps - cutCount
+	}
+
+	picked := make(map[int]bool, pickCount)
+	for len(picked) < pickCount {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(gaps)))
+		if err != nil {
+			return nil, err
+		}
+		picked[int(n.Int64())+1] = true
+	}
+
+	cuts := make([]int, 0, cutCount)
+	if complement {
+		// Here gaps is less than twice the number of shares, so walking
+		// every point is cheap.
+		for point := 1; point <= gaps; point++ {
+			if !picked[point] {
+				cuts = append(cuts, point)
+			}
+		}
+	} else {
+		for point := range picked {
+			cuts = append(cuts, point)
+		}
+		slices.Sort(cuts)
+	}
+
+	split := make([]int, shares)
+	previous := 0
+	for i, cut := range cuts {
+		split[i] = cut - previous
+		previous = cut
+	}
+	split[shares-1] = total - previous
+	return split, nil
+}
+
 func (s *GiftLinkService) GetGiftLinkByCode(code string) (*models.GiftLink, error) {
 	return s.giftLinkRepo.FindByCode(code)
 }
+
+// GiftLinkPreview is what a shared gift link may reveal to link unfurlers
+// before anyone opens it. Details are only filled in when Claimable is true.
+type GiftLinkPreview struct {
+	Code           string
+	Claimable      bool
+	From           string
+	Amount         int
+	Mode           models.GiftLinkMode
+	MaxRedemptions int
+	Message        string
+}
+
+// PreviewGiftLink describes a gift link for OpenGraph tags. Links that are
+// used up, expired, locked, restricted to certain users or protected by a PIN
+// get the same empty preview as unknown codes, so a preview never says who
+// sent what to whom.
+func (s *GiftLinkService) PreviewGiftLink(code string, now time.Time) (*GiftLinkPreview, error) {
+	preview := &GiftLinkPreview{Code: code}
+
+	giftLink, err := s.giftLinkRepo.FindByCode(code)
+	if err != nil {
+		return nil, err
+	}
+	if giftLink == nil {
+		return preview, nil
+	}
+
+	if !giftLink.Active || giftLink.RedeemedAt != nil || giftLink.ExpiredAt != nil || giftLink.LockedAt != nil {
+		return preview, nil
+	}
+	if giftLink.ExpiresAt != nil && !now.Before(*giftLink.ExpiresAt) {
+		return preview, nil
+	}
+	if giftLink.RemainingRedemptions() == 0 || giftLink.HasPin() || len(giftLink.AllowedRecipients) > 0 {
+		return preview, nil
+	}
+
+	preview.Claimable = true
+	preview.From = giftLink.FromUser.Username
+	preview.Amount = giftLink.Amount
+	preview.Mode = giftLink.Mode
+	preview.MaxRedemptions = giftLink.MaxRedemptions
+	preview.Message = giftLink.Message
+	return preview, nil
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"math"
 	"testing"
 	"time"
 
@@ -16,10 +17,10 @@ func setupGiftLinkTestDB(t *testing.T) *gorm.DB {
 	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
 	require.NoError(t, err)
 
-	err = db.AutoMigrate(&models.User{}, &models.Transaction{}, &models.GiftLink{})
+	err = db.AutoMigrate(&models.User{}, &models.Transaction{}, &models.GiftCampaign{}, &models.GiftLink{}, &models.GiftLinkRedemption{}, &models.GiftLinkShare{}, &models.GiftLinkRecipient{}, &models.LedgerEntry{})
 	require.NoError(t, err)
 
-	systemUser := &models.User{Username: "system", BeanAmount: 1000000}
+	systemUser := &models.User{Username: "system", BeanAmount: 1000000, Reserved: true}
 	require.NoError(t, db.Create(systemUser).Error)
 
 	return db
@@ -31,13 +32,13 @@ func TestGiftLinkService_CreateGiftLink(t *testing.T) {
 	transactionRepo := repository.NewTransactionRepository(db)
 	giftLinkRepo := repository.NewGiftLinkRepository(db)
 	transferService := NewTransferService(userRepo, transactionRepo, db)
-	service := NewGiftLinkService(giftLinkRepo, userRepo, transferService, db)
+	service := NewGiftLinkService(giftLinkRepo, userRepo, transferService, db, 0)
 
 	sender := &models.User{Username: "alice", BeanAmount: 500}
 	require.NoError(t, db.Create(sender).Error)
 
 	t.Run("creates gift link successfully", func(t *testing.T) {
-		giftLink, err := service.CreateGiftLink("alice", 100, "Happy Birthday!", "24h")
+		giftLink, err := service.CreateGiftLink(GiftLinkParams{From: "alice", Amount: 100, Message: "Happy Birthday!", ExpiresIn: "24h"})
 		require.NoError(t, err)
 		assert.NotEmpty(t, giftLink.Code)
 		assert.Equal(t, sender.ID, giftLink.FromUserID)
@@ -57,26 +58,26 @@ func TestGiftLinkService_CreateGiftLink(t *testing.T) {
 	})
 
 	t.Run("creates gift link without expiry", func(t *testing.T) {
-		giftLink, err := service.CreateGiftLink("alice", 50, "No expiry", "never")
+		giftLink, err := service.CreateGiftLink(GiftLinkParams{From: "alice", Amount: 50, Message: "No expiry", ExpiresIn: "never"})
 		require.NoError(t, err)
 		assert.Nil(t, giftLink.ExpiresAt)
 	})
 
 	t.Run("fails with insufficient balance", func(t *testing.T) {
-		_, err := service.CreateGiftLink("alice", 10000, "Too much", "24h")
+		_, err := service.CreateGiftLink(GiftLinkParams{From: "alice", Amount: 10000, Message: "Too much", ExpiresIn: "24h"})
 		assert.ErrorIs(t, err, ErrInsufficientBalanceForGift)
 	})
 
 	t.Run("fails with invalid amount", func(t *testing.T) {
-		_, err := service.CreateGiftLink("alice", 0, "Zero beans", "24h")
+		_, err := service.CreateGiftLink(GiftLinkParams{From: "alice", Amount: 0, Message: "Zero beans", ExpiresIn: "24h"})
 		assert.ErrorIs(t, err, ErrInvalidAmount)
 
-		_, err = service.CreateGiftLink("alice", -10, "Negative beans", "24h")
+		_, err = service.CreateGiftLink(GiftLinkParams{From: "alice", Amount: -10, Message: "Negative beans", ExpiresIn: "24h"})
 		assert.ErrorIs(t, err, ErrInvalidAmount)
 	})
 
 	t.Run("fails with non-existent user", func(t *testing.T) {
-		_, err := service.CreateGiftLink("nonexistent", 100, "Ghost", "24h")
+		_, err := service.CreateGiftLink(GiftLinkParams{From: "nonexistent", Amount: 100, Message: "Ghost", ExpiresIn: "24h"})
 		assert.Error(t, err)
 	})
 }
@@ -88,7 +89,7 @@ func TestGiftLinkService_RedeemGiftLink(t *testing.T) {
 		transactionRepo := repository.NewTransactionRepository(db)
 		giftLinkRepo := repository.NewGiftLinkRepository(db)
 		transferService := NewTransferService(userRepo, transactionRepo, db)
-		service := NewGiftLinkService(giftLinkRepo, userRepo, transferService, db)
+		service := NewGiftLinkService(giftLinkRepo, userRepo, transferService, db, 0)
 
 		sender := &models.User{Username: "alice", BeanAmount: 500}
 		require.NoError(t, db.Create(sender).Error)
@@ -96,10 +97,10 @@ func TestGiftLinkService_RedeemGiftLink(t *testing.T) {
 		recipient := &models.User{Username: "bob", BeanAmount: 100}
 		require.NoError(t, db.Create(recipient).Error)
 
-		giftLink, err := service.CreateGiftLink("alice", 100, "For you", "24h")
+		giftLink, err := service.CreateGiftLink(GiftLinkParams{From: "alice", Amount: 100, Message: "For you", ExpiresIn: "24h"})
 		require.NoError(t, err)
 
-		err = service.RedeemGiftLink(giftLink.Code, "bob")
+		err = service.RedeemGiftLink(giftLink.Code, "bob", "")
 		require.NoError(t, err)
 
 		var updatedRecipient models.User
@@ -119,15 +120,15 @@ func TestGiftLinkService_RedeemGiftLink(t *testing.T) {
 		transactionRepo := repository.NewTransactionRepository(db)
 		giftLinkRepo := repository.NewGiftLinkRepository(db)
 		transferService := NewTransferService(userRepo, transactionRepo, db)
-		service := NewGiftLinkService(giftLinkRepo, userRepo, transferService, db)
+		service := NewGiftLinkService(giftLinkRepo, userRepo, transferService, db, 0)
 
 		sender := &models.User{Username: "alice", BeanAmount: 500}
 		require.NoError(t, db.Create(sender).Error)
 
-		giftLink, err := service.CreateGiftLink("alice", 50, "New user", "24h")
+		giftLink, err := service.CreateGiftLink(GiftLinkParams{From: "alice", Amount: 50, Message: "New user", ExpiresIn: "24h"})
 		require.NoError(t, err)
 
-		err = service.RedeemGiftLink(giftLink.Code, "charlie")
+		err = service.RedeemGiftLink(giftLink.Code, "charlie", "")
 		require.NoError(t, err)
 
 		var newUser models.User
@@ -141,9 +142,9 @@ func TestGiftLinkService_RedeemGiftLink(t *testing.T) {
 		transactionRepo := repository.NewTransactionRepository(db)
 		giftLinkRepo := repository.NewGiftLinkRepository(db)
 		transferService := NewTransferService(userRepo, transactionRepo, db)
-		service := NewGiftLinkService(giftLinkRepo, userRepo, transferService, db)
+		service := NewGiftLinkService(giftLinkRepo, userRepo, transferService, db, 0)
 
-		err := service.RedeemGiftLink("nonexistent", "bob")
+		err := service.RedeemGiftLink("nonexistent", "bob", "")
 		assert.ErrorIs(t, err, ErrGiftLinkNotFound)
 	})
 
@@ -153,7 +154,7 @@ func TestGiftLinkService_RedeemGiftLink(t *testing.T) {
 		transactionRepo := repository.NewTransactionRepository(db)
 		giftLinkRepo := repository.NewGiftLinkRepository(db)
 		transferService := NewTransferService(userRepo, transactionRepo, db)
-		service := NewGiftLinkService(giftLinkRepo, userRepo, transferService, db)
+		service := NewGiftLinkService(giftLinkRepo, userRepo, transferService, db, 0)
 
 		sender := &models.User{Username: "alice", BeanAmount: 500}
 		require.NoError(t, db.Create(sender).Error)
@@ -161,13 +162,13 @@ func TestGiftLinkService_RedeemGiftLink(t *testing.T) {
 		recipient := &models.User{Username: "bob", BeanAmount: 100}
 		require.NoError(t, db.Create(recipient).Error)
 
-		giftLink, err := service.CreateGiftLink("alice", 100, "Once only", "24h")
+		giftLink, err := service.CreateGiftLink(GiftLinkParams{From: "alice", Amount: 100, Message: "Once only", ExpiresIn: "24h"})
 		require.NoError(t, err)
 
-		err = service.RedeemGiftLink(giftLink.Code, "bob")
+		err = service.RedeemGiftLink(giftLink.Code, "bob", "")
 		require.NoError(t, err)
 
-		err = service.RedeemGiftLink(giftLink.Code, "bob")
+		err = service.RedeemGiftLink(giftLink.Code, "bob", "")
 		assert.ErrorIs(t, err, ErrGiftLinkRedeemed)
 	})
 
@@ -177,18 +178,18 @@ func TestGiftLinkService_RedeemGiftLink(t *testing.T) {
 		transactionRepo := repository.NewTransactionRepository(db)
 		giftLinkRepo := repository.NewGiftLinkRepository(db)
 		transferService := NewTransferService(userRepo, transactionRepo, db)
-		service := NewGiftLinkService(giftLinkRepo, userRepo, transferService, db)
+		service := NewGiftLinkService(giftLinkRepo, userRepo, transferService, db, 0)
 
 		sender := &models.User{Username: "alice", BeanAmount: 500}
 		require.NoError(t, db.Create(sender).Error)
 
-		giftLink, err := service.CreateGiftLink("alice", 100, "Expired", "1h")
+		giftLink, err := service.CreateGiftLink(GiftLinkParams{From: "alice", Amount: 100, Message: "Expired", ExpiresIn: "1h"})
 		require.NoError(t, err)
 
 		pastTime := time.Now().Add(-2 * time.Hour)
 		require.NoError(t, db.Model(&giftLink).Update("expires_at", pastTime).Error)
 
-		err = service.RedeemGiftLink(giftLink.Code, "bob")
+		err = service.RedeemGiftLink(giftLink.Code, "bob", "")
 		assert.ErrorIs(t, err, ErrGiftLinkExpired)
 	})
 
@@ -198,17 +199,17 @@ func TestGiftLinkService_RedeemGiftLink(t *testing.T) {
 		transactionRepo := repository.NewTransactionRepository(db)
 		giftLinkRepo := repository.NewGiftLinkRepository(db)
 		transferService := NewTransferService(userRepo, transactionRepo, db)
-		service := NewGiftLinkService(giftLinkRepo, userRepo, transferService, db)
+		service := NewGiftLinkService(giftLinkRepo, userRepo, transferService, db, 0)
 
 		sender := &models.User{Username: "alice", BeanAmount: 500}
 		require.NoError(t, db.Create(sender).Error)
 
-		giftLink, err := service.CreateGiftLink("alice", 100, "Inactive", "24h")
+		giftLink, err := service.CreateGiftLink(GiftLinkParams{From: "alice", Amount: 100, Message: "Inactive", ExpiresIn: "24h"})
 		require.NoError(t, err)
 
 		require.NoError(t, db.Model(&giftLink).Update("active", false).Error)
 
-		err = service.RedeemGiftLink(giftLink.Code, "bob")
+		err = service.RedeemGiftLink(giftLink.Code, "bob", "")
 		assert.ErrorIs(t, err, ErrGiftLinkInactive)
 	})
 
@@ -218,15 +219,15 @@ func TestGiftLinkService_RedeemGiftLink(t *testing.T) {
 		transactionRepo := repository.NewTransactionRepository(db)
 		giftLinkRepo := repository.NewGiftLinkRepository(db)
 		transferService := NewTransferService(userRepo, transactionRepo, db)
-		service := NewGiftLinkService(giftLinkRepo, userRepo, transferService, db)
+		service := NewGiftLinkService(giftLinkRepo, userRepo, transferService, db, 0)
 
 		sender := &models.User{Username: "alice", BeanAmount: 500}
 		require.NoError(t, db.Create(sender).Error)
 
-		giftLink, err := service.CreateGiftLink("alice", 100, "Self gift", "24h")
+		giftLink, err := service.CreateGiftLink(GiftLinkParams{From: "alice", Amount: 100, Message: "Self gift", ExpiresIn: "24h"})
 		require.NoError(t, err)
 
-		err = service.RedeemGiftLink(giftLink.Code, "alice")
+		err = service.RedeemGiftLink(giftLink.Code, "alice", "")
 		assert.ErrorIs(t, err, ErrCannotRedeemOwnLink)
 	})
 }
@@ -238,14 +239,14 @@ func TestGiftLinkService_ListGiftLinks(t *testing.T) {
 		transactionRepo := repository.NewTransactionRepository(db)
 		giftLinkRepo := repository.NewGiftLinkRepository(db)
 		transferService := NewTransferService(userRepo, transactionRepo, db)
-		service := NewGiftLinkService(giftLinkRepo, userRepo, transferService, db)
+		service := NewGiftLinkService(giftLinkRepo, userRepo, transferService, db, 0)
 
 		sender := &models.User{Username: "alice", BeanAmount: 1000}
 		require.NoError(t, db.Create(sender).Error)
 
-		_, err := service.CreateGiftLink("alice", 100, "Gift 1", "24h")
+		_, err := service.CreateGiftLink(GiftLinkParams{From: "alice", Amount: 100, Message: "Gift 1", ExpiresIn: "24h"})
 		require.NoError(t, err)
-		_, err = service.CreateGiftLink("alice", 200, "Gift 2", "never")
+		_, err = service.CreateGiftLink(GiftLinkParams{From: "alice", Amount: 200, Message: "Gift 2", ExpiresIn: "never"})
 		require.NoError(t, err)
 
 		links, err := service.ListGiftLinks("alice")
@@ -259,12 +260,12 @@ func TestGiftLinkService_ListGiftLinks(t *testing.T) {
 		transactionRepo := repository.NewTransactionRepository(db)
 		giftLinkRepo := repository.NewGiftLinkRepository(db)
 		transferService := NewTransferService(userRepo, transactionRepo, db)
-		service := NewGiftLinkService(giftLinkRepo, userRepo, transferService, db)
+		service := NewGiftLinkService(giftLinkRepo, userRepo, transferService, db, 0)
 
 		sender := &models.User{Username: "alice", BeanAmount: 1000}
 		require.NoError(t, db.Create(sender).Error)
 
-		giftLink, err := service.CreateGiftLink("alice", 50, "Will be inactive", "24h")
+		giftLink, err := service.CreateGiftLink(GiftLinkParams{From: "alice", Amount: 50, Message: "Will be inactive", ExpiresIn: "24h"})
 		require.NoError(t, err)
 
 		require.NoError(t, db.Model(&giftLink).Update("active", false).Error)
@@ -283,7 +284,7 @@ func TestGiftLinkService_ListGiftLinks(t *testing.T) {
 		transactionRepo := repository.NewTransactionRepository(db)
 		giftLinkRepo := repository.NewGiftLinkRepository(db)
 		transferService := NewTransferService(userRepo, transactionRepo, db)
-		service := NewGiftLinkService(giftLinkRepo, userRepo, transferService, db)
+		service := NewGiftLinkService(giftLinkRepo, userRepo, transferService, db, 0)
 
 		otherUser := &models.User{Username: "bob", BeanAmount: 100}
 		require.NoError(t, db.Create(otherUser).Error)
@@ -299,7 +300,7 @@ func TestGiftLinkService_ListGiftLinks(t *testing.T) {
 		transactionRepo := repository.NewTransactionRepository(db)
 		giftLinkRepo := repository.NewGiftLinkRepository(db)
 		transferService := NewTransferService(userRepo, transactionRepo, db)
-		service := NewGiftLinkService(giftLinkRepo, userRepo, transferService, db)
+		service := NewGiftLinkService(giftLinkRepo, userRepo, transferService, db, 0)
 
 		_, err := service.ListGiftLinks("nonexistent")
 		assert.Error(t, err)
@@ -313,12 +314,12 @@ func TestGiftLinkService_DeleteGiftLink(t *testing.T) {
 		transactionRepo := repository.NewTransactionRepository(db)
 		giftLinkRepo := repository.NewGiftLinkRepository(db)
 		transferService := NewTransferService(userRepo, transactionRepo, db)
-		service := NewGiftLinkService(giftLinkRepo, userRepo, transferService, db)
+		service := NewGiftLinkService(giftLinkRepo, userRepo, transferService, db, 0)
 
 		sender := &models.User{Username: "alice", BeanAmount: 1000}
 		require.NoError(t, db.Create(sender).Error)
 
-		giftLink, err := service.CreateGiftLink("alice", 100, "Delete me", "24h")
+		giftLink, err := service.CreateGiftLink(GiftLinkParams{From: "alice", Amount: 100, Message: "Delete me", ExpiresIn: "24h"})
 		require.NoError(t, err)
 
 		var beforeBalance models.User
@@ -342,7 +343,7 @@ func TestGiftLinkService_DeleteGiftLink(t *testing.T) {
 		transactionRepo := repository.NewTransactionRepository(db)
 		giftLinkRepo := repository.NewGiftLinkRepository(db)
 		transferService := NewTransferService(userRepo, transactionRepo, db)
-		service := NewGiftLinkService(giftLinkRepo, userRepo, transferService, db)
+		service := NewGiftLinkService(giftLinkRepo, userRepo, transferService, db, 0)
 
 		sender := &models.User{Username: "alice", BeanAmount: 1000}
 		require.NoError(t, db.Create(sender).Error)
@@ -350,10 +351,10 @@ func TestGiftLinkService_DeleteGiftLink(t *testing.T) {
 		recipient := &models.User{Username: "bob", BeanAmount: 0}
 		require.NoError(t, db.Create(recipient).Error)
 
-		giftLink, err := service.CreateGiftLink("alice", 100, "Already redeemed", "24h")
+		giftLink, err := service.CreateGiftLink(GiftLinkParams{From: "alice", Amount: 100, Message: "Already redeemed", ExpiresIn: "24h"})
 		require.NoError(t, err)
 
-		err = service.RedeemGiftLink(giftLink.Code, "bob")
+		err = service.RedeemGiftLink(giftLink.Code, "bob", "")
 		require.NoError(t, err)
 
 		var beforeBalance models.User
@@ -373,7 +374,7 @@ func TestGiftLinkService_DeleteGiftLink(t *testing.T) {
 		transactionRepo := repository.NewTransactionRepository(db)
 		giftLinkRepo := repository.NewGiftLinkRepository(db)
 		transferService := NewTransferService(userRepo, transactionRepo, db)
-		service := NewGiftLinkService(giftLinkRepo, userRepo, transferService, db)
+		service := NewGiftLinkService(giftLinkRepo, userRepo, transferService, db, 0)
 
 		sender := &models.User{Username: "alice", BeanAmount: 1000}
 		require.NoError(t, db.Create(sender).Error)
@@ -381,7 +382,7 @@ func TestGiftLinkService_DeleteGiftLink(t *testing.T) {
 		otherUser := &models.User{Username: "charlie", BeanAmount: 100}
 		require.NoError(t, db.Create(otherUser).Error)
 
-		giftLink, err := service.CreateGiftLink("alice", 50, "Not yours", "24h")
+		giftLink, err := service.CreateGiftLink(GiftLinkParams{From: "alice", Amount: 50, Message: "Not yours", ExpiresIn: "24h"})
 		require.NoError(t, err)
 
 		err = service.DeleteGiftLink(giftLink.ID, "charlie")
@@ -395,7 +396,7 @@ func TestGiftLinkService_DeleteGiftLink(t *testing.T) {
 		transactionRepo := repository.NewTransactionRepository(db)
 		giftLinkRepo := repository.NewGiftLinkRepository(db)
 		transferService := NewTransferService(userRepo, transactionRepo, db)
-		service := NewGiftLinkService(giftLinkRepo, userRepo, transferService, db)
+		service := NewGiftLinkService(giftLinkRepo, userRepo, transferService, db, 0)
 
 		sender := &models.User{Username: "alice", BeanAmount: 1000}
 		require.NoError(t, db.Create(sender).Error)
@@ -410,13 +411,13 @@ func TestGiftLinkService_GetGiftLinkByCode(t *testing.T) {
 	transactionRepo := repository.NewTransactionRepository(db)
 	giftLinkRepo := repository.NewGiftLinkRepository(db)
 	transferService := NewTransferService(userRepo, transactionRepo, db)
-	service := NewGiftLinkService(giftLinkRepo, userRepo, transferService, db)
+	service := NewGiftLinkService(giftLinkRepo, userRepo, transferService, db, 0)
 
 	sender := &models.User{Username: "alice", BeanAmount: 500}
 	require.NoError(t, db.Create(sender).Error)
 
 	t.Run("retrieves gift link by code", func(t *testing.T) {
-		giftLink, err := service.CreateGiftLink("alice", 100, "Find me", "24h")
+		giftLink, err := service.CreateGiftLink(GiftLinkParams{From: "alice", Amount: 100, Message: "Find me", ExpiresIn: "24h"})
 		require.NoError(t, err)
 
 		found, err := service.GetGiftLinkByCode(giftLink.Code)
@@ -439,7 +440,7 @@ func TestGiftLinkService_ExpiryParsing(t *testing.T) {
 	transactionRepo := repository.NewTransactionRepository(db)
 	giftLinkRepo := repository.NewGiftLinkRepository(db)
 	transferService := NewTransferService(userRepo, transactionRepo, db)
-	service := NewGiftLinkService(giftLinkRepo, userRepo, transferService, db)
+	service := NewGiftLinkService(giftLinkRepo, userRepo, transferService, db, 0)
 
 	sender := &models.User{Username: "alice", BeanAmount: 1000}
 	require.NoError(t, db.Create(sender).Error)
@@ -449,19 +450,24 @@ func TestGiftLinkService_ExpiryParsing(t *testing.T) {
 		expiresIn      string
 		expectNil      bool
 		expectedOffset time.Duration
+		expectedErr    error
 	}{
-		{"1 hour", "1h", false, 1 * time.Hour},
-		{"24 hours", "24h", false, 24 * time.Hour},
-		{"7 days", "7d", false, 7 * 24 * time.Hour},
-		{"30 days", "30d", false, 30 * 24 * time.Hour},
-		{"never", "never", true, 0},
-		{"empty string", "", true, 0},
-		{"invalid", "invalid", true, 0},
+		{"1 hour", "1h", false, 1 * time.Hour, nil},
+		{"24 hours", "24h", false, 24 * time.Hour, nil},
+		{"7 days", "7d", false, 7 * 24 * time.Hour, nil},
+		{"30 days", "30d", false, 30 * 24 * time.Hour, nil},
+		{"never", "never", true, 0, nil},
+		{"empty string", "", true, 0, nil},
+		{"invalid", "invalid", false, 0, ErrInvalidExpiry},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			giftLink, err := service.CreateGiftLink("alice", 10, "Expiry test", tc.expiresIn)
+			giftLink, err := service.CreateGiftLink(GiftLinkParams{From: "alice", Amount: 10, Message: "Expiry test", ExpiresIn: tc.expiresIn})
+			if tc.expectedErr != nil {
+				assert.Equal(t, tc.expectedErr, err)
+				return
+			}
 			require.NoError(t, err)
 
 			if tc.expectNil {
@@ -474,3 +480,578 @@ func TestGiftLinkService_ExpiryParsing(t *testing.T) {
 		})
 	}
 }
+
+func TestGiftLinkService_TransactionKinds(t *testing.T) {
+	db := setupGiftLinkTestDB(t)
+	userRepo := repository.NewUserRepository(db)
+	transactionRepo := repository.NewTransactionRepository(db)
+	giftLinkRepo := repository.NewGiftLinkRepository(db)
+	transferService := NewTransferService(userRepo, transactionRepo, db)
+	service := NewGiftLinkService(giftLinkRepo, userRepo, transferService, db, 0)
+
+	require.NoError(t, db.Create(&models.User{Username: "alice", BeanAmount: 500}).Error)
+	require.NoError(t, db.Create(&models.User{Username: "bob", BeanAmount: 0}).Error)
+
+	redeemed, err := service.CreateGiftLink(GiftLinkParams{From: "alice", Amount: 100, Message: "For bob", ExpiresIn: "24h"})
+	require.NoError(t, err)
+	require.NoError(t, service.RedeemGiftLink(redeemed.Code, "bob", ""))
+
+	refunded, err := service.CreateGiftLink(GiftLinkParams{From: "alice", Amount: 50, Message: "Never mind", ExpiresIn: "24h"})
+	require.NoError(t, err)
+	require.NoError(t, service.DeleteGiftLink(refunded.ID, "alice"))
+
+	kindsFor := func(giftLinkID uint) []models.TransactionKind {
+		var transactions []models.Transaction
+		require.NoError(t, db.Where("gift_link_id = ?", giftLinkID).Order("id ASC").Find(&transactions).Error)
+		kinds := make([]models.TransactionKind, len(transactions))
+		for i, tx := range transactions {
+			kinds[i] = tx.Kind
+		}
+		return kinds
+	}
+
+	assert.Equal(t, []models.TransactionKind{models.TransactionKindGiftEscrow, models.TransactionKindGiftRedeem}, kindsFor(redeemed.ID))
+	assert.Equal(t, []models.TransactionKind{models.TransactionKindGiftEscrow, models.TransactionKindGiftRefund}, kindsFor(refunded.ID))
+}
+
+func TestGiftLinkService_SweepExpired(t *testing.T) {
+	t.Run("refunds expired links to their creators", func(t *testing.T) {
+		db := setupGiftLinkTestDB(t)
+		userRepo := repository.NewUserRepository(db)
+		transactionRepo := repository.NewTransactionRepository(db)
+		giftLinkRepo := repository.NewGiftLinkRepository(db)
+		transferService := NewTransferService(userRepo, transactionRepo, db)
+		service := NewGiftLinkService(giftLinkRepo, userRepo, transferService, db, 0)
+
+		sender := &models.User{Username: "alice", BeanAmount: 500}
+		require.NoError(t, db.Create(sender).Error)
+
+		expired, err := service.CreateGiftLink(GiftLinkParams{From: "alice", Amount: 100, Message: "Forgotten", ExpiresIn: "1h"})
+		require.NoError(t, err)
+		fresh, err := service.CreateGiftLink(GiftLinkParams{From: "alice", Amount: 50, Message: "Still good", ExpiresIn: "24h"})
+		require.NoError(t, err)
+		_, err = service.CreateGiftLink(GiftLinkParams{From: "alice", Amount: 25, Message: "Forever", ExpiresIn: "never"})
+		require.NoError(t, err)
+
+		now := time.Now().Add(2 * time.Hour)
+		refunded, err := service.SweepExpired(now)
+		require.NoError(t, err)
+		assert.Equal(t, 1, refunded)
+
+		var afterBalance models.User
+		require.NoError(t, db.First(&afterBalance, sender.ID).Error)
+		assert.Equal(t, 500-50-25, afterBalance.BeanAmount)
+
+		var updated models.GiftLink
+		require.NoError(t, db.First(&updated, expired.ID).Error)
+		assert.False(t, updated.Active)
+		require.NotNil(t, updated.ExpiredAt)
+		assert.True(t, updated.ExpiredAt.Equal(now))
+
+		var refund models.Transaction
+		require.NoError(t, db.Where("gift_link_id = ? AND kind = ?", expired.ID, models.TransactionKindGiftRefund).First(&refund).Error)
+		assert.Equal(t, sender.ID, refund.ToUserID)
+		assert.Equal(t, 100, refund.Amount)
+
+		links, err := service.ListGiftLinks("alice")
+		require.NoError(t, err)
+		assert.Len(t, links, 3, "expired links stay listed so the refund is visible")
+
+		err = service.RedeemGiftLink(expired.Code, "bob", "")
+		assert.ErrorIs(t, err, ErrGiftLinkExpired)
+
+		var stillActive models.GiftLink
+		require.NoError(t, db.First(&stillActive, fresh.ID).Error)
+		assert.True(t, stillActive.Active)
+		assert.Nil(t, stillActive.ExpiredAt)
+	})
+
+	t.Run("refunds each link only once", func(t *testing.T) {
+		db := setupGiftLinkTestDB(t)
+		userRepo := repository.NewUserRepository(db)
+		transactionRepo := repository.NewTransactionRepository(db)
+		giftLinkRepo := repository.NewGiftLinkRepository(db)
+		transferService := NewTransferService(userRepo, transactionRepo, db)
+		service := NewGiftLinkService(giftLinkRepo, userRepo, transferService, db, 0)
+
+		sender := &models.User{Username: "alice", BeanAmount: 500}
+		require.NoError(t, db.Create(sender).Error)
+
+		giftLink, err := service.CreateGiftLink(GiftLinkParams{From: "alice", Amount: 100, Message: "Forgotten", ExpiresIn: "1h"})
+		require.NoError(t, err)
+
+		now := time.Now().Add(2 * time.Hour)
+		_, err = service.SweepExpired(now)
+		require.NoError(t, err)
+
+		refunded, err := service.SweepExpired(now.Add(time.Hour))
+		require.NoError(t, err)
+		assert.Zero(t, refunded)
+
+		require.NoError(t, service.DeleteGiftLink(giftLink.ID, "alice"))
+
+		var afterBalance models.User
+		require.NoError(t, db.First(&afterBalance, sender.ID).Error)
+		assert.Equal(t, 500, afterBalance.BeanAmount)
+	})
+
+	t.Run("skips redeemed links", func(t *testing.T) {
+		db := setupGiftLinkTestDB(t)
+		userRepo := repository.NewUserRepository(db)
+		transactionRepo := repository.NewTransactionRepository(db)
+		giftLinkRepo := repository.NewGiftLinkRepository(db)
+		transferService := NewTransferService(userRepo, transactionRepo, db)
+		service := NewGiftLinkService(giftLinkRepo, userRepo, transferService, db, 0)
+
+		sender := &models.User{Username: "alice", BeanAmount: 500}
+		require.NoError(t, db.Create(sender).Error)
+
+		giftLink, err := service.CreateGiftLink(GiftLinkParams{From: "alice", Amount: 100, Message: "Claimed", ExpiresIn: "1h"})
+		require.NoError(t, err)
+		require.NoError(t, service.RedeemGiftLink(giftLink.Code, "bob", ""))
+
+		refunded, err := service.SweepExpired(time.Now().Add(2 * time.Hour))
+		require.NoError(t, err)
+		assert.Zero(t, refunded)
+	})
+}
+
+func TestGiftLinkService_MultiUse(t *testing.T) {
+	setup := func(t *testing.T) (*gorm.DB, *GiftLinkService, *models.User) {
+		db := setupGiftLinkTestDB(t)
+		userRepo := repository.NewUserRepository(db)
+		transactionRepo := repository.NewTransactionRepository(db)
+		giftLinkRepo := repository.NewGiftLinkRepository(db)
+		transferService := NewTransferService(userRepo, transactionRepo, db)
+		service := NewGiftLinkService(giftLinkRepo, userRepo, transferService, db, 0)
+
+		sender := &models.User{Username: "alice", BeanAmount: 500}
+		require.NoError(t, db.Create(sender).Error)
+		return db, service, sender
+	}
+
+	t.Run("escrows count times amount", func(t *testing.T) {
+		db, service, sender := setup(t)
+
+		giftLink, err := service.CreateGiftLink(GiftLinkParams{From: "alice", Amount: 10, Message: "Rain", ExpiresIn: "24h", MaxRedemptions: 20})
+		require.NoError(t, err)
+		assert.Equal(t, 20, giftLink.MaxRedemptions)
+		assert.Equal(t, 0, giftLink.RedemptionCount)
+
+		var afterBalance models.User
+		require.NoError(t, db.First(&afterBalance, sender.ID).Error)
+		assert.Equal(t, 300, afterBalance.BeanAmount)
+	})
+
+	t.Run("rejects escrow above balance", func(t *testing.T) {
+		_, service, _ := setup(t)
+
+		_, err := service.CreateGiftLink(GiftLinkParams{From: "alice", Amount: 100, MaxRedemptions: 6})
+		assert.ErrorIs(t, err, ErrInsufficientBalanceForGift)
+	})
+
+	t.Run("rejects invalid max redemptions", func(t *testing.T) {
+		_, service, _ := setup(t)
+
+		_, err := service.CreateGiftLink(GiftLinkParams{From: "alice", Amount: 1, MaxRedemptions: -1})
+		assert.ErrorIs(t, err, ErrInvalidMaxRedemptions)
+		_, err = service.CreateGiftLink(GiftLinkParams{From: "alice", Amount: 1, MaxRedemptions: MaxGiftRedemptions + 1})
+		assert.ErrorIs(t, err, ErrInvalidMaxRedemptions)
+	})
+
+	t.Run("each user claims once until used up", func(t *testing.T) {
+		db, service, _ := setup(t)
+
+		giftLink, err := service.CreateGiftLink(GiftLinkParams{From: "alice", Amount: 10, MaxRedemptions: 2})
+		require.NoError(t, err)
+
+		require.NoError(t, service.RedeemGiftLink(giftLink.Code, "bob", ""))
+
+		err = service.RedeemGiftLink(giftLink.Code, "bob", "")
+		assert.ErrorIs(t, err, ErrGiftLinkAlreadyClaimed)
+
+		var bob models.User
+		require.NoError(t, db.Where("username = ?", "bob").First(&bob).Error)
+		assert.Equal(t, 10, bob.BeanAmount, "a rejected second claim is rolled back")
+
+		var partial models.GiftLink
+		require.NoError(t, db.First(&partial, giftLink.ID).Error)
+		assert.True(t, partial.Active)
+		assert.Equal(t, 1, partial.RedemptionCount)
+		assert.Nil(t, partial.RedeemedAt)
+
+		require.NoError(t, service.RedeemGiftLink(giftLink.Code, "carol", ""))
+
+		err = service.RedeemGiftLink(giftLink.Code, "dave", "")
+		assert.ErrorIs(t, err, ErrGiftLinkRedeemed)
+
+		var usedUp models.GiftLink
+		require.NoError(t, db.First(&usedUp, giftLink.ID).Error)
+		assert.False(t, usedUp.Active)
+		assert.Equal(t, 2, usedUp.RedemptionCount)
+		assert.NotNil(t, usedUp.RedeemedAt)
+
+		var redemptions []models.GiftLinkRedemption
+		require.NoError(t, db.Where("gift_link_id = ?", giftLink.ID).Find(&redemptions).Error)
+		require.Len(t, redemptions, 2)
+		for _, redemption := range redemptions {
+			assert.Equal(t, 10, redemption.Amount)
+			assert.NotZero(t, redemption.TransactionID)
+		}
+	})
+
+	t.Run("lists redemptions for the creator", func(t *testing.T) {
+		_, service, _ := setup(t)
+
+		giftLink, err := service.CreateGiftLink(GiftLinkParams{From: "alice", Amount: 5, MaxRedemptions: 3})
+		require.NoError(t, err)
+		require.NoError(t, service.RedeemGiftLink(giftLink.Code, "bob", ""))
+
+		links, err := service.ListGiftLinks("alice")
+		require.NoError(t, err)
+		require.Len(t, links, 1)
+		require.Len(t, links[0].Redemptions, 1)
+		assert.Equal(t, "bob", links[0].Redemptions[0].User.Username)
+	})
+
+	t.Run("deleting refunds only the unclaimed remainder", func(t *testing.T) {
+		db, service, sender := setup(t)
+
+		giftLink, err := service.CreateGiftLink(GiftLinkParams{From: "alice", Amount: 10, MaxRedemptions: 5})
+		require.NoError(t, err)
+		require.NoError(t, service.RedeemGiftLink(giftLink.Code, "bob", ""))
+		require.NoError(t, service.RedeemGiftLink(giftLink.Code, "carol", ""))
+
+		require.NoError(t, service.DeleteGiftLink(giftLink.ID, "alice"))
+
+		var afterBalance models.User
+		require.NoError(t, db.First(&afterBalance, sender.ID).Error)
+		assert.Equal(t, 500-20, afterBalance.BeanAmount)
+
+		err = service.RedeemGiftLink(giftLink.Code, "dave", "")
+		assert.ErrorIs(t, err, ErrGiftLinkInactive)
+	})
+
+	t.Run("sweeper refunds only the unclaimed remainder", func(t *testing.T) {
+		db, service, sender := setup(t)
+
+		giftLink, err := service.CreateGiftLink(GiftLinkParams{From: "alice", Amount: 10, ExpiresIn: "1h", MaxRedemptions: 4})
+		require.NoError(t, err)
+		require.NoError(t, service.RedeemGiftLink(giftLink.Code, "bob", ""))
+
+		refunded, err := service.SweepExpired(time.Now().Add(2 * time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, 1, refunded)
+
+		var afterBalance models.User
+		require.NoError(t, db.First(&afterBalance, sender.ID).Error)
+		assert.Equal(t, 500-10, afterBalance.BeanAmount)
+	})
+}
+
+func TestSplitRandomly(t *testing.T) {
+	cases := []struct {
+		total  int
+		shares int
+	}{
+		{1, 1},
+		{100, 1},
+		{10, 10},
+		{100, 7},
+		{100, 90},
+		{1000, 1000},
+		{1000000, 1000},
+		// Cheap however large the total is.
+		{math.MaxInt32, 3},
+	}
+
+	for _, tc := range cases {
+		split, err := splitRandomly(tc.total, tc.shares)
+		require.NoError(t, err)
+		require.Len(t, split, tc.shares)
+
+		sum := 0
+		for _, share := range split {
+			assert.GreaterOrEqual(t, share, 1)
+			sum += share
+		}
+		assert.Equal(t, tc.total, sum, "total %d in %d shares", tc.total, tc.shares)
+	}
+
+	_, err := splitRandomly(5, 6)
+	assert.ErrorIs(t, err, ErrTooFewBeansForShares)
+}
+
+func TestGiftLinkService_RandomSplit(t *testing.T) {
+	setup := func(t *testing.T) (*gorm.DB, *GiftLinkService, *models.User) {
+		db := setupGiftLinkTestDB(t)
+		userRepo := repository.NewUserRepository(db)
+		transactionRepo := repository.NewTransactionRepository(db)
+		giftLinkRepo := repository.NewGiftLinkRepository(db)
+		transferService := NewTransferService(userRepo, transactionRepo, db)
+		service := NewGiftLinkService(giftLinkRepo, userRepo, transferService, db, 0)
+
+		sender := &models.User{Username: "alice", BeanAmount: 500}
+		require.NoError(t, db.Create(sender).Error)
+		return db, service, sender
+	}
+
+	t.Run("refuses a huge total before splitting it", func(t *testing.T) {
+		_, service, _ := setup(t)
+
+		_, err := service.CreateGiftLink(GiftLinkParams{From: "alice", Amount: math.MaxInt, MaxRedemptions: 3, Mode: models.GiftLinkModeRandom})
+		assert.ErrorIs(t, err, ErrInsufficientBalanceForGift)
+	})
+
+	t.Run("escrows the total and stores the split", func(t *testing.T) {
+		db, service, sender := setup(t)
+
+		giftLink, err := service.CreateGiftLink(GiftLinkParams{From: "alice", Amount: 100, MaxRedemptions: 5, Mode: models.GiftLinkModeRandom})
+		require.NoError(t, err)
+		assert.Equal(t, models.GiftLinkModeRandom, giftLink.Mode)
+
+		var afterBalance models.User
+		require.NoError(t, db.First(&afterBalance, sender.ID).Error)
+		assert.Equal(t, 400, afterBalance.BeanAmount)
+
+		var shares []models.GiftLinkShare
+		require.NoError(t, db.Where("gift_link_id = ?", giftLink.ID).Order("position").Find(&shares).Error)
+		require.Len(t, shares, 5)
+		sum := 0
+		for i, share := range shares {
+			assert.Equal(t, i, share.Position)
+			assert.GreaterOrEqual(t, share.Amount, 1)
+			sum += share.Amount
+		}
+		assert.Equal(t, 100, sum)
+	})
+
+	t.Run("claims pay out the stored shares in order", func(t *testing.T) {
+		db, service, _ := setup(t)
+
+		giftLink, err := service.CreateGiftLink(GiftLinkParams{From: "alice", Amount: 50, MaxRedemptions: 3, Mode: models.GiftLinkModeRandom})
+		require.NoError(t, err)
+
+		var shares []models.GiftLinkShare
+		require.NoError(t, db.Where("gift_link_id = ?", giftLink.ID).Order("position").Find(&shares).Error)
+
+		for i, username := range []string{"bob", "carol", "dave"} {
+			require.NoError(t, service.RedeemGiftLink(giftLink.Code, username, ""))
+
+			var redeemer models.User
+			require.NoError(t, db.Where("username = ?", username).First(&redeemer).Error)
+			assert.Equal(t, shares[i].Amount, redeemer.BeanAmount)
+		}
+
+		err = service.RedeemGiftLink(giftLink.Code, "erin", "")
+		assert.ErrorIs(t, err, ErrGiftLinkRedeemed)
+
+		found, err := service.GetGiftLinkByCode(giftLink.Code)
+		require.NoError(t, err)
+		assert.Equal(t, 0, found.RemainingRedemptions())
+	})
+
+	t.Run("deleting refunds the unclaimed shares", func(t *testing.T) {
+		db, service, sender := setup(t)
+
+		giftLink, err := service.CreateGiftLink(GiftLinkParams{From: "alice", Amount: 60, MaxRedemptions: 4, Mode: models.GiftLinkModeRandom})
+		require.NoError(t, err)
+		require.NoError(t, service.RedeemGiftLink(giftLink.Code, "bob", ""))
+
+		var first models.GiftLinkShare
+		require.NoError(t, db.Where("gift_link_id = ? AND position = 0", giftLink.ID).First(&first).Error)
+
+		require.NoError(t, service.DeleteGiftLink(giftLink.ID, "alice"))
+
+		var afterBalance models.User
+		require.NoError(t, db.First(&afterBalance, sender.ID).Error)
+		assert.Equal(t, 500-first.Amount, afterBalance.BeanAmount)
+	})
+
+	t.Run("validates the split", func(t *testing.T) {
+		_, service, _ := setup(t)
+
+		_, err := service.CreateGiftLink(GiftLinkParams{From: "alice", Amount: 3, MaxRedemptions: 4, Mode: models.GiftLinkModeRandom})
+		assert.ErrorIs(t, err, ErrTooFewBeansForShares)
+
+		_, err = service.CreateGiftLink(GiftLinkParams{From: "alice", Amount: 3, Mode: "lottery"})
+		assert.ErrorIs(t, err, ErrInvalidGiftLinkMode)
+	})
+}
+
+func TestGiftLinkService_Protections(t *testing.T) {
+	setup := func(t *testing.T) (*gorm.DB, *GiftLinkService) {
+		db := setupGiftLinkTestDB(t)
+		userRepo := repository.NewUserRepository(db)
+		transactionRepo := repository.NewTransactionRepository(db)
+		giftLinkRepo := repository.NewGiftLinkRepository(db)
+		transferService := NewTransferService(userRepo, transactionRepo, db)
+		service := NewGiftLinkService(giftLinkRepo, userRepo, transferService, db, 0)
+
+		sender := &models.User{Username: "alice", BeanAmount: 500}
+		require.NoError(t, db.Create(sender).Error)
+		return db, service
+	}
+
+	t.Run("only allowed recipients can redeem", func(t *testing.T) {
+		_, service := setup(t)
+
+		giftLink, err := service.CreateGiftLink(GiftLinkParams{
+			From: "alice", Amount: 10, MaxRedemptions: 2, AllowedRecipients: []string{" bob ", "carol", "bob"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"bob", "carol"}, giftLink.AllowedUsernames())
+
+		err = service.RedeemGiftLink(giftLink.Code, "mallory", "")
+		assert.ErrorIs(t, err, ErrGiftLinkNotForYou)
+
+		require.NoError(t, service.RedeemGiftLink(giftLink.Code, "bob", ""))
+		require.NoError(t, service.RedeemGiftLink(giftLink.Code, "carol", ""))
+	})
+
+	t.Run("rejects reserved recipients", func(t *testing.T) {
+		_, service := setup(t)
+
+		_, err := service.CreateGiftLink(GiftLinkParams{From: "alice", Amount: 10, AllowedRecipients: []string{models.SystemUsername}})
+		assert.ErrorIs(t, err, ErrReservedAccount)
+	})
+
+	t.Run("requires the correct pin", func(t *testing.T) {
+		db, service := setup(t)
+
+		giftLink, err := service.CreateGiftLink(GiftLinkParams{From: "alice", Amount: 10, Pin: "open sesame"})
+		require.NoError(t, err)
+		assert.True(t, giftLink.HasPin())
+		assert.NotContains(t, giftLink.PinHash, "open sesame")
+
+		err = service.RedeemGiftLink(giftLink.Code, "bob", "")
+		assert.ErrorIs(t, err, ErrGiftPinRequired)
+
+		err = service.RedeemGiftLink(giftLink.Code, "bob", "wrong")
+		assert.ErrorIs(t, err, ErrIncorrectGiftPin)
+
+		var attempted models.GiftLink
+		require.NoError(t, db.First(&attempted, giftLink.ID).Error)
+		assert.Equal(t, 1, attempted.FailedPinAttempts, "the failed attempt is committed")
+
+		require.NoError(t, service.RedeemGiftLink(giftLink.Code, "bob", "open sesame"))
+	})
+
+	t.Run("locks after repeated wrong pins", func(t *testing.T) {
+		db, service := setup(t)
+
+		giftLink, err := service.CreateGiftLink(GiftLinkParams{From: "alice", Amount: 10, Pin: "1234"})
+		require.NoError(t, err)
+
+		for i := 1; i < MaxGiftPinAttempts; i++ {
+			err = service.RedeemGiftLink(giftLink.Code, "bob", "0000")
+			assert.ErrorIs(t, err, ErrIncorrectGiftPin)
+		}
+		err = service.RedeemGiftLink(giftLink.Code, "bob", "0000")
+		assert.ErrorIs(t, err, ErrGiftLinkLocked)
+
+		err = service.RedeemGiftLink(giftLink.Code, "bob", "1234")
+		assert.ErrorIs(t, err, ErrGiftLinkLocked, "the right pin no longer works")
+
+		var locked models.GiftLink
+		require.NoError(t, db.First(&locked, giftLink.ID).Error)
+		assert.NotNil(t, locked.LockedAt)
+
+		require.NoError(t, service.DeleteGiftLink(giftLink.ID, "alice"))
+		var sender models.User
+		require.NoError(t, db.Where("username = ?", "alice").First(&sender).Error)
+		assert.Equal(t, 500, sender.BeanAmount, "the creator can still get the beans back")
+	})
+
+	t.Run("outsiders cannot use up pin attempts", func(t *testing.T) {
+		db, service := setup(t)
+
+		giftLink, err := service.CreateGiftLink(GiftLinkParams{From: "alice", Amount: 10, Pin: "1234", AllowedRecipients: []string{"bob"}})
+		require.NoError(t, err)
+
+		for i := 0; i < MaxGiftPinAttempts; i++ {
+			err = service.RedeemGiftLink(giftLink.Code, "mallory", "0000")
+			assert.ErrorIs(t, err, ErrGiftLinkNotForYou)
+		}
+
+		var untouched models.GiftLink
+		require.NoError(t, db.First(&untouched, giftLink.ID).Error)
+		assert.Zero(t, untouched.FailedPinAttempts)
+
+		require.NoError(t, service.RedeemGiftLink(giftLink.Code, "bob", "1234"))
+	})
+
+	t.Run("validates pin length", func(t *testing.T) {
+		_, service := setup(t)
+
+		_, err := service.CreateGiftLink(GiftLinkParams{From: "alice", Amount: 10, Pin: "123"})
+		assert.ErrorIs(t, err, ErrInvalidGiftPin)
+	})
+}
+
+func TestGiftLinkService_PreviewGiftLink(t *testing.T) {
+	db := setupGiftLinkTestDB(t)
+	userRepo := repository.NewUserRepository(db)
+	transactionRepo := repository.NewTransactionRepository(db)
+	giftLinkRepo := repository.NewGiftLinkRepository(db)
+	transferService := NewTransferService(userRepo, transactionRepo, db)
+	service := NewGiftLinkService(giftLinkRepo, userRepo, transferService, db, 0)
+
+	require.NoError(t, db.Create(&models.User{Username: "alice", BeanAmount: 500}).Error)
+
+	t.Run("open link shows its details", func(t *testing.T) {
+		giftLink, err := service.CreateGiftLink(GiftLinkParams{From: "alice", Amount: 30, Message: "Thanks!", ExpiresIn: "24h", MaxRedemptions: 3, Mode: models.GiftLinkModeRandom})
+		require.NoError(t, err)
+
+		preview, err := service.PreviewGiftLink(giftLink.Code, time.Now())
+		require.NoError(t, err)
+		assert.True(t, preview.Claimable)
+		assert.Equal(t, "alice", preview.From)
+		assert.Equal(t, 30, preview.Amount)
+		assert.Equal(t, models.GiftLinkModeRandom, preview.Mode)
+		assert.Equal(t, 3, preview.MaxRedemptions)
+		assert.Equal(t, "Thanks!", preview.Message)
+
+		preview, err = service.PreviewGiftLink(giftLink.Code, time.Now().Add(25*time.Hour))
+		require.NoError(t, err)
+		assert.False(t, preview.Claimable, "past the expiry time the preview should be generic")
+	})
+
+	hidden := map[string]func(t *testing.T) string{
+		"unknown code": func(t *testing.T) string {
+			return "does-not-exist"
+		},
+		"redeemed": func(t *testing.T) string {
+			giftLink, err := service.CreateGiftLink(GiftLinkParams{From: "alice", Amount: 10, Message: "secret"})
+			require.NoError(t, err)
+			require.NoError(t, service.RedeemGiftLink(giftLink.Code, "bob", ""))
+			return giftLink.Code
+		},
+		"deleted": func(t *testing.T) string {
+			giftLink, err := service.CreateGiftLink(GiftLinkParams{From: "alice", Amount: 10, Message: "secret"})
+			require.NoError(t, err)
+			require.NoError(t, service.DeleteGiftLink(giftLink.ID, "alice"))
+			return giftLink.Code
+		},
+		"restricted": func(t *testing.T) string {
+			giftLink, err := service.CreateGiftLink(GiftLinkParams{From: "alice", Amount: 10, Message: "secret", AllowedRecipients: []string{"bob"}})
+			require.NoError(t, err)
+			return giftLink.Code
+		},
+		"pin protected": func(t *testing.T) string {
+			giftLink, err := service.CreateGiftLink(GiftLinkParams{From: "alice", Amount: 10, Message: "secret", Pin: "hunter22"})
+			require.NoError(t, err)
+			return giftLink.Code
+		},
+	}
+
+	for name, create := range hidden {
+		t.Run(name+" link stays generic", func(t *testing.T) {
+			code := create(t)
+
+			preview, err := service.PreviewGiftLink(code, time.Now())
+			require.NoError(t, err)
+			assert.Equal(t, &GiftLinkPreview{Code: code}, preview)
+		})
+	}
+}
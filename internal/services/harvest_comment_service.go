@@ -0,0 +1,196 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/h4ks-com/bean-bank/internal/database"
+	"github.com/h4ks-com/bean-bank/internal/models"
+	"github.com/h4ks-com/bean-bank/internal/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrHarvestCommentNotFound = errors.New("comment not found")
+	ErrInvalidHarvestComment  = errors.New("comment must be between 1 and 2000 characters")
+	ErrNotCommentAuthor       = errors.New("you can only change your own comments")
+)
+
+// MaxHarvestCommentLength caps the text of a comment.
+const MaxHarvestCommentLength = 2000
+
+// HarvestCommentService runs the discussion on a harvest. Posting a comment
+// and an admin removing one are recorded on the harvest's timeline alongside
+// its status changes.
+type HarvestCommentService struct {
+	harvestService *HarvestService
+	harvestRepo    *repository.HarvestRepository
+	commentRepo    *repository.HarvestCommentRepository
+	db             *gorm.DB
+}
+
+func NewHarvestCommentService(
+	harvestService *HarvestService,
+	harvestRepo *repository.HarvestRepository,
+	commentRepo *repository.HarvestCommentRepository,
+	db *gorm.DB,
+) *HarvestCommentService {
+	return &HarvestCommentService{
+		harvestService: harvestService,
+		harvestRepo:    harvestRepo,
+		commentRepo:    commentRepo,
+		db:             db,
+	}
+}
+
+// AddComment posts a comment on a harvest.
+func (s *HarvestCommentService) AddComment(harvestID uint, username, body string) (*models.HarvestComment, error) {
+	body, err := checkCommentBody(body)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.harvestService.findParticipantUser(username)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.harvestService.findHarvest(harvestID); err != nil {
+		return nil, err
+	}
+
+	comment := &models.HarvestComment{HarvestID: harvestID, UserID: user.ID, Body: body}
+	err = database.Transaction(s.db, func(tx *gorm.DB) error {
+		if err := s.commentRepo.CreateInTx(tx, comment); err != nil {
+			return err
+		}
+		return s.recordCommentEvent(tx, comment, models.HarvestEventCommented, username, "")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	comment.User = *user
+	return comment, nil
+}
+
+// EditComment replaces the text of the user's own comment.
+func (s *HarvestCommentService) EditComment(harvestID, commentID uint, username, body string) (*models.HarvestComment, error) {
+	body, err := checkCommentBody(body)
+	if err != nil {
+		return nil, err
+	}
+
+	var comment *models.HarvestComment
+	err = database.Transaction(s.db, func(tx *gorm.DB) error {
+		comment, err = s.lockOwnComment(tx, harvestID, commentID, username)
+		if err != nil {
+			return err
+		}
+
+		now := s.harvestService.clock.Now()
+		comment.Body = body
+		comment.EditedAt = &now
+		return s.commentRepo.UpdateInTx(tx, comment)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
+
+// DeleteComment deletes the user's own comment. The timeline keeps a
+// placeholder where it was.
+func (s *HarvestCommentService) DeleteComment(harvestID, commentID uint, username string) error {
+	return database.Transaction(s.db, func(tx *gorm.DB) error {
+		comment, err := s.lockOwnComment(tx, harvestID, commentID, username)
+		if err != nil {
+			return err
+		}
+		return s.commentRepo.DeleteInTx(tx, comment)
+	})
+}
+
+// RemoveComment lets an admin take down any comment. The removal and its
+// reason go on the timeline.
+func (s *HarvestCommentService) RemoveComment(harvestID, commentID uint, admin, reason string) error {
+	reason = strings.TrimSpace(reason)
+	return database.Transaction(s.db, func(tx *gorm.DB) error {
+		comment, err := s.lockComment(tx, harvestID, commentID)
+		if err != nil {
+			return err
+		}
+
+		comment.RemovedBy = admin
+		comment.RemovalReason = reason
+		if err := s.commentRepo.UpdateInTx(tx, comment); err != nil {
+			return err
+		}
+		if err := s.commentRepo.DeleteInTx(tx, comment); err != nil {
+			return err
+		}
+		return s.recordCommentEvent(tx, comment, models.HarvestEventCommentRemoved, admin, reason)
+	})
+}
+
+// ListComments returns a page of a harvest's comments, oldest first, and how
+// many there are.
+func (s *HarvestCommentService) ListComments(harvestID uint, page, limit int) ([]models.HarvestComment, int64, error) {
+	if _, err := s.harvestService.findHarvest(harvestID); err != nil {
+		return nil, 0, err
+	}
+
+	comments, err := s.commentRepo.ListByHarvest(harvestID, page, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	count, err := s.commentRepo.CountByHarvest(harvestID)
+	if err != nil {
+		return nil, 0, err
+	}
+	return comments, count, nil
+}
+
+func (s *HarvestCommentService) lockComment(tx *gorm.DB, harvestID, commentID uint) (*models.HarvestComment, error) {
+	comment, err := s.commentRepo.FindByIDForUpdate(tx, commentID)
+	if err != nil {
+		return nil, err
+	}
+	if comment == nil || comment.HarvestID != harvestID {
+		return nil, ErrHarvestCommentNotFound
+	}
+	return comment, nil
+}
+
+func (s *HarvestCommentService) lockOwnComment(tx *gorm.DB, harvestID, commentID uint, username string) (*models.HarvestComment, error) {
+	comment, err := s.lockComment(tx, harvestID, commentID)
+	if err != nil {
+		return nil, err
+	}
+	if comment.User.Username != username {
+		return nil, ErrNotCommentAuthor
+	}
+	return comment, nil
+}
+
+func (s *HarvestCommentService) recordCommentEvent(tx *gorm.DB, comment *models.HarvestComment, eventType models.HarvestEventType, actor, note string) error {
+	event := &models.HarvestEvent{
+		HarvestID: comment.HarvestID,
+		Type:      eventType,
+		Actor:     actor,
+		Note:      note,
+		CommentID: &comment.ID,
+	}
+	if err := s.harvestRepo.CreateEventInTx(tx, event); err != nil {
+		return fmt.Errorf("failed to record harvest event: %w", err)
+	}
+	return nil
+}
+
+func checkCommentBody(body string) (string, error) {
+	body = strings.TrimSpace(body)
+	if body == "" || utf8.RuneCountInString(body) > MaxHarvestCommentLength {
+		return "", ErrInvalidHarvestComment
+	}
+	return body, nil
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/h4ks-com/bean-bank/internal/database"
+	"github.com/h4ks-com/bean-bank/internal/models"
+	"github.com/h4ks-com/bean-bank/internal/repository"
+	"github.com/h4ks-com/bean-bank/internal/scheduler"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupHarvestCommentTestDB(t *testing.T) (*HarvestService, *HarvestCommentService, *models.Harvest) {
+	db, err := database.Connect(":memory:")
+	assert.NoError(t, err)
+	assert.NoError(t, database.Migrate(db))
+
+	harvestRepo := repository.NewHarvestRepository(db)
+	userRepo := repository.NewUserRepository(db)
+	transactionRepo := repository.NewTransactionRepository(db)
+	clock := scheduler.NewManualClock(time.Date(2026, time.March, 2, 9, 0, 0, 0, time.UTC))
+	harvestService := NewHarvestService(harvestRepo, userRepo, transactionRepo, db, clock)
+	commentService := NewHarvestCommentService(harvestService, harvestRepo, repository.NewHarvestCommentRepository(db), db)
+
+	for _, username := range []string{"alice", "bob"} {
+		assert.NoError(t, userRepo.Create(&models.User{Username: username}))
+	}
+
+	harvest, err := harvestService.CreateHarvest(HarvestParams{Title: "Weed the garden", BeanAmount: 10})
+	assert.NoError(t, err)
+
+	return harvestService, commentService, harvest
+}
+
+func TestHarvestCommentService_AuthorCanEditAndDelete(t *testing.T) {
+	_, commentService, harvest := setupHarvestCommentTestDB(t)
+
+	comment, err := commentService.AddComment(harvest.ID, "alice", "  Which bed first?  ")
+	assert.NoError(t, err)
+	assert.Equal(t, "Which bed first?", comment.Body)
+	assert.Equal(t, "alice", comment.User.Username)
+	assert.Nil(t, comment.EditedAt)
+
+	_, err = commentService.EditComment(harvest.ID, comment.ID, "bob", "Mine now")
+	assert.Equal(t, ErrNotCommentAuthor, err)
+	assert.Equal(t, ErrNotCommentAuthor, commentService.DeleteComment(harvest.ID, comment.ID, "bob"))
+
+	edited, err := commentService.EditComment(harvest.ID, comment.ID, "alice", "Which bed should go first?")
+	assert.NoError(t, err)
+	assert.Equal(t, "Which bed should go first?", edited.Body)
+	assert.NotNil(t, edited.EditedAt)
+
+	assert.NoError(t, commentService.DeleteComment(harvest.ID, comment.ID, "alice"))
+	assert.Equal(t, ErrHarvestCommentNotFound, commentService.DeleteComment(harvest.ID, comment.ID, "alice"))
+
+	comments, total, err := commentService.ListComments(harvest.ID, 1, 20)
+	assert.NoError(t, err)
+	assert.Empty(t, comments)
+	assert.Equal(t, int64(0), total)
+}
+
+func TestHarvestCommentService_Guards(t *testing.T) {
+	harvestService, commentService, harvest := setupHarvestCommentTestDB(t)
+
+	_, err := commentService.AddComment(harvest.ID, "alice", "   ")
+	assert.Equal(t, ErrInvalidHarvestComment, err)
+	_, err = commentService.AddComment(harvest.ID, "alice", strings.Repeat("a", MaxHarvestCommentLength+1))
+	assert.Equal(t, ErrInvalidHarvestComment, err)
+	_, err = commentService.AddComment(harvest.ID+100, "alice", "Hello")
+	assert.Equal(t, ErrHarvestNotFound, err)
+	_, err = commentService.AddComment(harvest.ID, "nobody", "Hello")
+	assert.Equal(t, ErrUserNotFound, err)
+	_, err = commentService.AddComment(harvest.ID, models.SystemUsername, "Hello")
+	assert.Error(t, err)
+
+	comment, err := commentService.AddComment(harvest.ID, "alice", "Hello")
+	assert.NoError(t, err)
+
+	other, err := harvestService.CreateHarvest(HarvestParams{Title: "Paint the fence", BeanAmount: 5})
+	assert.NoError(t, err)
+	_, err = commentService.EditComment(other.ID, comment.ID, "alice", "Moved")
+	assert.Equal(t, ErrHarvestCommentNotFound, err)
+	assert.Equal(t, ErrHarvestCommentNotFound, commentService.RemoveComment(other.ID, comment.ID, "admin", "spam"))
+}
+
+func TestHarvestCommentService_RemovalAndTimeline(t *testing.T) {
+	harvestService, commentService, harvest := setupHarvestCommentTestDB(t)
+
+	first, err := commentService.AddComment(harvest.ID, "alice", "I can do it")
+	assert.NoError(t, err)
+	_, err = harvestService.ClaimHarvest(harvest.ID, "alice")
+	assert.NoError(t, err)
+	second, err := commentService.AddComment(harvest.ID, "bob", "Buy my seeds")
+	assert.NoError(t, err)
+
+	assert.NoError(t, commentService.RemoveComment(harvest.ID, second.ID, "admin", "spam"))
+
+	comments, total, err := commentService.ListComments(harvest.ID, 1, 20)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	if assert.Len(t, comments, 1) {
+		assert.Equal(t, first.ID, comments[0].ID)
+	}
+
+	events, err := harvestService.ListEvents(harvest.ID)
+	assert.NoError(t, err)
+	for _, event := range events {
+		assert.NotContains(t, models.HarvestCommentEventTypes, event.Type)
+	}
+
+	timeline, total, err := harvestService.ListTimeline(harvest.ID, 1, 20)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), total)
+	types := make([]models.HarvestEventType, len(timeline))
+	for i, event := range timeline {
+		types[i] = event.Type
+	}
+	assert.Equal(t, []models.HarvestEventType{
+		models.HarvestEventCreated,
+		models.HarvestEventCommented,
+		models.HarvestEventClaimed,
+		models.HarvestEventCommented,
+		models.HarvestEventCommentRemoved,
+	}, types)
+
+	if assert.NotNil(t, timeline[1].Comment) {
+		assert.Equal(t, "I can do it", timeline[1].Comment.Body)
+		assert.Equal(t, "alice", timeline[1].Comment.User.Username)
+	}
+	removed := timeline[4]
+	assert.Equal(t, "admin", removed.Actor)
+	assert.Equal(t, "spam", removed.Note)
+	if assert.NotNil(t, removed.Comment) {
+		assert.True(t, removed.Comment.DeletedAt.Valid)
+		assert.Equal(t, "admin", removed.Comment.RemovedBy)
+		assert.Equal(t, "bob", removed.Comment.User.Username)
+	}
+
+	page, total, err := harvestService.ListTimeline(harvest.ID, 2, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), total)
+	if assert.Len(t, page, 2) {
+		assert.Equal(t, models.HarvestEventClaimed, page[0].Type)
+	}
+}
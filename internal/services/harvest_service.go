@@ -3,24 +3,102 @@ package services
 import (
 	"errors"
 	"fmt"
+	"log"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
 
+	"github.com/h4ks-com/bean-bank/internal/database"
+	"github.com/h4ks-com/bean-bank/internal/duration"
 	"github.com/h4ks-com/bean-bank/internal/models"
 	"github.com/h4ks-com/bean-bank/internal/repository"
+	"github.com/h4ks-com/bean-bank/internal/scheduler"
 	"gorm.io/gorm"
-	"gorm.io/gorm/clause"
 )
 
 var (
-	ErrHarvestNotFound        = errors.New("harvest not found")
+	ErrHarvestNotFound         = errors.New("harvest not found")
 	ErrHarvestAlreadyCompleted = errors.New("harvest already completed")
-	ErrNoAssignedUser         = errors.New("harvest has no assigned user")
+	ErrNoAssignedUser          = errors.New("harvest has no assigned user")
+	ErrHarvestNotOpen          = errors.New("harvest is not open for claiming")
+	ErrHarvestNotAssignable    = errors.New("harvest can no longer be reassigned")
+	ErrNotHarvestAssignee      = errors.New("you are not assigned to this harvest")
+	ErrHarvestNotSubmittable   = errors.New("harvest can only be submitted while claimed or after a rejection")
+	ErrHarvestNotSubmitted     = errors.New("harvest has no submission awaiting review")
+	ErrHarvestNotWithdrawable  = errors.New("harvest has already been approved")
+	ErrInvalidHarvestProof     = errors.New("proof must be between 1 and 5000 characters")
+	ErrInvalidHarvestLinks     = errors.New("links must be at most 10 http or https URLs of up to 500 characters")
+	ErrReviewCommentRequired   = errors.New("a comment is required when rejecting a submission")
+	ErrHarvestNotJoinable      = errors.New("only a claimed harvest can be joined")
+	ErrAlreadyParticipant      = errors.New("you are already working on this harvest")
+	ErrExplicitSplitJoin       = errors.New("harvest has an explicit reward split, ask an admin to add you")
+	ErrInvalidParticipants     = errors.New("participants must be 1 to 20 different users")
+	ErrInvalidSplit            = errors.New("split must be equal or explicit")
+	ErrSplitMismatch           = errors.New("explicit shares must each be at least 1 and add up to the harvest reward")
+	ErrRewardTooSmall          = errors.New("reward is too small to give every participant at least one bean")
+	ErrInvalidDeadline         = errors.New("deadline must be a duration such as 36h, 7d or 2w, or an RFC 3339 timestamp in the future")
+	ErrInvalidClaimTimeout     = errors.New("claim_timeout must be a duration of at least a minute, such as 90m, 48h or 3d")
+	ErrBountyRewardFixed       = errors.New("a bounty's reward is its escrow; contributors add to it with fund")
+	ErrBountyEscrowHeld        = errors.New("bounty still holds escrowed beans, cancel it first to refund its contributors")
+	ErrInvalidHarvestCategory  = errors.New("category must be up to 32 lower-case letters, digits, dashes or underscores")
+	ErrInvalidHarvestTags      = errors.New("tags must be at most 10 labels of up to 32 lower-case letters, digits, dashes or underscores")
+	ErrHarvestNotCompleted     = errors.New("harvest has not been completed")
+	ErrInvalidClawbackPolicy   = errors.New("policy must be refuse, allow_negative or partial")
+	ErrClawbackShortfall       = errors.New("a recipient no longer holds their whole share of the reward")
+	ErrBountyPartialClawback   = errors.New("a bounty's escrow must be restored in full, use refuse or allow_negative")
+	ErrOwnBounty               = errors.New("you cannot work on a bounty you posted")
+	ErrNoPayoutsOnRecord       = errors.New("harvest has no payouts on record to claw back, it was paid before payouts were tracked")
+	ErrPayoutRecipientGone     = errors.New("a recipient of the reward no longer has a wallet")
 )
 
+// ClawbackPolicy decides what reverting a completion does when a recipient
+// has already spent some of their reward.
+type ClawbackPolicy string
+
+const (
+	// ClawbackRefuse leaves the harvest paid and fails the revert.
+	ClawbackRefuse ClawbackPolicy = "refuse"
+	// ClawbackAllowNegative takes the whole share back, leaving the
+	// recipient with a negative balance.
+	ClawbackAllowNegative ClawbackPolicy = "allow_negative"
+	// ClawbackPartial takes back what the recipient still has and writes
+	// off the rest.
+	ClawbackPartial ClawbackPolicy = "partial"
+)
+
+// HarvestClawback is what reverting a completion took back from one
+// recipient. Recovered is less than Owed when a partial clawback wrote some
+// of it off.
+type HarvestClawback struct {
+	Username  string
+	Owed      int
+	Recovered int
+}
+
+const (
+	// MaxHarvestProofLength caps the proof text of a submission.
+	MaxHarvestProofLength = 5000
+	// MaxHarvestLinks caps how many links one submission may carry.
+	MaxHarvestLinks = 10
+	// MaxHarvestParticipants caps how many users may work on one harvest.
+	MaxHarvestParticipants = 20
+	// MaxHarvestTags caps how many tags one harvest may carry.
+	MaxHarvestTags         = 10
+	maxHarvestLinkLength   = 500
+	harvestExpiryBatchSize = 100
+	harvestLabelListLimit  = 100
+)
+
+var harvestLabelPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
 type HarvestService struct {
 	harvestRepo     *repository.HarvestRepository
 	userRepo        *repository.UserRepository
 	transactionRepo *repository.TransactionRepository
 	db              *gorm.DB
+	clock           scheduler.Clock
 }
 
 func NewHarvestService(
@@ -28,24 +106,63 @@ func NewHarvestService(
 	userRepo *repository.UserRepository,
 	transactionRepo *repository.TransactionRepository,
 	db *gorm.DB,
+	clock scheduler.Clock,
 ) *HarvestService {
 	return &HarvestService{
 		harvestRepo:     harvestRepo,
 		userRepo:        userRepo,
 		transactionRepo: transactionRepo,
 		db:              db,
+		clock:           clock,
 	}
 }
 
-func (s *HarvestService) CreateHarvest(title, description string, beanAmount int) (*models.Harvest, error) {
+// HarvestParams describes a harvest to create or the new details of one being
+// edited. Deadline is a duration from now such as 7d or an RFC 3339 timestamp;
+// ClaimTimeout is how long a claim may go without a submission, such as 48h
+// or 3d. Either may be empty for none. Category and Tags are case-insensitive
+// labels; duplicate tags are dropped.
+type HarvestParams struct {
+	Title        string
+	Description  string
+	BeanAmount   int
+	Deadline     string
+	ClaimTimeout string
+	Category     string
+	Tags         []string
+}
+
+func (s *HarvestService) CreateHarvest(params HarvestParams) (*models.Harvest, error) {
+	now := s.clock.Now()
+	dueAt, claimTimeout, err := parseHarvestTiming(params, now)
+	if err != nil {
+		return nil, err
+	}
+	category, tags, err := parseHarvestLabels(params.Category, params.Tags)
+	if err != nil {
+		return nil, err
+	}
+
 	harvest := &models.Harvest{
-		Title:       title,
-		Description: description,
-		BeanAmount:  beanAmount,
-		Completed:   false,
+		Title:               params.Title,
+		Description:         params.Description,
+		BeanAmount:          params.BeanAmount,
+		DueAt:               dueAt,
+		ClaimTimeoutSeconds: int64(claimTimeout / time.Second),
+		Status:              models.HarvestOpen,
+		Completed:           false,
+		Category:            category,
 	}
 
-	err := s.harvestRepo.Create(harvest)
+	err = database.Transaction(s.db, func(tx *gorm.DB) error {
+		if err := s.harvestRepo.CreateInTx(tx, harvest); err != nil {
+			return err
+		}
+		if harvest.Tags, err = s.harvestRepo.SetTagsInTx(tx, harvest.ID, tags); err != nil {
+			return err
+		}
+		return s.recordEvent(tx, harvest, models.HarvestEventCreated, "", "", "")
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -53,30 +170,172 @@ func (s *HarvestService) CreateHarvest(title, description string, beanAmount int
 	return harvest, nil
 }
 
-func (s *HarvestService) UpdateHarvest(id uint, title, description string, beanAmount int) (*models.Harvest, error) {
-	harvest, err := s.harvestRepo.FindByID(id)
+// spawnInTx creates the next harvest of a template, claimed by the template's
+// assignee if it has one.
+func (s *HarvestService) spawnInTx(tx *gorm.DB, template *models.HarvestTemplate, now time.Time) (*models.Harvest, error) {
+	harvest := &models.Harvest{
+		Title:               template.Title,
+		Description:         template.Description,
+		BeanAmount:          template.BeanAmount,
+		ClaimTimeoutSeconds: template.ClaimTimeoutSeconds,
+		Status:              models.HarvestOpen,
+		TemplateID:          &template.ID,
+		Category:            template.Category,
+	}
+	if template.DeadlineSeconds > 0 {
+		dueAt := now.Add(time.Duration(template.DeadlineSeconds) * time.Second)
+		harvest.DueAt = &dueAt
+	}
+
+	if err := s.harvestRepo.CreateInTx(tx, harvest); err != nil {
+		return nil, err
+	}
+	tags, err := s.harvestRepo.SetTagsInTx(tx, harvest.ID, template.TagList())
 	if err != nil {
 		return nil, err
 	}
+	harvest.Tags = tags
+	note := fmt.Sprintf("Spawned from template #%d", template.ID)
+	if err := s.recordEvent(tx, harvest, models.HarvestEventCreated, "", "", note); err != nil {
+		return nil, err
+	}
 
-	harvest.Title = title
-	harvest.Description = description
-	harvest.BeanAmount = beanAmount
+	if template.AssigneeID == nil {
+		return harvest, nil
+	}
 
-	err = s.harvestRepo.Update(harvest)
-	if err != nil {
+	participants := []models.HarvestParticipant{{HarvestID: harvest.ID, UserID: *template.AssigneeID}}
+	if err := s.replaceParticipants(tx, harvest, participants); err != nil {
+		return nil, err
+	}
+	s.startClaimClock(harvest, now)
+	if err := s.transition(tx, harvest, models.HarvestClaimed, models.HarvestEventAssigned, "", ""); err != nil {
 		return nil, err
 	}
-
 	return harvest, nil
 }
 
-func (s *HarvestService) AssignUser(harvestID, userID uint) (*models.Harvest, error) {
-	harvest, err := s.harvestRepo.FindByID(harvestID)
+// UpdateHarvest replaces the details of a harvest. Giving an expired harvest a
+// new deadline, or none, reopens it for claiming.
+func (s *HarvestService) UpdateHarvest(id uint, params HarvestParams) (*models.Harvest, error) {
+	now := s.clock.Now()
+	dueAt, claimTimeout, err := parseHarvestTiming(params, now)
+	if err != nil {
+		return nil, err
+	}
+	category, tags, err := parseHarvestLabels(params.Category, params.Tags)
+	if err != nil {
+		return nil, err
+	}
+
+	err = database.Transaction(s.db, func(tx *gorm.DB) error {
+		harvest, err := s.lockHarvest(tx, id)
+		if err != nil {
+			return err
+		}
+
+		participants, err := s.harvestRepo.ListParticipantsInTx(tx, harvest.ID)
+		if err != nil {
+			return err
+		}
+
+		if harvest.IsBounty() && params.BeanAmount != harvest.BeanAmount {
+			return ErrBountyRewardFixed
+		}
+
+		harvest.Title = params.Title
+		harvest.Description = params.Description
+		harvest.BeanAmount = params.BeanAmount
+		harvest.DueAt = dueAt
+		harvest.ClaimTimeoutSeconds = int64(claimTimeout / time.Second)
+		harvest.Category = category
+		harvest.Participants = participants
+
+		if err := checkSplit(harvest); err != nil {
+			return err
+		}
+		harvest.Participants = nil
+
+		if _, err := s.harvestRepo.SetTagsInTx(tx, harvest.ID, tags); err != nil {
+			return err
+		}
+
+		switch {
+		case harvest.ClaimTimeoutSeconds == 0:
+			harvest.ClaimExpiresAt = nil
+		case harvest.ClaimExpiresAt == nil && (harvest.Status == models.HarvestClaimed || harvest.Status == models.HarvestRejected):
+			s.startClaimClock(harvest, now)
+		}
+
+		if harvest.Status == models.HarvestExpired {
+			if err := s.harvestRepo.ClearParticipantsInTx(tx, harvest.ID); err != nil {
+				return err
+			}
+			harvest.AssignedUserID = nil
+			harvest.AssignedUser = nil
+			harvest.SplitMode = models.HarvestSplitEqual
+			return s.transition(tx, harvest, models.HarvestOpen, models.HarvestEventReopened, "", "")
+		}
+
+		return s.harvestRepo.UpdateInTx(tx, harvest)
+	})
 	if err != nil {
 		return nil, err
 	}
 
+	return s.harvestRepo.FindByID(id)
+}
+
+// parseHarvestTiming reads the deadline and claim timeout of a harvest.
+func parseHarvestTiming(params HarvestParams, now time.Time) (*time.Time, time.Duration, error) {
+	dueAt, err := ParseExpiry(params.Deadline, now, 0)
+	if err != nil {
+		return nil, 0, ErrInvalidDeadline
+	}
+
+	var claimTimeout time.Duration
+	if value := strings.TrimSpace(params.ClaimTimeout); value != "" {
+		claimTimeout, err = duration.Parse(value)
+		if err != nil || claimTimeout < time.Minute {
+			return nil, 0, ErrInvalidClaimTimeout
+		}
+	}
+
+	return dueAt, claimTimeout, nil
+}
+
+// parseHarvestLabels lower-cases and checks the category and tags of a
+// harvest, dropping duplicate tags.
+func parseHarvestLabels(category string, tags []string) (string, []string, error) {
+	category = strings.ToLower(strings.TrimSpace(category))
+	if category != "" && !harvestLabelPattern.MatchString(category) {
+		return "", nil, ErrInvalidHarvestCategory
+	}
+
+	names := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if !harvestLabelPattern.MatchString(tag) {
+			return "", nil, ErrInvalidHarvestTags
+		}
+		if seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		names = append(names, tag)
+	}
+	if len(names) > MaxHarvestTags {
+		return "", nil, ErrInvalidHarvestTags
+	}
+
+	return category, names, nil
+}
+
+// AssignUser lets an admin hand a harvest to a single user, replacing whoever
+// was working on it. Harvests under review or already approved keep their
+// participants.
+func (s *HarvestService) AssignUser(harvestID, userID uint) (*models.Harvest, error) {
 	user, err := s.userRepo.FindByID(userID)
 	if err != nil {
 		return nil, err
@@ -84,10 +343,123 @@ func (s *HarvestService) AssignUser(harvestID, userID uint) (*models.Harvest, er
 	if user == nil {
 		return nil, ErrUserNotFound
 	}
+	return s.SetParticipants(harvestID, models.HarvestSplitEqual, []HarvestShare{{Username: user.Username}}, "")
+}
+
+func (s *HarvestService) AssignUserByUsername(harvestID uint, username string) (*models.Harvest, error) {
+	return s.SetParticipants(harvestID, models.HarvestSplitEqual, []HarvestShare{{Username: username}}, "")
+}
+
+// HarvestShare names a participant and, for an explicit split, their share of
+// the reward.
+type HarvestShare struct {
+	Username string
+	Amount   int
+}
+
+// SetParticipants lets an admin replace everyone working on a harvest. The
+// first participant becomes the lead. With an explicit split every share must
+// be at least one bean and the shares must add up to the reward; with an
+// equal split the amounts are ignored.
+func (s *HarvestService) SetParticipants(harvestID uint, split models.HarvestSplitMode, shares []HarvestShare, actor string) (*models.Harvest, error) {
+	if split != models.HarvestSplitEqual && split != models.HarvestSplitExplicit {
+		return nil, ErrInvalidSplit
+	}
+	if len(shares) == 0 || len(shares) > MaxHarvestParticipants {
+		return nil, ErrInvalidParticipants
+	}
+
+	users := make([]*models.User, len(shares))
+	seen := make(map[string]bool, len(shares))
+	for i, share := range shares {
+		if seen[share.Username] {
+			return nil, ErrInvalidParticipants
+		}
+		seen[share.Username] = true
+
+		if models.IsReservedUsername(share.Username) {
+			return nil, ErrReservedAccount
+		}
+		user, err := s.userRepo.FindByUsername(share.Username)
+		if err != nil {
+			return nil, err
+		}
+		if user == nil {
+			return nil, ErrUserNotFound
+		}
+		users[i] = user
+	}
+
+	err := database.Transaction(s.db, func(tx *gorm.DB) error {
+		harvest, err := s.lockHarvest(tx, harvestID)
+		if err != nil {
+			return err
+		}
+
+		switch harvest.Status {
+		case models.HarvestOpen, models.HarvestClaimed, models.HarvestRejected:
+		default:
+			return ErrHarvestNotAssignable
+		}
+
+		participants := make([]models.HarvestParticipant, len(users))
+		names := make([]string, len(users))
+		for i, user := range users {
+			participants[i] = models.HarvestParticipant{HarvestID: harvest.ID, UserID: user.ID}
+			if split == models.HarvestSplitExplicit {
+				participants[i].Amount = shares[i].Amount
+			}
+			names[i] = user.Username
+		}
+
+		harvest.SplitMode = split
+		harvest.Participants = participants
+		if err := checkSplit(harvest); err != nil {
+			return err
+		}
+
+		if err := s.replaceParticipants(tx, harvest, participants); err != nil {
+			return err
+		}
+		s.startClaimClock(harvest, s.clock.Now())
+		return s.transition(tx, harvest, models.HarvestClaimed, models.HarvestEventAssigned, actor, "Assigned to "+strings.Join(names, ", "))
+	})
+	if err != nil {
+		return nil, err
+	}
 
-	harvest.AssignedUserID = &userID
+	return s.harvestRepo.FindByID(harvestID)
+}
+
+// ClaimHarvest assigns an open harvest to the user who asked for it. The
+// poster of a bounty cannot claim it.
+func (s *HarvestService) ClaimHarvest(harvestID uint, username string) (*models.Harvest, error) {
+	user, err := s.findParticipantUser(username)
+	if err != nil {
+		return nil, err
+	}
+
+	err = database.Transaction(s.db, func(tx *gorm.DB) error {
+		harvest, err := s.lockHarvest(tx, harvestID)
+		if err != nil {
+			return err
+		}
+		now := s.clock.Now()
+		if harvest.Status != models.HarvestOpen || (harvest.DueAt != nil && !harvest.DueAt.After(now)) {
+			return ErrHarvestNotOpen
+		}
+		if harvest.PosterID != nil && *harvest.PosterID == user.ID {
+			return ErrOwnBounty
+		}
 
-	err = s.harvestRepo.Update(harvest)
+		harvest.SplitMode = models.HarvestSplitEqual
+		participants := []models.HarvestParticipant{{HarvestID: harvest.ID, UserID: user.ID}}
+		if err := s.replaceParticipants(tx, harvest, participants); err != nil {
+			return err
+		}
+		s.startClaimClock(harvest, now)
+		return s.transition(tx, harvest, models.HarvestClaimed, models.HarvestEventClaimed, username, "")
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -95,27 +467,250 @@ func (s *HarvestService) AssignUser(harvestID, userID uint) (*models.Harvest, er
 	return s.harvestRepo.FindByID(harvestID)
 }
 
-func (s *HarvestService) AssignUserByUsername(harvestID uint, username string) (*models.Harvest, error) {
-	user, err := s.userRepo.FindByUsername(username)
+// JoinHarvest adds the user to a harvest someone else has already claimed.
+// The reward is then split equally, so harvests with an explicit split can
+// only be changed by an admin. As with claiming, a bounty's poster cannot
+// join it.
+func (s *HarvestService) JoinHarvest(harvestID uint, username string) (*models.Harvest, error) {
+	user, err := s.findParticipantUser(username)
 	if err != nil {
 		return nil, err
 	}
-	if user == nil {
-		return nil, ErrUserNotFound
+
+	err = database.Transaction(s.db, func(tx *gorm.DB) error {
+		harvest, err := s.lockHarvest(tx, harvestID)
+		if err != nil {
+			return err
+		}
+		if harvest.Status != models.HarvestClaimed && harvest.Status != models.HarvestRejected {
+			return ErrHarvestNotJoinable
+		}
+		if harvest.SplitMode == models.HarvestSplitExplicit {
+			return ErrExplicitSplitJoin
+		}
+		if harvest.PosterID != nil && *harvest.PosterID == user.ID {
+			return ErrOwnBounty
+		}
+
+		participants, err := s.harvestRepo.ListParticipantsInTx(tx, harvest.ID)
+		if err != nil {
+			return err
+		}
+		for _, p := range participants {
+			if p.UserID == user.ID {
+				return ErrAlreadyParticipant
+			}
+		}
+		if len(participants) >= MaxHarvestParticipants {
+			return ErrInvalidParticipants
+		}
+
+		participant := models.HarvestParticipant{HarvestID: harvest.ID, UserID: user.ID}
+		harvest.Participants = append(participants, participant)
+		if err := checkSplit(harvest); err != nil {
+			return err
+		}
+
+		if err := s.harvestRepo.AddParticipantInTx(tx, &participant); err != nil {
+			return fmt.Errorf("failed to add participant: %w", err)
+		}
+		return s.transition(tx, harvest, harvest.Status, models.HarvestEventJoined, username, "")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.harvestRepo.FindByID(harvestID)
+}
+
+// WithdrawHarvest takes the user off a harvest. When the last participant
+// leaves the harvest goes back to open and a submission still awaiting review
+// is withdrawn with it. When others remain, the next participant becomes the
+// lead and an explicit split falls back to an equal one.
+func (s *HarvestService) WithdrawHarvest(harvestID uint, username string) (*models.Harvest, error) {
+	err := database.Transaction(s.db, func(tx *gorm.DB) error {
+		harvest, err := s.lockHarvest(tx, harvestID)
+		if err != nil {
+			return err
+		}
+		participants, err := s.harvestRepo.ListParticipantsInTx(tx, harvest.ID)
+		if err != nil {
+			return err
+		}
+
+		leaving := -1
+		for i, p := range participants {
+			if p.User.Username == username {
+				leaving = i
+			}
+		}
+		if leaving < 0 {
+			return ErrNotHarvestAssignee
+		}
+
+		switch harvest.Status {
+		case models.HarvestClaimed, models.HarvestSubmitted, models.HarvestRejected:
+		default:
+			return ErrHarvestNotWithdrawable
+		}
+
+		if err := s.harvestRepo.RemoveParticipantInTx(tx, harvest.ID, participants[leaving].UserID); err != nil {
+			return err
+		}
+		remaining := append(participants[:leaving:leaving], participants[leaving+1:]...)
+
+		if len(remaining) > 0 {
+			note := ""
+			harvest.AssignedUserID = &remaining[0].UserID
+			harvest.AssignedUser = nil
+			if harvest.SplitMode == models.HarvestSplitExplicit {
+				harvest.SplitMode = models.HarvestSplitEqual
+				err := tx.Model(&models.HarvestParticipant{}).
+					Where("harvest_id = ?", harvest.ID).
+					Update("amount", 0).Error
+				if err != nil {
+					return err
+				}
+				note = "Reward is now split equally"
+			}
+			return s.transition(tx, harvest, harvest.Status, models.HarvestEventWithdrawn, username, note)
+		}
+
+		submission, err := s.harvestRepo.FindPendingSubmissionForUpdate(tx, harvest.ID)
+		if err != nil {
+			return err
+		}
+		if submission != nil {
+			submission.Status = models.HarvestSubmissionWithdrawn
+			if err := s.harvestRepo.UpdateSubmissionInTx(tx, submission); err != nil {
+				return err
+			}
+		}
+
+		harvest.AssignedUserID = nil
+		harvest.AssignedUser = nil
+		harvest.SplitMode = models.HarvestSplitEqual
+		harvest.ClaimExpiresAt = nil
+		return s.transition(tx, harvest, models.HarvestOpen, models.HarvestEventWithdrawn, username, "")
+	})
+	if err != nil {
+		return nil, err
 	}
-	return s.AssignUser(harvestID, user.ID)
+
+	return s.harvestRepo.FindByID(harvestID)
 }
 
-func (s *HarvestService) CompleteHarvest(harvestID uint) (*models.Harvest, error) {
-	var harvest *models.Harvest
+// SubmitHarvest hands in proof of the work for an admin to review. Any
+// participant may submit on behalf of the team. Links must be http or https
+// URLs.
+func (s *HarvestService) SubmitHarvest(harvestID uint, username, proof string, links []string) (*models.HarvestSubmission, error) {
+	proof = strings.TrimSpace(proof)
+	if proof == "" || utf8.RuneCountInString(proof) > MaxHarvestProofLength {
+		return nil, ErrInvalidHarvestProof
+	}
+
+	cleanLinks, err := normalizeHarvestLinks(links)
+	if err != nil {
+		return nil, err
+	}
+
+	var submission *models.HarvestSubmission
+	err = database.Transaction(s.db, func(tx *gorm.DB) error {
+		harvest, err := s.lockHarvest(tx, harvestID)
+		if err != nil {
+			return err
+		}
+		participant, err := s.findParticipant(tx, harvest, username)
+		if err != nil {
+			return err
+		}
+		if harvest.Status != models.HarvestClaimed && harvest.Status != models.HarvestRejected {
+			return ErrHarvestNotSubmittable
+		}
+
+		submission = &models.HarvestSubmission{
+			HarvestID: harvest.ID,
+			UserID:    participant.UserID,
+			Proof:     proof,
+			Links:     strings.Join(cleanLinks, "\n"),
+			Status:    models.HarvestSubmissionPending,
+		}
+		if err := s.harvestRepo.CreateSubmissionInTx(tx, submission); err != nil {
+			return fmt.Errorf("failed to create submission: %w", err)
+		}
+
+		harvest.ClaimExpiresAt = nil
+		return s.transition(tx, harvest, models.HarvestSubmitted, models.HarvestEventSubmitted, username, "")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return submission, nil
+}
+
+// ReviewHarvest approves or rejects the submission awaiting review. Approval
+// pays the reward straight away; a rejection needs a comment and lets the
+// participants resubmit.
+func (s *HarvestService) ReviewHarvest(harvestID uint, reviewer string, approve bool, comment string) (*models.Harvest, error) {
+	comment = strings.TrimSpace(comment)
+	if !approve && comment == "" {
+		return nil, ErrReviewCommentRequired
+	}
 
-	err := s.db.Transaction(func(tx *gorm.DB) error {
-		var err error
-		harvest, err = s.harvestRepo.FindByIDForUpdate(tx, harvestID)
+	err := database.Transaction(s.db, func(tx *gorm.DB) error {
+		harvest, err := s.lockHarvest(tx, harvestID)
 		if err != nil {
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				return ErrHarvestNotFound
+			return err
+		}
+		if harvest.Status != models.HarvestSubmitted {
+			return ErrHarvestNotSubmitted
+		}
+
+		submission, err := s.harvestRepo.FindPendingSubmissionForUpdate(tx, harvest.ID)
+		if err != nil {
+			return err
+		}
+		if submission == nil {
+			return ErrHarvestNotSubmitted
+		}
+
+		now := s.clock.Now()
+		submission.Reviewer = reviewer
+		submission.ReviewComment = comment
+		submission.ReviewedAt = &now
+
+		if !approve {
+			submission.Status = models.HarvestSubmissionRejected
+			if err := s.harvestRepo.UpdateSubmissionInTx(tx, submission); err != nil {
+				return err
 			}
+			s.startClaimClock(harvest, now)
+			return s.transition(tx, harvest, models.HarvestRejected, models.HarvestEventRejected, reviewer, comment)
+		}
+
+		submission.Status = models.HarvestSubmissionApproved
+		if err := s.harvestRepo.UpdateSubmissionInTx(tx, submission); err != nil {
+			return err
+		}
+		if err := s.transition(tx, harvest, models.HarvestApproved, models.HarvestEventApproved, reviewer, comment); err != nil {
+			return err
+		}
+		return s.payInTx(tx, harvest, reviewer)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.harvestRepo.FindByID(harvestID)
+}
+
+// CompleteHarvest pays the participants straight away, skipping review. Any
+// submission still awaiting review counts as approved.
+func (s *HarvestService) CompleteHarvest(harvestID uint) (*models.Harvest, error) {
+	err := database.Transaction(s.db, func(tx *gorm.DB) error {
+		harvest, err := s.lockHarvest(tx, harvestID)
+		if err != nil {
 			return err
 		}
 
@@ -127,79 +722,502 @@ func (s *HarvestService) CompleteHarvest(harvestID uint) (*models.Harvest, error
 			return ErrNoAssignedUser
 		}
 
-		var assignedUser models.User
-		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&assignedUser, harvest.AssignedUserID).Error
+		submission, err := s.harvestRepo.FindPendingSubmissionForUpdate(tx, harvest.ID)
 		if err != nil {
 			return err
 		}
+		if submission != nil {
+			now := s.clock.Now()
+			submission.Status = models.HarvestSubmissionApproved
+			submission.ReviewedAt = &now
+			if err := s.harvestRepo.UpdateSubmissionInTx(tx, submission); err != nil {
+				return err
+			}
+		}
+
+		return s.payInTx(tx, harvest, "")
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return s.harvestRepo.FindByID(harvestID)
+}
+
+// RevertCompletion undoes a completion an admin made by mistake. Each
+// recipient's share goes back where it came from, the mint or a bounty's
+// escrow, in a harvest_clawback transaction. Only payouts since the last
+// revert are taken back. The harvest then reopens with no participants so
+// that it can be assigned again. A reason is required.
+func (s *HarvestService) RevertCompletion(harvestID uint, admin string, policy ClawbackPolicy, reason string) (*models.Harvest, []HarvestClawback, error) {
+	switch policy {
+	case ClawbackRefuse, ClawbackAllowNegative, ClawbackPartial:
+	default:
+		return nil, nil, ErrInvalidClawbackPolicy
+	}
 
-		assignedUser.BeanAmount += harvest.BeanAmount
+	reason, err := SanitizeNote(reason)
+	if err != nil {
+		return nil, nil, err
+	}
+	if reason == "" {
+		return nil, nil, ErrReasonRequired
+	}
 
-		err = tx.Save(&assignedUser).Error
+	var clawbacks []HarvestClawback
+	err = database.Transaction(s.db, func(tx *gorm.DB) error {
+		harvest, err := s.lockHarvest(tx, harvestID)
 		if err != nil {
 			return err
 		}
+		if !harvest.Completed {
+			return ErrHarvestNotCompleted
+		}
+		if harvest.IsBounty() && policy == ClawbackPartial {
+			return ErrBountyPartialClawback
+		}
 
-		var systemUser models.User
-		err = tx.Where("username = ?", "system").First(&systemUser).Error
+		payouts, err := s.harvestRepo.ListPayoutsInTx(tx, harvest.ID, harvest.SettledThroughTransactionID)
 		if err != nil {
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				systemUser = models.User{
-					Username:   "system",
-					BeanAmount: 0,
-				}
-				err = tx.Create(&systemUser).Error
-				if err != nil {
-					return err
+			return err
+		}
+		// Reopening a harvest whose reward can't be taken back would let
+		// it be paid twice.
+		if len(payouts) == 0 {
+			return ErrNoPayoutsOnRecord
+		}
+
+		usernames := make([]string, 0, len(payouts)+1)
+		for _, p := range payouts {
+			usernames = append(usernames, p.Username)
+		}
+		payee, note := models.MintUsername, "Harvest completion reverted: %s"
+		if harvest.IsBounty() {
+			payee, note = models.SystemUsername, "Bounty completion reverted: %s"
+		}
+
+		users, err := s.userRepo.LockUsers(tx, append(usernames, payee)...)
+		if err != nil {
+			return err
+		}
+
+		clawbacks = make([]HarvestClawback, 0, len(payouts))
+		for _, p := range payouts {
+			recipient := users[p.Username]
+			if recipient == nil {
+				return ErrPayoutRecipientGone
+			}
+			recovered := p.Amount
+			if recipient.BeanAmount < p.Amount {
+				switch policy {
+				case ClawbackRefuse:
+					return ErrClawbackShortfall
+				case ClawbackPartial:
+					recovered = max(recipient.BeanAmount, 0)
 				}
-			} else {
+			}
+			clawbacks = append(clawbacks, HarvestClawback{Username: p.Username, Owed: p.Amount, Recovered: recovered})
+			if recovered == 0 {
+				continue
+			}
+
+			transaction := &models.Transaction{
+				Amount:    recovered,
+				Note:      fmt.Sprintf(note, harvest.Title),
+				Kind:      models.TransactionKindHarvestClawback,
+				HarvestID: &harvest.ID,
+			}
+			if err := s.transactionRepo.Post(tx, recipient, users[payee], transaction); err != nil {
 				return err
 			}
 		}
 
+		if err := s.harvestRepo.ClearParticipantsInTx(tx, harvest.ID); err != nil {
+			return err
+		}
+		// Whatever a partial clawback wrote off is settled too, so that a
+		// later revert doesn't charge it again.
+		settled, err := s.harvestRepo.LastTransactionIDInTx(tx, harvest.ID)
+		if err != nil {
+			return err
+		}
+		harvest.SettledThroughTransactionID = settled
+		harvest.Completed = false
+		harvest.AssignedUserID = nil
+		harvest.AssignedUser = nil
+		harvest.SplitMode = models.HarvestSplitEqual
+		harvest.ClaimExpiresAt = nil
+		return s.transition(tx, harvest, models.HarvestOpen, models.HarvestEventReverted, admin, describeClawbacks(reason, clawbacks))
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	harvest, err := s.harvestRepo.FindByID(harvestID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return harvest, clawbacks, nil
+}
+
+// describeClawbacks is the note on a revert event: the admin's reason
+// followed by what was taken back from whom.
+func describeClawbacks(reason string, clawbacks []HarvestClawback) string {
+	if len(clawbacks) == 0 {
+		return reason
+	}
+
+	parts := make([]string, len(clawbacks))
+	for i, c := range clawbacks {
+		if c.Recovered == c.Owed {
+			parts[i] = fmt.Sprintf("%d beans from %s", c.Owed, c.Username)
+		} else {
+			parts[i] = fmt.Sprintf("%d of %d beans from %s", c.Recovered, c.Owed, c.Username)
+		}
+	}
+	return reason + ". Clawed back " + strings.Join(parts, ", ")
+}
+
+// payInTx pays each participant's share with one transaction apiece and
+// marks the harvest paid. Rewards are minted, except for bounties, which are
+// paid out of their escrow. Either every participant is paid or none is.
+func (s *HarvestService) payInTx(tx *gorm.DB, harvest *models.Harvest, actor string) error {
+	participants, err := s.harvestRepo.ListParticipantsInTx(tx, harvest.ID)
+	if err != nil {
+		return err
+	}
+	if len(participants) == 0 {
+		return ErrNoAssignedUser
+	}
+
+	harvest.Participants = participants
+	if err := checkSplit(harvest); err != nil {
+		return err
+	}
+	shares := harvest.Shares()
+
+	usernames := make([]string, 0, len(participants)+1)
+	for _, p := range participants {
+		usernames = append(usernames, p.User.Username)
+	}
+	payer, kind, note := models.MintUsername, models.TransactionKindHarvestReward, "Harvest completed: %s"
+	if harvest.IsBounty() {
+		payer, kind, note = models.SystemUsername, models.TransactionKindBountyPayout, "Bounty completed: %s"
+	}
+
+	users, err := s.userRepo.LockUsers(tx, append(usernames, payer)...)
+	if err != nil {
+		return err
+	}
+	payerUser := users[payer]
+
+	paid := make([]string, 0, len(participants))
+	for i, username := range usernames {
+		if shares[i] == 0 {
+			continue
+		}
+
 		transaction := &models.Transaction{
-			FromUserID: systemUser.ID,
-			ToUserID:   assignedUser.ID,
-			Amount:     harvest.BeanAmount,
-			Note:       fmt.Sprintf("Harvest completed: %s", harvest.Title),
+			Amount:    shares[i],
+			Note:      fmt.Sprintf(note, harvest.Title),
+			Kind:      kind,
+			HarvestID: &harvest.ID,
+		}
+		if err := s.transactionRepo.Post(tx, payerUser, users[username], transaction); err != nil {
+			return err
 		}
+		paid = append(paid, fmt.Sprintf("%d beans to %s", shares[i], username))
+	}
+
+	harvest.Completed = true
+	harvest.ClaimExpiresAt = nil
+	harvest.Participants = nil
+	return s.transition(tx, harvest, models.HarvestPaid, models.HarvestEventPaid, actor, "Paid "+strings.Join(paid, ", "))
+}
 
-		err = s.transactionRepo.Create(tx, transaction)
+// startClaimClock gives the participants the harvest's claim timeout, from
+// now, to submit their work.
+func (s *HarvestService) startClaimClock(harvest *models.Harvest, now time.Time) {
+	if harvest.ClaimTimeoutSeconds <= 0 {
+		harvest.ClaimExpiresAt = nil
+		return
+	}
+	expiresAt := now.Add(time.Duration(harvest.ClaimTimeoutSeconds) * time.Second)
+	harvest.ClaimExpiresAt = &expiresAt
+}
+
+// ExpireOverdue expires harvests whose deadline has passed before their work
+// was submitted, and releases claims that ran past the claim timeout so
+// someone else can take the harvest. It returns how many harvests changed.
+func (s *HarvestService) ExpireOverdue(now time.Time) (int, error) {
+	ids, err := s.harvestRepo.FindOverdueIDs(now, harvestExpiryBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	changed := 0
+	var firstErr error
+	for _, id := range ids {
+		ok, err := s.expireHarvest(id, now)
 		if err != nil {
-			return err
+			log.Printf("[Harvests] Failed to expire harvest %d: %v", id, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if ok {
+			changed++
 		}
+	}
+
+	return changed, firstErr
+}
+
+func (s *HarvestService) expireHarvest(id uint, now time.Time) (bool, error) {
+	changed := false
+
+	err := database.Transaction(s.db, func(tx *gorm.DB) error {
+		changed = false
 
-		harvest.Completed = true
-		err = s.harvestRepo.UpdateInTx(tx, harvest)
+		harvest, err := s.lockHarvest(tx, id)
 		if err != nil {
 			return err
 		}
 
+		// Someone may have submitted, withdrawn or been reassigned since the
+		// harvest was listed.
+		switch {
+		case harvest.DueAt != nil && !harvest.DueAt.After(now) &&
+			(harvest.Status == models.HarvestOpen || harvest.Status == models.HarvestClaimed || harvest.Status == models.HarvestRejected):
+			harvest.ClaimExpiresAt = nil
+			changed = true
+			return s.transition(tx, harvest, models.HarvestExpired, models.HarvestEventExpired, "", "Deadline passed")
+
+		case harvest.ClaimExpiresAt != nil && !harvest.ClaimExpiresAt.After(now) &&
+			(harvest.Status == models.HarvestClaimed || harvest.Status == models.HarvestRejected):
+			if err := s.harvestRepo.ClearParticipantsInTx(tx, harvest.ID); err != nil {
+				return err
+			}
+			harvest.AssignedUserID = nil
+			harvest.AssignedUser = nil
+			harvest.SplitMode = models.HarvestSplitEqual
+			harvest.ClaimExpiresAt = nil
+			changed = true
+			return s.transition(tx, harvest, models.HarvestOpen, models.HarvestEventReleased, "", "Claim timed out")
+		}
+
 		return nil
 	})
 
+	return changed, err
+}
+
+// checkSplit makes sure the reward can be paid out to the participants set
+// on the harvest.
+func checkSplit(harvest *models.Harvest) error {
+	if len(harvest.Participants) == 0 {
+		return nil
+	}
+
+	if harvest.SplitMode == models.HarvestSplitExplicit {
+		total := 0
+		for _, p := range harvest.Participants {
+			if p.Amount < 1 {
+				return ErrSplitMismatch
+			}
+			total += p.Amount
+		}
+		if total != harvest.BeanAmount {
+			return ErrSplitMismatch
+		}
+		return nil
+	}
+
+	if harvest.BeanAmount < len(harvest.Participants) {
+		return ErrRewardTooSmall
+	}
+	return nil
+}
+
+// replaceParticipants swaps the participants of a harvest for the given ones
+// and makes the first of them the lead.
+func (s *HarvestService) replaceParticipants(tx *gorm.DB, harvest *models.Harvest, participants []models.HarvestParticipant) error {
+	if err := s.harvestRepo.ClearParticipantsInTx(tx, harvest.ID); err != nil {
+		return err
+	}
+	for i := range participants {
+		if err := s.harvestRepo.AddParticipantInTx(tx, &participants[i]); err != nil {
+			return fmt.Errorf("failed to add participant: %w", err)
+		}
+	}
+
+	harvest.AssignedUserID = &participants[0].UserID
+	harvest.AssignedUser = nil
+	harvest.Participants = nil
+	return nil
+}
+
+// transition saves the harvest in its new status and records the change.
+func (s *HarvestService) transition(tx *gorm.DB, harvest *models.Harvest, to models.HarvestStatus, eventType models.HarvestEventType, actor, note string) error {
+	from := harvest.Status
+	harvest.Status = to
+	if err := s.harvestRepo.UpdateInTx(tx, harvest); err != nil {
+		return err
+	}
+	return s.recordEvent(tx, harvest, eventType, from, actor, note)
+}
+
+func (s *HarvestService) recordEvent(tx *gorm.DB, harvest *models.Harvest, eventType models.HarvestEventType, from models.HarvestStatus, actor, note string) error {
+	event := &models.HarvestEvent{
+		HarvestID:  harvest.ID,
+		Type:       eventType,
+		FromStatus: from,
+		ToStatus:   harvest.Status,
+		Actor:      actor,
+		Note:       note,
+	}
+	if err := s.harvestRepo.CreateEventInTx(tx, event); err != nil {
+		return fmt.Errorf("failed to record harvest event: %w", err)
+	}
+	return nil
+}
+
+func (s *HarvestService) lockHarvest(tx *gorm.DB, harvestID uint) (*models.Harvest, error) {
+	harvest, err := s.harvestRepo.FindByIDForUpdate(tx, harvestID)
 	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrHarvestNotFound
+		}
 		return nil, err
 	}
+	return harvest, nil
+}
+
+// findParticipantUser looks up a user who wants to work on a harvest.
+func (s *HarvestService) findParticipantUser(username string) (*models.User, error) {
+	if models.IsReservedUsername(username) {
+		return nil, ErrReservedAccount
+	}
 
-	return s.harvestRepo.FindByID(harvestID)
+	user, err := s.userRepo.FindByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
+
+func (s *HarvestService) findParticipant(tx *gorm.DB, harvest *models.Harvest, username string) (*models.HarvestParticipant, error) {
+	participants, err := s.harvestRepo.ListParticipantsInTx(tx, harvest.ID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range participants {
+		if participants[i].User.Username == username {
+			return &participants[i], nil
+		}
+	}
+	return nil, ErrNotHarvestAssignee
+}
+
+// normalizeHarvestLinks trims the links, drops blanks and checks that each is
+// an absolute http or https URL.
+func normalizeHarvestLinks(links []string) ([]string, error) {
+	cleaned := make([]string, 0, len(links))
+	for _, link := range links {
+		link = strings.TrimSpace(link)
+		if link == "" {
+			continue
+		}
+		if len(link) > maxHarvestLinkLength {
+			return nil, ErrInvalidHarvestLinks
+		}
+		parsed, err := url.Parse(link)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return nil, ErrInvalidHarvestLinks
+		}
+		cleaned = append(cleaned, link)
+	}
+	if len(cleaned) > MaxHarvestLinks {
+		return nil, ErrInvalidHarvestLinks
+	}
+	return cleaned, nil
 }
 
 func (s *HarvestService) GetHarvest(id uint) (*models.Harvest, error) {
 	return s.harvestRepo.FindByID(id)
 }
 
+// ListEvents returns the history of a harvest, oldest first.
+func (s *HarvestService) ListEvents(harvestID uint) ([]models.HarvestEvent, error) {
+	if _, err := s.findHarvest(harvestID); err != nil {
+		return nil, err
+	}
+	return s.harvestRepo.ListEvents(harvestID)
+}
+
+// ListTimeline returns a page of a harvest's status changes and comments,
+// oldest first, and how many entries there are.
+func (s *HarvestService) ListTimeline(harvestID uint, page, limit int) ([]models.HarvestEvent, int64, error) {
+	if _, err := s.findHarvest(harvestID); err != nil {
+		return nil, 0, err
+	}
+
+	events, err := s.harvestRepo.ListTimeline(harvestID, page, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	count, err := s.harvestRepo.CountTimeline(harvestID)
+	if err != nil {
+		return nil, 0, err
+	}
+	return events, count, nil
+}
+
+// ListSubmissions returns every submission of a harvest, newest first.
+func (s *HarvestService) ListSubmissions(harvestID uint) ([]models.HarvestSubmission, error) {
+	if _, err := s.findHarvest(harvestID); err != nil {
+		return nil, err
+	}
+	return s.harvestRepo.ListSubmissions(harvestID)
+}
+
+func (s *HarvestService) findHarvest(id uint) (*models.Harvest, error) {
+	harvest, err := s.harvestRepo.FindByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrHarvestNotFound
+		}
+		return nil, err
+	}
+	return harvest, nil
+}
+
 func (s *HarvestService) GetAllHarvests() ([]models.Harvest, error) {
 	return s.harvestRepo.FindAll()
 }
 
-func (s *HarvestService) SearchHarvests(query string, page, limit int) ([]models.Harvest, int64, error) {
-	harvests, err := s.harvestRepo.Search(query, page, limit)
+// SearchHarvests returns a page of harvests and how many match in total.
+// Category and tags are matched case-insensitively.
+func (s *HarvestService) SearchHarvests(filter repository.HarvestFilter) ([]models.Harvest, int64, error) {
+	if filter.Now.IsZero() {
+		filter.Now = s.clock.Now()
+	}
+	filter.Category = strings.ToLower(strings.TrimSpace(filter.Category))
+	for i, tag := range filter.Tags {
+		filter.Tags[i] = strings.ToLower(strings.TrimSpace(tag))
+	}
+
+	harvests, err := s.harvestRepo.Search(filter)
 	if err != nil {
 		return nil, 0, err
 	}
 
-	count, err := s.harvestRepo.CountSearch(query)
+	count, err := s.harvestRepo.CountSearch(filter)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -207,6 +1225,28 @@ func (s *HarvestService) SearchHarvests(query string, page, limit int) ([]models
 	return harvests, count, nil
 }
 
+// ListLabels returns the tags and categories in use, most used first.
+func (s *HarvestService) ListLabels() ([]repository.HarvestLabelCount, []repository.HarvestLabelCount, error) {
+	tags, err := s.harvestRepo.ListTags(harvestLabelListLimit)
+	if err != nil {
+		return nil, nil, err
+	}
+	categories, err := s.harvestRepo.ListCategories(harvestLabelListLimit)
+	if err != nil {
+		return nil, nil, err
+	}
+	return tags, categories, nil
+}
+
+// DeleteHarvest removes a harvest. A bounty must be paid or cancelled first
+// so that its escrow is not stranded.
 func (s *HarvestService) DeleteHarvest(id uint) error {
+	harvest, err := s.findHarvest(id)
+	if err != nil {
+		return err
+	}
+	if harvest.IsBounty() && !harvest.Completed && harvest.Status != models.HarvestCancelled {
+		return ErrBountyEscrowHeld
+	}
 	return s.harvestRepo.Delete(id)
 }
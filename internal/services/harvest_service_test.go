@@ -2,14 +2,22 @@ package services
 
 import (
 	"testing"
+	"time"
 
 	"github.com/h4ks-com/bean-bank/internal/database"
 	"github.com/h4ks-com/bean-bank/internal/models"
 	"github.com/h4ks-com/bean-bank/internal/repository"
+	"github.com/h4ks-com/bean-bank/internal/scheduler"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func setupHarvestTestDB(t *testing.T) (*repository.HarvestRepository, *repository.UserRepository, *repository.TransactionRepository, *HarvestService) {
+	harvestRepo, userRepo, transactionRepo, harvestService, _ := setupHarvestTestDBWithClock(t)
+	return harvestRepo, userRepo, transactionRepo, harvestService
+}
+
+func setupHarvestTestDBWithClock(t *testing.T) (*repository.HarvestRepository, *repository.UserRepository, *repository.TransactionRepository, *HarvestService, *scheduler.ManualClock) {
 	db, err := database.Connect(":memory:")
 	assert.NoError(t, err)
 
@@ -19,15 +27,16 @@ func setupHarvestTestDB(t *testing.T) (*repository.HarvestRepository, *repositor
 	harvestRepo := repository.NewHarvestRepository(db)
 	userRepo := repository.NewUserRepository(db)
 	transactionRepo := repository.NewTransactionRepository(db)
-	harvestService := NewHarvestService(harvestRepo, userRepo, transactionRepo, db)
+	clock := scheduler.NewManualClock(time.Date(2026, time.March, 2, 9, 0, 0, 0, time.UTC))
+	harvestService := NewHarvestService(harvestRepo, userRepo, transactionRepo, db, clock)
 
-	return harvestRepo, userRepo, transactionRepo, harvestService
+	return harvestRepo, userRepo, transactionRepo, harvestService, clock
 }
 
 func TestHarvestService_CreateHarvest(t *testing.T) {
 	_, _, _, harvestService := setupHarvestTestDB(t)
 
-	harvest, err := harvestService.CreateHarvest("Test Harvest", "This is a test harvest description", 50)
+	harvest, err := harvestService.CreateHarvest(HarvestParams{Title: "Test Harvest", Description: "This is a test harvest description", BeanAmount: 50})
 	assert.NoError(t, err)
 	assert.NotNil(t, harvest)
 	assert.Equal(t, "Test Harvest", harvest.Title)
@@ -40,10 +49,10 @@ func TestHarvestService_CreateHarvest(t *testing.T) {
 func TestHarvestService_UpdateHarvest(t *testing.T) {
 	_, _, _, harvestService := setupHarvestTestDB(t)
 
-	harvest, err := harvestService.CreateHarvest("Original", "Original description", 100)
+	harvest, err := harvestService.CreateHarvest(HarvestParams{Title: "Original", Description: "Original description", BeanAmount: 100})
 	assert.NoError(t, err)
 
-	updated, err := harvestService.UpdateHarvest(harvest.ID, "Updated", "Updated description", 150)
+	updated, err := harvestService.UpdateHarvest(harvest.ID, HarvestParams{Title: "Updated", Description: "Updated description", BeanAmount: 150})
 	assert.NoError(t, err)
 	assert.Equal(t, "Updated", updated.Title)
 	assert.Equal(t, "Updated description", updated.Description)
@@ -57,7 +66,7 @@ func TestHarvestService_AssignUser(t *testing.T) {
 	err := userRepo.Create(user)
 	assert.NoError(t, err)
 
-	harvest, err := harvestService.CreateHarvest("Test", "Description", 50)
+	harvest, err := harvestService.CreateHarvest(HarvestParams{Title: "Test", Description: "Description", BeanAmount: 50})
 	assert.NoError(t, err)
 
 	assigned, err := harvestService.AssignUser(harvest.ID, user.ID)
@@ -75,7 +84,7 @@ func TestHarvestService_CompleteHarvest(t *testing.T) {
 	err := userRepo.Create(user)
 	assert.NoError(t, err)
 
-	harvest, err := harvestService.CreateHarvest("Complete Test", "Test completion", 50)
+	harvest, err := harvestService.CreateHarvest(HarvestParams{Title: "Complete Test", Description: "Test completion", BeanAmount: 50})
 	assert.NoError(t, err)
 
 	_, err = harvestService.AssignUser(harvest.ID, user.ID)
@@ -94,13 +103,15 @@ func TestHarvestService_CompleteHarvest(t *testing.T) {
 	assert.Len(t, transactions, 1)
 	assert.Equal(t, 50, transactions[0].Amount)
 	assert.Equal(t, "Harvest completed: Complete Test", transactions[0].Note)
-	assert.Equal(t, "system", transactions[0].FromUser.Username)
+	assert.Equal(t, "mint", transactions[0].FromUser.Username)
+	assert.Equal(t, models.TransactionKindHarvestReward, transactions[0].Kind)
+	assert.Equal(t, harvest.ID, *transactions[0].HarvestID)
 }
 
 func TestHarvestService_CompleteUnassignedHarvest(t *testing.T) {
 	_, _, _, harvestService := setupHarvestTestDB(t)
 
-	harvest, err := harvestService.CreateHarvest("Unassigned", "No user assigned", 50)
+	harvest, err := harvestService.CreateHarvest(HarvestParams{Title: "Unassigned", Description: "No user assigned", BeanAmount: 50})
 	assert.NoError(t, err)
 
 	_, err = harvestService.CompleteHarvest(harvest.ID)
@@ -114,7 +125,7 @@ func TestHarvestService_CompleteAlreadyCompleted(t *testing.T) {
 	err := userRepo.Create(user)
 	assert.NoError(t, err)
 
-	harvest, err := harvestService.CreateHarvest("Double Complete", "Test", 50)
+	harvest, err := harvestService.CreateHarvest(HarvestParams{Title: "Double Complete", Description: "Test", BeanAmount: 50})
 	assert.NoError(t, err)
 
 	_, err = harvestService.AssignUser(harvest.ID, user.ID)
@@ -130,7 +141,7 @@ func TestHarvestService_CompleteAlreadyCompleted(t *testing.T) {
 func TestHarvestService_GetHarvest(t *testing.T) {
 	_, _, _, harvestService := setupHarvestTestDB(t)
 
-	created, err := harvestService.CreateHarvest("Get Test", "Description", 75)
+	created, err := harvestService.CreateHarvest(HarvestParams{Title: "Get Test", Description: "Description", BeanAmount: 75})
 	assert.NoError(t, err)
 
 	retrieved, err := harvestService.GetHarvest(created.ID)
@@ -142,11 +153,11 @@ func TestHarvestService_GetHarvest(t *testing.T) {
 func TestHarvestService_GetAllHarvests(t *testing.T) {
 	_, _, _, harvestService := setupHarvestTestDB(t)
 
-	_, err := harvestService.CreateHarvest("Harvest 1", "Desc 1", 10)
+	_, err := harvestService.CreateHarvest(HarvestParams{Title: "Harvest 1", Description: "Desc 1", BeanAmount: 10})
 	assert.NoError(t, err)
-	_, err = harvestService.CreateHarvest("Harvest 2", "Desc 2", 20)
+	_, err = harvestService.CreateHarvest(HarvestParams{Title: "Harvest 2", Description: "Desc 2", BeanAmount: 20})
 	assert.NoError(t, err)
-	_, err = harvestService.CreateHarvest("Harvest 3", "Desc 3", 30)
+	_, err = harvestService.CreateHarvest(HarvestParams{Title: "Harvest 3", Description: "Desc 3", BeanAmount: 30})
 	assert.NoError(t, err)
 
 	harvests, err := harvestService.GetAllHarvests()
@@ -157,19 +168,19 @@ func TestHarvestService_GetAllHarvests(t *testing.T) {
 func TestHarvestService_SearchHarvests(t *testing.T) {
 	_, _, _, harvestService := setupHarvestTestDB(t)
 
-	_, err := harvestService.CreateHarvest("Find Me", "Description with keyword", 10)
+	_, err := harvestService.CreateHarvest(HarvestParams{Title: "Find Me", Description: "Description with keyword", BeanAmount: 10})
 	assert.NoError(t, err)
-	_, err = harvestService.CreateHarvest("Other Task", "keyword in description", 20)
+	_, err = harvestService.CreateHarvest(HarvestParams{Title: "Other Task", Description: "keyword in description", BeanAmount: 20})
 	assert.NoError(t, err)
-	_, err = harvestService.CreateHarvest("Unrelated", "Nothing here", 30)
+	_, err = harvestService.CreateHarvest(HarvestParams{Title: "Unrelated", Description: "Nothing here", BeanAmount: 30})
 	assert.NoError(t, err)
 
-	harvests, total, err := harvestService.SearchHarvests("keyword", 1, 10)
+	harvests, total, err := harvestService.SearchHarvests(repository.HarvestFilter{Query: "keyword", Page: 1, Limit: 10})
 	assert.NoError(t, err)
 	assert.Len(t, harvests, 2)
 	assert.Equal(t, int64(2), total)
 
-	harvests, total, err = harvestService.SearchHarvests("Find", 1, 10)
+	harvests, total, err = harvestService.SearchHarvests(repository.HarvestFilter{Query: "Find", Page: 1, Limit: 10})
 	assert.NoError(t, err)
 	assert.Len(t, harvests, 1)
 	assert.Equal(t, int64(1), total)
@@ -180,21 +191,21 @@ func TestHarvestService_SearchHarvestsPagination(t *testing.T) {
 	_, _, _, harvestService := setupHarvestTestDB(t)
 
 	for i := 1; i <= 25; i++ {
-		_, err := harvestService.CreateHarvest("Harvest", "Description", 10)
+		_, err := harvestService.CreateHarvest(HarvestParams{Title: "Harvest", Description: "Description", BeanAmount: 10})
 		assert.NoError(t, err)
 	}
 
-	harvests, total, err := harvestService.SearchHarvests("", 1, 10)
+	harvests, total, err := harvestService.SearchHarvests(repository.HarvestFilter{Query: "", Page: 1, Limit: 10})
 	assert.NoError(t, err)
 	assert.Len(t, harvests, 10)
 	assert.Equal(t, int64(25), total)
 
-	harvests, total, err = harvestService.SearchHarvests("", 2, 10)
+	harvests, total, err = harvestService.SearchHarvests(repository.HarvestFilter{Query: "", Page: 2, Limit: 10})
 	assert.NoError(t, err)
 	assert.Len(t, harvests, 10)
 	assert.Equal(t, int64(25), total)
 
-	harvests, total, err = harvestService.SearchHarvests("", 3, 10)
+	harvests, total, err = harvestService.SearchHarvests(repository.HarvestFilter{Query: "", Page: 3, Limit: 10})
 	assert.NoError(t, err)
 	assert.Len(t, harvests, 5)
 	assert.Equal(t, int64(25), total)
@@ -203,7 +214,7 @@ func TestHarvestService_SearchHarvestsPagination(t *testing.T) {
 func TestHarvestService_DeleteHarvest(t *testing.T) {
 	harvestRepo, _, _, harvestService := setupHarvestTestDB(t)
 
-	harvest, err := harvestService.CreateHarvest("Delete Me", "Will be deleted", 50)
+	harvest, err := harvestService.CreateHarvest(HarvestParams{Title: "Delete Me", Description: "Will be deleted", BeanAmount: 50})
 	assert.NoError(t, err)
 
 	err = harvestService.DeleteHarvest(harvest.ID)
@@ -212,3 +223,748 @@ func TestHarvestService_DeleteHarvest(t *testing.T) {
 	_, err = harvestRepo.FindByID(harvest.ID)
 	assert.Error(t, err)
 }
+
+func TestHarvestService_ClaimSubmitApprove(t *testing.T) {
+	_, userRepo, transactionRepo, harvestService := setupHarvestTestDB(t)
+
+	user := &models.User{Username: "worker", BeanAmount: 10}
+	assert.NoError(t, userRepo.Create(user))
+
+	harvest, err := harvestService.CreateHarvest(HarvestParams{Title: "Lifecycle", Description: "Do the thing", BeanAmount: 40})
+	assert.NoError(t, err)
+	assert.Equal(t, models.HarvestOpen, harvest.Status)
+
+	claimed, err := harvestService.ClaimHarvest(harvest.ID, "worker")
+	assert.NoError(t, err)
+	assert.Equal(t, models.HarvestClaimed, claimed.Status)
+	assert.Equal(t, user.ID, *claimed.AssignedUserID)
+
+	submission, err := harvestService.SubmitHarvest(harvest.ID, "worker", "Done it", []string{" https://example.com/pr/1 ", ""})
+	assert.NoError(t, err)
+	assert.Equal(t, models.HarvestSubmissionPending, submission.Status)
+	assert.Equal(t, []string{"https://example.com/pr/1"}, submission.LinkList())
+
+	paid, err := harvestService.ReviewHarvest(harvest.ID, "admin", true, "Nice work")
+	assert.NoError(t, err)
+	assert.Equal(t, models.HarvestPaid, paid.Status)
+	assert.True(t, paid.Completed)
+
+	worker, err := userRepo.FindByUsername("worker")
+	assert.NoError(t, err)
+	assert.Equal(t, 50, worker.BeanAmount)
+
+	transactions, err := transactionRepo.FindByUsername("worker")
+	assert.NoError(t, err)
+	assert.Len(t, transactions, 1)
+	assert.Equal(t, "mint", transactions[0].FromUser.Username)
+
+	submissions, err := harvestService.ListSubmissions(harvest.ID)
+	assert.NoError(t, err)
+	assert.Len(t, submissions, 1)
+	assert.Equal(t, models.HarvestSubmissionApproved, submissions[0].Status)
+	assert.Equal(t, "admin", submissions[0].Reviewer)
+	assert.Equal(t, "Nice work", submissions[0].ReviewComment)
+
+	events, err := harvestService.ListEvents(harvest.ID)
+	assert.NoError(t, err)
+	types := make([]models.HarvestEventType, len(events))
+	for i, event := range events {
+		types[i] = event.Type
+	}
+	assert.Equal(t, []models.HarvestEventType{
+		models.HarvestEventCreated,
+		models.HarvestEventClaimed,
+		models.HarvestEventSubmitted,
+		models.HarvestEventApproved,
+		models.HarvestEventPaid,
+	}, types)
+	assert.Equal(t, models.HarvestSubmitted, events[3].FromStatus)
+	assert.Equal(t, models.HarvestApproved, events[3].ToStatus)
+	assert.Equal(t, "worker", events[1].Actor)
+}
+
+func TestHarvestService_RejectAndResubmit(t *testing.T) {
+	_, userRepo, _, harvestService := setupHarvestTestDB(t)
+
+	assert.NoError(t, userRepo.Create(&models.User{Username: "worker"}))
+
+	harvest, err := harvestService.CreateHarvest(HarvestParams{Title: "Reject", Description: "Needs work", BeanAmount: 20})
+	assert.NoError(t, err)
+	_, err = harvestService.ClaimHarvest(harvest.ID, "worker")
+	assert.NoError(t, err)
+	_, err = harvestService.SubmitHarvest(harvest.ID, "worker", "First try", nil)
+	assert.NoError(t, err)
+
+	_, err = harvestService.ReviewHarvest(harvest.ID, "admin", false, "  ")
+	assert.Equal(t, ErrReviewCommentRequired, err)
+
+	rejected, err := harvestService.ReviewHarvest(harvest.ID, "admin", false, "Missing the screenshot")
+	assert.NoError(t, err)
+	assert.Equal(t, models.HarvestRejected, rejected.Status)
+	assert.False(t, rejected.Completed)
+
+	_, err = harvestService.ReviewHarvest(harvest.ID, "admin", true, "")
+	assert.Equal(t, ErrHarvestNotSubmitted, err)
+
+	_, err = harvestService.SubmitHarvest(harvest.ID, "worker", "Second try", []string{"https://example.com/shot.png"})
+	assert.NoError(t, err)
+
+	submissions, err := harvestService.ListSubmissions(harvest.ID)
+	assert.NoError(t, err)
+	assert.Len(t, submissions, 2)
+	assert.Equal(t, models.HarvestSubmissionPending, submissions[0].Status)
+	assert.Equal(t, models.HarvestSubmissionRejected, submissions[1].Status)
+	assert.Equal(t, "Missing the screenshot", submissions[1].ReviewComment)
+}
+
+func TestHarvestService_Withdraw(t *testing.T) {
+	_, userRepo, _, harvestService := setupHarvestTestDB(t)
+
+	assert.NoError(t, userRepo.Create(&models.User{Username: "worker"}))
+	assert.NoError(t, userRepo.Create(&models.User{Username: "other"}))
+
+	harvest, err := harvestService.CreateHarvest(HarvestParams{Title: "Withdraw", Description: "Changed my mind", BeanAmount: 20})
+	assert.NoError(t, err)
+	_, err = harvestService.ClaimHarvest(harvest.ID, "worker")
+	assert.NoError(t, err)
+	_, err = harvestService.SubmitHarvest(harvest.ID, "worker", "Half done", nil)
+	assert.NoError(t, err)
+
+	_, err = harvestService.WithdrawHarvest(harvest.ID, "other")
+	assert.Equal(t, ErrNotHarvestAssignee, err)
+
+	reopened, err := harvestService.WithdrawHarvest(harvest.ID, "worker")
+	assert.NoError(t, err)
+	assert.Equal(t, models.HarvestOpen, reopened.Status)
+	assert.Nil(t, reopened.AssignedUserID)
+
+	submissions, err := harvestService.ListSubmissions(harvest.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.HarvestSubmissionWithdrawn, submissions[0].Status)
+
+	_, err = harvestService.ClaimHarvest(harvest.ID, "other")
+	assert.NoError(t, err)
+}
+
+func TestHarvestService_LifecycleGuards(t *testing.T) {
+	_, userRepo, _, harvestService := setupHarvestTestDB(t)
+
+	assert.NoError(t, userRepo.Create(&models.User{Username: "worker"}))
+	assert.NoError(t, userRepo.Create(&models.User{Username: "other"}))
+
+	harvest, err := harvestService.CreateHarvest(HarvestParams{Title: "Guards", Description: "", BeanAmount: 20})
+	assert.NoError(t, err)
+
+	_, err = harvestService.SubmitHarvest(harvest.ID, "worker", "Sneaky", nil)
+	assert.Equal(t, ErrNotHarvestAssignee, err)
+
+	_, err = harvestService.ClaimHarvest(harvest.ID, "mint")
+	assert.Equal(t, ErrReservedAccount, err)
+
+	_, err = harvestService.ClaimHarvest(999, "worker")
+	assert.Equal(t, ErrHarvestNotFound, err)
+
+	_, err = harvestService.ClaimHarvest(harvest.ID, "worker")
+	assert.NoError(t, err)
+
+	_, err = harvestService.ClaimHarvest(harvest.ID, "other")
+	assert.Equal(t, ErrHarvestNotOpen, err)
+
+	_, err = harvestService.SubmitHarvest(harvest.ID, "worker", "", nil)
+	assert.Equal(t, ErrInvalidHarvestProof, err)
+
+	_, err = harvestService.SubmitHarvest(harvest.ID, "worker", "Done", []string{"javascript:alert(1)"})
+	assert.Equal(t, ErrInvalidHarvestLinks, err)
+
+	_, err = harvestService.SubmitHarvest(harvest.ID, "worker", "Done", nil)
+	assert.NoError(t, err)
+
+	_, err = harvestService.SubmitHarvest(harvest.ID, "worker", "Done again", nil)
+	assert.Equal(t, ErrHarvestNotSubmittable, err)
+
+	other, err := userRepo.FindByUsername("other")
+	assert.NoError(t, err)
+	_, err = harvestService.AssignUser(harvest.ID, other.ID)
+	assert.Equal(t, ErrHarvestNotAssignable, err)
+
+	_, err = harvestService.ReviewHarvest(harvest.ID, "admin", true, "")
+	assert.NoError(t, err)
+
+	_, err = harvestService.WithdrawHarvest(harvest.ID, "worker")
+	assert.Equal(t, ErrHarvestNotWithdrawable, err)
+}
+
+func TestHarvestShares(t *testing.T) {
+	participants := make([]models.HarvestParticipant, 3)
+
+	equal := &models.Harvest{BeanAmount: 10, SplitMode: models.HarvestSplitEqual, Participants: participants}
+	assert.Equal(t, []int{4, 3, 3}, equal.Shares())
+
+	explicit := &models.Harvest{BeanAmount: 10, SplitMode: models.HarvestSplitExplicit, Participants: []models.HarvestParticipant{
+		{Amount: 7}, {Amount: 2}, {Amount: 1},
+	}}
+	assert.Equal(t, []int{7, 2, 1}, explicit.Shares())
+
+	assert.Empty(t, (&models.Harvest{BeanAmount: 10}).Shares())
+}
+
+func TestHarvestService_CompletePaysEveryParticipant(t *testing.T) {
+	_, userRepo, transactionRepo, harvestService := setupHarvestTestDB(t)
+
+	for _, name := range []string{"alice", "bob", "carol"} {
+		assert.NoError(t, userRepo.Create(&models.User{Username: name}))
+	}
+
+	harvest, err := harvestService.CreateHarvest(HarvestParams{Title: "Team", Description: "Everyone helps", BeanAmount: 10})
+	assert.NoError(t, err)
+
+	set, err := harvestService.SetParticipants(harvest.ID, models.HarvestSplitEqual, []HarvestShare{
+		{Username: "alice"}, {Username: "bob"}, {Username: "carol"},
+	}, "admin")
+	assert.NoError(t, err)
+	assert.Equal(t, models.HarvestClaimed, set.Status)
+	assert.Equal(t, "alice", set.AssignedUser.Username)
+	assert.Len(t, set.Participants, 3)
+
+	_, err = harvestService.CompleteHarvest(harvest.ID)
+	assert.NoError(t, err)
+
+	for name, want := range map[string]int{"alice": 4, "bob": 3, "carol": 3} {
+		user, err := userRepo.FindByUsername(name)
+		assert.NoError(t, err)
+		assert.Equal(t, want, user.BeanAmount, name)
+
+		transactions, err := transactionRepo.FindByUsername(name)
+		assert.NoError(t, err)
+		assert.Len(t, transactions, 1, name)
+		assert.Equal(t, harvest.ID, *transactions[0].HarvestID)
+	}
+
+	events, err := harvestService.ListEvents(harvest.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Paid 4 beans to alice, 3 beans to bob, 3 beans to carol", events[len(events)-1].Note)
+}
+
+func TestHarvestService_ExplicitSplit(t *testing.T) {
+	_, userRepo, _, harvestService := setupHarvestTestDB(t)
+
+	assert.NoError(t, userRepo.Create(&models.User{Username: "alice"}))
+	assert.NoError(t, userRepo.Create(&models.User{Username: "bob"}))
+
+	harvest, err := harvestService.CreateHarvest(HarvestParams{Title: "Explicit", Description: "", BeanAmount: 50})
+	assert.NoError(t, err)
+
+	_, err = harvestService.SetParticipants(harvest.ID, models.HarvestSplitExplicit, []HarvestShare{
+		{Username: "alice", Amount: 30}, {Username: "bob", Amount: 10},
+	}, "admin")
+	assert.Equal(t, ErrSplitMismatch, err)
+
+	_, err = harvestService.SetParticipants(harvest.ID, models.HarvestSplitExplicit, []HarvestShare{
+		{Username: "alice", Amount: 50}, {Username: "bob", Amount: 0},
+	}, "admin")
+	assert.Equal(t, ErrSplitMismatch, err)
+
+	_, err = harvestService.SetParticipants(harvest.ID, models.HarvestSplitEqual, []HarvestShare{
+		{Username: "alice"}, {Username: "alice"},
+	}, "admin")
+	assert.Equal(t, ErrInvalidParticipants, err)
+
+	_, err = harvestService.SetParticipants(harvest.ID, models.HarvestSplitExplicit, []HarvestShare{
+		{Username: "alice", Amount: 30}, {Username: "bob", Amount: 20},
+	}, "admin")
+	assert.NoError(t, err)
+
+	_, err = harvestService.UpdateHarvest(harvest.ID, HarvestParams{Title: "Explicit", Description: "", BeanAmount: 60})
+	assert.Equal(t, ErrSplitMismatch, err)
+
+	_, err = harvestService.CompleteHarvest(harvest.ID)
+	assert.NoError(t, err)
+
+	alice, err := userRepo.FindByUsername("alice")
+	assert.NoError(t, err)
+	assert.Equal(t, 30, alice.BeanAmount)
+	bob, err := userRepo.FindByUsername("bob")
+	assert.NoError(t, err)
+	assert.Equal(t, 20, bob.BeanAmount)
+}
+
+func TestHarvestService_JoinAndWithdraw(t *testing.T) {
+	_, userRepo, _, harvestService := setupHarvestTestDB(t)
+
+	for _, name := range []string{"alice", "bob", "carol"} {
+		assert.NoError(t, userRepo.Create(&models.User{Username: name}))
+	}
+
+	harvest, err := harvestService.CreateHarvest(HarvestParams{Title: "Join", Description: "", BeanAmount: 2})
+	assert.NoError(t, err)
+
+	_, err = harvestService.JoinHarvest(harvest.ID, "bob")
+	assert.Equal(t, ErrHarvestNotJoinable, err)
+
+	_, err = harvestService.ClaimHarvest(harvest.ID, "alice")
+	assert.NoError(t, err)
+
+	_, err = harvestService.JoinHarvest(harvest.ID, "alice")
+	assert.Equal(t, ErrAlreadyParticipant, err)
+
+	joined, err := harvestService.JoinHarvest(harvest.ID, "bob")
+	assert.NoError(t, err)
+	assert.Len(t, joined.Participants, 2)
+
+	_, err = harvestService.JoinHarvest(harvest.ID, "carol")
+	assert.Equal(t, ErrRewardTooSmall, err)
+
+	_, err = harvestService.SubmitHarvest(harvest.ID, "bob", "We did it", nil)
+	assert.NoError(t, err)
+
+	afterLeave, err := harvestService.WithdrawHarvest(harvest.ID, "alice")
+	assert.NoError(t, err)
+	assert.Equal(t, models.HarvestSubmitted, afterLeave.Status)
+	assert.Equal(t, "bob", afterLeave.AssignedUser.Username)
+	assert.Len(t, afterLeave.Participants, 1)
+
+	reopened, err := harvestService.WithdrawHarvest(harvest.ID, "bob")
+	assert.NoError(t, err)
+	assert.Equal(t, models.HarvestOpen, reopened.Status)
+	assert.Empty(t, reopened.Participants)
+
+	_, err = harvestService.ClaimHarvest(harvest.ID, "alice")
+	assert.NoError(t, err)
+}
+
+func TestHarvestService_JoinExplicitSplitRefused(t *testing.T) {
+	_, userRepo, _, harvestService := setupHarvestTestDB(t)
+
+	assert.NoError(t, userRepo.Create(&models.User{Username: "alice"}))
+	assert.NoError(t, userRepo.Create(&models.User{Username: "bob"}))
+
+	harvest, err := harvestService.CreateHarvest(HarvestParams{Title: "Explicit", Description: "", BeanAmount: 10})
+	assert.NoError(t, err)
+	_, err = harvestService.SetParticipants(harvest.ID, models.HarvestSplitExplicit, []HarvestShare{
+		{Username: "alice", Amount: 10},
+	}, "admin")
+	assert.NoError(t, err)
+
+	_, err = harvestService.JoinHarvest(harvest.ID, "bob")
+	assert.Equal(t, ErrExplicitSplitJoin, err)
+}
+
+func TestHarvestService_ClaimTimeoutReleases(t *testing.T) {
+	_, userRepo, _, harvestService, clock := setupHarvestTestDBWithClock(t)
+
+	assert.NoError(t, userRepo.Create(&models.User{Username: "worker"}))
+
+	harvest, err := harvestService.CreateHarvest(HarvestParams{Title: "Timeout", Description: "", BeanAmount: 10, ClaimTimeout: "48h"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(48*3600), harvest.ClaimTimeoutSeconds)
+
+	claimed, err := harvestService.ClaimHarvest(harvest.ID, "worker")
+	assert.NoError(t, err)
+	assert.NotNil(t, claimed.ClaimExpiresAt)
+	assert.Equal(t, clock.Now().Add(48*time.Hour), claimed.ClaimExpiresAt.UTC())
+
+	clock.Advance(47 * time.Hour)
+	changed, err := harvestService.ExpireOverdue(clock.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, changed)
+
+	clock.Advance(time.Hour)
+	changed, err = harvestService.ExpireOverdue(clock.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, changed)
+
+	released, err := harvestService.GetHarvest(harvest.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.HarvestOpen, released.Status)
+	assert.Nil(t, released.AssignedUserID)
+	assert.Nil(t, released.ClaimExpiresAt)
+	assert.Empty(t, released.Participants)
+
+	events, err := harvestService.ListEvents(harvest.ID)
+	assert.NoError(t, err)
+	last := events[len(events)-1]
+	assert.Equal(t, models.HarvestEventReleased, last.Type)
+	assert.Equal(t, models.HarvestClaimed, last.FromStatus)
+
+	_, err = harvestService.ClaimHarvest(harvest.ID, "worker")
+	assert.NoError(t, err)
+}
+
+func TestHarvestService_SubmissionStopsClaimClock(t *testing.T) {
+	_, userRepo, _, harvestService, clock := setupHarvestTestDBWithClock(t)
+
+	assert.NoError(t, userRepo.Create(&models.User{Username: "worker"}))
+
+	harvest, err := harvestService.CreateHarvest(HarvestParams{Title: "Submitted", Description: "", BeanAmount: 10, Deadline: "3d", ClaimTimeout: "1h"})
+	assert.NoError(t, err)
+	_, err = harvestService.ClaimHarvest(harvest.ID, "worker")
+	assert.NoError(t, err)
+	_, err = harvestService.SubmitHarvest(harvest.ID, "worker", "Done", nil)
+	assert.NoError(t, err)
+
+	clock.Advance(4 * 24 * time.Hour)
+	changed, err := harvestService.ExpireOverdue(clock.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, changed)
+
+	submitted, err := harvestService.GetHarvest(harvest.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.HarvestSubmitted, submitted.Status)
+	assert.True(t, submitted.Overdue(clock.Now()))
+}
+
+func TestHarvestService_DeadlineExpiresAndReopens(t *testing.T) {
+	_, userRepo, _, harvestService, clock := setupHarvestTestDBWithClock(t)
+
+	assert.NoError(t, userRepo.Create(&models.User{Username: "worker"}))
+
+	harvest, err := harvestService.CreateHarvest(HarvestParams{Title: "Deadline", Description: "", BeanAmount: 10, Deadline: "2d"})
+	assert.NoError(t, err)
+	assert.Equal(t, clock.Now().Add(48*time.Hour), harvest.DueAt.UTC())
+
+	_, err = harvestService.ClaimHarvest(harvest.ID, "worker")
+	assert.NoError(t, err)
+
+	clock.Advance(48 * time.Hour)
+	changed, err := harvestService.ExpireOverdue(clock.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, changed)
+
+	expired, err := harvestService.GetHarvest(harvest.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.HarvestExpired, expired.Status)
+	assert.True(t, expired.Overdue(clock.Now()))
+
+	_, err = harvestService.SubmitHarvest(harvest.ID, "worker", "Too late", nil)
+	assert.Equal(t, ErrHarvestNotSubmittable, err)
+
+	reopened, err := harvestService.UpdateHarvest(harvest.ID, HarvestParams{Title: "Deadline", Description: "", BeanAmount: 10, Deadline: "1w"})
+	assert.NoError(t, err)
+	assert.Equal(t, models.HarvestOpen, reopened.Status)
+	assert.Empty(t, reopened.Participants)
+	assert.False(t, reopened.Overdue(clock.Now()))
+
+	events, err := harvestService.ListEvents(harvest.ID)
+	assert.NoError(t, err)
+	types := make([]models.HarvestEventType, len(events))
+	for i, event := range events {
+		types[i] = event.Type
+	}
+	assert.Equal(t, []models.HarvestEventType{
+		models.HarvestEventCreated,
+		models.HarvestEventClaimed,
+		models.HarvestEventExpired,
+		models.HarvestEventReopened,
+	}, types)
+}
+
+func TestHarvestService_ClaimAfterDeadlineRefused(t *testing.T) {
+	_, userRepo, _, harvestService, clock := setupHarvestTestDBWithClock(t)
+
+	assert.NoError(t, userRepo.Create(&models.User{Username: "worker"}))
+
+	harvest, err := harvestService.CreateHarvest(HarvestParams{Title: "Late", Description: "", BeanAmount: 10, Deadline: "1h"})
+	assert.NoError(t, err)
+
+	clock.Advance(2 * time.Hour)
+	_, err = harvestService.ClaimHarvest(harvest.ID, "worker")
+	assert.Equal(t, ErrHarvestNotOpen, err)
+}
+
+func TestHarvestService_InvalidTiming(t *testing.T) {
+	_, _, _, harvestService := setupHarvestTestDB(t)
+
+	_, err := harvestService.CreateHarvest(HarvestParams{Title: "Bad", BeanAmount: 10, Deadline: "soon"})
+	assert.Equal(t, ErrInvalidDeadline, err)
+
+	_, err = harvestService.CreateHarvest(HarvestParams{Title: "Bad", BeanAmount: 10, Deadline: "2020-01-01T00:00:00Z"})
+	assert.Equal(t, ErrInvalidDeadline, err)
+
+	_, err = harvestService.CreateHarvest(HarvestParams{Title: "Bad", BeanAmount: 10, ClaimTimeout: "30s"})
+	assert.Equal(t, ErrInvalidClaimTimeout, err)
+
+	_, err = harvestService.CreateHarvest(HarvestParams{Title: "Bad", BeanAmount: 10, ClaimTimeout: "forever"})
+	assert.Equal(t, ErrInvalidClaimTimeout, err)
+}
+
+func TestHarvestService_SearchByStatus(t *testing.T) {
+	_, userRepo, _, harvestService, clock := setupHarvestTestDBWithClock(t)
+
+	assert.NoError(t, userRepo.Create(&models.User{Username: "worker"}))
+
+	open, err := harvestService.CreateHarvest(HarvestParams{Title: "Open", BeanAmount: 10})
+	assert.NoError(t, err)
+	assigned, err := harvestService.CreateHarvest(HarvestParams{Title: "Assigned", BeanAmount: 10})
+	assert.NoError(t, err)
+	_, err = harvestService.ClaimHarvest(assigned.ID, "worker")
+	assert.NoError(t, err)
+	overdue, err := harvestService.CreateHarvest(HarvestParams{Title: "Overdue", BeanAmount: 10, Deadline: "1h"})
+	assert.NoError(t, err)
+	done, err := harvestService.CreateHarvest(HarvestParams{Title: "Done", BeanAmount: 10})
+	assert.NoError(t, err)
+	_, err = harvestService.AssignUserByUsername(done.ID, "worker")
+	assert.NoError(t, err)
+	_, err = harvestService.CompleteHarvest(done.ID)
+	assert.NoError(t, err)
+
+	clock.Advance(2 * time.Hour)
+
+	for status, want := range map[repository.HarvestListStatus]uint{
+		repository.HarvestListOpen:      open.ID,
+		repository.HarvestListAssigned:  assigned.ID,
+		repository.HarvestListOverdue:   overdue.ID,
+		repository.HarvestListCompleted: done.ID,
+	} {
+		harvests, total, err := harvestService.SearchHarvests(repository.HarvestFilter{Status: status, Page: 1, Limit: 10})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), total, status)
+		if assert.Len(t, harvests, 1, status) {
+			assert.Equal(t, want, harvests[0].ID, status)
+		}
+	}
+}
+
+func TestHarvestService_TagsAndCategory(t *testing.T) {
+	_, _, _, harvestService := setupHarvestTestDB(t)
+
+	harvest, err := harvestService.CreateHarvest(HarvestParams{
+		Title: "Rotate keys", BeanAmount: 10, Category: " Ops ", Tags: []string{"Security", "security", "infra"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "ops", harvest.Category)
+	assert.Equal(t, []string{"security", "infra"}, harvest.TagNames())
+
+	updated, err := harvestService.UpdateHarvest(harvest.ID, HarvestParams{
+		Title: "Rotate keys", BeanAmount: 10, Tags: []string{"infra", "keys"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "", updated.Category)
+	assert.Equal(t, []string{"infra", "keys"}, updated.TagNames())
+
+	_, err = harvestService.CreateHarvest(HarvestParams{Title: "Bad", BeanAmount: 10, Tags: []string{"two words"}})
+	assert.Equal(t, ErrInvalidHarvestTags, err)
+	_, err = harvestService.CreateHarvest(HarvestParams{Title: "Bad", BeanAmount: 10, Tags: make([]string, MaxHarvestTags+1)})
+	assert.Equal(t, ErrInvalidHarvestTags, err)
+	_, err = harvestService.CreateHarvest(HarvestParams{Title: "Bad", BeanAmount: 10, Category: "-ops"})
+	assert.Equal(t, ErrInvalidHarvestCategory, err)
+
+	tags, categories, err := harvestService.ListLabels()
+	assert.NoError(t, err)
+	assert.Equal(t, []repository.HarvestLabelCount{{Name: "infra", Count: 1}, {Name: "keys", Count: 1}}, tags)
+	assert.Empty(t, categories)
+}
+
+func TestHarvestService_SearchFiltersAndSorts(t *testing.T) {
+	_, userRepo, _, harvestService, clock := setupHarvestTestDBWithClock(t)
+	assert.NoError(t, userRepo.Create(&models.User{Username: "worker"}))
+
+	small, err := harvestService.CreateHarvest(HarvestParams{
+		Title: "Small", BeanAmount: 5, Deadline: "3d", Category: "ops", Tags: []string{"infra"},
+	})
+	assert.NoError(t, err)
+	clock.Advance(time.Minute)
+	big, err := harvestService.CreateHarvest(HarvestParams{
+		Title: "Big", BeanAmount: 50, Deadline: "1d", Category: "ops", Tags: []string{"infra", "urgent"},
+	})
+	assert.NoError(t, err)
+	clock.Advance(time.Minute)
+	medium, err := harvestService.CreateHarvest(HarvestParams{
+		Title: "Medium", BeanAmount: 20, Category: "docs", Tags: []string{"urgent"},
+	})
+	assert.NoError(t, err)
+	_, err = harvestService.AssignUserByUsername(medium.ID, "worker")
+	assert.NoError(t, err)
+
+	ids := func(filter repository.HarvestFilter) []uint {
+		filter.Page, filter.Limit = 1, 10
+		harvests, total, err := harvestService.SearchHarvests(filter)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(len(harvests)), total)
+		ids := make([]uint, len(harvests))
+		for i, harvest := range harvests {
+			ids[i] = harvest.ID
+		}
+		return ids
+	}
+
+	assert.ElementsMatch(t, []uint{small.ID, big.ID}, ids(repository.HarvestFilter{Tags: []string{"Infra"}}))
+	assert.Equal(t, []uint{big.ID}, ids(repository.HarvestFilter{Tags: []string{"infra", "urgent"}}))
+	assert.ElementsMatch(t, []uint{small.ID, big.ID}, ids(repository.HarvestFilter{Category: "OPS"}))
+	assert.Equal(t, []uint{medium.ID}, ids(repository.HarvestFilter{Assignee: "worker"}))
+	assert.Equal(t, []uint{medium.ID}, ids(repository.HarvestFilter{MinReward: 10, MaxReward: 20}))
+	assert.Equal(t, []uint{big.ID}, ids(repository.HarvestFilter{Category: "ops", MinReward: 10}))
+
+	assert.Equal(t, []uint{big.ID, medium.ID, small.ID}, ids(repository.HarvestFilter{Sort: repository.HarvestSortReward}))
+	assert.Equal(t, []uint{medium.ID, big.ID, small.ID}, ids(repository.HarvestFilter{Sort: repository.HarvestSortNewest}))
+	assert.Equal(t, []uint{big.ID, small.ID, medium.ID}, ids(repository.HarvestFilter{Sort: repository.HarvestSortDeadline}))
+
+	tags, categories, err := harvestService.ListLabels()
+	assert.NoError(t, err)
+	assert.Equal(t, []repository.HarvestLabelCount{{Name: "infra", Count: 2}, {Name: "urgent", Count: 2}}, tags)
+	assert.Equal(t, []repository.HarvestLabelCount{{Name: "ops", Count: 2}, {Name: "docs", Count: 1}}, categories)
+}
+
+func TestHarvestService_SearchRanksFullText(t *testing.T) {
+	db, err := database.Connect(":memory:")
+	assert.NoError(t, err)
+	assert.NoError(t, database.Migrate(db))
+	if !db.Migrator().HasTable(database.HarvestSearchTable) {
+		t.Skip("SQLite was built without FTS5, build with -tags sqlite_fts5")
+	}
+
+	harvestRepo := repository.NewHarvestRepository(db)
+	clock := scheduler.NewManualClock(time.Date(2026, time.March, 2, 9, 0, 0, 0, time.UTC))
+	harvestService := NewHarvestService(harvestRepo, repository.NewUserRepository(db), repository.NewTransactionRepository(db), db, clock)
+
+	inDescription, err := harvestService.CreateHarvest(HarvestParams{Title: "Weekly chores", Description: "Check the backups restore", BeanAmount: 10})
+	assert.NoError(t, err)
+	inTitle, err := harvestService.CreateHarvest(HarvestParams{Title: "Test backup restores", Description: "Restore last night's backup", BeanAmount: 10})
+	assert.NoError(t, err)
+	_, err = harvestService.CreateHarvest(HarvestParams{Title: "Unrelated", Description: "Nothing to see", BeanAmount: 10})
+	assert.NoError(t, err)
+
+	harvests, total, err := harvestService.SearchHarvests(repository.HarvestFilter{Query: "backup restore", Page: 1, Limit: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	if assert.Len(t, harvests, 2) {
+		assert.Equal(t, inTitle.ID, harvests[0].ID)
+		assert.Equal(t, inDescription.ID, harvests[1].ID)
+	}
+
+	// Edits are reindexed and punctuation is not query syntax.
+	_, err = harvestService.UpdateHarvest(inDescription.ID, HarvestParams{Title: "Weekly chores", Description: "Sweep", BeanAmount: 10})
+	assert.NoError(t, err)
+	harvests, _, err = harvestService.SearchHarvests(repository.HarvestFilter{Query: `"backup" (restore*`, Page: 1, Limit: 10})
+	assert.NoError(t, err)
+	if assert.Len(t, harvests, 1) {
+		assert.Equal(t, inTitle.ID, harvests[0].ID)
+	}
+}
+
+func TestHarvestService_RevertCompletion(t *testing.T) {
+	env := setupLedgerTestDB(t)
+	for _, username := range []string{"alice", "bob", "carol"} {
+		_, err := env.walletService.GetOrCreateWallet(username)
+		require.NoError(t, err)
+	}
+
+	harvest, err := env.harvestService.CreateHarvest(HarvestParams{Title: "Sort the seeds", BeanAmount: 30})
+	require.NoError(t, err)
+	_, err = env.harvestService.SetParticipants(harvest.ID, models.HarvestSplitEqual, []HarvestShare{{Username: "alice"}, {Username: "bob"}}, "admin")
+	require.NoError(t, err)
+	_, err = env.harvestService.CompleteHarvest(harvest.ID)
+	require.NoError(t, err)
+	// alice keeps 6 of her 15 beans, counting the signup bonus.
+	require.NoError(t, env.transferService.Transfer("alice", "carol", 10, true, "spent"))
+
+	_, _, err = env.harvestService.RevertCompletion(harvest.ID, "admin", ClawbackRefuse, " ")
+	assert.Equal(t, ErrReasonRequired, err)
+	_, _, err = env.harvestService.RevertCompletion(harvest.ID, "admin", "sometimes", "Wrong harvest")
+	assert.Equal(t, ErrInvalidClawbackPolicy, err)
+
+	_, _, err = env.harvestService.RevertCompletion(harvest.ID, "admin", ClawbackRefuse, "Wrong harvest")
+	assert.Equal(t, ErrClawbackShortfall, err)
+	unchanged, err := env.harvestService.GetHarvest(harvest.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.HarvestPaid, unchanged.Status)
+	assertBalance(t, env.userRepo, "alice", 6)
+
+	reverted, clawbacks, err := env.harvestService.RevertCompletion(harvest.ID, "admin", ClawbackPartial, "Wrong harvest")
+	require.NoError(t, err)
+	assert.Equal(t, models.HarvestOpen, reverted.Status)
+	assert.False(t, reverted.Completed)
+	assert.Nil(t, reverted.AssignedUserID)
+	assert.Empty(t, reverted.Participants)
+	assert.Equal(t, []HarvestClawback{
+		{Username: "alice", Owed: 15, Recovered: 6},
+		{Username: "bob", Owed: 15, Recovered: 15},
+	}, clawbacks)
+	assertBalance(t, env.userRepo, "alice", 0)
+	assertBalance(t, env.userRepo, "bob", 1)
+	assertBalance(t, env.userRepo, "mint", -12)
+
+	events, err := env.harvestService.ListEvents(harvest.ID)
+	require.NoError(t, err)
+	last := events[len(events)-1]
+	assert.Equal(t, models.HarvestEventReverted, last.Type)
+	assert.Equal(t, models.HarvestPaid, last.FromStatus)
+	assert.Equal(t, "admin", last.Actor)
+	assert.Equal(t, "Wrong harvest. Clawed back 6 of 15 beans from alice, 15 beans from bob", last.Note)
+
+	_, _, err = env.harvestService.RevertCompletion(harvest.ID, "admin", ClawbackRefuse, "Again")
+	assert.Equal(t, ErrHarvestNotCompleted, err)
+
+	// The 9 beans written off from alice stay written off when the harvest
+	// is paid and reverted a second time.
+	_, err = env.harvestService.SetParticipants(harvest.ID, models.HarvestSplitEqual, []HarvestShare{{Username: "alice"}, {Username: "bob"}}, "admin")
+	require.NoError(t, err)
+	_, err = env.harvestService.CompleteHarvest(harvest.ID)
+	require.NoError(t, err)
+	_, clawbacks, err = env.harvestService.RevertCompletion(harvest.ID, "admin", ClawbackRefuse, "Still wrong")
+	require.NoError(t, err)
+	assert.Equal(t, []HarvestClawback{
+		{Username: "alice", Owed: 15, Recovered: 15},
+		{Username: "bob", Owed: 15, Recovered: 15},
+	}, clawbacks)
+	assertBalance(t, env.userRepo, "alice", 0)
+	assertBalance(t, env.userRepo, "bob", 1)
+
+	report, err := env.ledgerService.Reconcile(false)
+	require.NoError(t, err)
+	assert.True(t, report.Clean())
+}
+
+func TestHarvestService_RevertRefusesUntrackedPayouts(t *testing.T) {
+	env := setupLedgerTestDB(t)
+
+	// A harvest paid before payouts were tagged with their harvest.
+	harvest, err := env.harvestService.CreateHarvest(HarvestParams{Title: "Old news", BeanAmount: 10})
+	require.NoError(t, err)
+	require.NoError(t, env.db.Model(&models.Harvest{}).Where("id = ?", harvest.ID).
+		Updates(map[string]interface{}{"completed": true, "status": models.HarvestPaid}).Error)
+
+	_, _, err = env.harvestService.RevertCompletion(harvest.ID, "admin", ClawbackAllowNegative, "Paid twice")
+	assert.Equal(t, ErrNoPayoutsOnRecord, err)
+
+	unchanged, err := env.harvestService.GetHarvest(harvest.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.HarvestPaid, unchanged.Status)
+	assert.True(t, unchanged.Completed)
+}
+
+func TestHarvestService_RevertAllowsNegativeBalance(t *testing.T) {
+	env := setupLedgerTestDB(t)
+	for _, username := range []string{"alice", "carol"} {
+		_, err := env.walletService.GetOrCreateWallet(username)
+		require.NoError(t, err)
+	}
+
+	harvest, err := env.harvestService.CreateHarvest(HarvestParams{Title: "Water the beans", BeanAmount: 20})
+	require.NoError(t, err)
+	_, err = env.harvestService.AssignUserByUsername(harvest.ID, "alice")
+	require.NoError(t, err)
+	_, err = env.harvestService.CompleteHarvest(harvest.ID)
+	require.NoError(t, err)
+	// alice keeps 6 of her 20 beans, counting the signup bonus.
+	require.NoError(t, env.transferService.Transfer("alice", "carol", 15, true, "spent"))
+
+	_, clawbacks, err := env.harvestService.RevertCompletion(harvest.ID, "admin", ClawbackAllowNegative, "Paid the wrong person")
+	require.NoError(t, err)
+	assert.Equal(t, []HarvestClawback{{Username: "alice", Owed: 20, Recovered: 20}}, clawbacks)
+	assertBalance(t, env.userRepo, "alice", -14)
+
+	// Completing it again and reverting only takes back the new payout.
+	_, err = env.harvestService.AssignUserByUsername(harvest.ID, "carol")
+	require.NoError(t, err)
+	_, err = env.harvestService.CompleteHarvest(harvest.ID)
+	require.NoError(t, err)
+	_, clawbacks, err = env.harvestService.RevertCompletion(harvest.ID, "admin", ClawbackRefuse, "Not done yet")
+	require.NoError(t, err)
+	assert.Equal(t, []HarvestClawback{{Username: "carol", Owed: 20, Recovered: 20}}, clawbacks)
+	assertBalance(t, env.userRepo, "carol", 16)
+	assertBalance(t, env.userRepo, "alice", -14)
+
+	report, err := env.ledgerService.Reconcile(false)
+	require.NoError(t, err)
+	assert.True(t, report.Clean())
+}
@@ -0,0 +1,374 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/h4ks-com/bean-bank/internal/database"
+	"github.com/h4ks-com/bean-bank/internal/duration"
+	"github.com/h4ks-com/bean-bank/internal/models"
+	"github.com/h4ks-com/bean-bank/internal/repository"
+	"github.com/h4ks-com/bean-bank/internal/scheduler"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrHarvestTemplateNotFound  = errors.New("harvest template not found")
+	ErrInvalidTemplateFrequency = errors.New("frequency must be one of daily, weekly, monthly or cron")
+	ErrInvalidTemplateDeadline  = errors.New("deadline must be a duration of at least a minute, such as 36h, 7d or 2w")
+	ErrHarvestTemplateNotPaused = errors.New("harvest template is not paused")
+	ErrHarvestTemplateNotActive = errors.New("harvest template is not active")
+)
+
+const harvestTemplateBatchSize = 100
+
+// HarvestTemplateParams describes a new harvest template or the new details
+// of one being edited. Deadline and ClaimTimeout are durations counted from
+// each spawn. Assignee, if set, claims every spawned harvest. StartAt
+// anchors daily, weekly and monthly rules; it defaults to now, or to the
+// current anchor when editing.
+type HarvestTemplateParams struct {
+	Title        string
+	Description  string
+	BeanAmount   int
+	Deadline     string
+	ClaimTimeout string
+	Category     string
+	Tags         []string
+	Assignee     string
+	Frequency    models.ScheduleFrequency
+	Cron         string
+	StartAt      *time.Time
+	EndsAt       *time.Time
+}
+
+type HarvestTemplateService struct {
+	templateRepo   *repository.HarvestTemplateRepository
+	harvestService *HarvestService
+	db             *gorm.DB
+	clock          scheduler.Clock
+}
+
+func NewHarvestTemplateService(
+	templateRepo *repository.HarvestTemplateRepository,
+	harvestService *HarvestService,
+	db *gorm.DB,
+	clock scheduler.Clock,
+) *HarvestTemplateService {
+	return &HarvestTemplateService{
+		templateRepo:   templateRepo,
+		harvestService: harvestService,
+		db:             db,
+		clock:          clock,
+	}
+}
+
+func (s *HarvestTemplateService) CreateTemplate(params HarvestTemplateParams) (*models.HarvestTemplate, error) {
+	now := s.clock.Now()
+	template := &models.HarvestTemplate{Status: models.HarvestTemplateActive}
+	if err := s.applyParams(template, params, now); err != nil {
+		return nil, err
+	}
+
+	if err := s.templateRepo.Create(template); err != nil {
+		return nil, fmt.Errorf("failed to create harvest template: %w", err)
+	}
+
+	return s.templateRepo.FindByID(template.ID)
+}
+
+// UpdateTemplate replaces the details and recurrence rule of a template. An
+// active template is rescheduled from its last spawn, so editing it does not
+// spawn the current occurrence twice.
+func (s *HarvestTemplateService) UpdateTemplate(id uint, params HarvestTemplateParams) (*models.HarvestTemplate, error) {
+	now := s.clock.Now()
+
+	err := database.Transaction(s.db, func(tx *gorm.DB) error {
+		template, err := s.lockTemplate(tx, id)
+		if err != nil {
+			return err
+		}
+		if err := s.applyParams(template, params, now); err != nil {
+			return err
+		}
+		if template.Status == models.HarvestTemplatePaused {
+			template.NextRunAt = nil
+		}
+		return s.templateRepo.UpdateInTx(tx, template)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.templateRepo.FindByID(id)
+}
+
+// applyParams validates params and copies them onto template, working out
+// when it next spawns. A finished template whose new rule has occurrences
+// left becomes active again.
+func (s *HarvestTemplateService) applyParams(template *models.HarvestTemplate, params HarvestTemplateParams, now time.Time) error {
+	if params.BeanAmount <= 0 {
+		return ErrInvalidAmount
+	}
+
+	var deadline, claimTimeout time.Duration
+	var err error
+	if value := strings.TrimSpace(params.Deadline); value != "" {
+		deadline, err = duration.Parse(value)
+		if err != nil || deadline < time.Minute {
+			return ErrInvalidTemplateDeadline
+		}
+	}
+	if value := strings.TrimSpace(params.ClaimTimeout); value != "" {
+		claimTimeout, err = duration.Parse(value)
+		if err != nil || claimTimeout < time.Minute {
+			return ErrInvalidClaimTimeout
+		}
+	}
+
+	category, tags, err := parseHarvestLabels(params.Category, params.Tags)
+	if err != nil {
+		return err
+	}
+
+	var assignee *models.User
+	if params.Assignee != "" {
+		assignee, err = s.harvestService.findParticipantUser(params.Assignee)
+		if err != nil {
+			return err
+		}
+	}
+
+	// An edit without a start time keeps the template's anchor.
+	start := now
+	if template.ID != 0 {
+		start = template.StartAt
+	}
+	if params.StartAt != nil {
+		start = *params.StartAt
+	}
+	if params.EndsAt != nil && !params.EndsAt.After(start) {
+		return ErrScheduleEndsBeforeStart
+	}
+
+	cronExpr := ""
+	switch params.Frequency {
+	case models.ScheduleDaily, models.ScheduleWeekly, models.ScheduleMonthly:
+	case models.ScheduleCron:
+		cron, err := scheduler.ParseCron(params.Cron)
+		if err != nil {
+			return ErrInvalidCron
+		}
+		cronExpr = cron.String()
+	default:
+		return ErrInvalidTemplateFrequency
+	}
+
+	// The next spawn is the first occurrence from now on, or after the last
+	// spawn if that is later, so nothing is spawned twice.
+	after := now.Add(-time.Nanosecond)
+	if template.LastRunAt != nil && template.LastRunAt.After(after) {
+		after = *template.LastRunAt
+	}
+	if start.After(after) {
+		after = start.Add(-time.Nanosecond)
+	}
+	next, err := nextRecurrence(params.Frequency, cronExpr, start, params.EndsAt, after)
+	if err != nil {
+		return err
+	}
+	if next == nil && template.SpawnCount == 0 {
+		return ErrScheduleNeverRuns
+	}
+
+	template.Title = params.Title
+	template.Description = params.Description
+	template.BeanAmount = params.BeanAmount
+	template.DeadlineSeconds = int64(deadline / time.Second)
+	template.ClaimTimeoutSeconds = int64(claimTimeout / time.Second)
+	template.Category = category
+	template.Tags = strings.Join(tags, "\n")
+	template.AssigneeID = nil
+	template.Assignee = nil
+	if assignee != nil {
+		template.AssigneeID = &assignee.ID
+	}
+	template.Frequency = params.Frequency
+	template.CronExpr = cronExpr
+	template.StartAt = start
+	template.EndsAt = params.EndsAt
+	template.NextRunAt = next
+
+	switch {
+	case next == nil:
+		template.Status = models.HarvestTemplateFinished
+	case template.Status == models.HarvestTemplateFinished:
+		template.Status = models.HarvestTemplateActive
+	}
+	return nil
+}
+
+func (s *HarvestTemplateService) ListTemplates() ([]models.HarvestTemplate, error) {
+	return s.templateRepo.FindAll()
+}
+
+func (s *HarvestTemplateService) GetTemplate(id uint) (*models.HarvestTemplate, error) {
+	template, err := s.templateRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if template == nil {
+		return nil, ErrHarvestTemplateNotFound
+	}
+	return template, nil
+}
+
+// PauseTemplate stops a template from spawning until it is resumed.
+func (s *HarvestTemplateService) PauseTemplate(id uint) (*models.HarvestTemplate, error) {
+	err := database.Transaction(s.db, func(tx *gorm.DB) error {
+		template, err := s.lockTemplate(tx, id)
+		if err != nil {
+			return err
+		}
+		if template.Status != models.HarvestTemplateActive {
+			return ErrHarvestTemplateNotActive
+		}
+
+		template.Status = models.HarvestTemplatePaused
+		template.NextRunAt = nil
+		return s.templateRepo.UpdateInTx(tx, template)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.templateRepo.FindByID(id)
+}
+
+// ResumeTemplate picks a paused template up again from its next occurrence.
+// Occurrences that fell while it was paused are not spawned.
+func (s *HarvestTemplateService) ResumeTemplate(id uint) (*models.HarvestTemplate, error) {
+	now := s.clock.Now()
+
+	err := database.Transaction(s.db, func(tx *gorm.DB) error {
+		template, err := s.lockTemplate(tx, id)
+		if err != nil {
+			return err
+		}
+		if template.Status != models.HarvestTemplatePaused {
+			return ErrHarvestTemplateNotPaused
+		}
+
+		after := now
+		if template.StartAt.After(after) {
+			after = template.StartAt.Add(-time.Nanosecond)
+		}
+		next, err := nextRecurrence(template.Frequency, template.CronExpr, template.StartAt, template.EndsAt, after)
+		if err != nil {
+			return err
+		}
+
+		template.NextRunAt = next
+		template.Status = models.HarvestTemplateActive
+		if next == nil {
+			template.Status = models.HarvestTemplateFinished
+		}
+		return s.templateRepo.UpdateInTx(tx, template)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.templateRepo.FindByID(id)
+}
+
+// DeleteTemplate removes a template. Harvests it already spawned are kept.
+func (s *HarvestTemplateService) DeleteTemplate(id uint) error {
+	if _, err := s.GetTemplate(id); err != nil {
+		return err
+	}
+	return s.templateRepo.Delete(id)
+}
+
+// RunDue spawns a harvest for every template that is due at now and returns
+// how many were spawned.
+func (s *HarvestTemplateService) RunDue(now time.Time) (int, error) {
+	ids, err := s.templateRepo.FindDueIDs(now, harvestTemplateBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	spawned := 0
+	var firstErr error
+	for _, id := range ids {
+		ok, err := s.spawn(id, now)
+		if err != nil {
+			log.Printf("[HarvestTemplates] Failed to spawn from template %d: %v", id, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if ok {
+			spawned++
+		}
+	}
+
+	return spawned, firstErr
+}
+
+func (s *HarvestTemplateService) spawn(id uint, now time.Time) (bool, error) {
+	spawned := false
+
+	err := database.Transaction(s.db, func(tx *gorm.DB) error {
+		spawned = false
+
+		template, err := s.templateRepo.FindByIDForUpdate(tx, id)
+		if err != nil {
+			return err
+		}
+		// Another instance may have spawned or paused it since it was listed.
+		if template == nil || template.Status != models.HarvestTemplateActive ||
+			template.NextRunAt == nil || template.NextRunAt.After(now) {
+			return nil
+		}
+
+		if _, err := s.harvestService.spawnInTx(tx, template, now); err != nil {
+			return err
+		}
+
+		next, err := nextRecurrence(template.Frequency, template.CronExpr, template.StartAt, template.EndsAt, now)
+		if err != nil {
+			return err
+		}
+
+		template.LastRunAt = &now
+		template.NextRunAt = next
+		template.SpawnCount++
+		if next == nil {
+			template.Status = models.HarvestTemplateFinished
+		}
+		if err := s.templateRepo.UpdateInTx(tx, template); err != nil {
+			return fmt.Errorf("failed to update harvest template: %w", err)
+		}
+
+		spawned = true
+		return nil
+	})
+
+	return spawned, err
+}
+
+func (s *HarvestTemplateService) lockTemplate(tx *gorm.DB, id uint) (*models.HarvestTemplate, error) {
+	template, err := s.templateRepo.FindByIDForUpdate(tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if template == nil {
+		return nil, ErrHarvestTemplateNotFound
+	}
+	return template, nil
+}
@@ -0,0 +1,197 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/h4ks-com/bean-bank/internal/database"
+	"github.com/h4ks-com/bean-bank/internal/models"
+	"github.com/h4ks-com/bean-bank/internal/repository"
+	"github.com/h4ks-com/bean-bank/internal/scheduler"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupHarvestTemplateTestDB(t *testing.T) (*repository.UserRepository, *HarvestService, *HarvestTemplateService, *scheduler.ManualClock) {
+	db, err := database.Connect(":memory:")
+	assert.NoError(t, err)
+	assert.NoError(t, database.Migrate(db))
+
+	userRepo := repository.NewUserRepository(db)
+	clock := scheduler.NewManualClock(time.Date(2026, time.March, 2, 9, 0, 0, 0, time.UTC))
+	harvestService := NewHarvestService(repository.NewHarvestRepository(db), userRepo, repository.NewTransactionRepository(db), db, clock)
+	templateService := NewHarvestTemplateService(repository.NewHarvestTemplateRepository(db), harvestService, db, clock)
+
+	return userRepo, harvestService, templateService, clock
+}
+
+func TestHarvestTemplateService_SpawnsWeekly(t *testing.T) {
+	_, harvestService, templateService, clock := setupHarvestTemplateTestDB(t)
+
+	template, err := templateService.CreateTemplate(HarvestTemplateParams{
+		Title:        "Rotate backups",
+		Description:  "Weekly chore",
+		BeanAmount:   25,
+		Deadline:     "3d",
+		ClaimTimeout: "1d",
+		Frequency:    models.ScheduleWeekly,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, models.HarvestTemplateActive, template.Status)
+	assert.Equal(t, clock.Now(), template.NextRunAt.UTC())
+
+	spawned, err := templateService.RunDue(clock.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, spawned)
+
+	spawned, err = templateService.RunDue(clock.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, spawned)
+
+	harvests, err := harvestService.GetAllHarvests()
+	assert.NoError(t, err)
+	assert.Len(t, harvests, 1)
+	harvest := harvests[0]
+	assert.Equal(t, "Rotate backups", harvest.Title)
+	assert.Equal(t, 25, harvest.BeanAmount)
+	assert.Equal(t, models.HarvestOpen, harvest.Status)
+	assert.Equal(t, template.ID, *harvest.TemplateID)
+	assert.Equal(t, clock.Now().Add(72*time.Hour), harvest.DueAt.UTC())
+	assert.Equal(t, int64(86400), harvest.ClaimTimeoutSeconds)
+
+	events, err := harvestService.ListEvents(harvest.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Spawned from template #1", events[0].Note)
+
+	clock.Advance(7 * 24 * time.Hour)
+	spawned, err = templateService.RunDue(clock.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, spawned)
+
+	template, err = templateService.GetTemplate(template.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, template.SpawnCount)
+	assert.Equal(t, clock.Now().Add(7*24*time.Hour), template.NextRunAt.UTC())
+}
+
+func TestHarvestTemplateService_PreAssigned(t *testing.T) {
+	userRepo, harvestService, templateService, clock := setupHarvestTemplateTestDB(t)
+
+	assert.NoError(t, userRepo.Create(&models.User{Username: "mod"}))
+
+	_, err := templateService.CreateTemplate(HarvestTemplateParams{
+		Title:        "Moderate #h4ks",
+		BeanAmount:   10,
+		ClaimTimeout: "2d",
+		Assignee:     "mod",
+		Frequency:    models.ScheduleCron,
+		Cron:         "0 9 * * 1",
+	})
+	assert.NoError(t, err)
+
+	spawned, err := templateService.RunDue(clock.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, spawned)
+
+	harvests, err := harvestService.GetAllHarvests()
+	assert.NoError(t, err)
+	assert.Len(t, harvests, 1)
+	assert.Equal(t, models.HarvestClaimed, harvests[0].Status)
+	assert.Equal(t, "mod", harvests[0].AssignedUser.Username)
+	assert.Len(t, harvests[0].Participants, 1)
+	assert.Equal(t, clock.Now().Add(48*time.Hour), harvests[0].ClaimExpiresAt.UTC())
+
+	_, err = templateService.CreateTemplate(HarvestTemplateParams{Title: "Ghost", BeanAmount: 1, Assignee: "nobody", Frequency: models.ScheduleDaily})
+	assert.Equal(t, ErrUserNotFound, err)
+
+	_, err = templateService.CreateTemplate(HarvestTemplateParams{Title: "Mint", BeanAmount: 1, Assignee: "mint", Frequency: models.ScheduleDaily})
+	assert.Equal(t, ErrReservedAccount, err)
+}
+
+func TestHarvestTemplateService_PauseResume(t *testing.T) {
+	_, harvestService, templateService, clock := setupHarvestTemplateTestDB(t)
+
+	template, err := templateService.CreateTemplate(HarvestTemplateParams{Title: "Daily", BeanAmount: 5, Frequency: models.ScheduleDaily})
+	assert.NoError(t, err)
+
+	paused, err := templateService.PauseTemplate(template.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.HarvestTemplatePaused, paused.Status)
+	assert.Nil(t, paused.NextRunAt)
+
+	_, err = templateService.PauseTemplate(template.ID)
+	assert.Equal(t, ErrHarvestTemplateNotActive, err)
+
+	clock.Advance(3*24*time.Hour + time.Hour)
+	spawned, err := templateService.RunDue(clock.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, spawned)
+
+	resumed, err := templateService.ResumeTemplate(template.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.HarvestTemplateActive, resumed.Status)
+	assert.Equal(t, time.Date(2026, time.March, 6, 9, 0, 0, 0, time.UTC), resumed.NextRunAt.UTC())
+
+	_, err = templateService.ResumeTemplate(template.ID)
+	assert.Equal(t, ErrHarvestTemplateNotPaused, err)
+
+	harvests, err := harvestService.GetAllHarvests()
+	assert.NoError(t, err)
+	assert.Empty(t, harvests)
+}
+
+func TestHarvestTemplateService_Update(t *testing.T) {
+	_, harvestService, templateService, clock := setupHarvestTemplateTestDB(t)
+
+	template, err := templateService.CreateTemplate(HarvestTemplateParams{Title: "Weekly", BeanAmount: 5, Frequency: models.ScheduleWeekly})
+	assert.NoError(t, err)
+
+	_, err = templateService.RunDue(clock.Now())
+	assert.NoError(t, err)
+
+	updated, err := templateService.UpdateTemplate(template.ID, HarvestTemplateParams{Title: "Daily now", BeanAmount: 8, Frequency: models.ScheduleDaily})
+	assert.NoError(t, err)
+	assert.Equal(t, "Daily now", updated.Title)
+	assert.Equal(t, clock.Now().Add(24*time.Hour), updated.NextRunAt.UTC())
+	assert.Equal(t, 1, updated.SpawnCount)
+
+	spawned, err := templateService.RunDue(clock.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, spawned)
+
+	harvests, err := harvestService.GetAllHarvests()
+	assert.NoError(t, err)
+	assert.Len(t, harvests, 1)
+	assert.Equal(t, "Weekly", harvests[0].Title)
+
+	_, err = templateService.UpdateTemplate(template.ID, HarvestTemplateParams{Title: "Bad", BeanAmount: 5, Frequency: models.ScheduleOnce})
+	assert.Equal(t, ErrInvalidTemplateFrequency, err)
+
+	_, err = templateService.UpdateTemplate(template.ID, HarvestTemplateParams{Title: "Bad", BeanAmount: 5, Frequency: models.ScheduleCron, Cron: "nope"})
+	assert.Equal(t, ErrInvalidCron, err)
+
+	_, err = templateService.UpdateTemplate(template.ID, HarvestTemplateParams{Title: "Bad", BeanAmount: 5, Frequency: models.ScheduleDaily, Deadline: "30s"})
+	assert.Equal(t, ErrInvalidTemplateDeadline, err)
+
+	_, err = templateService.UpdateTemplate(999, HarvestTemplateParams{Title: "Missing", BeanAmount: 5, Frequency: models.ScheduleDaily})
+	assert.Equal(t, ErrHarvestTemplateNotFound, err)
+}
+
+func TestHarvestTemplateService_FinishesAtEnd(t *testing.T) {
+	_, _, templateService, clock := setupHarvestTemplateTestDB(t)
+
+	endsAt := clock.Now().Add(36 * time.Hour)
+	template, err := templateService.CreateTemplate(HarvestTemplateParams{Title: "Short", BeanAmount: 5, Frequency: models.ScheduleDaily, EndsAt: &endsAt})
+	assert.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		_, err = templateService.RunDue(clock.Now())
+		assert.NoError(t, err)
+		clock.Advance(24 * time.Hour)
+	}
+
+	template, err = templateService.GetTemplate(template.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, template.SpawnCount)
+	assert.Equal(t, models.HarvestTemplateFinished, template.Status)
+	assert.Nil(t, template.NextRunAt)
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/h4ks-com/bean-bank/internal/models"
+	"github.com/h4ks-com/bean-bank/internal/repository"
+)
+
+var (
+	ErrIdempotencyKeyReused     = errors.New("idempotency key was already used with a different request")
+	ErrIdempotencyKeyInProgress = errors.New("a request with this idempotency key is still in progress")
+	ErrIdempotencyKeyInvalid    = errors.New("idempotency key must be between 1 and 255 characters")
+)
+
+// IdempotencyKeyTTL is how long a stored response can be replayed before the
+// key may be reused for a new request.
+const IdempotencyKeyTTL = 24 * time.Hour
+
+type IdempotencyService struct {
+	idempotencyRepo *repository.IdempotencyRepository
+	userRepo        *repository.UserRepository
+}
+
+func NewIdempotencyService(idempotencyRepo *repository.IdempotencyRepository, userRepo *repository.UserRepository) *IdempotencyService {
+	return &IdempotencyService{
+		idempotencyRepo: idempotencyRepo,
+		userRepo:        userRepo,
+	}
+}
+
+// Fingerprint hashes the method, path and body of a request. JSON bodies are
+// re-encoded first so that whitespace and key order do not matter.
+func (s *IdempotencyService) Fingerprint(method, path string, body []byte) string {
+	var decoded interface{}
+	if err := json.Unmarshal(body, &decoded); err == nil {
+		if canonical, err := json.Marshal(decoded); err == nil {
+			body = canonical
+		}
+	}
+
+	h := sha256.New()
+	h.Write([]byte(method))
+	h.Write([]byte(" "))
+	h.Write([]byte(path))
+	h.Write([]byte("\n"))
+	h.Write(bytes.TrimSpace(body))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Begin reserves the key for the user. If the key was already used for the same
+// request and the response is stored, that record is returned with replay set
+// to true. A nil record means the user has no wallet and the request should
+// proceed without idempotency tracking.
+func (s *IdempotencyService) Begin(username, key, fingerprint string) (record *models.IdempotencyKey, replay bool, err error) {
+	if len(key) == 0 || len(key) > 255 {
+		return nil, false, ErrIdempotencyKeyInvalid
+	}
+
+	user, err := s.userRepo.FindByUsername(username)
+	if err != nil {
+		return nil, false, err
+	}
+	if user == nil {
+		return nil, false, nil
+	}
+
+	existing, err := s.idempotencyRepo.FindByUserAndKey(user.ID, key)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if existing != nil && time.Since(existing.CreatedAt) > IdempotencyKeyTTL {
+		if err := s.idempotencyRepo.Delete(existing.ID); err != nil {
+			return nil, false, err
+		}
+		existing = nil
+	}
+
+	if existing == nil {
+		record = &models.IdempotencyKey{
+			UserID:      user.ID,
+			Key:         key,
+			RequestHash: fingerprint,
+		}
+		createErr := s.idempotencyRepo.Create(record)
+		if createErr == nil {
+			return record, false, nil
+		}
+
+		// Another request may have reserved the key in the meantime.
+		existing, err = s.idempotencyRepo.FindByUserAndKey(user.ID, key)
+		if err != nil {
+			return nil, false, err
+		}
+		if existing == nil {
+			return nil, false, createErr
+		}
+	}
+
+	if existing.RequestHash != fingerprint {
+		return nil, false, ErrIdempotencyKeyReused
+	}
+
+	// An unfinished key is never handed to a retry, however old: the
+	// response is stored after the request's own transaction commits, so
+	// the request may have moved beans even if it never finished.
+	if !existing.Completed {
+		return nil, false, ErrIdempotencyKeyInProgress
+	}
+
+	return existing, true, nil
+}
+
+// Complete stores the response so later retries can replay it.
+func (s *IdempotencyService) Complete(record *models.IdempotencyKey, statusCode int, body []byte) error {
+	record.StatusCode = statusCode
+	record.ResponseBody = body
+	record.Completed = true
+	return s.idempotencyRepo.Update(record)
+}
+
+// Release frees the key so the request can be retried, used when the request
+// failed without a result worth replaying.
+func (s *IdempotencyService) Release(record *models.IdempotencyKey) error {
+	return s.idempotencyRepo.Delete(record.ID)
+}
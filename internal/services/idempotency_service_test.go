@@ -0,0 +1,153 @@
+package services
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/h4ks-com/bean-bank/internal/database"
+	"github.com/h4ks-com/bean-bank/internal/models"
+	"github.com/h4ks-com/bean-bank/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupIdempotencyTestDB(t *testing.T) (*repository.UserRepository, *IdempotencyService) {
+	db, err := database.Connect(":memory:")
+	require.NoError(t, err)
+
+	err = database.Migrate(db)
+	require.NoError(t, err)
+
+	userRepo := repository.NewUserRepository(db)
+	idempotencyRepo := repository.NewIdempotencyRepository(db)
+	idempotencyService := NewIdempotencyService(idempotencyRepo, userRepo)
+
+	return userRepo, idempotencyService
+}
+
+func TestIdempotencyService_Fingerprint(t *testing.T) {
+	_, service := setupIdempotencyTestDB(t)
+
+	a := service.Fingerprint(http.MethodPost, "/api/v1/transfer", []byte(`{"to_user":"bob","amount":5}`))
+	b := service.Fingerprint(http.MethodPost, "/api/v1/transfer", []byte(`{ "amount": 5, "to_user": "bob" }`))
+	c := service.Fingerprint(http.MethodPost, "/api/v1/transfer", []byte(`{"to_user":"bob","amount":6}`))
+	d := service.Fingerprint(http.MethodPost, "/api/v1/giftlinks", []byte(`{"to_user":"bob","amount":5}`))
+
+	assert.Equal(t, a, b, "key order and whitespace should not change the fingerprint")
+	assert.NotEqual(t, a, c)
+	assert.NotEqual(t, a, d)
+}
+
+func TestIdempotencyService_BeginAndReplay(t *testing.T) {
+	userRepo, service := setupIdempotencyTestDB(t)
+	require.NoError(t, userRepo.Create(&models.User{Username: "alice", BeanAmount: 100}))
+
+	fingerprint := service.Fingerprint(http.MethodPost, "/api/v1/transfer", []byte(`{"to_user":"bob","amount":5}`))
+
+	record, replay, err := service.Begin("alice", "key-1", fingerprint)
+	require.NoError(t, err)
+	require.NotNil(t, record)
+	assert.False(t, replay)
+
+	t.Run("concurrent retry is rejected while in progress", func(t *testing.T) {
+		_, _, err := service.Begin("alice", "key-1", fingerprint)
+		assert.ErrorIs(t, err, ErrIdempotencyKeyInProgress)
+	})
+
+	require.NoError(t, service.Complete(record, http.StatusOK, []byte(`{"message":"transfer successful"}`)))
+
+	t.Run("retry replays stored response", func(t *testing.T) {
+		stored, replay, err := service.Begin("alice", "key-1", fingerprint)
+		require.NoError(t, err)
+		assert.True(t, replay)
+		assert.Equal(t, http.StatusOK, stored.StatusCode)
+		assert.JSONEq(t, `{"message":"transfer successful"}`, string(stored.ResponseBody))
+	})
+
+	t.Run("reuse with different body conflicts", func(t *testing.T) {
+		other := service.Fingerprint(http.MethodPost, "/api/v1/transfer", []byte(`{"to_user":"bob","amount":500}`))
+		_, _, err := service.Begin("alice", "key-1", other)
+		assert.ErrorIs(t, err, ErrIdempotencyKeyReused)
+	})
+}
+
+func TestIdempotencyService_KeysAreScopedPerUser(t *testing.T) {
+	userRepo, service := setupIdempotencyTestDB(t)
+	require.NoError(t, userRepo.Create(&models.User{Username: "alice", BeanAmount: 100}))
+	require.NoError(t, userRepo.Create(&models.User{Username: "bob", BeanAmount: 100}))
+
+	aliceRecord, _, err := service.Begin("alice", "shared", "hash-a")
+	require.NoError(t, err)
+	require.NoError(t, service.Complete(aliceRecord, http.StatusOK, []byte(`{}`)))
+
+	bobRecord, replay, err := service.Begin("bob", "shared", "hash-b")
+	require.NoError(t, err)
+	assert.False(t, replay)
+	assert.NotEqual(t, aliceRecord.ID, bobRecord.ID)
+}
+
+func TestIdempotencyService_Release(t *testing.T) {
+	userRepo, service := setupIdempotencyTestDB(t)
+	require.NoError(t, userRepo.Create(&models.User{Username: "alice", BeanAmount: 100}))
+
+	record, _, err := service.Begin("alice", "key-1", "hash")
+	require.NoError(t, err)
+	require.NoError(t, service.Release(record))
+
+	again, replay, err := service.Begin("alice", "key-1", "other-hash")
+	require.NoError(t, err)
+	assert.False(t, replay)
+	assert.NotNil(t, again)
+}
+
+func TestIdempotencyService_UnfinishedKeyStaysInProgress(t *testing.T) {
+	db, err := database.Connect(":memory:")
+	require.NoError(t, err)
+	require.NoError(t, database.Migrate(db))
+
+	userRepo := repository.NewUserRepository(db)
+	service := NewIdempotencyService(repository.NewIdempotencyRepository(db), userRepo)
+	require.NoError(t, userRepo.Create(&models.User{Username: "alice", BeanAmount: 100}))
+
+	// The first request reserves the key and dies without storing its
+	// response, possibly after its transfer committed.
+	abandoned, _, err := service.Begin("alice", "key-1", "hash")
+	require.NoError(t, err)
+	require.NoError(t, db.Model(&models.IdempotencyKey{}).
+		Where("id = ?", abandoned.ID).
+		UpdateColumns(map[string]interface{}{
+			"created_at": time.Now().Add(-time.Hour),
+			"updated_at": time.Now().Add(-time.Hour),
+		}).Error)
+
+	_, _, err = service.Begin("alice", "key-1", "hash")
+	assert.ErrorIs(t, err, ErrIdempotencyKeyInProgress)
+
+	require.NoError(t, db.Model(&models.IdempotencyKey{}).
+		Where("id = ?", abandoned.ID).
+		UpdateColumn("created_at", time.Now().Add(-IdempotencyKeyTTL-time.Minute)).Error)
+
+	record, replay, err := service.Begin("alice", "key-1", "hash")
+	require.NoError(t, err)
+	assert.False(t, replay)
+	assert.NotNil(t, record, "an expired key can be used again")
+}
+
+func TestIdempotencyService_InvalidKey(t *testing.T) {
+	userRepo, service := setupIdempotencyTestDB(t)
+	require.NoError(t, userRepo.Create(&models.User{Username: "alice", BeanAmount: 100}))
+
+	_, _, err := service.Begin("alice", strings.Repeat("k", 256), "hash")
+	assert.ErrorIs(t, err, ErrIdempotencyKeyInvalid)
+}
+
+func TestIdempotencyService_UnknownUserSkipsTracking(t *testing.T) {
+	_, service := setupIdempotencyTestDB(t)
+
+	record, replay, err := service.Begin("ghost", "key-1", "hash")
+	require.NoError(t, err)
+	assert.Nil(t, record)
+	assert.False(t, replay)
+}
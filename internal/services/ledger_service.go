@@ -0,0 +1,180 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/h4ks-com/bean-bank/internal/database"
+	"github.com/h4ks-com/bean-bank/internal/models"
+	"github.com/h4ks-com/bean-bank/internal/repository"
+	"gorm.io/gorm"
+)
+
+type LedgerService struct {
+	userRepo        *repository.UserRepository
+	transactionRepo *repository.TransactionRepository
+	ledgerRepo      *repository.LedgerRepository
+	db              *gorm.DB
+}
+
+func NewLedgerService(userRepo *repository.UserRepository, transactionRepo *repository.TransactionRepository, ledgerRepo *repository.LedgerRepository, db *gorm.DB) *LedgerService {
+	return &LedgerService{
+		userRepo:        userRepo,
+		transactionRepo: transactionRepo,
+		ledgerRepo:      ledgerRepo,
+		db:              db,
+	}
+}
+
+// AccountDrift describes a wallet whose stored balance does not match the sum
+// of its ledger entries.
+type AccountDrift struct {
+	UserID        uint
+	Username      string
+	StoredBalance int
+	LedgerBalance int
+}
+
+func (d AccountDrift) Drift() int {
+	return d.StoredBalance - d.LedgerBalance
+}
+
+type ReconcileReport struct {
+	AccountsChecked        int
+	Drifts                 []AccountDrift
+	UnbalancedTransactions []uint
+	UnpostedTransactions   int64
+	TotalMinted            int
+	TotalCirculating       int
+	CorrectionsPosted      int
+}
+
+func (r *ReconcileReport) Clean() bool {
+	return len(r.Drifts) == 0 && len(r.UnbalancedTransactions) == 0
+}
+
+// Reconcile recomputes every wallet from its ledger entries and reports any
+// account whose stored balance has drifted. With fix set, drift is corrected by
+// posting reconciliation entries against the mint so the ledger matches the
+// stored balances again.
+//
+// Every wallet is locked before balances and ledger sums are read, so a
+// transfer committing halfway through cannot show up as drift and be "fixed".
+// Transfers wait until the check is done, which makes it safe to run against
+// a live server.
+func (s *LedgerService) Reconcile(fix bool) (*ReconcileReport, error) {
+	report := &ReconcileReport{}
+
+	err := database.Transaction(s.db, func(tx *gorm.DB) error {
+		users, err := s.userRepo.LockAll(tx)
+		if err != nil {
+			return err
+		}
+
+		sums, err := s.ledgerRepo.SumByUser(tx)
+		if err != nil {
+			return err
+		}
+
+		var mintUser *models.User
+		report.AccountsChecked = len(users)
+		for i, user := range users {
+			if user.Username == models.MintUsername {
+				mintUser = &users[i]
+				continue
+			}
+			report.TotalCirculating += user.BeanAmount
+			if user.BeanAmount != sums[user.ID] {
+				report.Drifts = append(report.Drifts, AccountDrift{
+					UserID:        user.ID,
+					Username:      user.Username,
+					StoredBalance: user.BeanAmount,
+					LedgerBalance: sums[user.ID],
+				})
+			}
+		}
+
+		report.UnbalancedTransactions, err = s.ledgerRepo.FindUnbalancedTransactionIDs(tx)
+		if err != nil {
+			return err
+		}
+
+		report.UnpostedTransactions, err = s.ledgerRepo.CountTransactionsWithoutEntries(tx)
+		if err != nil {
+			return err
+		}
+
+		if fix {
+			for _, drift := range report.Drifts {
+				if err := s.postCorrection(tx, mintUser, drift); err != nil {
+					return err
+				}
+				report.CorrectionsPosted++
+			}
+		}
+
+		mintBalance, err := s.ledgerRepo.SumForUser(tx, mintUser.ID)
+		if err != nil {
+			return err
+		}
+
+		if mintUser.BeanAmount != mintBalance {
+			report.Drifts = append(report.Drifts, AccountDrift{
+				UserID:        mintUser.ID,
+				Username:      mintUser.Username,
+				StoredBalance: mintUser.BeanAmount,
+				LedgerBalance: mintBalance,
+			})
+			if fix {
+				// The mint balance is derived entirely from the ledger.
+				mintUser.BeanAmount = mintBalance
+				if err := s.userRepo.UpdateInTx(tx, mintUser); err != nil {
+					return err
+				}
+				report.CorrectionsPosted++
+			}
+		}
+		report.TotalMinted = -mintUser.BeanAmount
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// postCorrection writes ledger entries that bring the account's ledger balance
+// in line with its stored balance. The stored balance of the account is left
+// untouched; the mint absorbs the difference.
+func (s *LedgerService) postCorrection(tx *gorm.DB, mintUser *models.User, drift AccountDrift) error {
+	amount := drift.Drift()
+
+	mintUser.BeanAmount -= amount
+	if err := s.userRepo.UpdateInTx(tx, mintUser); err != nil {
+		return err
+	}
+
+	transaction := &models.Transaction{
+		FromUserID: mintUser.ID,
+		ToUserID:   drift.UserID,
+		Amount:     amount,
+		Note:       fmt.Sprintf("Ledger correction: stored balance %d, ledger balance %d", drift.StoredBalance, drift.LedgerBalance),
+		Kind:       models.TransactionKindReconciliation,
+	}
+	if amount < 0 {
+		transaction.FromUserID = drift.UserID
+		transaction.ToUserID = mintUser.ID
+		transaction.Amount = -amount
+	}
+
+	if err := s.transactionRepo.Create(tx, transaction); err != nil {
+		return err
+	}
+
+	return s.ledgerRepo.CreateEntries(tx, []models.LedgerEntry{
+		{TransactionID: transaction.ID, UserID: mintUser.ID, Amount: -amount, BalanceAfter: mintUser.BeanAmount},
+		{TransactionID: transaction.ID, UserID: drift.UserID, Amount: amount, BalanceAfter: drift.StoredBalance},
+	})
+}
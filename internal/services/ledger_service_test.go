@@ -0,0 +1,231 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/h4ks-com/bean-bank/internal/database"
+	"github.com/h4ks-com/bean-bank/internal/models"
+	"github.com/h4ks-com/bean-bank/internal/repository"
+	"github.com/h4ks-com/bean-bank/internal/scheduler"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type ledgerTestEnv struct {
+	db              *gorm.DB
+	userRepo        *repository.UserRepository
+	ledgerRepo      *repository.LedgerRepository
+	walletService   *WalletService
+	transferService *TransferService
+	harvestService  *HarvestService
+	ledgerService   *LedgerService
+}
+
+func setupLedgerTestDB(t *testing.T) *ledgerTestEnv {
+	db, err := database.Connect(":memory:")
+	require.NoError(t, err)
+
+	err = database.Migrate(db)
+	require.NoError(t, err)
+
+	userRepo := repository.NewUserRepository(db)
+	transactionRepo := repository.NewTransactionRepository(db)
+	ledgerRepo := repository.NewLedgerRepository(db)
+	harvestRepo := repository.NewHarvestRepository(db)
+
+	return &ledgerTestEnv{
+		db:              db,
+		userRepo:        userRepo,
+		ledgerRepo:      ledgerRepo,
+		walletService:   NewWalletService(userRepo, transactionRepo, db),
+		transferService: NewTransferService(userRepo, transactionRepo, db),
+		harvestService:  NewHarvestService(harvestRepo, userRepo, transactionRepo, db, scheduler.SystemClock()),
+		ledgerService:   NewLedgerService(userRepo, transactionRepo, ledgerRepo, db),
+	}
+}
+
+func TestLedgerService_TransferPostsBalancedEntries(t *testing.T) {
+	env := setupLedgerTestDB(t)
+
+	_, err := env.walletService.GetOrCreateWallet("alice")
+	require.NoError(t, err)
+	require.NoError(t, env.walletService.SetBalance("alice", 100, models.TransactionKindImport, ""))
+
+	var transaction models.Transaction
+	require.NoError(t, env.db.Transaction(func(tx *gorm.DB) error {
+		created, err := env.transferService.ExecuteInTx(tx, TransferParams{From: "alice", To: "bob", Amount: 30, Force: true})
+		if err == nil {
+			transaction = *created
+		}
+		return err
+	}))
+
+	entries, err := env.ledgerRepo.FindByTransactionID(transaction.ID)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, -30, entries[0].Amount)
+	assert.Equal(t, 70, entries[0].BalanceAfter)
+	assert.Equal(t, 30, entries[1].Amount)
+	assert.Equal(t, 30, entries[1].BalanceAfter)
+}
+
+func TestLedgerService_ReconcileClean(t *testing.T) {
+	env := setupLedgerTestDB(t)
+
+	_, err := env.walletService.GetOrCreateWallet("alice")
+	require.NoError(t, err)
+	require.NoError(t, env.walletService.SetBalance("alice", 100, models.TransactionKindImport, ""))
+	require.NoError(t, env.transferService.Transfer("alice", "bob", 40, true, ""))
+
+	harvest, err := env.harvestService.CreateHarvest(HarvestParams{Title: "Harvest", Description: "Do things", BeanAmount: 25})
+	require.NoError(t, err)
+	_, err = env.harvestService.AssignUserByUsername(harvest.ID, "bob")
+	require.NoError(t, err)
+	_, err = env.harvestService.CompleteHarvest(harvest.ID)
+	require.NoError(t, err)
+
+	report, err := env.ledgerService.Reconcile(false)
+	require.NoError(t, err)
+	assert.True(t, report.Clean())
+	assert.Empty(t, report.Drifts)
+	assert.Equal(t, 125, report.TotalMinted)
+	assert.Equal(t, 125, report.TotalCirculating)
+}
+
+func TestLedgerService_ReconcileReportsAndFixesDrift(t *testing.T) {
+	env := setupLedgerTestDB(t)
+
+	_, err := env.walletService.GetOrCreateWallet("alice")
+	require.NoError(t, err)
+	require.NoError(t, env.walletService.SetBalance("alice", 100, models.TransactionKindImport, ""))
+
+	require.NoError(t, env.db.Model(&models.User{}).Where("username = ?", "alice").Update("bean_amount", 150).Error)
+
+	report, err := env.ledgerService.Reconcile(false)
+	require.NoError(t, err)
+	assert.False(t, report.Clean())
+	require.Len(t, report.Drifts, 1)
+	assert.Equal(t, "alice", report.Drifts[0].Username)
+	assert.Equal(t, 150, report.Drifts[0].StoredBalance)
+	assert.Equal(t, 100, report.Drifts[0].LedgerBalance)
+	assert.Equal(t, 50, report.Drifts[0].Drift())
+	assert.Equal(t, 0, report.CorrectionsPosted)
+
+	report, err = env.ledgerService.Reconcile(true)
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.CorrectionsPosted)
+
+	report, err = env.ledgerService.Reconcile(false)
+	require.NoError(t, err)
+	assert.True(t, report.Clean())
+	assert.Equal(t, 150, report.TotalMinted)
+
+	balance, err := env.walletService.GetBalance("alice")
+	require.NoError(t, err)
+	assert.Equal(t, 150, balance, "fixing drift must not change the stored balance")
+}
+
+func TestLedgerService_MintExcludedFromTotals(t *testing.T) {
+	env := setupLedgerTestDB(t)
+
+	_, err := env.walletService.GetOrCreateWallet("alice")
+	require.NoError(t, err)
+	require.NoError(t, env.walletService.SetBalance("alice", 100, models.TransactionKindImport, ""))
+
+	total, err := env.walletService.GetTotalBeans()
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), total)
+
+	wallets, err := env.walletService.GetTopWallets(10)
+	require.NoError(t, err)
+	for _, wallet := range wallets {
+		assert.NotEqual(t, models.MintUsername, wallet.Username)
+	}
+
+	assert.Error(t, env.userRepo.Create(&models.User{Username: models.MintUsername}))
+}
+
+func TestLedgerService_MigrateRefusesPersonNamedMint(t *testing.T) {
+	db, err := database.Connect(":memory:")
+	require.NoError(t, err)
+	require.NoError(t, database.Migrate(db))
+
+	// A wallet named mint from before the name was reserved.
+	person := &models.User{Username: models.MintUsername, BeanAmount: 25}
+	require.NoError(t, db.Create(person).Error)
+
+	userRepo := repository.NewUserRepository(db)
+	err = db.Transaction(func(tx *gorm.DB) error {
+		_, err := userRepo.LockUsers(tx, "alice", models.MintUsername)
+		return err
+	})
+	assert.Error(t, err)
+
+	err = database.Migrate(db)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "rename")
+	}
+
+	require.NoError(t, db.Model(person).Update("username", "mint-the-person").Error)
+	require.NoError(t, database.Migrate(db))
+	mint, err := userRepo.FindByUsername(models.MintUsername)
+	require.NoError(t, err)
+	assert.Nil(t, mint)
+}
+
+func TestLedgerService_MigrateAdoptsUnflaggedMint(t *testing.T) {
+	db, err := database.Connect(":memory:")
+	require.NoError(t, err)
+	require.NoError(t, database.Migrate(db))
+
+	// The mint account as it was created before Reserved existed.
+	require.NoError(t, db.Create(&models.User{Username: models.MintUsername, BeanAmount: -40}).Error)
+	require.NoError(t, database.Migrate(db))
+
+	var mint models.User
+	require.NoError(t, db.Where("username = ?", models.MintUsername).First(&mint).Error)
+	assert.True(t, mint.Reserved)
+	assert.Equal(t, -40, mint.BeanAmount)
+}
+
+func TestLedgerService_MigrateOpensLedgerForExistingWallets(t *testing.T) {
+	env := setupLedgerTestDB(t)
+
+	// Wallets funded before the ledger existed. bob's balance comes only
+	// from a transfer posted since, so he needs no opening entry.
+	for _, user := range []models.User{
+		{Username: "alice", BeanAmount: 30},
+		{Username: "bob", BeanAmount: 0},
+		{Username: models.SystemUsername, BeanAmount: 5, Reserved: true},
+	} {
+		require.NoError(t, env.db.Create(&user).Error)
+	}
+	require.NoError(t, env.transferService.Transfer("alice", "bob", 10, false, ""))
+
+	report, err := env.ledgerService.Reconcile(false)
+	require.NoError(t, err)
+	require.Len(t, report.Drifts, 2)
+
+	// Upgrading a database that never ran the migration.
+	require.NoError(t, env.db.Unscoped().Where("name = ?", "ledger_opening_balances").Delete(&models.DataMigration{}).Error)
+	require.NoError(t, database.Migrate(env.db))
+
+	report, err = env.ledgerService.Reconcile(false)
+	require.NoError(t, err)
+	assert.True(t, report.Clean(), "ledger drift: %+v", report.Drifts)
+	assert.Equal(t, 35, report.TotalMinted)
+	assertBalance(t, env.userRepo, "alice", 20)
+	assertBalance(t, env.userRepo, "bob", 10)
+
+	var openings int64
+	require.NoError(t, env.db.Model(&models.Transaction{}).Where("kind = ?", models.TransactionKindOpeningBalance).Count(&openings).Error)
+	assert.Equal(t, int64(2), openings)
+
+	// It only runs once, so later drift is still reported.
+	require.NoError(t, env.db.Model(&models.User{}).Where("username = ?", "bob").Update("bean_amount", 99).Error)
+	require.NoError(t, database.Migrate(env.db))
+	report, err = env.ledgerService.Reconcile(false)
+	require.NoError(t, err)
+	assert.Len(t, report.Drifts, 1)
+}
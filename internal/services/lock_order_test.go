@@ -0,0 +1,133 @@
+package services
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/h4ks-com/bean-bank/internal/models"
+	"github.com/h4ks-com/bean-bank/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// walletLockRecorder watches every SELECT ... FOR UPDATE on the users table
+// and checks the rule LockUsers documents: within a transaction, wallets are
+// locked in ascending ID order, and a later lock never takes a wallet below
+// one the transaction already holds. SQLite has no row locks, so this is how
+// the SQLite tests catch an ordering that would deadlock on Postgres.
+type walletLockRecorder struct {
+	mu         sync.Mutex
+	held       map[gorm.ConnPool][]uint
+	violations []string
+}
+
+func recordWalletLocks(t *testing.T, db *gorm.DB) *walletLockRecorder {
+	r := &walletLockRecorder{held: make(map[gorm.ConnPool][]uint)}
+	err := db.Callback().Query().After("gorm:query").Register("test:record_wallet_locks", r.record)
+	require.NoError(t, err)
+	return r
+}
+
+func (r *walletLockRecorder) record(tx *gorm.DB) {
+	if _, locking := tx.Statement.Clauses["FOR"]; !locking || tx.Error != nil || tx.Statement.Table != "users" {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	users, ok := tx.Statement.Dest.(*[]models.User)
+	if !ok {
+		r.violations = append(r.violations, fmt.Sprintf("wallet locked into %T rather than through LockUsers", tx.Statement.Dest))
+		return
+	}
+	pool := tx.Statement.ConnPool
+	if _, inTx := pool.(gorm.TxCommitter); !inTx {
+		r.violations = append(r.violations, "wallets locked outside a transaction")
+		return
+	}
+
+	held := r.held[pool]
+	for _, user := range *users {
+		if slices.Contains(held, user.ID) {
+			continue
+		}
+		if len(held) > 0 && user.ID < slices.Max(held) {
+			r.violations = append(r.violations, fmt.Sprintf("wallet %d (%s) locked after wallet %d", user.ID, user.Username, slices.Max(held)))
+		}
+		held = append(held, user.ID)
+	}
+	r.held[pool] = held
+}
+
+func (r *walletLockRecorder) assertOrdered(t *testing.T) {
+	t.Helper()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	assert.NotEmpty(t, r.held, "no wallet locks were recorded")
+	assert.Empty(t, r.violations)
+}
+
+func TestWalletLockOrder(t *testing.T) {
+	env := setupLedgerTestDB(t)
+	locks := recordWalletLocks(t, env.db)
+
+	transactionRepo := repository.NewTransactionRepository(env.db)
+	harvestRepo := repository.NewHarvestRepository(env.db)
+	adjustmentService := NewAdjustmentService(env.userRepo, transactionRepo, repository.NewAdjustmentRepository(env.db), env.db)
+	bountyService := NewBountyService(env.harvestService, harvestRepo, env.userRepo, transactionRepo, env.db)
+
+	// Created in reverse alphabetical order so that ID order and name order
+	// disagree.
+	for _, username := range []string{"zed", "amy"} {
+		require.NoError(t, env.userRepo.Create(&models.User{Username: username}))
+		_, err := adjustmentService.Adjust(AdjustmentParams{Actor: "admin", Username: username, Delta: intPtr(100), Reason: "opening balance"})
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, env.transferService.Transfer("amy", "zed", 5, false, ""))
+	require.NoError(t, env.transferService.Transfer("zed", "amy", 5, false, ""))
+	_, err := env.walletService.GetOrCreateWallet("newbie")
+	require.NoError(t, err)
+	_, err = env.transferService.BatchTransfer(BatchTransferParams{
+		From:       "zed",
+		Recipients: []BatchRecipient{{To: "newbie"}, {To: "amy"}},
+		Total:      10,
+	})
+	require.NoError(t, err)
+	_, err = adjustmentService.Adjust(AdjustmentParams{Actor: "admin", Username: "amy", Delta: intPtr(20), Reason: "prize"})
+	require.NoError(t, err)
+
+	harvest, err := env.harvestService.CreateHarvest(HarvestParams{Title: "Weed the garden", BeanAmount: 12})
+	require.NoError(t, err)
+	_, err = env.harvestService.SetParticipants(harvest.ID, models.HarvestSplitEqual, []HarvestShare{
+		{Username: "zed"}, {Username: "newbie"}, {Username: "amy"},
+	}, "admin")
+	require.NoError(t, err)
+	_, err = env.harvestService.CompleteHarvest(harvest.ID)
+	require.NoError(t, err)
+	_, _, err = env.harvestService.RevertCompletion(harvest.ID, "admin", ClawbackRefuse, "Not done")
+	require.NoError(t, err)
+
+	bounty, err := bountyService.CreateBounty("zed", HarvestParams{Title: "Fix the fence", BeanAmount: 10})
+	require.NoError(t, err)
+	_, err = bountyService.FundBounty(bounty.ID, "amy", 5)
+	require.NoError(t, err)
+	_, err = env.harvestService.ClaimHarvest(bounty.ID, "newbie")
+	require.NoError(t, err)
+	_, err = env.harvestService.CompleteHarvest(bounty.ID)
+	require.NoError(t, err)
+	_, _, err = env.harvestService.RevertCompletion(bounty.ID, "admin", ClawbackRefuse, "Not fixed")
+	require.NoError(t, err)
+	_, err = bountyService.CancelBounty(bounty.ID, "zed", false)
+	require.NoError(t, err)
+
+	report, err := env.ledgerService.Reconcile(false)
+	require.NoError(t, err)
+	assert.True(t, report.Clean(), "ledger drift: %+v", report.Drifts)
+
+	locks.assertOrdered(t)
+}
@@ -0,0 +1,312 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/h4ks-com/bean-bank/internal/database"
+	"github.com/h4ks-com/bean-bank/internal/models"
+	"github.com/h4ks-com/bean-bank/internal/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrPaymentRequestNotFound   = errors.New("payment request not found")
+	ErrPaymentRequestExpired    = errors.New("payment request has expired")
+	ErrPaymentRequestNotPending = errors.New("payment request is no longer pending")
+	ErrPayerNotFound            = errors.New("payer not found")
+	ErrCannotPayOwnRequest      = errors.New("cannot pay your own payment request")
+	ErrNotRequestPayer          = errors.New("payment request is addressed to another user")
+	ErrNotRequestRequester      = errors.New("only the requester can cancel a payment request")
+	ErrInvalidRequestDirection  = errors.New("direction must be 'incoming' or 'outgoing'")
+	ErrInvalidRequestStatus     = errors.New("status must be one of pending, paid, declined or cancelled")
+)
+
+// PaymentRequestParams describes a new payment request. Payer may be left
+// empty so that anyone with the link can pay.
+type PaymentRequestParams struct {
+	Requester string
+	Payer     string
+	Amount    int
+	Memo      string
+	ExpiresIn string
+}
+
+type PaymentRequestService struct {
+	paymentRequestRepo *repository.PaymentRequestRepository
+	userRepo           *repository.UserRepository
+	transferService    *TransferService
+	db                 *gorm.DB
+	maxExpiry          time.Duration
+}
+
+// NewPaymentRequestService creates the payment request service. A non-zero
+// maxExpiry caps how far ahead new requests may expire.
+func NewPaymentRequestService(
+	paymentRequestRepo *repository.PaymentRequestRepository,
+	userRepo *repository.UserRepository,
+	transferService *TransferService,
+	db *gorm.DB,
+	maxExpiry time.Duration,
+) *PaymentRequestService {
+	return &PaymentRequestService{
+		paymentRequestRepo: paymentRequestRepo,
+		userRepo:           userRepo,
+		transferService:    transferService,
+		db:                 db,
+		maxExpiry:          maxExpiry,
+	}
+}
+
+func (s *PaymentRequestService) generateUniqueCode() (string, error) {
+	for i := 0; i < 10; i++ {
+		bytes := make([]byte, 32)
+		if _, err := rand.Read(bytes); err != nil {
+			return "", err
+		}
+
+		code := base64.URLEncoding.EncodeToString(bytes)
+
+		existing, err := s.paymentRequestRepo.FindByCode(code)
+		if err != nil {
+			return "", err
+		}
+		if existing == nil {
+			return code, nil
+		}
+	}
+	return "", errors.New("failed to generate unique code after 10 attempts")
+}
+
+func (s *PaymentRequestService) CreatePaymentRequest(params PaymentRequestParams) (*models.PaymentRequest, error) {
+	if params.Amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+
+	memo, err := SanitizeNote(params.Memo)
+	if err != nil {
+		return nil, err
+	}
+
+	expiry, err := ParseExpiry(params.ExpiresIn, time.Now(), s.maxExpiry)
+	if err != nil {
+		return nil, err
+	}
+
+	if params.Payer == params.Requester {
+		return nil, ErrCannotPayOwnRequest
+	}
+	if models.IsReservedUsername(params.Payer) {
+		return nil, ErrReservedAccount
+	}
+
+	requester, err := s.userRepo.FindByUsername(params.Requester)
+	if err != nil {
+		return nil, err
+	}
+	if requester == nil {
+		return nil, ErrUserNotFound
+	}
+
+	var payerID *uint
+	if params.Payer != "" {
+		payer, err := s.userRepo.FindByUsername(params.Payer)
+		if err != nil {
+			return nil, err
+		}
+		if payer == nil {
+			return nil, ErrPayerNotFound
+		}
+		payerID = &payer.ID
+	}
+
+	code, err := s.generateUniqueCode()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate payment request code: %w", err)
+	}
+
+	request := &models.PaymentRequest{
+		Code:        code,
+		RequesterID: requester.ID,
+		PayerID:     payerID,
+		Amount:      params.Amount,
+		Memo:        memo,
+		ExpiresAt:   expiry,
+		Status:      models.PaymentRequestPending,
+	}
+
+	err = database.Transaction(s.db, func(tx *gorm.DB) error {
+		return s.paymentRequestRepo.CreateInTx(tx, request)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create payment request: %w", err)
+	}
+
+	return s.paymentRequestRepo.FindByID(request.ID)
+}
+
+// PayPaymentRequest sends the requested beans from the payer to the requester
+// and links the resulting transaction to the request.
+func (s *PaymentRequestService) PayPaymentRequest(code string, payerUsername string) (*models.PaymentRequest, error) {
+	var requestID uint
+
+	err := database.Transaction(s.db, func(tx *gorm.DB) error {
+		request, err := s.paymentRequestRepo.FindByCodeForUpdate(tx, code)
+		if err != nil {
+			return err
+		}
+		if request == nil {
+			return ErrPaymentRequestNotFound
+		}
+		requestID = request.ID
+
+		if request.Status != models.PaymentRequestPending {
+			return ErrPaymentRequestNotPending
+		}
+		if request.IsExpired(time.Now()) {
+			return ErrPaymentRequestExpired
+		}
+
+		var requester models.User
+		if err := tx.First(&requester, request.RequesterID).Error; err != nil {
+			return err
+		}
+		if requester.Username == payerUsername {
+			return ErrCannotPayOwnRequest
+		}
+
+		if request.PayerID != nil {
+			var payer models.User
+			if err := tx.First(&payer, *request.PayerID).Error; err != nil {
+				return err
+			}
+			if payer.Username != payerUsername {
+				return ErrNotRequestPayer
+			}
+		}
+
+		transaction, err := s.transferService.ExecuteInTx(tx, TransferParams{
+			From:             payerUsername,
+			To:               requester.Username,
+			Amount:           request.Amount,
+			Force:            true,
+			Note:             request.Memo,
+			Kind:             models.TransactionKindPaymentRequest,
+			PaymentRequestID: &request.ID,
+		})
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		request.Status = models.PaymentRequestPaid
+		request.PaidAt = &now
+		request.PaidByID = &transaction.FromUserID
+		request.TransactionID = &transaction.ID
+
+		if err := s.paymentRequestRepo.UpdateInTx(tx, request); err != nil {
+			return fmt.Errorf("failed to update payment request: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.paymentRequestRepo.FindByID(requestID)
+}
+
+// DeclinePaymentRequest lets the addressed payer turn a request down. Open
+// requests cannot be declined since they are not addressed to anyone.
+func (s *PaymentRequestService) DeclinePaymentRequest(id uint, username string) (*models.PaymentRequest, error) {
+	return s.resolve(id, username, models.PaymentRequestDeclined, func(request *models.PaymentRequest, user *models.User) error {
+		if request.PayerID == nil || *request.PayerID != user.ID {
+			return ErrNotRequestPayer
+		}
+		return nil
+	})
+}
+
+// CancelPaymentRequest withdraws a pending request. Only the requester may
+// cancel it.
+func (s *PaymentRequestService) CancelPaymentRequest(id uint, username string) (*models.PaymentRequest, error) {
+	return s.resolve(id, username, models.PaymentRequestCancelled, func(request *models.PaymentRequest, user *models.User) error {
+		if request.RequesterID != user.ID {
+			return ErrNotRequestRequester
+		}
+		return nil
+	})
+}
+
+// resolve moves a pending request to a final status. Users who are not a party
+// to the request get ErrPaymentRequestNotFound so ids cannot be probed.
+func (s *PaymentRequestService) resolve(id uint, username string, status models.PaymentRequestStatus, authorize func(*models.PaymentRequest, *models.User) error) (*models.PaymentRequest, error) {
+	user, err := s.userRepo.FindByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	err = database.Transaction(s.db, func(tx *gorm.DB) error {
+		request, err := s.paymentRequestRepo.FindByIDForUpdate(tx, id)
+		if err != nil {
+			return err
+		}
+		if request == nil || (request.RequesterID != user.ID && (request.PayerID == nil || *request.PayerID != user.ID)) {
+			return ErrPaymentRequestNotFound
+		}
+
+		if err := authorize(request, user); err != nil {
+			return err
+		}
+		if request.Status != models.PaymentRequestPending {
+			return ErrPaymentRequestNotPending
+		}
+
+		request.Status = status
+		return s.paymentRequestRepo.UpdateInTx(tx, request)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.paymentRequestRepo.FindByID(id)
+}
+
+// ListPaymentRequests returns the user's incoming and outgoing requests.
+// Direction and status may be empty to include everything.
+func (s *PaymentRequestService) ListPaymentRequests(username string, direction string, status string) ([]models.PaymentRequest, error) {
+	dir := repository.PaymentRequestDirection(direction)
+	switch dir {
+	case "", repository.PaymentRequestIncoming, repository.PaymentRequestOutgoing:
+	default:
+		return nil, ErrInvalidRequestDirection
+	}
+
+	st := models.PaymentRequestStatus(status)
+	switch st {
+	case "", models.PaymentRequestPending, models.PaymentRequestPaid, models.PaymentRequestDeclined, models.PaymentRequestCancelled:
+	default:
+		return nil, ErrInvalidRequestStatus
+	}
+
+	user, err := s.userRepo.FindByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	return s.paymentRequestRepo.ListForUser(user.ID, dir, st)
+}
+
+func (s *PaymentRequestService) GetPaymentRequestByCode(code string) (*models.PaymentRequest, error) {
+	return s.paymentRequestRepo.FindByCode(code)
+}
@@ -0,0 +1,183 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/h4ks-com/bean-bank/internal/database"
+	"github.com/h4ks-com/bean-bank/internal/models"
+	"github.com/h4ks-com/bean-bank/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func setupPaymentRequestTestDB(t *testing.T) (*gorm.DB, *repository.UserRepository, *PaymentRequestService) {
+	db, err := database.Connect(":memory:")
+	require.NoError(t, err)
+
+	err = database.Migrate(db)
+	require.NoError(t, err)
+
+	userRepo := repository.NewUserRepository(db)
+	transactionRepo := repository.NewTransactionRepository(db)
+	paymentRequestRepo := repository.NewPaymentRequestRepository(db)
+	transferService := NewTransferService(userRepo, transactionRepo, db)
+	service := NewPaymentRequestService(paymentRequestRepo, userRepo, transferService, db, 0)
+
+	require.NoError(t, userRepo.Create(&models.User{Username: "alice", BeanAmount: 100}))
+	require.NoError(t, userRepo.Create(&models.User{Username: "bob", BeanAmount: 100}))
+	require.NoError(t, userRepo.Create(&models.User{Username: "carol", BeanAmount: 100}))
+
+	return db, userRepo, service
+}
+
+func TestPaymentRequestService_PayLinksTransaction(t *testing.T) {
+	db, userRepo, service := setupPaymentRequestTestDB(t)
+
+	request, err := service.CreatePaymentRequest(PaymentRequestParams{Requester: "alice", Payer: "bob", Amount: 40, Memo: "pizza"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, request.Code)
+	assert.Equal(t, models.PaymentRequestPending, request.Status)
+	require.NotNil(t, request.Payer)
+	assert.Equal(t, "bob", request.Payer.Username)
+
+	t.Run("only the addressed payer can pay", func(t *testing.T) {
+		_, err := service.PayPaymentRequest(request.Code, "carol")
+		assert.ErrorIs(t, err, ErrNotRequestPayer)
+	})
+
+	paid, err := service.PayPaymentRequest(request.Code, "bob")
+	require.NoError(t, err)
+	assert.Equal(t, models.PaymentRequestPaid, paid.Status)
+	require.NotNil(t, paid.PaidBy)
+	assert.Equal(t, "bob", paid.PaidBy.Username)
+	require.NotNil(t, paid.TransactionID)
+
+	var transaction models.Transaction
+	require.NoError(t, db.First(&transaction, *paid.TransactionID).Error)
+	assert.Equal(t, models.TransactionKindPaymentRequest, transaction.Kind)
+	assert.Equal(t, 40, transaction.Amount)
+	assert.Equal(t, "pizza", transaction.Note)
+	require.NotNil(t, transaction.PaymentRequestID)
+	assert.Equal(t, paid.ID, *transaction.PaymentRequestID)
+
+	alice, err := userRepo.FindByUsername("alice")
+	require.NoError(t, err)
+	assert.Equal(t, 140, alice.BeanAmount)
+	bob, err := userRepo.FindByUsername("bob")
+	require.NoError(t, err)
+	assert.Equal(t, 60, bob.BeanAmount)
+
+	t.Run("cannot pay twice", func(t *testing.T) {
+		_, err := service.PayPaymentRequest(request.Code, "bob")
+		assert.ErrorIs(t, err, ErrPaymentRequestNotPending)
+	})
+}
+
+func TestPaymentRequestService_OpenRequest(t *testing.T) {
+	_, _, service := setupPaymentRequestTestDB(t)
+
+	request, err := service.CreatePaymentRequest(PaymentRequestParams{Requester: "alice", Amount: 10})
+	require.NoError(t, err)
+	assert.Nil(t, request.PayerID)
+
+	_, err = service.PayPaymentRequest(request.Code, "alice")
+	assert.ErrorIs(t, err, ErrCannotPayOwnRequest)
+
+	_, err = service.DeclinePaymentRequest(request.ID, "carol")
+	assert.ErrorIs(t, err, ErrPaymentRequestNotFound, "strangers cannot see open requests by id")
+
+	paid, err := service.PayPaymentRequest(request.Code, "carol")
+	require.NoError(t, err)
+	assert.Equal(t, "carol", paid.PaidBy.Username)
+}
+
+func TestPaymentRequestService_FailedPaymentStaysPending(t *testing.T) {
+	_, _, service := setupPaymentRequestTestDB(t)
+
+	request, err := service.CreatePaymentRequest(PaymentRequestParams{Requester: "alice", Payer: "bob", Amount: 500})
+	require.NoError(t, err)
+
+	_, err = service.PayPaymentRequest(request.Code, "bob")
+	assert.ErrorIs(t, err, ErrInsufficientBalance)
+
+	requests, err := service.ListPaymentRequests("bob", "incoming", "pending")
+	require.NoError(t, err)
+	require.Len(t, requests, 1)
+	assert.Nil(t, requests[0].TransactionID)
+}
+
+func TestPaymentRequestService_DeclineAndCancel(t *testing.T) {
+	_, _, service := setupPaymentRequestTestDB(t)
+
+	first, err := service.CreatePaymentRequest(PaymentRequestParams{Requester: "alice", Payer: "bob", Amount: 5})
+	require.NoError(t, err)
+	second, err := service.CreatePaymentRequest(PaymentRequestParams{Requester: "alice", Payer: "bob", Amount: 6})
+	require.NoError(t, err)
+
+	t.Run("requester cannot decline", func(t *testing.T) {
+		_, err := service.DeclinePaymentRequest(first.ID, "alice")
+		assert.ErrorIs(t, err, ErrNotRequestPayer)
+	})
+
+	t.Run("payer cannot cancel", func(t *testing.T) {
+		_, err := service.CancelPaymentRequest(first.ID, "bob")
+		assert.ErrorIs(t, err, ErrNotRequestRequester)
+	})
+
+	declined, err := service.DeclinePaymentRequest(first.ID, "bob")
+	require.NoError(t, err)
+	assert.Equal(t, models.PaymentRequestDeclined, declined.Status)
+
+	cancelled, err := service.CancelPaymentRequest(second.ID, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, models.PaymentRequestCancelled, cancelled.Status)
+
+	_, err = service.PayPaymentRequest(first.Code, "bob")
+	assert.ErrorIs(t, err, ErrPaymentRequestNotPending)
+
+	_, err = service.CancelPaymentRequest(first.ID, "alice")
+	assert.ErrorIs(t, err, ErrPaymentRequestNotPending)
+
+	outgoing, err := service.ListPaymentRequests("alice", "outgoing", "")
+	require.NoError(t, err)
+	assert.Len(t, outgoing, 2)
+
+	incoming, err := service.ListPaymentRequests("alice", "incoming", "")
+	require.NoError(t, err)
+	assert.Empty(t, incoming)
+}
+
+func TestPaymentRequestService_Expired(t *testing.T) {
+	db, _, service := setupPaymentRequestTestDB(t)
+
+	request, err := service.CreatePaymentRequest(PaymentRequestParams{Requester: "alice", Payer: "bob", Amount: 5, ExpiresIn: "1h"})
+	require.NoError(t, err)
+	require.NotNil(t, request.ExpiresAt)
+
+	past := time.Now().Add(-time.Minute)
+	require.NoError(t, db.Model(&models.PaymentRequest{}).Where("id = ?", request.ID).Update("expires_at", past).Error)
+
+	_, err = service.PayPaymentRequest(request.Code, "bob")
+	assert.ErrorIs(t, err, ErrPaymentRequestExpired)
+}
+
+func TestPaymentRequestService_Validation(t *testing.T) {
+	_, _, service := setupPaymentRequestTestDB(t)
+
+	_, err := service.CreatePaymentRequest(PaymentRequestParams{Requester: "alice", Payer: "bob", Amount: 0})
+	assert.ErrorIs(t, err, ErrInvalidAmount)
+
+	_, err = service.CreatePaymentRequest(PaymentRequestParams{Requester: "alice", Payer: "alice", Amount: 5})
+	assert.ErrorIs(t, err, ErrCannotPayOwnRequest)
+
+	_, err = service.CreatePaymentRequest(PaymentRequestParams{Requester: "alice", Payer: "ghost", Amount: 5})
+	assert.ErrorIs(t, err, ErrPayerNotFound)
+
+	_, err = service.CreatePaymentRequest(PaymentRequestParams{Requester: "alice", Payer: models.MintUsername, Amount: 5})
+	assert.ErrorIs(t, err, ErrReservedAccount)
+
+	_, err = service.ListPaymentRequests("alice", "sideways", "")
+	assert.ErrorIs(t, err, ErrInvalidRequestDirection)
+}
@@ -0,0 +1,444 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/h4ks-com/bean-bank/internal/database"
+	"github.com/h4ks-com/bean-bank/internal/models"
+	"github.com/h4ks-com/bean-bank/internal/repository"
+	"github.com/h4ks-com/bean-bank/internal/scheduler"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrScheduledTransferNotFound = errors.New("scheduled transfer not found")
+	ErrScheduledTransferInactive = errors.New("scheduled transfer is no longer active")
+	ErrInvalidFrequency          = errors.New("frequency must be one of once, daily, weekly, monthly or cron")
+	ErrInvalidCron               = errors.New("invalid cron expression")
+	ErrScheduleInPast            = errors.New("start time must not be in the past")
+	ErrScheduleEndsBeforeStart   = errors.New("end time must be after the start time")
+	ErrScheduleNeverRuns         = errors.New("schedule has no run before its end time")
+	ErrInvalidFailurePolicy      = errors.New("failure_policy must be 'skip' or 'retry'")
+	ErrInvalidMaxRetries         = errors.New("max_retries must be between 0 and 10")
+)
+
+const (
+	// MaxScheduledRetries caps how often a failed occurrence is retried.
+	MaxScheduledRetries = 10
+
+	// DefaultScheduledRetries is used when the retry policy is chosen
+	// without a retry count.
+	DefaultScheduledRetries = 3
+
+	// ScheduledRetryBaseDelay is the wait before the first retry. Each
+	// further retry doubles it.
+	ScheduledRetryBaseDelay = 5 * time.Minute
+
+	scheduledRunBatchSize = 100
+)
+
+// ScheduledTransferParams describes a new scheduled transfer. StartAt defaults
+// to now. Cron is only used with the cron frequency.
+type ScheduledTransferParams struct {
+	From          string
+	To            string
+	Amount        int
+	Note          string
+	Force         bool
+	Frequency     models.ScheduleFrequency
+	Cron          string
+	StartAt       *time.Time
+	EndsAt        *time.Time
+	FailurePolicy models.FailurePolicy
+	MaxRetries    int
+}
+
+type ScheduledTransferService struct {
+	scheduledRepo   *repository.ScheduledTransferRepository
+	userRepo        *repository.UserRepository
+	transferService *TransferService
+	db              *gorm.DB
+	clock           scheduler.Clock
+}
+
+func NewScheduledTransferService(
+	scheduledRepo *repository.ScheduledTransferRepository,
+	userRepo *repository.UserRepository,
+	transferService *TransferService,
+	db *gorm.DB,
+	clock scheduler.Clock,
+) *ScheduledTransferService {
+	return &ScheduledTransferService{
+		scheduledRepo:   scheduledRepo,
+		userRepo:        userRepo,
+		transferService: transferService,
+		db:              db,
+		clock:           clock,
+	}
+}
+
+func (s *ScheduledTransferService) CreateScheduledTransfer(params ScheduledTransferParams) (*models.ScheduledTransfer, error) {
+	if params.Amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+
+	note, err := SanitizeNote(params.Note)
+	if err != nil {
+		return nil, err
+	}
+
+	if params.From == params.To {
+		return nil, ErrSelfTransfer
+	}
+	if models.IsReservedUsername(params.To) {
+		return nil, ErrReservedAccount
+	}
+
+	policy := params.FailurePolicy
+	maxRetries := params.MaxRetries
+	switch policy {
+	case "", models.FailurePolicySkip:
+		policy = models.FailurePolicySkip
+		maxRetries = 0
+	case models.FailurePolicyRetry:
+		if maxRetries == 0 {
+			maxRetries = DefaultScheduledRetries
+		}
+	default:
+		return nil, ErrInvalidFailurePolicy
+	}
+	if maxRetries < 0 || maxRetries > MaxScheduledRetries {
+		return nil, ErrInvalidMaxRetries
+	}
+
+	now := s.clock.Now()
+	start := now
+	if params.StartAt != nil {
+		if params.StartAt.Before(now.Add(-time.Minute)) {
+			return nil, ErrScheduleInPast
+		}
+		start = *params.StartAt
+	}
+	if params.EndsAt != nil && !params.EndsAt.After(start) {
+		return nil, ErrScheduleEndsBeforeStart
+	}
+
+	scheduled := &models.ScheduledTransfer{
+		ToUsername:    params.To,
+		Amount:        params.Amount,
+		Note:          note,
+		Force:         params.Force,
+		Frequency:     params.Frequency,
+		StartAt:       start,
+		EndsAt:        params.EndsAt,
+		Status:        models.ScheduledTransferActive,
+		FailurePolicy: policy,
+		MaxRetries:    maxRetries,
+	}
+
+	switch params.Frequency {
+	case models.ScheduleOnce, models.ScheduleDaily, models.ScheduleWeekly, models.ScheduleMonthly:
+		scheduled.NextRunAt = &start
+	case models.ScheduleCron:
+		cron, err := scheduler.ParseCron(params.Cron)
+		if err != nil {
+			return nil, ErrInvalidCron
+		}
+		scheduled.CronExpr = cron.String()
+		first := cron.Next(start.Add(-time.Nanosecond))
+		if first.IsZero() {
+			return nil, ErrScheduleNeverRuns
+		}
+		scheduled.NextRunAt = &first
+	default:
+		return nil, ErrInvalidFrequency
+	}
+	if scheduled.EndsAt != nil && scheduled.NextRunAt.After(*scheduled.EndsAt) {
+		return nil, ErrScheduleNeverRuns
+	}
+
+	fromUser, err := s.userRepo.FindByUsername(params.From)
+	if err != nil {
+		return nil, err
+	}
+	if fromUser == nil {
+		return nil, ErrUserNotFound
+	}
+	scheduled.FromUserID = fromUser.ID
+
+	if !params.Force {
+		toUser, err := s.userRepo.FindByUsername(params.To)
+		if err != nil {
+			return nil, err
+		}
+		if toUser == nil {
+			return nil, ErrRecipientNotFound
+		}
+	}
+
+	if err := s.scheduledRepo.Create(scheduled); err != nil {
+		return nil, fmt.Errorf("failed to create scheduled transfer: %w", err)
+	}
+
+	return s.scheduledRepo.FindByID(scheduled.ID)
+}
+
+func (s *ScheduledTransferService) ListScheduledTransfers(username string) ([]models.ScheduledTransfer, error) {
+	user, err := s.userRepo.FindByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	return s.scheduledRepo.ListByFromUserID(user.ID)
+}
+
+// ListRuns returns the most recent attempts of a schedule owned by username.
+func (s *ScheduledTransferService) ListRuns(id uint, username string, limit int) ([]models.ScheduledTransferRun, error) {
+	if _, err := s.findOwned(id, username); err != nil {
+		return nil, err
+	}
+	return s.scheduledRepo.ListRuns(id, limit)
+}
+
+func (s *ScheduledTransferService) CancelScheduledTransfer(id uint, username string) (*models.ScheduledTransfer, error) {
+	if _, err := s.findOwned(id, username); err != nil {
+		return nil, err
+	}
+
+	err := database.Transaction(s.db, func(tx *gorm.DB) error {
+		scheduled, err := s.scheduledRepo.FindByIDForUpdate(tx, id)
+		if err != nil {
+			return err
+		}
+		if scheduled == nil {
+			return ErrScheduledTransferNotFound
+		}
+		if scheduled.Status != models.ScheduledTransferActive {
+			return ErrScheduledTransferInactive
+		}
+
+		scheduled.Status = models.ScheduledTransferCancelled
+		scheduled.NextRunAt = nil
+		return s.scheduledRepo.UpdateInTx(tx, scheduled)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.scheduledRepo.FindByID(id)
+}
+
+func (s *ScheduledTransferService) findOwned(id uint, username string) (*models.ScheduledTransfer, error) {
+	scheduled, err := s.scheduledRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if scheduled == nil || scheduled.FromUser.Username != username {
+		return nil, ErrScheduledTransferNotFound
+	}
+	return scheduled, nil
+}
+
+// RunDue executes every schedule that is due at now and returns how many
+// were attempted. Transfer failures are recorded on the schedule rather than
+// returned; only database errors are returned.
+func (s *ScheduledTransferService) RunDue(now time.Time) (int, error) {
+	ids, err := s.scheduledRepo.FindDueIDs(now, scheduledRunBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	attempted := 0
+	var firstErr error
+	for _, id := range ids {
+		ran, err := s.runScheduledTransfer(id, now)
+		if err != nil {
+			log.Printf("[ScheduledTransfers] Failed to run schedule %d: %v", id, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if ran {
+			attempted++
+		}
+	}
+
+	return attempted, firstErr
+}
+
+func (s *ScheduledTransferService) runScheduledTransfer(id uint, now time.Time) (bool, error) {
+	ran := false
+
+	err := database.Transaction(s.db, func(tx *gorm.DB) error {
+		ran = false
+
+		scheduled, err := s.scheduledRepo.FindByIDForUpdate(tx, id)
+		if err != nil {
+			return err
+		}
+		// Another instance may have run or cancelled it since it was listed.
+		if scheduled == nil || scheduled.Status != models.ScheduledTransferActive ||
+			scheduled.NextRunAt == nil || scheduled.NextRunAt.After(now) {
+			return nil
+		}
+
+		run := &models.ScheduledTransferRun{
+			ScheduledTransferID: scheduled.ID,
+			RunAt:               now,
+			Attempt:             scheduled.RetryCount + 1,
+		}
+
+		// The transfer runs in a savepoint so that a failed attempt can be
+		// rolled back while the failure itself is still recorded.
+		var transaction *models.Transaction
+		transferErr := tx.Transaction(func(stx *gorm.DB) error {
+			var err error
+			transaction, err = s.transferService.ExecuteInTx(stx, TransferParams{
+				From:                scheduled.FromUser.Username,
+				To:                  scheduled.ToUsername,
+				Amount:              scheduled.Amount,
+				Force:               scheduled.Force,
+				Note:                scheduled.Note,
+				Kind:                models.TransactionKindScheduled,
+				ScheduledTransferID: &scheduled.ID,
+			})
+			return err
+		})
+		if transferErr != nil && database.IsRetryable(transferErr) {
+			return transferErr
+		}
+
+		next, err := nextOccurrence(scheduled, now)
+		if err != nil {
+			return err
+		}
+
+		scheduled.LastRunAt = &now
+		if transferErr == nil {
+			run.Status = models.ScheduledRunSucceeded
+			run.TransactionID = &transaction.ID
+			scheduled.RunCount++
+			scheduled.RetryCount = 0
+			scheduled.LastError = ""
+			scheduled.NextRunAt = next
+			if next == nil {
+				scheduled.Status = models.ScheduledTransferCompleted
+			}
+		} else {
+			run.Error = transferErr.Error()
+			scheduled.FailureCount++
+			scheduled.LastError = run.Error
+
+			retryAt := now.Add(scheduledRetryDelay(run.Attempt))
+			canRetry := scheduled.FailurePolicy == models.FailurePolicyRetry &&
+				scheduled.RetryCount < scheduled.MaxRetries &&
+				(next == nil || retryAt.Before(*next))
+
+			if canRetry {
+				run.Status = models.ScheduledRunFailed
+				scheduled.RetryCount++
+				scheduled.NextRunAt = &retryAt
+			} else {
+				run.Status = models.ScheduledRunSkipped
+				scheduled.RetryCount = 0
+				scheduled.NextRunAt = next
+				if next == nil {
+					scheduled.Status = models.ScheduledTransferCompleted
+					if scheduled.Frequency == models.ScheduleOnce {
+						scheduled.Status = models.ScheduledTransferFailed
+					}
+				}
+			}
+		}
+
+		if err := s.scheduledRepo.CreateRunInTx(tx, run); err != nil {
+			return fmt.Errorf("failed to record scheduled run: %w", err)
+		}
+		if err := s.scheduledRepo.UpdateInTx(tx, scheduled); err != nil {
+			return fmt.Errorf("failed to update scheduled transfer: %w", err)
+		}
+
+		ran = true
+		return nil
+	})
+
+	return ran, err
+}
+
+func scheduledRetryDelay(attempt int) time.Duration {
+	return ScheduledRetryBaseDelay << uint(attempt-1)
+}
+
+// nextOccurrence returns the first regular occurrence strictly after t, or
+// nil when the schedule has no more runs. Occurrences missed while the
+// server was down are not replayed; the schedule resumes from t.
+func nextOccurrence(scheduled *models.ScheduledTransfer, t time.Time) (*time.Time, error) {
+	return nextRecurrence(scheduled.Frequency, scheduled.CronExpr, scheduled.StartAt, scheduled.EndsAt, t)
+}
+
+// nextRecurrence returns the first occurrence of a recurrence rule strictly
+// after t, or nil when it has none left. Daily, weekly and monthly rules are
+// anchored at start; cron rules only use the expression.
+func nextRecurrence(frequency models.ScheduleFrequency, cronExpr string, start time.Time, endsAt *time.Time, t time.Time) (*time.Time, error) {
+	var next time.Time
+
+	switch frequency {
+	case models.ScheduleOnce:
+		return nil, nil
+	case models.ScheduleDaily:
+		next = occurrenceAfter(start, t, func(start time.Time, n int) time.Time {
+			return start.AddDate(0, 0, n)
+		})
+	case models.ScheduleWeekly:
+		next = occurrenceAfter(start, t, func(start time.Time, n int) time.Time {
+			return start.AddDate(0, 0, 7*n)
+		})
+	case models.ScheduleMonthly:
+		next = occurrenceAfter(start, t, addMonthsClamped)
+	case models.ScheduleCron:
+		cron, err := scheduler.ParseCron(cronExpr)
+		if err != nil {
+			return nil, err
+		}
+		next = cron.Next(t)
+		if next.IsZero() {
+			return nil, nil
+		}
+	default:
+		return nil, ErrInvalidFrequency
+	}
+
+	if endsAt != nil && next.After(*endsAt) {
+		return nil, nil
+	}
+	return &next, nil
+}
+
+// occurrenceAfter returns the first step(start, n) that is after t.
+func occurrenceAfter(start, t time.Time, step func(time.Time, int) time.Time) time.Time {
+	for n := 0; ; n++ {
+		if next := step(start, n); next.After(t) {
+			return next
+		}
+	}
+}
+
+// addMonthsClamped adds n months to t, keeping the day of month where
+// possible. A schedule on the 31st runs on the last day of shorter months.
+func addMonthsClamped(t time.Time, n int) time.Time {
+	first := time.Date(t.Year(), t.Month()+time.Month(n), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	lastDay := first.AddDate(0, 1, -1).Day()
+
+	day := t.Day()
+	if day > lastDay {
+		day = lastDay
+	}
+	return first.AddDate(0, 0, day-1)
+}
@@ -0,0 +1,294 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/h4ks-com/bean-bank/internal/database"
+	"github.com/h4ks-com/bean-bank/internal/models"
+	"github.com/h4ks-com/bean-bank/internal/repository"
+	"github.com/h4ks-com/bean-bank/internal/scheduler"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupScheduledTransferTestDB(t *testing.T) (*repository.UserRepository, *ScheduledTransferService, *scheduler.ManualClock) {
+	db, err := database.Connect(":memory:")
+	require.NoError(t, err)
+
+	err = database.Migrate(db)
+	require.NoError(t, err)
+
+	userRepo := repository.NewUserRepository(db)
+	transactionRepo := repository.NewTransactionRepository(db)
+	scheduledRepo := repository.NewScheduledTransferRepository(db)
+	transferService := NewTransferService(userRepo, transactionRepo, db)
+	clock := scheduler.NewManualClock(time.Date(2026, time.January, 31, 9, 0, 0, 0, time.UTC))
+	service := NewScheduledTransferService(scheduledRepo, userRepo, transferService, db, clock)
+
+	require.NoError(t, userRepo.Create(&models.User{Username: "alice", BeanAmount: 100}))
+	require.NoError(t, userRepo.Create(&models.User{Username: "bob", BeanAmount: 0}))
+
+	return userRepo, service, clock
+}
+
+func balanceOf(t *testing.T, userRepo *repository.UserRepository, username string) int {
+	user, err := userRepo.FindByUsername(username)
+	require.NoError(t, err)
+	require.NotNil(t, user)
+	return user.BeanAmount
+}
+
+func TestScheduledTransferService_OneOff(t *testing.T) {
+	userRepo, service, clock := setupScheduledTransferTestDB(t)
+
+	startAt := clock.Now().Add(time.Hour)
+	scheduled, err := service.CreateScheduledTransfer(ScheduledTransferParams{
+		From: "alice", To: "bob", Amount: 25, Note: "rent", Frequency: models.ScheduleOnce, StartAt: &startAt,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, scheduled.NextRunAt)
+	assert.True(t, scheduled.NextRunAt.Equal(startAt))
+
+	attempted, err := service.RunDue(clock.Now())
+	require.NoError(t, err)
+	assert.Zero(t, attempted, "nothing is due before the start time")
+
+	clock.Advance(time.Hour)
+	attempted, err = service.RunDue(clock.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 1, attempted)
+
+	assert.Equal(t, 75, balanceOf(t, userRepo, "alice"))
+	assert.Equal(t, 25, balanceOf(t, userRepo, "bob"))
+
+	schedules, err := service.ListScheduledTransfers("alice")
+	require.NoError(t, err)
+	require.Len(t, schedules, 1)
+	assert.Equal(t, models.ScheduledTransferCompleted, schedules[0].Status)
+	assert.Nil(t, schedules[0].NextRunAt)
+	assert.Equal(t, 1, schedules[0].RunCount)
+
+	runs, err := service.ListRuns(scheduled.ID, "alice", 10)
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.Equal(t, models.ScheduledRunSucceeded, runs[0].Status)
+	assert.NotNil(t, runs[0].TransactionID)
+
+	attempted, err = service.RunDue(clock.Now().Add(24 * time.Hour))
+	require.NoError(t, err)
+	assert.Zero(t, attempted, "a completed one-off never runs again")
+}
+
+func TestScheduledTransferService_WeeklyStipend(t *testing.T) {
+	userRepo, service, clock := setupScheduledTransferTestDB(t)
+
+	_, err := service.CreateScheduledTransfer(ScheduledTransferParams{
+		From: "alice", To: "bob", Amount: 10, Frequency: models.ScheduleWeekly,
+	})
+	require.NoError(t, err)
+
+	for week := 0; week < 3; week++ {
+		attempted, err := service.RunDue(clock.Now())
+		require.NoError(t, err)
+		assert.Equal(t, 1, attempted)
+		clock.Advance(7 * 24 * time.Hour)
+	}
+
+	assert.Equal(t, 30, balanceOf(t, userRepo, "bob"))
+
+	schedules, err := service.ListScheduledTransfers("alice")
+	require.NoError(t, err)
+	expected := time.Date(2026, time.February, 21, 9, 0, 0, 0, time.UTC)
+	assert.True(t, schedules[0].NextRunAt.Equal(expected), "next run %v", schedules[0].NextRunAt)
+
+	t.Run("missed weeks are not replayed", func(t *testing.T) {
+		clock.Advance(3 * 7 * 24 * time.Hour)
+		attempted, err := service.RunDue(clock.Now())
+		require.NoError(t, err)
+		assert.Equal(t, 1, attempted)
+		assert.Equal(t, 40, balanceOf(t, userRepo, "bob"))
+	})
+}
+
+func TestScheduledTransferService_MonthlyClampsToMonthEnd(t *testing.T) {
+	_, service, clock := setupScheduledTransferTestDB(t)
+
+	scheduled, err := service.CreateScheduledTransfer(ScheduledTransferParams{
+		From: "alice", To: "bob", Amount: 1, Frequency: models.ScheduleMonthly,
+	})
+	require.NoError(t, err)
+
+	_, err = service.RunDue(clock.Now())
+	require.NoError(t, err)
+
+	schedules, err := service.ListScheduledTransfers("alice")
+	require.NoError(t, err)
+	require.Equal(t, scheduled.ID, schedules[0].ID)
+	assert.True(t, schedules[0].NextRunAt.Equal(time.Date(2026, time.February, 28, 9, 0, 0, 0, time.UTC)))
+}
+
+func TestScheduledTransferService_SkipOnInsufficientBalance(t *testing.T) {
+	userRepo, service, clock := setupScheduledTransferTestDB(t)
+
+	scheduled, err := service.CreateScheduledTransfer(ScheduledTransferParams{
+		From: "alice", To: "bob", Amount: 500, Frequency: models.ScheduleDaily,
+	})
+	require.NoError(t, err)
+
+	attempted, err := service.RunDue(clock.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 1, attempted)
+	assert.Equal(t, 100, balanceOf(t, userRepo, "alice"))
+
+	runs, err := service.ListRuns(scheduled.ID, "alice", 10)
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.Equal(t, models.ScheduledRunSkipped, runs[0].Status)
+	assert.Equal(t, ErrInsufficientBalance.Error(), runs[0].Error)
+
+	schedules, err := service.ListScheduledTransfers("alice")
+	require.NoError(t, err)
+	assert.Equal(t, models.ScheduledTransferActive, schedules[0].Status)
+	assert.Equal(t, 1, schedules[0].FailureCount)
+	assert.True(t, schedules[0].NextRunAt.Equal(clock.Now().Add(24*time.Hour)))
+}
+
+func TestScheduledTransferService_RetryWithBackoff(t *testing.T) {
+	userRepo, service, clock := setupScheduledTransferTestDB(t)
+	start := clock.Now()
+
+	scheduled, err := service.CreateScheduledTransfer(ScheduledTransferParams{
+		From: "alice", To: "bob", Amount: 150, Frequency: models.ScheduleOnce,
+		FailurePolicy: models.FailurePolicyRetry, MaxRetries: 2,
+	})
+	require.NoError(t, err)
+
+	_, err = service.RunDue(clock.Now())
+	require.NoError(t, err)
+
+	schedules, err := service.ListScheduledTransfers("alice")
+	require.NoError(t, err)
+	assert.True(t, schedules[0].NextRunAt.Equal(start.Add(ScheduledRetryBaseDelay)))
+
+	clock.Advance(ScheduledRetryBaseDelay)
+	_, err = service.RunDue(clock.Now())
+	require.NoError(t, err)
+
+	schedules, err = service.ListScheduledTransfers("alice")
+	require.NoError(t, err)
+	assert.True(t, schedules[0].NextRunAt.Equal(clock.Now().Add(2*ScheduledRetryBaseDelay)), "delay doubles")
+
+	t.Run("succeeds once funded", func(t *testing.T) {
+		require.NoError(t, userRepo.Create(&models.User{Username: "carol", BeanAmount: 0}))
+		alice, err := userRepo.FindByUsername("alice")
+		require.NoError(t, err)
+		alice.BeanAmount = 200
+		require.NoError(t, userRepo.Update(alice))
+
+		clock.Advance(2 * ScheduledRetryBaseDelay)
+		_, err = service.RunDue(clock.Now())
+		require.NoError(t, err)
+
+		assert.Equal(t, 150, balanceOf(t, userRepo, "bob"))
+
+		runs, err := service.ListRuns(scheduled.ID, "alice", 10)
+		require.NoError(t, err)
+		require.Len(t, runs, 3)
+		assert.Equal(t, models.ScheduledRunSucceeded, runs[0].Status)
+		assert.Equal(t, 3, runs[0].Attempt)
+		assert.Equal(t, models.ScheduledRunFailed, runs[1].Status)
+	})
+}
+
+func TestScheduledTransferService_RetriesExhausted(t *testing.T) {
+	_, service, clock := setupScheduledTransferTestDB(t)
+
+	_, err := service.CreateScheduledTransfer(ScheduledTransferParams{
+		From: "alice", To: "bob", Amount: 150, Frequency: models.ScheduleOnce,
+		FailurePolicy: models.FailurePolicyRetry, MaxRetries: 1,
+	})
+	require.NoError(t, err)
+
+	_, err = service.RunDue(clock.Now())
+	require.NoError(t, err)
+	clock.Advance(ScheduledRetryBaseDelay)
+	_, err = service.RunDue(clock.Now())
+	require.NoError(t, err)
+
+	schedules, err := service.ListScheduledTransfers("alice")
+	require.NoError(t, err)
+	assert.Equal(t, models.ScheduledTransferFailed, schedules[0].Status)
+	assert.Equal(t, 2, schedules[0].FailureCount)
+	assert.Nil(t, schedules[0].NextRunAt)
+}
+
+func TestScheduledTransferService_Cron(t *testing.T) {
+	_, service, clock := setupScheduledTransferTestDB(t)
+
+	scheduled, err := service.CreateScheduledTransfer(ScheduledTransferParams{
+		From: "alice", To: "bob", Amount: 1, Frequency: models.ScheduleCron, Cron: "0 9 * * 1",
+	})
+	require.NoError(t, err)
+	assert.True(t, scheduled.NextRunAt.Equal(time.Date(2026, time.February, 2, 9, 0, 0, 0, time.UTC)))
+
+	_, err = service.CreateScheduledTransfer(ScheduledTransferParams{
+		From: "alice", To: "bob", Amount: 1, Frequency: models.ScheduleCron, Cron: "every tuesday",
+	})
+	assert.ErrorIs(t, err, ErrInvalidCron)
+
+	clock.Set(*scheduled.NextRunAt)
+	attempted, err := service.RunDue(clock.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 1, attempted)
+}
+
+func TestScheduledTransferService_Cancel(t *testing.T) {
+	_, service, clock := setupScheduledTransferTestDB(t)
+
+	scheduled, err := service.CreateScheduledTransfer(ScheduledTransferParams{
+		From: "alice", To: "bob", Amount: 5, Frequency: models.ScheduleDaily,
+	})
+	require.NoError(t, err)
+
+	_, err = service.CancelScheduledTransfer(scheduled.ID, "bob")
+	assert.ErrorIs(t, err, ErrScheduledTransferNotFound)
+
+	cancelled, err := service.CancelScheduledTransfer(scheduled.ID, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, models.ScheduledTransferCancelled, cancelled.Status)
+
+	_, err = service.CancelScheduledTransfer(scheduled.ID, "alice")
+	assert.ErrorIs(t, err, ErrScheduledTransferInactive)
+
+	attempted, err := service.RunDue(clock.Now())
+	require.NoError(t, err)
+	assert.Zero(t, attempted)
+}
+
+func TestScheduledTransferService_Validation(t *testing.T) {
+	_, service, clock := setupScheduledTransferTestDB(t)
+	past := clock.Now().Add(-time.Hour)
+	end := clock.Now().Add(-2 * time.Hour)
+
+	cases := []struct {
+		name   string
+		params ScheduledTransferParams
+		err    error
+	}{
+		{"bad frequency", ScheduledTransferParams{From: "alice", To: "bob", Amount: 1, Frequency: "hourly"}, ErrInvalidFrequency},
+		{"start in past", ScheduledTransferParams{From: "alice", To: "bob", Amount: 1, Frequency: models.ScheduleOnce, StartAt: &past}, ErrScheduleInPast},
+		{"ends before start", ScheduledTransferParams{From: "alice", To: "bob", Amount: 1, Frequency: models.ScheduleDaily, EndsAt: &end}, ErrScheduleEndsBeforeStart},
+		{"bad policy", ScheduledTransferParams{From: "alice", To: "bob", Amount: 1, Frequency: models.ScheduleDaily, FailurePolicy: "panic"}, ErrInvalidFailurePolicy},
+		{"too many retries", ScheduledTransferParams{From: "alice", To: "bob", Amount: 1, Frequency: models.ScheduleDaily, FailurePolicy: models.FailurePolicyRetry, MaxRetries: 99}, ErrInvalidMaxRetries},
+		{"unknown recipient", ScheduledTransferParams{From: "alice", To: "ghost", Amount: 1, Frequency: models.ScheduleDaily}, ErrRecipientNotFound},
+		{"self transfer", ScheduledTransferParams{From: "alice", To: "alice", Amount: 1, Frequency: models.ScheduleDaily}, ErrSelfTransfer},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := service.CreateScheduledTransfer(tc.params)
+			assert.ErrorIs(t, err, tc.err)
+		})
+	}
+}
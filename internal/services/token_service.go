@@ -20,34 +20,55 @@ type TokenClaims struct {
 	jwt.RegisteredClaims
 }
 
+// neverExpiringTokenLifetime is how long a token created with "never" stays
+// valid, since API tokens always carry an expiry.
+const neverExpiringTokenLifetime = 87600 * time.Hour
+
 type TokenService struct {
 	tokenRepo *repository.TokenRepository
 	userRepo  *repository.UserRepository
 	jwtSecret string
+	maxExpiry time.Duration
 }
 
-func NewTokenService(tokenRepo *repository.TokenRepository, userRepo *repository.UserRepository, jwtSecret string) *TokenService {
+// NewTokenService creates the token service. A non-zero maxExpiry caps how
+// far ahead new tokens may expire.
+func NewTokenService(tokenRepo *repository.TokenRepository, userRepo *repository.UserRepository, jwtSecret string, maxExpiry time.Duration) *TokenService {
 	return &TokenService{
 		tokenRepo: tokenRepo,
 		userRepo:  userRepo,
 		jwtSecret: jwtSecret,
+		maxExpiry: maxExpiry,
 	}
 }
 
-func (s *TokenService) GenerateToken(username string, expiresIn time.Duration) (string, error) {
+// GenerateToken issues an API token for the user. expiresIn uses the same
+// grammar as gift links, see ParseExpiry. It returns the signed token and
+// when it expires.
+func (s *TokenService) GenerateToken(username string, expiresIn string) (string, time.Time, error) {
+	now := time.Now()
+	expiry, err := ParseExpiry(expiresIn, now, s.maxExpiry)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	expiresAt := now.Add(neverExpiringTokenLifetime)
+	if expiry != nil {
+		expiresAt = *expiry
+	}
+
 	user, err := s.userRepo.FindByUsername(username)
 	if err != nil {
-		return "", err
+		return "", time.Time{}, err
 	}
 	if user == nil {
-		return "", ErrUserNotFound
+		return "", time.Time{}, ErrUserNotFound
 	}
 
 	claims := TokenClaims{
 		Username: username,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
 			Issuer:    "bean-bank",
 			ID:        uuid.New().String(),
 		},
@@ -56,21 +77,21 @@ func (s *TokenService) GenerateToken(username string, expiresIn time.Duration) (
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	tokenString, err := token.SignedString([]byte(s.jwtSecret))
 	if err != nil {
-		return "", err
+		return "", time.Time{}, err
 	}
 
 	apiToken := &models.APIToken{
 		UserID:    user.ID,
 		Token:     tokenString,
-		ExpiresAt: time.Now().Add(expiresIn),
+		ExpiresAt: expiresAt,
 	}
 
 	err = s.tokenRepo.Create(apiToken)
 	if err != nil {
-		return "", err
+		return "", time.Time{}, err
 	}
 
-	return tokenString, nil
+	return tokenString, expiresAt, nil
 }
 
 func (s *TokenService) ValidateToken(tokenString string) (*TokenClaims, error) {
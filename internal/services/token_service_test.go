@@ -0,0 +1,67 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/h4ks-com/bean-bank/internal/database"
+	"github.com/h4ks-com/bean-bank/internal/models"
+	"github.com/h4ks-com/bean-bank/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenService_GenerateTokenExpiry(t *testing.T) {
+	db, err := database.Connect(":memory:")
+	require.NoError(t, err)
+	require.NoError(t, database.Migrate(db))
+	require.NoError(t, db.Create(&models.User{Username: "alice"}).Error)
+
+	tokenRepo := repository.NewTokenRepository(db)
+	userRepo := repository.NewUserRepository(db)
+
+	t.Run("never gets a long-lived expiry", func(t *testing.T) {
+		service := NewTokenService(tokenRepo, userRepo, "test-secret", 0)
+
+		token, expiresAt, err := service.GenerateToken("alice", "never")
+		require.NoError(t, err)
+		assert.NotEmpty(t, token)
+		assert.InDelta(t, time.Now().Add(neverExpiringTokenLifetime).Unix(), expiresAt.Unix(), 5)
+
+		claims, err := service.ValidateToken(token)
+		require.NoError(t, err)
+		assert.Equal(t, expiresAt.Unix(), claims.ExpiresAt.Unix())
+	})
+
+	t.Run("accepts days and weeks", func(t *testing.T) {
+		service := NewTokenService(tokenRepo, userRepo, "test-secret", 0)
+
+		_, expiresAt, err := service.GenerateToken("alice", "1w2d")
+		require.NoError(t, err)
+		assert.InDelta(t, time.Now().Add(9*24*time.Hour).Unix(), expiresAt.Unix(), 5)
+	})
+
+	t.Run("maximum expiry rejects never", func(t *testing.T) {
+		service := NewTokenService(tokenRepo, userRepo, "test-secret", 30*24*time.Hour)
+
+		_, _, err := service.GenerateToken("alice", "never")
+		assert.Equal(t, ErrExpiryTooFar, err)
+
+		_, _, err = service.GenerateToken("alice", "30d")
+		assert.NoError(t, err)
+
+		_, expiresAt, err := service.GenerateToken("alice", "")
+		require.NoError(t, err)
+		assert.InDelta(t, time.Now().Add(30*24*time.Hour).Unix(), expiresAt.Unix(), 5)
+	})
+
+	t.Run("rejects invalid expiries", func(t *testing.T) {
+		service := NewTokenService(tokenRepo, userRepo, "test-secret", 0)
+
+		_, _, err := service.GenerateToken("alice", "forever")
+		assert.Equal(t, ErrInvalidExpiry, err)
+
+		_, _, err = service.GenerateToken("alice", "-1h")
+		assert.Equal(t, ErrExpiryNotInFuture, err)
+	})
+}
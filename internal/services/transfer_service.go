@@ -2,7 +2,11 @@ package services
 
 import (
 	"errors"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 
+	"github.com/h4ks-com/bean-bank/internal/database"
 	"github.com/h4ks-com/bean-bank/internal/models"
 	"github.com/h4ks-com/bean-bank/internal/repository"
 	"gorm.io/gorm"
@@ -13,8 +17,22 @@ var (
 	ErrRecipientNotFound   = errors.New("recipient not found")
 	ErrInvalidAmount       = errors.New("invalid amount")
 	ErrSelfTransfer        = errors.New("cannot transfer to yourself")
+	ErrNoteTooLong         = errors.New("note is too long")
+	ErrNoRecipients        = errors.New("at least one recipient is required")
+	ErrTooManyRecipients   = errors.New("too many recipients")
+	ErrDuplicateRecipient  = errors.New("recipient listed more than once")
+	ErrSplitTooSmall       = errors.New("total is too small to give every recipient at least one bean")
+	ErrAmountAndTotal      = errors.New("give either a total to split or an amount per recipient, not both")
+	ErrInvalidBatchMode    = errors.New("mode must be 'atomic' or 'best_effort'")
+	ErrBatchAborted        = errors.New("batch transfer aborted, no beans were sent")
 )
 
+// MaxNoteLength is the maximum number of characters allowed in a transfer note.
+const MaxNoteLength = 200
+
+// MaxBatchRecipients is the maximum number of recipients in one batch transfer.
+const MaxBatchRecipients = 100
+
 type TransferService struct {
 	userRepo        *repository.UserRepository
 	transactionRepo *repository.TransactionRepository
@@ -29,67 +47,309 @@ func NewTransferService(userRepo *repository.UserRepository, transactionRepo *re
 	}
 }
 
-func (s *TransferService) TransferInTx(tx *gorm.DB, fromUsername, toUsername string, amount int, force bool) error {
-	if amount <= 0 {
-		return ErrInvalidAmount
+// SanitizeNote trims the note and strips control characters so it is safe to
+// store and display. Notes longer than MaxNoteLength are rejected.
+func SanitizeNote(note string) (string, error) {
+	cleaned := strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\t' {
+			return ' '
+		}
+		if unicode.IsControl(r) || r == utf8.RuneError {
+			return -1
+		}
+		return r
+	}, note)
+	cleaned = strings.TrimSpace(cleaned)
+
+	if utf8.RuneCountInString(cleaned) > MaxNoteLength {
+		return "", ErrNoteTooLong
 	}
+	return cleaned, nil
+}
+
+// TransferParams describes a single movement of beans between two wallets.
+// Kind defaults to a plain transfer when left empty.
+type TransferParams struct {
+	From                string
+	To                  string
+	Amount              int
+	Force               bool
+	Note                string
+	Kind                models.TransactionKind
+	GiftLinkID          *uint
+	GiftCampaignID      *uint
+	HarvestID           *uint
+	PaymentRequestID    *uint
+	ScheduledTransferID *uint
+}
 
-	if fromUsername == toUsername {
-		return ErrSelfTransfer
+func (s *TransferService) ExecuteInTx(tx *gorm.DB, params TransferParams) (*models.Transaction, error) {
+	if params.Amount <= 0 {
+		return nil, ErrInvalidAmount
 	}
 
-	fromUser, err := s.userRepo.FindByUsernameForUpdate(tx, fromUsername)
+	note, err := SanitizeNote(params.Note)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return ErrUserNotFound
-		}
-		return err
+		return nil, err
+	}
+
+	if params.From == params.To {
+		return nil, ErrSelfTransfer
+	}
+
+	kind := params.Kind
+	if kind == "" {
+		kind = models.TransactionKindTransfer
+	}
+
+	users, err := s.userRepo.LockUsers(tx, params.From, params.To)
+	if err != nil {
+		return nil, err
 	}
 
-	if fromUser.BeanAmount < amount {
-		return ErrInsufficientBalance
+	fromUser := users[params.From]
+	if fromUser == nil {
+		return nil, ErrUserNotFound
 	}
 
-	toUser, err := s.userRepo.FindByUsernameForUpdate(tx, toUsername)
-	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
-		return err
+	if fromUser.BeanAmount < params.Amount {
+		return nil, ErrInsufficientBalance
 	}
 
+	toUser := users[params.To]
 	if toUser == nil {
-		if !force {
-			return ErrRecipientNotFound
+		if !params.Force {
+			return nil, ErrRecipientNotFound
 		}
 		toUser = &models.User{
-			Username:   toUsername,
+			Username:   params.To,
 			BeanAmount: 0,
 		}
 		if err := tx.Create(toUser).Error; err != nil {
-			return err
+			return nil, err
 		}
 	}
 
-	fromUser.BeanAmount -= amount
-	toUser.BeanAmount += amount
-
-	if err := s.userRepo.UpdateInTx(tx, fromUser); err != nil {
-		return err
+	transaction := &models.Transaction{
+		Amount:              params.Amount,
+		Note:                note,
+		Kind:                kind,
+		GiftLinkID:          params.GiftLinkID,
+		GiftCampaignID:      params.GiftCampaignID,
+		HarvestID:           params.HarvestID,
+		PaymentRequestID:    params.PaymentRequestID,
+		ScheduledTransferID: params.ScheduledTransferID,
 	}
 
-	if err := s.userRepo.UpdateInTx(tx, toUser); err != nil {
-		return err
+	if err := s.transactionRepo.Post(tx, fromUser, toUser, transaction); err != nil {
+		return nil, err
 	}
 
-	transaction := &models.Transaction{
-		FromUserID: fromUser.ID,
-		ToUserID:   toUser.ID,
-		Amount:     amount,
+	return transaction, nil
+}
+
+func (s *TransferService) TransferInTx(tx *gorm.DB, fromUsername, toUsername string, amount int, force bool, note string) error {
+	_, err := s.ExecuteInTx(tx, TransferParams{
+		From:   fromUsername,
+		To:     toUsername,
+		Amount: amount,
+		Force:  force,
+		Note:   note,
+		Kind:   models.TransactionKindTransfer,
+	})
+	return err
+}
+
+func (s *TransferService) Transfer(fromUsername, toUsername string, amount int, force bool, note string) error {
+	return database.Transaction(s.db, func(tx *gorm.DB) error {
+		return s.TransferInTx(tx, fromUsername, toUsername, amount, force, note)
+	})
+}
+
+type BatchMode string
+
+const (
+	// BatchModeAtomic sends to every recipient or to none of them.
+	BatchModeAtomic BatchMode = "atomic"
+	// BatchModeBestEffort sends to every recipient it can and reports the
+	// ones that failed.
+	BatchModeBestEffort BatchMode = "best_effort"
+)
+
+type BatchRecipient struct {
+	To     string
+	Amount int
+}
+
+// BatchTransferParams describes a transfer from one sender to many recipients.
+// When Total is set the recipients' amounts must be left at zero and the total
+// is split evenly; see SplitEvenly for the remainder rule.
+type BatchTransferParams struct {
+	From       string
+	Recipients []BatchRecipient
+	Total      int
+	Force      bool
+	Note       string
+	Mode       BatchMode
+}
+
+type BatchResultStatus string
+
+const (
+	BatchResultSent       BatchResultStatus = "sent"
+	BatchResultFailed     BatchResultStatus = "failed"
+	BatchResultRolledBack BatchResultStatus = "rolled_back"
+)
+
+type BatchTransferResult struct {
+	To            string
+	Amount        int
+	Status        BatchResultStatus
+	TransactionID uint
+	Err           error
+}
+
+// SplitEvenly divides total between n recipients. Every recipient gets
+// total/n beans and the remainder is handed out one bean at a time to the
+// first recipients in list order.
+func SplitEvenly(total, n int) ([]int, error) {
+	if n <= 0 {
+		return nil, ErrNoRecipients
+	}
+	if total < n {
+		return nil, ErrSplitTooSmall
 	}
 
-	return s.transactionRepo.Create(tx, transaction)
+	shares := make([]int, n)
+	for i := range shares {
+		shares[i] = total / n
+		if i < total%n {
+			shares[i]++
+		}
+	}
+	return shares, nil
 }
 
-func (s *TransferService) Transfer(fromUsername, toUsername string, amount int, force bool) error {
-	return s.db.Transaction(func(tx *gorm.DB) error {
-		return s.TransferInTx(tx, fromUsername, toUsername, amount, force)
+// BatchTransfer sends beans to several recipients in a single database
+// transaction. In atomic mode the first failure rolls back the whole batch and
+// ErrBatchAborted is returned together with the per-recipient results. In
+// best-effort mode each recipient runs in its own savepoint so failures only
+// undo that recipient.
+func (s *TransferService) BatchTransfer(params BatchTransferParams) ([]BatchTransferResult, error) {
+	mode := params.Mode
+	if mode == "" {
+		mode = BatchModeAtomic
+	}
+	if mode != BatchModeAtomic && mode != BatchModeBestEffort {
+		return nil, ErrInvalidBatchMode
+	}
+
+	if len(params.Recipients) == 0 {
+		return nil, ErrNoRecipients
+	}
+	if len(params.Recipients) > MaxBatchRecipients {
+		return nil, ErrTooManyRecipients
+	}
+
+	note, err := SanitizeNote(params.Note)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(params.Recipients))
+	usernames := []string{params.From}
+	for _, recipient := range params.Recipients {
+		if seen[recipient.To] {
+			return nil, ErrDuplicateRecipient
+		}
+		seen[recipient.To] = true
+		usernames = append(usernames, recipient.To)
+
+		if params.Total > 0 && recipient.Amount != 0 {
+			return nil, ErrAmountAndTotal
+		}
+	}
+
+	results := make([]BatchTransferResult, len(params.Recipients))
+	for i, recipient := range params.Recipients {
+		results[i] = BatchTransferResult{To: recipient.To, Amount: recipient.Amount}
+	}
+
+	if params.Total > 0 {
+		shares, err := SplitEvenly(params.Total, len(params.Recipients))
+		if err != nil {
+			return nil, err
+		}
+		for i := range results {
+			results[i].Amount = shares[i]
+		}
+	}
+
+	err = database.Transaction(s.db, func(tx *gorm.DB) error {
+		for i := range results {
+			results[i].Status, results[i].TransactionID, results[i].Err = "", 0, nil
+		}
+
+		// Lock every wallet up front so the individual transfers below never
+		// wait on each other out of ID order.
+		if _, err := s.userRepo.LockUsers(tx, usernames...); err != nil {
+			return err
+		}
+
+		for i := range results {
+			result := &results[i]
+			transfer := func(tx *gorm.DB) error {
+				transaction, err := s.ExecuteInTx(tx, TransferParams{
+					From:   params.From,
+					To:     result.To,
+					Amount: result.Amount,
+					Force:  params.Force,
+					Note:   note,
+					Kind:   models.TransactionKindTransfer,
+				})
+				if err != nil {
+					return err
+				}
+				result.TransactionID = transaction.ID
+				return nil
+			}
+
+			var err error
+			if mode == BatchModeBestEffort {
+				err = tx.Transaction(transfer)
+			} else {
+				err = transfer(tx)
+			}
+
+			if err == nil {
+				result.Status = BatchResultSent
+				continue
+			}
+			if database.IsRetryable(err) {
+				return err
+			}
+
+			result.Status = BatchResultFailed
+			result.Err = err
+			if mode == BatchModeAtomic {
+				return ErrBatchAborted
+			}
+		}
+		return nil
 	})
+
+	if errors.Is(err, ErrBatchAborted) {
+		for i := range results {
+			if results[i].Status == BatchResultSent || results[i].Status == "" {
+				results[i].Status = BatchResultRolledBack
+				results[i].TransactionID = 0
+			}
+		}
+		return results, ErrBatchAborted
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
 }
@@ -1,12 +1,22 @@
 package services
 
 import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/h4ks-com/bean-bank/internal/database"
 	"github.com/h4ks-com/bean-bank/internal/models"
 	"github.com/h4ks-com/bean-bank/internal/repository"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+	"gorm.io/gorm"
 )
 
 func setupTestDB(t *testing.T) (*repository.UserRepository, *repository.TransactionRepository, *TransferService) {
@@ -33,7 +43,7 @@ func TestTransferService_SuccessfulTransfer(t *testing.T) {
 	err = userRepo.Create(bob)
 	assert.NoError(t, err)
 
-	err = transferService.Transfer("alice", "bob", 30, false)
+	err = transferService.Transfer("alice", "bob", 30, false, "")
 	assert.NoError(t, err)
 
 	aliceAfter, _ := userRepo.FindByUsername("alice")
@@ -51,7 +61,7 @@ func TestTransferService_InsufficientBalance(t *testing.T) {
 	userRepo.Create(alice)
 	userRepo.Create(bob)
 
-	err := transferService.Transfer("alice", "bob", 20, false)
+	err := transferService.Transfer("alice", "bob", 20, false, "")
 	assert.Equal(t, ErrInsufficientBalance, err)
 
 	aliceAfter, _ := userRepo.FindByUsername("alice")
@@ -67,7 +77,7 @@ func TestTransferService_RecipientNotFound(t *testing.T) {
 	alice := &models.User{Username: "alice", BeanAmount: 100}
 	userRepo.Create(alice)
 
-	err := transferService.Transfer("alice", "nonexistent", 10, false)
+	err := transferService.Transfer("alice", "nonexistent", 10, false, "")
 	assert.Equal(t, ErrRecipientNotFound, err)
 }
 
@@ -77,7 +87,7 @@ func TestTransferService_ForceCreateRecipient(t *testing.T) {
 	alice := &models.User{Username: "alice", BeanAmount: 100}
 	userRepo.Create(alice)
 
-	err := transferService.Transfer("alice", "newuser", 10, true)
+	err := transferService.Transfer("alice", "newuser", 10, true, "")
 	assert.NoError(t, err)
 
 	newUser, _ := userRepo.FindByUsername("newuser")
@@ -96,10 +106,10 @@ func TestTransferService_InvalidAmount(t *testing.T) {
 	userRepo.Create(alice)
 	userRepo.Create(bob)
 
-	err := transferService.Transfer("alice", "bob", 0, false)
+	err := transferService.Transfer("alice", "bob", 0, false, "")
 	assert.Equal(t, ErrInvalidAmount, err)
 
-	err = transferService.Transfer("alice", "bob", -10, false)
+	err = transferService.Transfer("alice", "bob", -10, false, "")
 	assert.Equal(t, ErrInvalidAmount, err)
 }
 
@@ -109,9 +119,307 @@ func TestTransferService_SelfTransfer(t *testing.T) {
 	alice := &models.User{Username: "alice", BeanAmount: 100}
 	userRepo.Create(alice)
 
-	err := transferService.Transfer("alice", "alice", 10, false)
+	err := transferService.Transfer("alice", "alice", 10, false, "")
 	assert.Equal(t, ErrSelfTransfer, err)
 
 	aliceAfter, _ := userRepo.FindByUsername("alice")
 	assert.Equal(t, 100, aliceAfter.BeanAmount)
 }
+
+func TestTransferService_TransferWithNote(t *testing.T) {
+	userRepo, transactionRepo, transferService := setupTestDB(t)
+
+	alice := &models.User{Username: "alice", BeanAmount: 100}
+	bob := &models.User{Username: "bob", BeanAmount: 50}
+	userRepo.Create(alice)
+	userRepo.Create(bob)
+
+	err := transferService.Transfer("alice", "bob", 10, false, "  pizza\tmoney\x00  ")
+	assert.NoError(t, err)
+
+	transactions, err := transactionRepo.FindByUsername("bob")
+	assert.NoError(t, err)
+	assert.Len(t, transactions, 1)
+	assert.Equal(t, "pizza money", transactions[0].Note)
+	assert.Equal(t, models.TransactionKindTransfer, transactions[0].Kind)
+}
+
+func TestTransferService_NoteTooLong(t *testing.T) {
+	userRepo, _, transferService := setupTestDB(t)
+
+	alice := &models.User{Username: "alice", BeanAmount: 100}
+	bob := &models.User{Username: "bob", BeanAmount: 50}
+	userRepo.Create(alice)
+	userRepo.Create(bob)
+
+	err := transferService.Transfer("alice", "bob", 10, false, strings.Repeat("a", MaxNoteLength+1))
+	assert.Equal(t, ErrNoteTooLong, err)
+
+	aliceAfter, _ := userRepo.FindByUsername("alice")
+	assert.Equal(t, 100, aliceAfter.BeanAmount)
+}
+
+func TestSanitizeNote(t *testing.T) {
+	note, err := SanitizeNote("  héllo\nworld 🫘 ")
+	assert.NoError(t, err)
+	assert.Equal(t, "héllo world 🫘", note)
+
+	note, err = SanitizeNote(strings.Repeat("🫘", MaxNoteLength))
+	assert.NoError(t, err)
+	assert.Equal(t, MaxNoteLength, len([]rune(note)))
+}
+
+func TestTransferService_ConcurrentCrossTransfers(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping concurrency stress test")
+	}
+
+	// A file-backed database so that goroutines really use separate
+	// connections. SQLite takes the write lock when a transaction begins, so
+	// this only checks the bookkeeping; the Postgres variant below is the one
+	// that exercises row locks.
+	db, err := database.Connect("sqlite:" + filepath.Join(t.TempDir(), "stress.db"))
+	require.NoError(t, err)
+	runCrossTransferStress(t, db)
+}
+
+func TestTransferService_ConcurrentCrossTransfersPostgres(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping concurrency stress test")
+	}
+	testcontainers.SkipIfProviderIsNotHealthy(t)
+
+	ctx := context.Background()
+	container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        "postgres:16-alpine",
+			ExposedPorts: []string{"5432/tcp"},
+			Env: map[string]string{
+				"POSTGRES_USER":     "beanbank",
+				"POSTGRES_PASSWORD": "beanbank",
+				"POSTGRES_DB":       "beanbank",
+			},
+			// The server restarts once after initdb, so wait for it to
+			// be ready the second time.
+			WaitingFor: wait.ForLog("database system is ready to accept connections").
+				WithOccurrence(2).
+				WithStartupTimeout(2 * time.Minute),
+		},
+		Started: true,
+	})
+	testcontainers.CleanupContainer(t, container)
+	require.NoError(t, err)
+
+	host, err := container.Host(ctx)
+	require.NoError(t, err)
+	port, err := container.MappedPort(ctx, "5432/tcp")
+	require.NoError(t, err)
+
+	db, err := database.Connect(fmt.Sprintf("postgres://beanbank:beanbank@%s:%s/beanbank?sslmode=disable", host, port.Port()))
+	require.NoError(t, err)
+	runCrossTransferStress(t, db)
+}
+
+// runCrossTransferStress has workers send beans back and forth between a few
+// wallets at once, then checks that no beans were lost and the ledger still
+// reconciles. Wallets locked out of order would deadlock on Postgres and
+// fail some of the transfers.
+func runCrossTransferStress(t *testing.T, db *gorm.DB) {
+	require.NoError(t, database.Migrate(db))
+	locks := recordWalletLocks(t, db)
+
+	userRepo := repository.NewUserRepository(db)
+	transactionRepo := repository.NewTransactionRepository(db)
+	transferService := NewTransferService(userRepo, transactionRepo, db)
+	adjustmentService := NewAdjustmentService(userRepo, transactionRepo, repository.NewAdjustmentRepository(db), db)
+	ledgerService := NewLedgerService(userRepo, transactionRepo, repository.NewLedgerRepository(db), db)
+
+	const (
+		wallets       = 4
+		startBalance  = 100
+		workers       = 8
+		transfersEach = 25
+	)
+
+	names := make([]string, wallets)
+	for i := range names {
+		names[i] = fmt.Sprintf("user%d", i)
+		require.NoError(t, userRepo.Create(&models.User{Username: names[i]}))
+		_, err := adjustmentService.Adjust(AdjustmentParams{Actor: "test", Username: names[i], Delta: intPtr(startBalance), Reason: "stress test"})
+		require.NoError(t, err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*transfersEach)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < transfersEach; i++ {
+				// Neighbouring workers send in opposite directions over the
+				// same pairs of wallets.
+				from, to := names[(w+i)%wallets], names[(w+i+1)%wallets]
+				if w%2 == 1 {
+					from, to = to, from
+				}
+				err := transferService.Transfer(from, to, 1+(w+i)%5, false, "")
+				if err != nil && err != ErrInsufficientBalance {
+					errs <- err
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("transfer failed: %v", err)
+	}
+
+	total := 0
+	for _, name := range names {
+		user, err := userRepo.FindByUsername(name)
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, user.BeanAmount, 0)
+		total += user.BeanAmount
+	}
+	assert.Equal(t, wallets*startBalance, total, "beans were created or destroyed")
+
+	report, err := ledgerService.Reconcile(false)
+	require.NoError(t, err)
+	assert.True(t, report.Clean(), "ledger drift: %+v", report.Drifts)
+	locks.assertOrdered(t)
+}
+
+func TestSplitEvenly(t *testing.T) {
+	shares, err := SplitEvenly(10, 3)
+	require.NoError(t, err)
+	assert.Equal(t, []int{4, 3, 3}, shares)
+
+	shares, err = SplitEvenly(9, 3)
+	require.NoError(t, err)
+	assert.Equal(t, []int{3, 3, 3}, shares)
+
+	_, err = SplitEvenly(2, 3)
+	assert.Equal(t, ErrSplitTooSmall, err)
+}
+
+func TestTransferService_BatchTransferSplit(t *testing.T) {
+	userRepo, transactionRepo, transferService := setupTestDB(t)
+
+	userRepo.Create(&models.User{Username: "alice", BeanAmount: 100})
+	userRepo.Create(&models.User{Username: "bob", BeanAmount: 0})
+	userRepo.Create(&models.User{Username: "carol", BeanAmount: 0})
+	userRepo.Create(&models.User{Username: "dave", BeanAmount: 0})
+
+	results, err := transferService.BatchTransfer(BatchTransferParams{
+		From:       "alice",
+		Recipients: []BatchRecipient{{To: "bob"}, {To: "carol"}, {To: "dave"}},
+		Total:      10,
+		Note:       "make it rain",
+	})
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+
+	expected := map[string]int{"bob": 4, "carol": 3, "dave": 3}
+	for _, result := range results {
+		assert.Equal(t, BatchResultSent, result.Status)
+		assert.NotZero(t, result.TransactionID)
+		assert.Equal(t, expected[result.To], result.Amount)
+
+		user, _ := userRepo.FindByUsername(result.To)
+		assert.Equal(t, expected[result.To], user.BeanAmount)
+	}
+
+	alice, _ := userRepo.FindByUsername("alice")
+	assert.Equal(t, 90, alice.BeanAmount)
+
+	transactions, err := transactionRepo.FindByUsername("bob")
+	require.NoError(t, err)
+	require.Len(t, transactions, 1)
+	assert.Equal(t, "make it rain", transactions[0].Note)
+}
+
+func TestTransferService_BatchTransferAtomic(t *testing.T) {
+	userRepo, _, transferService := setupTestDB(t)
+
+	userRepo.Create(&models.User{Username: "alice", BeanAmount: 100})
+	userRepo.Create(&models.User{Username: "bob", BeanAmount: 0})
+
+	results, err := transferService.BatchTransfer(BatchTransferParams{
+		From:       "alice",
+		Recipients: []BatchRecipient{{To: "bob", Amount: 5}, {To: "ghost", Amount: 5}},
+		Mode:       BatchModeAtomic,
+	})
+	assert.Equal(t, ErrBatchAborted, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, BatchResultRolledBack, results[0].Status)
+	assert.Zero(t, results[0].TransactionID)
+	assert.Equal(t, BatchResultFailed, results[1].Status)
+	assert.Equal(t, ErrRecipientNotFound, results[1].Err)
+
+	alice, _ := userRepo.FindByUsername("alice")
+	bob, _ := userRepo.FindByUsername("bob")
+	assert.Equal(t, 100, alice.BeanAmount)
+	assert.Equal(t, 0, bob.BeanAmount)
+}
+
+func TestTransferService_BatchTransferBestEffort(t *testing.T) {
+	userRepo, _, transferService := setupTestDB(t)
+
+	userRepo.Create(&models.User{Username: "alice", BeanAmount: 12})
+	userRepo.Create(&models.User{Username: "bob", BeanAmount: 0})
+	userRepo.Create(&models.User{Username: "carol", BeanAmount: 0})
+
+	results, err := transferService.BatchTransfer(BatchTransferParams{
+		From: "alice",
+		Recipients: []BatchRecipient{
+			{To: "bob", Amount: 5},
+			{To: "alice", Amount: 1},
+			{To: "ghost", Amount: 1},
+			{To: "carol", Amount: 10},
+			{To: "dave", Amount: 7},
+		},
+		Mode:  BatchModeBestEffort,
+		Force: false,
+	})
+	require.NoError(t, err)
+	require.Len(t, results, 5)
+
+	assert.Equal(t, BatchResultSent, results[0].Status)
+	assert.Equal(t, ErrSelfTransfer, results[1].Err)
+	assert.Equal(t, ErrRecipientNotFound, results[2].Err)
+	assert.Equal(t, ErrInsufficientBalance, results[3].Err)
+	assert.Equal(t, ErrRecipientNotFound, results[4].Err)
+
+	alice, _ := userRepo.FindByUsername("alice")
+	bob, _ := userRepo.FindByUsername("bob")
+	carol, _ := userRepo.FindByUsername("carol")
+	assert.Equal(t, 7, alice.BeanAmount)
+	assert.Equal(t, 5, bob.BeanAmount)
+	assert.Equal(t, 0, carol.BeanAmount)
+}
+
+func TestTransferService_BatchTransferValidation(t *testing.T) {
+	userRepo, _, transferService := setupTestDB(t)
+	userRepo.Create(&models.User{Username: "alice", BeanAmount: 100})
+
+	tests := []struct {
+		name   string
+		params BatchTransferParams
+		err    error
+	}{
+		{"no recipients", BatchTransferParams{From: "alice"}, ErrNoRecipients},
+		{"duplicate", BatchTransferParams{From: "alice", Recipients: []BatchRecipient{{To: "bob", Amount: 1}, {To: "bob", Amount: 1}}}, ErrDuplicateRecipient},
+		{"amount and total", BatchTransferParams{From: "alice", Recipients: []BatchRecipient{{To: "bob", Amount: 1}}, Total: 5}, ErrAmountAndTotal},
+		{"split too small", BatchTransferParams{From: "alice", Recipients: []BatchRecipient{{To: "bob"}, {To: "carol"}}, Total: 1}, ErrSplitTooSmall},
+		{"bad mode", BatchTransferParams{From: "alice", Recipients: []BatchRecipient{{To: "bob", Amount: 1}}, Mode: "yolo"}, ErrInvalidBatchMode},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := transferService.BatchTransfer(tt.params)
+			assert.Equal(t, tt.err, err)
+		})
+	}
+}
@@ -1,43 +1,74 @@
 package services
 
 import (
+	"encoding/base64"
 	"errors"
+	"fmt"
+	"strconv"
 
+	"github.com/h4ks-com/bean-bank/internal/database"
 	"github.com/h4ks-com/bean-bank/internal/models"
 	"github.com/h4ks-com/bean-bank/internal/repository"
+	"gorm.io/gorm"
 )
 
 var (
-	ErrUserNotFound = errors.New("user not found")
+	ErrUserNotFound  = errors.New("user not found")
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+// SignupBonus is the number of beans minted into every new wallet.
+const SignupBonus = 1
+
+const (
+	DefaultTransactionPageSize = 50
+	MaxTransactionPageSize     = 200
 )
 
 type WalletService struct {
 	userRepo        *repository.UserRepository
 	transactionRepo *repository.TransactionRepository
+	db              *gorm.DB
 }
 
-func NewWalletService(userRepo *repository.UserRepository, transactionRepo *repository.TransactionRepository) *WalletService {
+func NewWalletService(userRepo *repository.UserRepository, transactionRepo *repository.TransactionRepository, db *gorm.DB) *WalletService {
 	return &WalletService{
 		userRepo:        userRepo,
 		transactionRepo: transactionRepo,
+		db:              db,
 	}
 }
 
+// GetOrCreateWallet returns the user's wallet, creating it with the signup
+// bonus on first use. The wallet and its bonus are created together, so a
+// wallet never exists without its bonus.
 func (s *WalletService) GetOrCreateWallet(username string) (*models.User, error) {
 	user, err := s.userRepo.FindByUsername(username)
-	if err != nil {
-		return nil, err
+	if err != nil || user != nil {
+		return user, err
 	}
 
-	if user == nil {
-		user = &models.User{
-			Username:   username,
-			BeanAmount: 1,
+	err = database.Transaction(s.db, func(tx *gorm.DB) error {
+		if err := s.userRepo.CreateInTx(tx, &models.User{Username: username}); err != nil {
+			return err
 		}
-		err = s.userRepo.Create(user)
+		users, err := s.userRepo.LockUsers(tx, username, models.MintUsername)
 		if err != nil {
-			return nil, err
+			return err
 		}
+		user = users[username]
+		return s.transactionRepo.Post(tx, users[models.MintUsername], user, &models.Transaction{
+			Amount: SignupBonus,
+			Note:   "Welcome bonus",
+			Kind:   models.TransactionKindSignupBonus,
+		})
+	})
+	if err != nil {
+		// Another request may have created the wallet in the meantime.
+		if existing, findErr := s.userRepo.FindByUsername(username); findErr == nil && existing != nil {
+			return existing, nil
+		}
+		return nil, err
 	}
 
 	return user, nil
@@ -54,25 +85,100 @@ func (s *WalletService) GetBalance(username string) (int, error) {
 	return user.BeanAmount, nil
 }
 
-func (s *WalletService) GetTransactionHistory(username string) ([]models.Transaction, error) {
-	return s.transactionRepo.FindByUsername(username)
+// GetTransactionPage returns one page of transactions matching the filter and
+// the cursor for the next page, which is empty on the last page. The cursor is
+// the opaque form of filter.BeforeID.
+func (s *WalletService) GetTransactionPage(filter repository.TransactionFilter, cursor string) ([]models.Transaction, string, error) {
+	if cursor != "" {
+		beforeID, err := DecodeTransactionCursor(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		filter.BeforeID = beforeID
+	}
+
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultTransactionPageSize
+	}
+	if filter.Limit > MaxTransactionPageSize {
+		filter.Limit = MaxTransactionPageSize
+	}
+
+	limit := filter.Limit
+	filter.Limit = limit + 1
+
+	transactions, err := s.transactionRepo.FindPage(filter)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if len(transactions) <= limit {
+		return transactions, "", nil
+	}
+
+	transactions = transactions[:limit]
+	return transactions, EncodeTransactionCursor(transactions[limit-1].ID), nil
+}
+
+func EncodeTransactionCursor(id uint) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatUint(uint64(id), 10)))
+}
+
+func DecodeTransactionCursor(cursor string) (uint, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+	id, err := strconv.ParseUint(string(raw), 10, 64)
+	if err != nil || id == 0 {
+		return 0, ErrInvalidCursor
+	}
+	return uint(id), nil
 }
 
 func (s *WalletService) GetTotalBeans() (int64, error) {
 	return s.userRepo.GetTotalBeans()
 }
 
-func (s *WalletService) UpdateBalance(username string, newAmount int) error {
-	user, err := s.userRepo.FindByUsername(username)
-	if err != nil {
-		return err
-	}
-	if user == nil {
-		return ErrUserNotFound
+// SetBalance overwrites a wallet balance. The difference is minted or burned
+// through the mint account so the change is posted to the ledger. It is used by
+// the importer; admin changes go through AdjustmentService so they are audited.
+func (s *WalletService) SetBalance(username string, newAmount int, kind models.TransactionKind, note string) error {
+	if models.IsReservedUsername(username) {
+		return ErrReservedAccount
 	}
 
-	user.BeanAmount = newAmount
-	return s.userRepo.Update(user)
+	return database.Transaction(s.db, func(tx *gorm.DB) error {
+		users, err := s.userRepo.LockUsers(tx, username, models.MintUsername)
+		if err != nil {
+			return err
+		}
+
+		user, mintUser := users[username], users[models.MintUsername]
+		if user == nil {
+			return ErrUserNotFound
+		}
+
+		delta := newAmount - user.BeanAmount
+		if delta == 0 {
+			return nil
+		}
+
+		if note == "" {
+			note = fmt.Sprintf("Balance changed from %d to %d", user.BeanAmount, newAmount)
+		}
+
+		transaction := &models.Transaction{
+			Amount: delta,
+			Note:   note,
+			Kind:   kind,
+		}
+		if delta < 0 {
+			transaction.Amount = -delta
+			return s.transactionRepo.Post(tx, user, mintUser, transaction)
+		}
+		return s.transactionRepo.Post(tx, mintUser, user, transaction)
+	})
 }
 
 func (s *WalletService) GetTopWallets(limit int) ([]models.User, error) {
@@ -2,6 +2,7 @@ package services
 
 import (
 	"testing"
+	"time"
 
 	"github.com/h4ks-com/bean-bank/internal/database"
 	"github.com/h4ks-com/bean-bank/internal/models"
@@ -10,6 +11,11 @@ import (
 )
 
 func setupWalletTestDB(t *testing.T) (*repository.UserRepository, *WalletService) {
+	userRepo, _, walletService := setupWalletTestDBWithTransactions(t)
+	return userRepo, walletService
+}
+
+func setupWalletTestDBWithTransactions(t *testing.T) (*repository.UserRepository, *repository.TransactionRepository, *WalletService) {
 	db, err := database.Connect(":memory:")
 	assert.NoError(t, err)
 
@@ -18,9 +24,9 @@ func setupWalletTestDB(t *testing.T) (*repository.UserRepository, *WalletService
 
 	userRepo := repository.NewUserRepository(db)
 	transactionRepo := repository.NewTransactionRepository(db)
-	walletService := NewWalletService(userRepo, transactionRepo)
+	walletService := NewWalletService(userRepo, transactionRepo, db)
 
-	return userRepo, walletService
+	return userRepo, transactionRepo, walletService
 }
 
 func TestWalletService_GetOrCreateWallet_NewUser(t *testing.T) {
@@ -33,6 +39,27 @@ func TestWalletService_GetOrCreateWallet_NewUser(t *testing.T) {
 	assert.Equal(t, 1, user.BeanAmount)
 }
 
+func TestWalletService_GetOrCreateWallet_BonusFailureLeavesNoWallet(t *testing.T) {
+	userRepo, walletService := setupWalletTestDB(t)
+
+	// An unflagged wallet squatting on the mint name makes the bonus fail.
+	squatter := &models.User{Username: models.MintUsername}
+	assert.NoError(t, walletService.db.Create(squatter).Error)
+
+	_, err := walletService.GetOrCreateWallet("alice")
+	assert.Error(t, err)
+	user, err := userRepo.FindByUsername("alice")
+	assert.NoError(t, err)
+	assert.Nil(t, user, "the wallet must not exist without its bonus")
+
+	assert.NoError(t, walletService.db.Model(squatter).Update("reserved", true).Error)
+	user, err = walletService.GetOrCreateWallet("alice")
+	assert.NoError(t, err)
+	if assert.NotNil(t, user) {
+		assert.Equal(t, SignupBonus, user.BeanAmount)
+	}
+}
+
 func TestWalletService_GetOrCreateWallet_ExistingUser(t *testing.T) {
 	userRepo, walletService := setupWalletTestDB(t)
 
@@ -64,19 +91,38 @@ func TestWalletService_GetBalance_UserNotFound(t *testing.T) {
 	assert.Equal(t, ErrUserNotFound, err)
 }
 
-func TestWalletService_UpdateBalance(t *testing.T) {
+func TestWalletService_SetBalance(t *testing.T) {
 	userRepo, walletService := setupWalletTestDB(t)
 
 	user := &models.User{Username: "alice", BeanAmount: 100}
 	userRepo.Create(user)
 
-	err := walletService.UpdateBalance("alice", 200)
+	err := walletService.SetBalance("alice", 200, models.TransactionKindImport, "")
 	assert.NoError(t, err)
 
 	balance, _ := walletService.GetBalance("alice")
 	assert.Equal(t, 200, balance)
 }
 
+func TestWalletService_SetBalanceImport(t *testing.T) {
+	userRepo, transactionRepo, walletService := setupWalletTestDBWithTransactions(t)
+
+	userRepo.Create(&models.User{Username: "alice", BeanAmount: 1})
+
+	assert.NoError(t, walletService.SetBalance("alice", 500, models.TransactionKindImport, "Imported from wallets.json"))
+
+	transactions, err := transactionRepo.FindByUsername("alice")
+	assert.NoError(t, err)
+	assert.Len(t, transactions, 1)
+	assert.Equal(t, models.TransactionKindImport, transactions[0].Kind)
+	assert.Equal(t, 499, transactions[0].Amount)
+	assert.Equal(t, "Imported from wallets.json", transactions[0].Note)
+
+	_, err = walletService.GetBalance("alice")
+	assert.NoError(t, err)
+	assert.Equal(t, ErrUserNotFound, walletService.SetBalance("ghost", 5, models.TransactionKindImport, ""))
+}
+
 func TestWalletService_GetTotalBeans(t *testing.T) {
 	userRepo, walletService := setupWalletTestDB(t)
 
@@ -88,3 +134,103 @@ func TestWalletService_GetTotalBeans(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, int64(225), total)
 }
+
+func TestWalletService_GetTransactionPage(t *testing.T) {
+	userRepo, transactionRepo, walletService := setupWalletTestDBWithTransactions(t)
+	transferService := NewTransferService(userRepo, transactionRepo, walletService.db)
+
+	userRepo.Create(&models.User{Username: "alice", BeanAmount: 1000})
+	userRepo.Create(&models.User{Username: "bob", BeanAmount: 1000})
+	userRepo.Create(&models.User{Username: "carol", BeanAmount: 1000})
+
+	for i := 1; i <= 5; i++ {
+		assert.NoError(t, transferService.Transfer("alice", "bob", i, false, "coffee"))
+	}
+	assert.NoError(t, transferService.Transfer("carol", "alice", 50, false, "rent share"))
+	assert.NoError(t, transferService.Transfer("alice", "carol", 7, false, "100%_legit"))
+
+	t.Run("pages are stable and complete", func(t *testing.T) {
+		var seen []uint
+		cursor := ""
+		for {
+			page, next, err := walletService.GetTransactionPage(repository.TransactionFilter{Username: "alice", Limit: 3}, cursor)
+			assert.NoError(t, err)
+			assert.LessOrEqual(t, len(page), 3)
+			for _, tx := range page {
+				seen = append(seen, tx.ID)
+			}
+			if next == "" {
+				break
+			}
+			cursor = next
+		}
+		assert.Len(t, seen, 7)
+		for i := 1; i < len(seen); i++ {
+			assert.Greater(t, seen[i-1], seen[i], "transactions should be newest first without repeats")
+		}
+	})
+
+	t.Run("filters", func(t *testing.T) {
+		minAmount, maxAmount := 2, 4
+		tests := []struct {
+			name   string
+			filter repository.TransactionFilter
+			count  int
+		}{
+			{"incoming", repository.TransactionFilter{Username: "alice", Direction: repository.TransactionDirectionIn}, 1},
+			{"outgoing", repository.TransactionFilter{Username: "alice", Direction: repository.TransactionDirectionOut}, 6},
+			{"counterparty", repository.TransactionFilter{Username: "alice", Counterparty: "carol"}, 2},
+			{"amount range", repository.TransactionFilter{Username: "alice", MinAmount: &minAmount, MaxAmount: &maxAmount}, 3},
+			{"note text", repository.TransactionFilter{Username: "alice", NoteQuery: "RENT"}, 1},
+			{"like wildcards are literal", repository.TransactionFilter{Username: "alice", NoteQuery: "%_"}, 1},
+			{"kind", repository.TransactionFilter{Username: "alice", Kinds: []models.TransactionKind{models.TransactionKindGiftRedeem}}, 0},
+			{"all users", repository.TransactionFilter{Counterparty: "bob"}, 5},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				page, next, err := walletService.GetTransactionPage(tt.filter, "")
+				assert.NoError(t, err)
+				assert.Len(t, page, tt.count)
+				assert.Empty(t, next)
+			})
+		}
+	})
+
+	t.Run("date range", func(t *testing.T) {
+		future := time.Now().Add(time.Hour)
+		page, _, err := walletService.GetTransactionPage(repository.TransactionFilter{Username: "alice", Since: &future}, "")
+		assert.NoError(t, err)
+		assert.Empty(t, page)
+
+		page, _, err = walletService.GetTransactionPage(repository.TransactionFilter{Username: "alice", Until: &future}, "")
+		assert.NoError(t, err)
+		assert.Len(t, page, 7)
+	})
+
+	t.Run("inclusive upper bound", func(t *testing.T) {
+		page, _, err := walletService.GetTransactionPage(repository.TransactionFilter{Username: "alice", Limit: 1}, "")
+		assert.NoError(t, err)
+		if !assert.Len(t, page, 1) {
+			return
+		}
+		newest := page[0]
+
+		page, _, err = walletService.GetTransactionPage(repository.TransactionFilter{Username: "alice", Until: &newest.Timestamp}, "")
+		assert.NoError(t, err)
+		assert.Len(t, page, 6)
+		for _, tx := range page {
+			assert.NotEqual(t, newest.ID, tx.ID)
+		}
+
+		page, _, err = walletService.GetTransactionPage(repository.TransactionFilter{Username: "alice", Until: &newest.Timestamp, UntilInclusive: true}, "")
+		assert.NoError(t, err)
+		assert.Len(t, page, 7)
+		assert.Equal(t, newest.ID, page[0].ID)
+	})
+
+	t.Run("invalid cursor", func(t *testing.T) {
+		_, _, err := walletService.GetTransactionPage(repository.TransactionFilter{Username: "alice"}, "not-a-cursor!")
+		assert.Equal(t, ErrInvalidCursor, err)
+	})
+}
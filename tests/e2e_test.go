@@ -315,6 +315,63 @@ func TestE2E_TransferWithForce(t *testing.T) {
 	assert.Equal(t, 1.0, newuserBalance, "newuser should have 1 bean (force-created with 0 + 1 transfer)")
 }
 
+func TestE2E_TransferIdempotency(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping E2E test")
+	}
+
+	ctx := context.Background()
+	beanBankC, err := setupBeanBank(ctx, t)
+	require.NoError(t, err)
+	testcontainers.CleanupContainer(t, beanBankC)
+
+	ensureWalletExists(t, beanBankC.URI, "retrybot")
+	ensureWalletExists(t, beanBankC.URI, "retrytarget")
+
+	adminReq, err := http.NewRequest(http.MethodPut, beanBankC.URI+"/api/v1/admin/wallet/retrybot", strings.NewReader(`{"bean_amount": 100, "reason": "test funding"}`))
+	require.NoError(t, err)
+	adminReq.Header.Set("Content-Type", "application/json")
+	adminReq.Header.Set("X-Test-Username", "admin")
+
+	adminResp, err := http.DefaultClient.Do(adminReq)
+	require.NoError(t, err)
+	adminResp.Body.Close()
+
+	sendTransfer := func(body string) *http.Response {
+		req, err := http.NewRequest(http.MethodPost, beanBankC.URI+"/api/v1/transfer", strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Test-Username", "retrybot")
+		req.Header.Set("Idempotency-Key", "rain-1")
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		return resp
+	}
+
+	resp := sendTransfer(`{"to_user": "retrytarget", "amount": 10}`)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	t.Run("retry_replays_without_moving_beans", func(t *testing.T) {
+		resp := sendTransfer(`{"to_user": "retrytarget", "amount": 10}`)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "true", resp.Header.Get("Idempotent-Replayed"))
+
+		wallet := getWalletTestMode(t, beanBankC.URI, "retrybot")
+		assert.Equal(t, 90.0, wallet["bean_amount"].(float64), "beans should only be sent once")
+	})
+
+	t.Run("reused_key_with_different_body_conflicts", func(t *testing.T) {
+		resp := sendTransfer(`{"to_user": "retrytarget", "amount": 20}`)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	})
+}
+
 func TestE2E_GetTransactions(t *testing.T) {
 	if testing.Short() {
 		t.Skip("skipping E2E test")
@@ -351,9 +408,13 @@ func TestE2E_GetTransactions(t *testing.T) {
 	body, err := io.ReadAll(resp.Body)
 	require.NoError(t, err)
 
-	var transactions []map[string]interface{}
-	err = json.Unmarshal(body, &transactions)
+	var page struct {
+		Transactions []map[string]interface{} `json:"transactions"`
+		NextCursor   *string                  `json:"next_cursor"`
+	}
+	err = json.Unmarshal(body, &page)
 	require.NoError(t, err)
+	transactions := page.Transactions
 
 	assert.Greater(t, len(transactions), 0, "should have at least one transaction")
 
@@ -361,6 +422,22 @@ func TestE2E_GetTransactions(t *testing.T) {
 	assert.Equal(t, "dave", lastTx["from_user"].(string))
 	assert.Equal(t, "eve", lastTx["to_user"].(string))
 	assert.Equal(t, 1.0, lastTx["amount"].(float64))
+	assert.Equal(t, "transfer", lastTx["kind"].(string))
+
+	req, err = http.NewRequest(http.MethodGet, beanBankC.URI+"/api/v1/transactions?limit=1&direction=out&counterparty=eve", nil)
+	require.NoError(t, err)
+	req.Header.Set("X-Test-Username", "dave")
+
+	filteredResp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer filteredResp.Body.Close()
+	require.Equal(t, http.StatusOK, filteredResp.StatusCode)
+
+	err = json.NewDecoder(filteredResp.Body).Decode(&page)
+	require.NoError(t, err)
+	require.Len(t, page.Transactions, 1)
+	assert.Equal(t, "eve", page.Transactions[0]["to_user"].(string))
+	assert.Nil(t, page.NextCursor, "dave sent only one transfer to eve")
 }
 
 func createToken(t *testing.T, baseURL, username string) string {
@@ -512,11 +589,13 @@ func TestE2E_TokenAuthentication(t *testing.T) {
 		body, err := io.ReadAll(resp.Body)
 		require.NoError(t, err)
 
-		var transactions []map[string]interface{}
-		err = json.Unmarshal(body, &transactions)
+		var page struct {
+			Transactions []map[string]interface{} `json:"transactions"`
+		}
+		err = json.Unmarshal(body, &page)
 		require.NoError(t, err)
 
-		assert.Greater(t, len(transactions), 0, "should have at least one transaction")
+		assert.Greater(t, len(page.Transactions), 0, "should have at least one transaction")
 	})
 }
 
@@ -669,7 +748,7 @@ func TestE2E_TransactionExportAndVerify(t *testing.T) {
 	ensureWalletExists(t, beanBankC.URI, "receiver1")
 	ensureWalletExists(t, beanBankC.URI, "receiver2")
 
-	adminReq, err := http.NewRequest(http.MethodPut, beanBankC.URI+"/api/v1/admin/wallet/exporter", strings.NewReader(`{"bean_amount": 100}`))
+	adminReq, err := http.NewRequest(http.MethodPut, beanBankC.URI+"/api/v1/admin/wallet/exporter", strings.NewReader(`{"bean_amount": 100, "reason": "test funding"}`))
 	require.NoError(t, err)
 	adminReq.Header.Set("Content-Type", "application/json")
 	adminReq.Header.Set("X-Test-Username", "admin")
@@ -780,7 +859,7 @@ func TestE2E_TransactionExportTampering(t *testing.T) {
 	ensureWalletExists(t, beanBankC.URI, "tampertest")
 	ensureWalletExists(t, beanBankC.URI, "tamperreceiver")
 
-	adminReq, err := http.NewRequest(http.MethodPut, beanBankC.URI+"/api/v1/admin/wallet/tampertest", strings.NewReader(`{"bean_amount": 100}`))
+	adminReq, err := http.NewRequest(http.MethodPut, beanBankC.URI+"/api/v1/admin/wallet/tampertest", strings.NewReader(`{"bean_amount": 100, "reason": "test funding"}`))
 	require.NoError(t, err)
 	adminReq.Header.Set("Content-Type", "application/json")
 	adminReq.Header.Set("X-Test-Username", "admin")
@@ -984,7 +1063,7 @@ func TestE2E_HarvestCompleteWorkflow(t *testing.T) {
 
 	t.Run("complete_harvest_workflow", func(t *testing.T) {
 		harvestUser := "harvest_user"
-		
+
 		// Step 1: Create a harvest as admin
 		harvestData := map[string]interface{}{
 			"title":       "Test Harvest Task",
@@ -1011,7 +1090,7 @@ func TestE2E_HarvestCompleteWorkflow(t *testing.T) {
 		var harvestResponse map[string]interface{}
 		err = json.Unmarshal(body, &harvestResponse)
 		require.NoError(t, err)
-		
+
 		harvestID := int(harvestResponse["id"].(float64))
 		t.Logf("Created harvest with ID: %d", harvestID)
 
@@ -1154,7 +1233,7 @@ func TestE2E_GiftLinkCreateAndList(t *testing.T) {
 
 	ensureWalletExists(t, beanBankC.URI, "giftcreator")
 
-	adminReq, err := http.NewRequest(http.MethodPut, beanBankC.URI+"/api/v1/admin/wallet/giftcreator", strings.NewReader(`{"bean_amount": 100}`))
+	adminReq, err := http.NewRequest(http.MethodPut, beanBankC.URI+"/api/v1/admin/wallet/giftcreator", strings.NewReader(`{"bean_amount": 100, "reason": "test funding"}`))
 	require.NoError(t, err)
 	adminReq.Header.Set("Content-Type", "application/json")
 	adminReq.Header.Set("X-Test-Username", "admin")
@@ -1234,7 +1313,7 @@ func TestE2E_GiftLinkRedeem(t *testing.T) {
 	ensureWalletExists(t, beanBankC.URI, "giftsender")
 	ensureWalletExists(t, beanBankC.URI, "giftreceiver")
 
-	adminReq, err := http.NewRequest(http.MethodPut, beanBankC.URI+"/api/v1/admin/wallet/giftsender", strings.NewReader(`{"bean_amount": 100}`))
+	adminReq, err := http.NewRequest(http.MethodPut, beanBankC.URI+"/api/v1/admin/wallet/giftsender", strings.NewReader(`{"bean_amount": 100, "reason": "test funding"}`))
 	require.NoError(t, err)
 	adminReq.Header.Set("Content-Type", "application/json")
 	adminReq.Header.Set("X-Test-Username", "admin")
@@ -1341,7 +1420,7 @@ func TestE2E_GiftLinkDelete(t *testing.T) {
 
 	ensureWalletExists(t, beanBankC.URI, "giftdeleter")
 
-	adminReq, err := http.NewRequest(http.MethodPut, beanBankC.URI+"/api/v1/admin/wallet/giftdeleter", strings.NewReader(`{"bean_amount": 100}`))
+	adminReq, err := http.NewRequest(http.MethodPut, beanBankC.URI+"/api/v1/admin/wallet/giftdeleter", strings.NewReader(`{"bean_amount": 100, "reason": "test funding"}`))
 	require.NoError(t, err)
 	adminReq.Header.Set("Content-Type", "application/json")
 	adminReq.Header.Set("X-Test-Username", "admin")
@@ -1410,7 +1489,7 @@ func TestE2E_GiftLinkEdgeCases(t *testing.T) {
 
 	ensureWalletExists(t, beanBankC.URI, "edgecaseuser")
 
-	adminReq, err := http.NewRequest(http.MethodPut, beanBankC.URI+"/api/v1/admin/wallet/edgecaseuser", strings.NewReader(`{"bean_amount": 100}`))
+	adminReq, err := http.NewRequest(http.MethodPut, beanBankC.URI+"/api/v1/admin/wallet/edgecaseuser", strings.NewReader(`{"bean_amount": 100, "reason": "test funding"}`))
 	require.NoError(t, err)
 	adminReq.Header.Set("Content-Type", "application/json")
 	adminReq.Header.Set("X-Test-Username", "admin")
@@ -1494,3 +1573,99 @@ func TestE2E_GiftLinkEdgeCases(t *testing.T) {
 		assert.Equal(t, http.StatusNotFound, resp.StatusCode, "should return 404 for nonexistent gift link")
 	})
 }
+
+func TestE2E_PaymentRequestPay(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping E2E test")
+	}
+
+	ctx := context.Background()
+	beanBankC, err := setupBeanBank(ctx, t)
+	require.NoError(t, err)
+	testcontainers.CleanupContainer(t, beanBankC)
+
+	ensureWalletExists(t, beanBankC.URI, "invoicer")
+	ensureWalletExists(t, beanBankC.URI, "invoicee")
+
+	adminReq, err := http.NewRequest(http.MethodPut, beanBankC.URI+"/api/v1/admin/wallet/invoicee", strings.NewReader(`{"bean_amount": 100, "reason": "test funding"}`))
+	require.NoError(t, err)
+	adminReq.Header.Set("Content-Type", "application/json")
+	adminReq.Header.Set("X-Test-Username", "admin")
+
+	adminResp, err := http.DefaultClient.Do(adminReq)
+	require.NoError(t, err)
+	adminResp.Body.Close()
+
+	req, err := http.NewRequest(http.MethodPost, beanBankC.URI+"/api/v1/paymentrequests", strings.NewReader(`{"payer": "invoicee", "amount": 25, "memo": "lunch"}`))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Test-Username", "invoicer")
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+
+	var paymentRequest map[string]interface{}
+	require.NoError(t, json.Unmarshal(body, &paymentRequest))
+	assert.Equal(t, "pending", paymentRequest["status"])
+	requestCode := paymentRequest["code"].(string)
+
+	t.Run("payer_sees_incoming_request", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, beanBankC.URI+"/api/v1/paymentrequests?direction=incoming", nil)
+		require.NoError(t, err)
+		req.Header.Set("X-Test-Username", "invoicee")
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var requests []map[string]interface{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&requests))
+		require.Len(t, requests, 1)
+		assert.Equal(t, "invoicer", requests[0]["requester"])
+	})
+
+	t.Run("pay_request", func(t *testing.T) {
+		payJSON, err := json.Marshal(map[string]string{"code": requestCode})
+		require.NoError(t, err)
+
+		req, err := http.NewRequest(http.MethodPost, beanBankC.URI+"/api/v1/paymentrequests/pay", strings.NewReader(string(payJSON)))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Test-Username", "invoicee")
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var paid map[string]interface{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&paid))
+		assert.Equal(t, "paid", paid["status"])
+		assert.Equal(t, "invoicee", paid["paid_by"])
+		assert.NotNil(t, paid["transaction_id"])
+
+		payerWallet := getWalletTestMode(t, beanBankC.URI, "invoicee")
+		assert.Equal(t, 75, int(payerWallet["bean_amount"].(float64)))
+	})
+
+	t.Run("cannot_pay_twice", func(t *testing.T) {
+		payJSON, err := json.Marshal(map[string]string{"code": requestCode})
+		require.NoError(t, err)
+
+		req, err := http.NewRequest(http.MethodPost, beanBankC.URI+"/api/v1/paymentrequests/pay", strings.NewReader(string(payJSON)))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Test-Username", "invoicee")
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	})
+}